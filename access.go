@@ -0,0 +1,119 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SetAccessRecording enables the recording of the configuration fields read with the
+// accessors, Get and Read, so that UnreadFields reports the options nobody uses.
+// Disabling it clears the recorded reads. Disabled by default.
+func SetAccessRecording(enabled bool) { gf.SetAccessRecording(enabled) }
+
+// SetAccessRecording enables the recording of the configuration fields read with the
+// accessors, Get and Read, so that UnreadFields reports the options nobody uses.
+// Disabling it clears the recorded reads. Disabled by default.
+func (gf *Gofig) SetAccessRecording(enabled bool) {
+	gf.readsMu.Lock()
+	defer gf.readsMu.Unlock()
+	if !enabled {
+		gf.reads = nil
+	} else if gf.reads == nil {
+		gf.reads = make(map[string]struct{})
+	}
+}
+
+// Get returns the value of the parsed configuration field at the dot-separated key path
+// (which follows the json tags, e.g. "sub.str"), recording the read.
+func Get(key string) (interface{}, error) { return gf.Get(key) }
+
+// Get returns the value of the parsed configuration field at the dot-separated key path
+// (which follows the json tags, e.g. "sub.str"), recording the read.
+func (gf *Gofig) Get(key string) (interface{}, error) {
+	key = strings.ToLower(key)
+	gf.mu.Lock()
+	defer gf.mu.Unlock()
+	if gf.target == nil {
+		return nil, errNotParsed
+	}
+	f, ok := fieldByKey(gf.target, key)
+	if !ok {
+		return nil, fmt.Errorf("unknown key '%v'", key)
+	}
+	gf.recordRead(key)
+	return f.Interface(), nil
+}
+
+// Read returns the value of the parsed configuration field pointed to by fieldPtr,
+// recording the read if the access recording of gf (the package-level instance if nil)
+// is enabled, e.g. port := gofig.Read(gf, &cfg.DB.Port).
+func Read[T any](gf *Gofig, fieldPtr *T) T {
+	if gf == nil {
+		gf = defaultGofig()
+	}
+	if gf.recording() {
+		if path, err := gf.pathFor(fieldPtr, "json"); err == nil {
+			gf.recordRead(strings.Join(path, "."))
+		}
+	}
+	return *fieldPtr
+}
+
+// UnreadFields returns the sorted key paths of the parsed configuration fields which
+// weren't read with the accessors since the access recording was enabled, or nil if it
+// isn't.
+func UnreadFields() []string { return gf.UnreadFields() }
+
+// UnreadFields returns the sorted key paths of the parsed configuration fields which
+// weren't read with the accessors since the access recording was enabled, or nil if it
+// isn't.
+func (gf *Gofig) UnreadFields() []string {
+	gf.mu.Lock()
+	target := gf.target
+	gf.mu.Unlock()
+	gf.readsMu.Lock()
+	defer gf.readsMu.Unlock()
+	if gf.reads == nil || target == nil {
+		return nil
+	}
+
+	unread := []string{}
+	_ = parseStruct(target, func(path []string, name string, f *reflect.Value, tags *reflect.StructTag) error {
+		key := strings.Join(path, ".")
+		if _, ok := gf.reads[key]; !ok {
+			unread = append(unread, key)
+		}
+		return nil
+	}, "json")
+	sort.Strings(unread)
+	return unread
+}
+
+// recording returns whether the access recording is enabled.
+func (gf *Gofig) recording() bool {
+	gf.readsMu.Lock()
+	defer gf.readsMu.Unlock()
+	return gf.reads != nil
+}
+
+// recordRead records the read of the field at the key path, if the access recording is
+// enabled.
+func (gf *Gofig) recordRead(key string) {
+	gf.readsMu.Lock()
+	defer gf.readsMu.Unlock()
+	if gf.reads != nil {
+		gf.reads[key] = struct{}{}
+	}
+}
+
+// defaultGofig returns the package-level instance.
+func defaultGofig() *Gofig {
+	return gf
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessRecording(t *testing.T) {
+	s := buildTestStruct()
+	gf := New(ContinueOnError)
+	err := gf.ParseWithArgs(s, []string{"-int", "3"})
+	assert.NoError(t, err)
+
+	// Case 1: not recorded
+	assert.Equal(t, 3, Read(gf, &s.Int))
+	assert.Nil(t, gf.UnreadFields())
+
+	// Case 2: reads recorded by the accessors
+	gf.SetAccessRecording(true)
+	assert.Equal(t, 3, Read(gf, &s.Int))
+	assert.Equal(t, "renamed-user-defined", Read(gf, &s.Sub.RenamedStr))
+	val, err := gf.Get("Bool")
+	assert.NoError(t, err)
+	assert.Equal(t, false, val)
+	_, err = gf.Get("missing")
+	assert.EqualError(t, err, "unknown key 'missing'")
+	assert.Equal(t, []string{"duration", "float", "int64", "str", "uint", "uint64"}, gf.UnreadFields())
+
+	// Case 3: the reads are kept across reloads
+	assert.NoError(t, gf.Override("str", "x"))
+	assert.Equal(t, "x", Read(gf, &s.Str))
+	assert.Equal(t, []string{"duration", "float", "int64", "uint", "uint64"}, gf.UnreadFields())
+
+	// Case 4: cleared
+	gf.SetAccessRecording(false)
+	gf.SetAccessRecording(true)
+	assert.Len(t, gf.UnreadFields(), 9)
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"reflect"
+	"strings"
+)
+
+// ParseErrors are the errors of a parse collected when SetAggregateErrors is enabled,
+// in the order of the parse stages. Unwrap returns them, so that errors.Is and
+// errors.As (Go 1.20+) check each of them.
+type ParseErrors []error
+
+func (e ParseErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (e ParseErrors) Unwrap() []error {
+	return e
+}
+
+// SetAggregateErrors makes the parse functions collect the errors of all the parse
+// stages (building the flags, decoding the config documents, the env variables and the
+// flags, and the validation) instead of failing on the first one, so that they can all
+// be fixed in one pass. Several errors are returned as ParseErrors. The help flag is
+// still reported alone. Disabled by default.
+func SetAggregateErrors(enabled bool) { gf.SetAggregateErrors(enabled) }
+
+// SetAggregateErrors makes the parse functions collect the errors of all the parse
+// stages (building the flags, decoding the config documents, the env variables and the
+// flags, and the validation) instead of failing on the first one, so that they can all
+// be fixed in one pass. Several errors are returned as ParseErrors. The help flag is
+// still reported alone. Disabled by default.
+func (gf *Gofig) SetAggregateErrors(enabled bool) {
+	gf.aggregate = enabled
+}
+
+// errorList collects the errors of a parse.
+type errorList struct {
+	aggregate bool
+	errs      ParseErrors
+}
+
+// add adds err to the list, returning whether the parse must stop, i.e. on any error
+// unless the errors are aggregated.
+func (l *errorList) add(err error) bool {
+	if err == nil {
+		return false
+	}
+	l.errs = append(l.errs, err)
+	return !l.aggregate
+}
+
+// err returns the single error of the list, or all of them as ParseErrors.
+func (l *errorList) err() error {
+	switch len(l.errs) {
+	case 0:
+		return nil
+	case 1:
+		return l.errs[0]
+	}
+	return l.errs
+}
+
+// collect returns a fieldParser adding the errors of parser, wrapped with wrap if not nil,
+// to the list instead of stopping the walk, if the errors are aggregated.
+func (l *errorList) collect(parser fieldParser, wrap func(err error) error) fieldParser {
+	if !l.aggregate {
+		return parser
+	}
+	return func(path []string, name string, val *reflect.Value, tags *reflect.StructTag) error {
+		err := parser(path, name, val, tags)
+		if err != nil && wrap != nil {
+			err = wrap(err)
+		}
+		l.add(err)
+		return nil
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type aggregateStruct struct {
+	Port    int    `json:"port"`
+	Workers int    `json:"workers"`
+	Token   string `json:"token" required:"true"`
+	Env     string `json:"env" enum:"dev,prod"`
+	Level   int    `json:"level" max:"3"`
+}
+
+func TestAggregateErrors(t *testing.T) {
+	os.Setenv("GFA_PORT", "http")
+	os.Setenv("GFA_WORKERS", "many")
+	defer os.Unsetenv("GFA_PORT")
+	defer os.Unsetenv("GFA_WORKERS")
+	args := []string{"-env", "test", "-level", "5"}
+
+	// Case 1: fail fast by default
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("GFA")
+	err := gf.ParseWithArgs(&aggregateStruct{}, args)
+	assert.EqualError(t, err, "error parsing environment variable 'GFA_PORT' with value 'http' into int (flag -port, env GFA_PORT, key port)")
+
+	// Case 2: all the errors of the stages
+	gf = New(ContinueOnError)
+	gf.SetEnvPrefix("GFA")
+	gf.SetAggregateErrors(true)
+	err = gf.ParseWithArgs(&aggregateStruct{}, args)
+	var errs ParseErrors
+	if assert.True(t, errors.As(err, &errs)) {
+		assert.Len(t, errs, 5)
+	}
+	assert.Equal(t, strings.Join([]string{
+		"error parsing environment variable 'GFA_PORT' with value 'http' into int (flag -port, env GFA_PORT, key port)",
+		"error parsing environment variable 'GFA_WORKERS' with value 'many' into int (flag -workers, env GFA_WORKERS, key workers)",
+		"missing required field Token (flag -token, env GFA_TOKEN, key token)",
+		"invalid value 'test' of field Env (flag -env, env GFA_ENV, key env), it must be one of dev, prod",
+		"invalid field Level (flag -level, env GFA_LEVEL, key level): must be at most 3",
+	}, "\n"), err.Error())
+
+	// Case 3: a single error isn't wrapped, and each error is formatted
+	gf = New(ContinueOnError)
+	gf.SetAggregateErrors(true)
+	err = gf.ParseWithArgs(&aggregateStruct{Token: "x"}, []string{"-level", "5"})
+	assert.False(t, errors.As(err, &errs))
+
+	gf = New(ContinueOnError)
+	gf.SetAggregateErrors(true)
+	gf.SetErrorFormat(ErrorsJSON)
+	err = gf.ParseWithArgs(&aggregateStruct{}, []string{"-level", "5"})
+	assert.Equal(t, `{"error":"missing required field Token (flag -token, env TOKEN, key token)"}`+"\n"+
+		`{"error":"invalid field Level (flag -level, env LEVEL, key level): must be at most 3"}`, err.Error())
+}
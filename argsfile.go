@@ -0,0 +1,81 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"os"
+	"strings"
+)
+
+// SetArgsFiles enables the arguments files: an @path argument is replaced by the
+// arguments of the file at path, for command-line length limits or to keep the flags
+// of a deployment in a file. The file holds a flag per line, e.g. -db-port=5432 or
+// -db-host localhost (the flag being split from its value at the first space), the
+// empty lines and the lines starting with # being ignored. The arguments after the --
+// terminator and the arguments of the files aren't expanded, and @@ is an escaped @.
+// The files are read when parsing, not when reloading. Disabled by default.
+func SetArgsFiles(enabled bool) { gf.SetArgsFiles(enabled) }
+
+// SetArgsFiles enables the arguments files: an @path argument is replaced by the
+// arguments of the file at path, for command-line length limits or to keep the flags
+// of a deployment in a file. The file holds a flag per line, e.g. -db-port=5432 or
+// -db-host localhost (the flag being split from its value at the first space), the
+// empty lines and the lines starting with # being ignored. The arguments after the --
+// terminator and the arguments of the files aren't expanded, and @@ is an escaped @.
+// The files are read when parsing, not when reloading. Disabled by default.
+func (gf *Gofig) SetArgsFiles(enabled bool) {
+	gf.argsFiles = enabled
+}
+
+// expandArgs replaces the @path arguments by the arguments of their file if the
+// arguments files are enabled.
+func (gf *Gofig) expandArgs(args []string) ([]string, error) {
+	if !gf.argsFiles {
+		return args, nil
+	}
+	var expanded []string
+	for i, arg := range args {
+		switch {
+		case arg == "--":
+			return append(expanded, args[i:]...), nil
+		case strings.HasPrefix(arg, "@@"):
+			expanded = append(expanded, arg[1:])
+		case strings.HasPrefix(arg, "@") && len(arg) > 1:
+			fileArgs, err := readArgsFile(arg[1:])
+			if err != nil {
+				return nil, err
+			}
+			expanded = append(expanded, fileArgs...)
+		default:
+			expanded = append(expanded, arg)
+		}
+	}
+	return expanded, nil
+}
+
+// readArgsFile reads the arguments of an arguments file.
+func readArgsFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errorf("error reading arguments file: %v", err)
+	}
+	var args []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// -flag value, unless the value is set with -flag=value
+		if i := strings.IndexAny(line, " \t"); strings.HasPrefix(line, "-") && i >= 0 {
+			if eq := strings.IndexByte(line, '='); eq < 0 || i < eq {
+				args = append(args, line[:i], strings.TrimSpace(line[i:]))
+				continue
+			}
+		}
+		args = append(args, line)
+	}
+	return args, nil
+}
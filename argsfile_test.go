@@ -0,0 +1,51 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArgsFiles(t *testing.T) {
+	type config struct {
+		Host     string `json:"host"`
+		Port     int    `json:"port"`
+		Greeting string `json:"greeting"`
+		Debug    bool   `json:"debug"`
+	}
+	path := filepath.Join(t.TempDir(), "args.txt")
+	err := os.WriteFile(path, []byte("# deployment flags\n-host db.internal\n\n-port=5432\n  -greeting hello world  \n"), 0600)
+	assert.NoError(t, err)
+
+	// Case 1: the file arguments replace the @path argument, in place
+	s := &config{}
+	gf := New(ContinueOnError)
+	gf.SetArgsFiles(true)
+	err = gf.ParseWithArgs(s, []string{"-debug", "@" + path, "-port", "6432"})
+	assert.NoError(t, err)
+	assert.Equal(t, config{Host: "db.internal", Port: 6432, Greeting: "hello world", Debug: true}, *s)
+
+	// Case 2: escaped @ and terminator
+	args, err := gf.expandArgs([]string{"-greeting", "@@home", "--", "@" + path})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"-greeting", "@home", "--", "@" + path}, args)
+
+	// Case 3: missing file, and disabled arguments files
+	gf = New(ContinueOnError)
+	gf.SetArgsFiles(true)
+	err = gf.ParseWithArgs(&config{}, []string{"@" + path + ".missing"})
+	assert.Error(t, err)
+
+	s = &config{}
+	gf = New(ContinueOnError)
+	err = gf.ParseWithArgs(s, []string{"-greeting", "@" + path})
+	assert.NoError(t, err)
+	assert.Equal(t, "@"+path, s.Greeting)
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"os"
+)
+
+// MustParse is like Parse but panics if the configuration can't be parsed, and exits
+// once a built-in flag is handled.
+func MustParse(v interface{}) { gf.MustParse(v) }
+
+// MustParse is like Parse but panics if the configuration can't be parsed, and exits
+// once a built-in flag is handled.
+func (gf *Gofig) MustParse(v interface{}) {
+	err := gf.parse(v, os.Args[1:])
+	if err == nil {
+		err = gf.runBuiltinFlags(v)
+	}
+	err = gf.formatError(err)
+	if err == ErrHandled {
+		gf.exitHandled()
+	} else if err != nil {
+		panic(err)
+	}
+}
+
+// Builder configures and parses a configuration with a fluent API, e.g.:
+//
+//	err := gofig.NewBuilder().EnvPrefix("GF").File("default").Flags(os.Args[1:]).Parse(&cfg)
+type Builder struct {
+	o *options
+}
+
+// NewBuilder returns a Builder of a new Gofig instance with ContinueOnError, parsing os.Args[1:].
+func NewBuilder() *Builder {
+	return &Builder{o: newOptions(nil)}
+}
+
+// With applies options to the Gofig instance.
+func (b *Builder) With(opts ...Option) *Builder {
+	for _, opt := range opts {
+		opt(b.o)
+	}
+	return b
+}
+
+// EnvPrefix sets the environment variables prefix, see SetEnvPrefix.
+func (b *Builder) EnvPrefix(prefix string) *Builder { return b.With(WithEnvPrefix(prefix)) }
+
+// File adds config files, see AddConfigFile.
+func (b *Builder) File(path ...string) *Builder { return b.With(WithConfigFile(path...)) }
+
+// FileFlag adds a config file flag, see SetConfigFileFlag.
+func (b *Builder) FileFlag(name string, desc string) *Builder {
+	return b.With(WithConfigFileFlag(name, desc))
+}
+
+// Source adds a config source, see AddSource.
+func (b *Builder) Source(src Source) *Builder { return b.With(WithSource(src)) }
+
+// Flags sets the arguments to parse.
+func (b *Builder) Flags(args []string) *Builder { return b.With(WithArgs(args)) }
+
+// Gofig returns the configured Gofig instance.
+func (b *Builder) Gofig() *Gofig { return b.o.gf }
+
+// Parse parses the configuration into v.
+func (b *Builder) Parse(v interface{}) error {
+	return b.o.gf.ParseWithArgs(v, b.o.args)
+}
+
+// MustParse is like Parse but panics if the configuration can't be parsed, and exits
+// once a built-in flag is handled.
+func (b *Builder) MustParse(v interface{}) {
+	err := b.Parse(v)
+	if err == ErrHandled {
+		b.o.gf.exitHandled()
+	} else if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuilder(t *testing.T) {
+	os.Setenv("GFB_INT", "1")
+	defer os.Unsetenv("GFB_INT")
+
+	s := &TestStruct{}
+	b := NewBuilder().EnvPrefix("GFB").File("fake_file", "gofig_test_toml").Flags([]string{"-str", "flag"})
+	err := b.Parse(s)
+	assert.NoError(t, err)
+	assert.Equal(t, "flag", s.Str)
+	assert.Equal(t, 1, s.Int)
+	assert.Equal(t, "renamed-config-file", s.Sub.RenamedStr)
+	assert.NotNil(t, b.Gofig().flagSet.Lookup("str"))
+
+	assert.Panics(t, func() {
+		NewBuilder().Flags([]string{"-int", "one"}).MustParse(&TestStruct{})
+	})
+}
+
+func TestMustParse(t *testing.T) {
+	os.Setenv("GFM_INT", "one")
+	defer os.Unsetenv("GFM_INT")
+
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("GFM")
+	defer func() {
+		err, _ := recover().(error)
+		assert.EqualError(t, err, "error parsing environment variable 'GFM_INT' with value 'one' into int (flag -int, env GFM_INT, key int)")
+	}()
+	gf.MustParse(&TestStruct{})
+}
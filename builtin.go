@@ -0,0 +1,162 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// builtinFlagPrefix is the prefix of the flag names reserved for the built-in flags.
+const builtinFlagPrefix = "gofig-"
+
+// ErrHandled is returned by Parse when a built-in flag (-gofig-print-config,
+// -gofig-check-config or -gofig-completion) was handled, meaning that the application
+// should exit successfully. With ExitOnError, Parse exits with status 0 instead.
+var ErrHandled = errors.New("built-in flag handled")
+
+// builtinFlags holds the values of the built-in flags
+type builtinFlags struct {
+	disabled    bool
+	printConfig bool
+	checkConfig bool
+	completion  string
+}
+
+// SetBuiltinFlags enables (the default) or disables the built-in flags, hidden from the
+// usage message:
+//   - -gofig-print-config prints the effective configuration as YAML, secrets redacted
+//   - -gofig-check-config checks the configuration, printing the warnings and unused keys
+//   - -gofig-completion bash|zsh|fish prints a shell completion script of the flags
+//
+// The -gofig- prefix is reserved for the built-in flags, whether enabled or not.
+func SetBuiltinFlags(enabled bool) { gf.SetBuiltinFlags(enabled) }
+
+// SetBuiltinFlags enables (the default) or disables the built-in flags, hidden from the
+// usage message:
+//   - -gofig-print-config prints the effective configuration as YAML, secrets redacted
+//   - -gofig-check-config checks the configuration, printing the warnings and unused keys
+//   - -gofig-completion bash|zsh|fish prints a shell completion script of the flags
+//
+// The -gofig- prefix is reserved for the built-in flags, whether enabled or not.
+func (gf *Gofig) SetBuiltinFlags(enabled bool) {
+	gf.builtins.disabled = !enabled
+}
+
+// isBuiltinFlag returns whether a flag name is in the namespace of the built-in flags.
+func isBuiltinFlag(name string) bool {
+	return strings.HasPrefix(name, builtinFlagPrefix)
+}
+
+// declareBuiltinFlags declares the built-in flags into the flag set, once.
+func (gf *Gofig) declareBuiltinFlags() {
+	fs := gf.flagSet
+	if gf.builtins.disabled || gf.scope != nil || fs.Lookup(builtinFlagPrefix+"print-config") != nil {
+		return
+	}
+	fs.BoolVar(&gf.builtins.printConfig, builtinFlagPrefix+"print-config", false, gf.translate("print the effective configuration and exit"))
+	fs.BoolVar(&gf.builtins.checkConfig, builtinFlagPrefix+"check-config", false, gf.translate("check the configuration and exit"))
+	fs.StringVar(&gf.builtins.completion, builtinFlagPrefix+"completion", "", gf.translate("print the `shell` (bash, zsh or fish) completion script and exit"))
+}
+
+// runBuiltinFlags runs the action of the built-in flags set, once v is parsed, and
+// returns ErrHandled if one was.
+func (gf *Gofig) runBuiltinFlags(v interface{}) error {
+	b := &gf.builtins
+	if b.disabled || gf.scope != nil {
+		return nil
+	}
+	w := gf.flagSet.Output()
+	var err error
+	switch {
+	case b.completion != "":
+		err = gf.writeCompletion(w, b.completion)
+	case b.printConfig:
+		err = gf.encodeSections(w, gf.redactSecrets(v))
+	case b.checkConfig:
+		for _, warning := range gf.Warnings() {
+			fmt.Fprintln(w, gf.translatef("warning: %v", warning))
+		}
+		for _, key := range gf.UnusedKeys() {
+			fmt.Fprintln(w, gf.translatef("warning: unused key '%v'", key))
+		}
+		_, err = fmt.Fprintln(w, gf.translate("configuration is valid"))
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return ErrHandled
+}
+
+// redactSecrets returns a copy of the struct pointed to by v where the secret fields are
+// redacted.
+func (gf *Gofig) redactSecrets(v interface{}) interface{} {
+	v = Clone(v)
+	_ = parseStruct(v, func(path []string, name string, val *reflect.Value, tags *reflect.StructTag) error {
+		if !gf.isSecretField(name, tags) {
+			return nil
+		}
+		if val.Kind() == reflect.String {
+			val.SetString("<redacted>")
+		} else {
+			val.Set(reflect.Zero(val.Type()))
+		}
+		return nil
+	}, "yaml")
+	return v
+}
+
+// completionName matches the characters which can't be used in a shell function name.
+var completionName = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// writeCompletion writes a completion script of the flags for shell.
+func (gf *Gofig) writeCompletion(w io.Writer, shell string) error {
+	prog := filepath.Base(gf.flagSet.Name())
+	var flags []*flag.Flag
+	gf.flagSet.VisitAll(func(f *flag.Flag) {
+		if !isBuiltinFlag(f.Name) {
+			flags = append(flags, f)
+		}
+	})
+
+	var b strings.Builder
+	switch shell {
+	case "bash":
+		fn := "_" + completionName.ReplaceAllString(prog, "_")
+		names := make([]string, len(flags))
+		for i, f := range flags {
+			names[i] = "-" + f.Name
+		}
+		fmt.Fprintf(&b, "%v() {\n", fn)
+		fmt.Fprintf(&b, "    COMPREPLY=($(compgen -W %v -- \"${COMP_WORDS[COMP_CWORD]}\"))\n", shellQuote(strings.Join(names, " ")))
+		fmt.Fprintf(&b, "}\ncomplete -F %v %v\n", fn, shellQuote(prog))
+	case "zsh":
+		fmt.Fprintf(&b, "#compdef %v\n_arguments \\\n", prog)
+		for _, f := range flags {
+			_, usage := flag.UnquoteUsage(f)
+			desc := strings.NewReplacer("[", "\\[", "]", "\\]", "\n", " ").Replace(usage)
+			fmt.Fprintf(&b, "  %v \\\n", shellQuote("-"+f.Name+"["+desc+"]"))
+		}
+		b.WriteString("  '*:file:_files'\n")
+	case "fish":
+		for _, f := range flags {
+			_, usage := flag.UnquoteUsage(f)
+			fmt.Fprintf(&b, "complete -c %v -o %v -d %v\n", shellQuote(prog), f.Name, shellQuote(strings.ReplaceAll(usage, "\n", " ")))
+		}
+	default:
+		return errorf("completion shell %v not supported", shell)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
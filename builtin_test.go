@@ -0,0 +1,81 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuiltinFlags(t *testing.T) {
+	type Config struct {
+		Host     string `desc:"host to listen on"`
+		Port     int    `desc:"port to listen on"`
+		Password string `secret:"true"`
+	}
+	parse := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		gf := New(ContinueOnError)
+		gf.SetFlagOutput(&out)
+		gf.flagSet.Init("server", 0)
+		s := &Config{Port: 80}
+		err := gf.ParseWithArgs(s, args)
+		return out.String(), err
+	}
+
+	// Case 1: print the effective configuration
+	out, err := parse("-host", "localhost", "-password", "secret", "-gofig-print-config")
+	assert.Equal(t, ErrHandled, err)
+	assert.Equal(t, "host: localhost\nport: 80\npassword: <redacted>\n", out)
+
+	// Case 2: check the configuration
+	out, err = parse("-gofig-check-config")
+	assert.Equal(t, ErrHandled, err)
+	assert.Equal(t, "configuration is valid\n", out)
+	_, err = parse("-port", "x", "-gofig-check-config")
+	assert.Error(t, err)
+	assert.NotEqual(t, ErrHandled, err)
+
+	// Case 3: completion scripts
+	out, err = parse("-gofig-completion", "bash")
+	assert.Equal(t, ErrHandled, err)
+	assert.Equal(t, `_server() {
+    COMPREPLY=($(compgen -W '-host -password -port' -- "${COMP_WORDS[COMP_CWORD]}"))
+}
+complete -F _server 'server'
+`, out)
+	out, err = parse("-gofig-completion", "fish")
+	assert.Equal(t, ErrHandled, err)
+	assert.Equal(t, `complete -c 'server' -o host -d 'host to listen on'
+complete -c 'server' -o password -d ''
+complete -c 'server' -o port -d 'port to listen on'
+`, out)
+	out, err = parse("-gofig-completion", "zsh")
+	assert.Equal(t, ErrHandled, err)
+	assert.Contains(t, out, "#compdef server\n_arguments \\\n  '-host[host to listen on]' \\\n")
+	_, err = parse("-gofig-completion", "csh")
+	assert.EqualError(t, err, "completion shell csh not supported")
+
+	// Case 4: hidden from the usage message
+	out, err = parse("-h")
+	assert.Error(t, err)
+	assert.NotContains(t, out, "gofig-")
+	assert.Contains(t, out, "-port int")
+
+	// Case 5: opt-out and reserved namespace
+	gf := New(ContinueOnError)
+	gf.SetFlagOutput(&bytes.Buffer{})
+	gf.SetBuiltinFlags(false)
+	err = gf.ParseWithArgs(&Config{}, []string{"-gofig-print-config"})
+	assert.EqualError(t, err, "flag provided but not defined: -gofig-print-config")
+	type Reserved struct {
+		Debug bool `flag:"gofig-debug"`
+	}
+	err = New(ContinueOnError).ParseWithArgs(&Reserved{}, []string{})
+	assert.EqualError(t, err, "flag -gofig-debug of field Debug uses the gofig- prefix reserved for the built-in flags")
+}
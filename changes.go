@@ -0,0 +1,99 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+const (
+	// reloadTag is the struct tag telling whether a field change can be applied to the
+	// running process ("live", the default) or requires a restart ("restart").
+	reloadTag     = "reload"
+	reloadRestart = "restart"
+)
+
+// Change is a field whose value differs between two configurations.
+type Change struct {
+	// Key is the dot-separated key path of the field, following the json tags.
+	Key string
+	// Restart is set if the field is tagged reload:"restart", i.e. the change can't be
+	// applied live and requires restarting the process.
+	Restart bool
+}
+
+// Diff returns the fields whose value differs between two configuration structs of the
+// same type, in field order.
+func Diff(old interface{}, new interface{}) ([]Change, error) {
+	if reflect.TypeOf(old) != reflect.TypeOf(new) {
+		return nil, fmt.Errorf("cannot compare %T with %T", old, new)
+	}
+
+	type leaf struct {
+		val     reflect.Value
+		restart bool
+	}
+	collect := func(v interface{}) ([]string, map[string]leaf, error) {
+		var keys []string
+		leaves := make(map[string]leaf)
+		err := parseStruct(v, func(path []string, name string, val *reflect.Value, tags *reflect.StructTag) error {
+			key := strings.Join(path, ".")
+			keys = append(keys, key)
+			leaves[key] = leaf{val: *val, restart: tags.Get(reloadTag) == reloadRestart}
+			return nil
+		}, "json")
+		return keys, leaves, err
+	}
+
+	oldKeys, oldLeaves, err := collect(old)
+	if err != nil {
+		return nil, err
+	}
+	newKeys, newLeaves, err := collect(new)
+	if err != nil {
+		return nil, err
+	}
+
+	// a nil pointer to a struct is a leaf, while its fields are leaves once allocated
+	var changes []Change
+	for _, key := range oldKeys {
+		o := oldLeaves[key]
+		n, ok := newLeaves[key]
+		if !ok || !reflect.DeepEqual(o.val.Interface(), n.val.Interface()) {
+			changes = append(changes, Change{Key: key, Restart: o.restart})
+		}
+	}
+	for _, key := range newKeys {
+		if _, ok := oldLeaves[key]; !ok {
+			changes = append(changes, Change{Key: key, Restart: newLeaves[key].restart})
+		}
+	}
+	return changes, nil
+}
+
+// NeedsRestart returns whether one of the changes requires restarting the process.
+func NeedsRestart(changes []Change) bool {
+	for _, c := range changes {
+		if c.Restart {
+			return true
+		}
+	}
+	return false
+}
+
+// Changes returns the fields changed by the last reload (Reload, Override, Push...), so
+// that reload tooling can restart the process when restart-only fields changed.
+func Changes() []Change { return gf.Changes() }
+
+// Changes returns the fields changed by the last reload (Reload, Override, Push...), so
+// that reload tooling can restart the process when restart-only fields changed.
+func (gf *Gofig) Changes() []Change {
+	gf.mu.Lock()
+	defer gf.mu.Unlock()
+	return gf.changes
+}
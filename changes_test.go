@@ -0,0 +1,72 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type reloadSub struct {
+	Host string `json:"host" reload:"restart"`
+}
+
+type reloadConfig struct {
+	Port     int    `json:"port" reload:"restart"`
+	LogLevel string `json:"log_level" reload:"live"`
+	Timeout  int    `json:"timeout"`
+	DB       *reloadSub
+}
+
+func TestDiff(t *testing.T) {
+	old := &reloadConfig{Port: 80, LogLevel: "info"}
+	new := &reloadConfig{Port: 80, LogLevel: "debug", Timeout: 5}
+
+	// Case 1: live changes only
+	changes, err := Diff(old, new)
+	assert.NoError(t, err)
+	assert.Equal(t, []Change{{Key: "log_level"}, {Key: "timeout"}}, changes)
+	assert.False(t, NeedsRestart(changes))
+
+	// Case 2: restart-only fields, including fields of an allocated struct pointer
+	new = &reloadConfig{Port: 8080, LogLevel: "info", DB: &reloadSub{Host: "db"}}
+	changes, err = Diff(old, new)
+	assert.NoError(t, err)
+	assert.Equal(t, []Change{{Key: "port", Restart: true}, {Key: "db"}, {Key: "db.host", Restart: true}}, changes)
+	assert.True(t, NeedsRestart(changes))
+
+	// Case 3: no change
+	changes, err = Diff(old, old)
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+
+	// Case 4: different types
+	_, err = Diff(old, &reloadSub{})
+	assert.EqualError(t, err, "cannot compare *gofig.reloadConfig with *gofig.reloadSub")
+}
+
+func TestChanges(t *testing.T) {
+	s := &reloadConfig{Port: 80}
+	gf := New(ContinueOnError)
+	err := gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Empty(t, gf.Changes())
+
+	var changes []Change
+	gf.OnChange(func() { changes = gf.Changes() })
+
+	err = gf.Override("log_level", "debug")
+	assert.NoError(t, err)
+	assert.Equal(t, []Change{{Key: "log_level"}}, changes)
+	assert.False(t, NeedsRestart(changes))
+
+	err = gf.Override("port", "8080")
+	assert.NoError(t, err)
+	assert.Equal(t, []Change{{Key: "port", Restart: true}}, changes)
+	assert.True(t, NeedsRestart(changes))
+	assert.Equal(t, 8080, s.Port)
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Check is a check of the parsed configuration struct v, e.g. NoDuplicatePorts.
+type Check func(v interface{}) error
+
+// AddCheck adds checks run once all the sources are applied, after the Validate methods
+// of the config structs, their errors being handled like any parsing error.
+func AddCheck(checks ...Check) { gf.AddCheck(checks...) }
+
+// AddCheck adds checks run once all the sources are applied, after the Validate methods
+// of the config structs, their errors being handled like any parsing error.
+func (gf *Gofig) AddCheck(checks ...Check) {
+	gf.checks = append(gf.checks, checks...)
+}
+
+// runChecks runs the checks added with AddCheck on v.
+func (gf *Gofig) runChecks(v interface{}) error {
+	for _, check := range gf.checks {
+		err := check(v)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NoDuplicatePorts returns a check that the fields at the dot-separated key paths don't
+// bind the same port. The fields are port numbers, listen addresses (e.g. ":8080" or
+// "127.0.0.1:8080") or lists of them. Two addresses conflict if their hosts are the
+// same or one of them is a wildcard address. The zero ports and the fields of the
+// inactive sections are ignored.
+func NoDuplicatePorts(paths ...string) Check {
+	return func(v interface{}) error {
+		type listener struct {
+			key  string
+			host string
+		}
+		ports := make(map[string][]listener)
+		return visitKeyValues(v, paths, func(key string, val string) error {
+			host, port := "", val
+			if strings.Contains(val, ":") {
+				var err error
+				host, port, err = net.SplitHostPort(val)
+				if err != nil {
+					return errorf("invalid listen address '%v' of key %v: %v", val, key, err)
+				}
+			}
+			if n, err := strconv.Atoi(port); err != nil || n == 0 {
+				return nil // a named or random port
+			}
+			for _, l := range ports[port] {
+				if l.host == host || isWildcardHost(l.host) || isWildcardHost(host) {
+					return errorf("port %v of key %v is already used by key %v", port, key, l.key)
+				}
+			}
+			ports[port] = append(ports[port], listener{key: key, host: host})
+			return nil
+		})
+	}
+}
+
+// NoDuplicates returns a check that the fields at the dot-separated key paths, or the
+// items of the lists, hold different values. The zero values and the fields of the
+// inactive sections are ignored.
+func NoDuplicates(paths ...string) Check {
+	return func(v interface{}) error {
+		keys := make(map[string]string)
+		return visitKeyValues(v, paths, func(key string, val string) error {
+			if prev, ok := keys[val]; ok {
+				return errorf("value '%v' of key %v is already used by key %v", val, key, prev)
+			}
+			keys[val] = key
+			return nil
+		})
+	}
+}
+
+// isWildcardHost returns whether a listen address host binds all the interfaces.
+func isWildcardHost(host string) bool {
+	ip := net.ParseIP(host)
+	return host == "" || (ip != nil && ip.IsUnspecified())
+}
+
+// visitKeyValues calls fn with the text of the non-zero values of the fields of v at the
+// key paths, or the items of the lists, skipping the fields of the inactive sections.
+func visitKeyValues(v interface{}, paths []string, fn func(key string, val string) error) error {
+	found, err := sections(v, "json")
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		key := strings.ToLower(path)
+		f, ok := fieldByKey(v, key)
+		if !ok {
+			return errorf("unknown key '%v'", key)
+		}
+		if _, inactive := inactiveSection(found, strings.Split(key, ".")); inactive {
+			continue
+		}
+		if f.Kind() == reflect.Ptr {
+			if f.IsNil() {
+				continue
+			}
+			f = f.Elem()
+		}
+		items := []reflect.Value{f}
+		if (f.Kind() == reflect.Slice || f.Kind() == reflect.Array) && !isValueType(f.Type()) {
+			items = items[:0]
+			for i := 0; i < f.Len(); i++ {
+				items = append(items, f.Index(i))
+			}
+		}
+		for _, item := range items {
+			if item.IsZero() {
+				continue
+			}
+			val, ok := encodeString(&item)
+			if !ok {
+				return errorf("key %v isn't a single value or a list", key)
+			}
+			err := fn(key, val)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
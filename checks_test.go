@@ -0,0 +1,63 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type listenersStruct struct {
+	HTTP    string   `json:"http"`
+	GRPC    int      `json:"grpc"`
+	Extra   []string `json:"extra"`
+	Metrics struct {
+		Enabled bool   `json:"enabled"`
+		Addr    string `json:"addr"`
+	} `json:"metrics" enabledBy:"metrics.enabled"`
+	Name  string `json:"name"`
+	Alias string `json:"alias"`
+}
+
+func TestChecks(t *testing.T) {
+	for args, expected := range map[string]string{
+		"":                       "",
+		"-http :8080 -grpc 9090": "",
+		"-http :8080 -grpc 8080": "port 8080 of key grpc is already used by key http",
+		"-http 127.0.0.1:8080 -extra 10.0.0.1:8080":        "",
+		"-http 127.0.0.1:8080 -extra 0.0.0.0:8080":         "port 8080 of key extra is already used by key http",
+		"-extra :9000,[::]:9000":                           "port 9000 of key extra is already used by key extra",
+		"-http :0 -grpc 0 -extra :0":                       "",
+		"-http :8080 -metrics-addr :8080":                  "",
+		"-http :8080 -metrics-addr :8080 -metrics-enabled": "port 8080 of key metrics.addr is already used by key http",
+		"-http a:b:c":      "invalid listen address 'a:b:c' of key http: address a:b:c: too many colons in address",
+		"-name a -alias a": "value 'a' of key alias is already used by key name",
+	} {
+		gf := New(ContinueOnError)
+		gf.AddCheck(NoDuplicatePorts("http", "grpc", "extra", "metrics.addr"), NoDuplicates("name", "alias"))
+		err := gf.ParseWithArgs(&listenersStruct{}, strings.Fields(args))
+		if expected == "" {
+			assert.NoError(t, err, args)
+		} else {
+			assert.EqualError(t, err, expected, args)
+		}
+	}
+
+	// custom check and unknown key
+	errCheck := errors.New("check failed")
+	gf := New(ContinueOnError)
+	gf.AddCheck(func(v interface{}) error { return errCheck })
+	err := gf.ParseWithArgs(&listenersStruct{}, []string{})
+	assert.Equal(t, errCheck, err)
+
+	gf = New(ContinueOnError)
+	gf.AddCheck(NoDuplicatePorts("http", "https"))
+	err = gf.ParseWithArgs(&listenersStruct{}, []string{})
+	assert.EqualError(t, err, "unknown key 'https'")
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// SetChecksumFlag adds a flag pinning the remote config documents added with AddConfigURL
+// to their SHA-256 checksum, so that an immutable deployment only accepts the reviewed
+// documents, e.g. -config-sha256 9f86d081...; it can also be set with its environment
+// variable, e.g. PREFIX_CONFIG_SHA256. Its value is a comma-separated list of hex-encoded
+// checksums, which each remote document must match one of. The documents aren't pinned
+// if it's not set.
+func SetChecksumFlag(name string, desc string) { gf.SetChecksumFlag(name, desc) }
+
+// SetChecksumFlag adds a flag pinning the remote config documents added with AddConfigURL
+// to their SHA-256 checksum, so that an immutable deployment only accepts the reviewed
+// documents, e.g. -config-sha256 9f86d081...; it can also be set with its environment
+// variable, e.g. PREFIX_CONFIG_SHA256. Its value is a comma-separated list of hex-encoded
+// checksums, which each remote document must match one of. The documents aren't pinned
+// if it's not set.
+func (gf *Gofig) SetChecksumFlag(name string, desc string) {
+	gf.sumFlagName = name
+	gf.flagSet.String(name, "", gf.translate(desc))
+	gf.declareFlag(gf.flagSet, name)
+}
+
+// pinnedChecksums returns the checksums the remote documents are pinned to, set by the
+// checksum flag in args or its environment variable, if any.
+func (gf *Gofig) pinnedChecksums(args []string) []string {
+	if gf.sumFlagName == "" {
+		return nil
+	}
+	val := flagArg(args, gf.sumFlagName)
+	if val == "" {
+		val, _ = gf.lookupEnv(gf.getEnvKey(strings.Split(gf.sumFlagName, flagSeparator)))
+	}
+	var sums []string
+	for _, sum := range strings.Split(val, ",") {
+		if sum = strings.ToLower(strings.TrimSpace(sum)); sum != "" {
+			sums = append(sums, strings.TrimPrefix(sum, "sha256:"))
+		}
+	}
+	return sums
+}
+
+// verifyChecksum verifies that the document of a remote source matches one of the pinned
+// checksums.
+func verifyChecksum(src Source, doc *Document, sums []string) error {
+	if doc == nil {
+		return nil
+	}
+	sum := sha256.Sum256(doc.Data)
+	actual := hex.EncodeToString(sum[:])
+	for _, s := range sums {
+		if s == actual {
+			return nil
+		}
+	}
+	return errorf("checksum mismatch of %v: sha256 %v isn't pinned", src.Name(), actual)
+}
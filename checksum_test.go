@@ -0,0 +1,56 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecksumFlag(t *testing.T) {
+	const doc = "str: reviewed"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		_, _ = w.Write([]byte(doc))
+	}))
+	defer server.Close()
+	sum := sha256.Sum256([]byte(doc))
+	pinned := hex.EncodeToString(sum[:])
+
+	parse := func(args []string) (*TestStruct, error) {
+		s := buildTestStruct()
+		gf := New(ContinueOnError)
+		gf.SetEnvPrefix("PIN")
+		gf.SetChecksumFlag("config-sha256", "SHA-256 of the remote config")
+		gf.AddConfigURL(server.URL + "/config.yaml")
+		return s, gf.ParseWithArgs(s, args)
+	}
+
+	// not pinned
+	s, err := parse([]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "reviewed", s.Str)
+
+	s, err = parse([]string{"-config-sha256", "0000," + pinned})
+	assert.NoError(t, err)
+	assert.Equal(t, "reviewed", s.Str)
+
+	_, err = parse([]string{"-config-sha256=sha256:0000"})
+	assert.EqualError(t, err, "checksum mismatch of "+server.URL+"/config.yaml: sha256 "+pinned+" isn't pinned")
+
+	os.Setenv("PIN_CONFIG_SHA256", "0000")
+	defer os.Unsetenv("PIN_CONFIG_SHA256")
+	_, err = parse([]string{})
+	assert.Error(t, err)
+	_, err = parse([]string{"-config-sha256", pinned})
+	assert.NoError(t, err)
+}
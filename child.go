@@ -0,0 +1,122 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Child returns a Gofig scoped to the sub-path name of the configuration (e.g. "db"),
+// so that a subsystem can parse its own config struct. The child inherits the settings
+// of its parent: its environment variables, flags and config document keys are those
+// of the struct at name in the parent configuration (e.g. PREFIX_DB_PORT, -db-port and
+// db.port), and its flags are declared into the parent flag set. Children should be
+// parsed before their parent, which parses their flags; a child parsed after its parent
+// parses the arguments itself.
+func Child(name string) *Gofig { return gf.Child(name) }
+
+// Child returns a Gofig scoped to the sub-path name of the configuration (e.g. "db"),
+// so that a subsystem can parse its own config struct. The child inherits the settings
+// of its parent: its environment variables, flags and config document keys are those
+// of the struct at name in the parent configuration (e.g. PREFIX_DB_PORT, -db-port and
+// db.port), and its flags are declared into the parent flag set. Children should be
+// parsed before their parent, which parses their flags; a child parsed after its parent
+// parses the arguments itself.
+func (gf *Gofig) Child(name string) *Gofig {
+	urlSources := make(map[Source]bool, len(gf.urlSources))
+	for src := range gf.urlSources {
+		urlSources[src] = true
+	}
+	scope := append(gf.scope[:len(gf.scope):len(gf.scope)], strings.ToLower(name))
+	gf.claim(strings.Join(scope, "."))
+	return &Gofig{
+		envPrefix:   gf.envPrefix,
+		envExpand:   gf.envExpand,
+		argsFiles:   gf.argsFiles,
+		envNoCase:   gf.envNoCase,
+		lenient:     gf.lenient,
+		strict:      gf.strict,
+		returnFatal: gf.returnFatal,
+		cfgFlagName: gf.cfgFlagName,
+		sumFlagName: gf.sumFlagName,
+		cfgFiles:    gf.cfgFiles[:len(gf.cfgFiles):len(gf.cfgFiles)],
+		errHandling: gf.errHandling,
+		errFormat:   gf.errFormat,
+		translator:  gf.translator,
+		flagSet:     gf.flagSet,
+		flags:       gf.flags,
+		limits:      gf.limits,
+		keyring:     gf.keyring,
+		resolvers:   gf.resolvers,
+		verifier:    gf.verifier,
+		sigPolicy:   gf.sigPolicy,
+		rollout:     gf.rollout,
+		labels:      gf.labels,
+		middlewares: gf.middlewares[:len(gf.middlewares):len(gf.middlewares)],
+		facts:       gf.facts,
+		transforms:  gf.transforms[:len(gf.transforms):len(gf.transforms)],
+		validation:  gf.validation,
+		jsonnet:     gf.jsonnet,
+		extVarFlag:  gf.extVarFlag,
+		aggregate:   gf.aggregate,
+
+		sources:           gf.sources[:len(gf.sources):len(gf.sources)],
+		urlSources:        urlSources,
+		sourceConcurrency: gf.sourceConcurrency,
+		parseTimeout:      gf.parseTimeout,
+		watchQuietPeriod:  gf.watchQuietPeriod,
+		scope:             scope,
+	}
+}
+
+// scoped returns a fieldParser prepending the scope of a child to the key paths.
+func (gf *Gofig) scoped(parser fieldParser) fieldParser {
+	if len(gf.scope) == 0 {
+		return parser
+	}
+	scope := gf.scope[:len(gf.scope):len(gf.scope)]
+	return func(path []string, name string, val *reflect.Value, tags *reflect.StructTag) error {
+		return parser(append(scope, path...), name, val, tags)
+	}
+}
+
+// scopeWrapper returns a pointer to a struct nesting a copy of the struct pointed to by v
+// at the scope path of a child, to decode the config documents into it, and a function
+// copying the nested struct back into v.
+func (gf *Gofig) scopeWrapper(v interface{}) (interface{}, func()) {
+	rv := reflect.ValueOf(v).Elem()
+	rt := rv.Type()
+	for i := len(gf.scope) - 1; i >= 0; i-- {
+		rt = reflect.StructOf([]reflect.StructField{{
+			Name: "Scope",
+			Type: rt,
+			Tag:  reflect.StructTag(fmt.Sprintf(`json:%[1]q toml:%[1]q yaml:%[1]q env:%[1]q flag:%[1]q`, gf.scope[i])),
+		}})
+	}
+
+	wrapper := reflect.New(rt)
+	nested := wrapper.Elem()
+	for range gf.scope {
+		nested = nested.Field(0)
+	}
+	nested.Set(rv)
+	return wrapper.Interface(), func() { rv.Set(nested) }
+}
+
+// ignoredFlag declares a flag whose values are ignored.
+type ignoredFlag struct {
+	flag.Value
+}
+
+func (f ignoredFlag) Set(string) error { return nil }
+
+func (f ignoredFlag) IsBoolFlag() bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type childTestConfig struct {
+	Port int
+	Host string
+	User string
+}
+
+func TestChild(t *testing.T) {
+	os.Setenv("GFC_DB_HOST", "env")
+	defer os.Unsetenv("GFC_DB_HOST")
+
+	parent := New(ContinueOnError)
+	parent.SetEnvPrefix("GFC")
+	parent.AddSource(&testSource{name: "test", doc: &Document{Format: "yaml", Data: []byte("str: source\ndb:\n  port: 5432\n  user: source\n")}})
+
+	// Case 1: child parsed before its parent
+	db := &childTestConfig{User: "default"}
+	child := parent.Child("db")
+	err := child.ParseWithArgs(db, []string{"-str", "flag", "-db-user", "flag"})
+	assert.NoError(t, err)
+	assert.Equal(t, 5432, db.Port)
+	assert.Equal(t, "env", db.Host)
+	assert.Equal(t, "source", db.User)
+
+	s := &TestStruct{}
+	err = parent.ParseWithArgs(s, []string{"-str", "flag", "-db-user", "flag"})
+	assert.NoError(t, err)
+	assert.Equal(t, "flag", s.Str)
+	assert.Equal(t, "flag", db.User)
+
+	// the child is reloaded on its own
+	err = child.Override("port", "6543")
+	assert.NoError(t, err)
+	assert.Equal(t, 6543, db.Port)
+	assert.Equal(t, "flag", db.User)
+	key, err := child.FlagFor(&db.Port)
+	assert.NoError(t, err)
+	assert.Equal(t, "db-port", key)
+
+	// and so is its parent
+	err = parent.Override("int", "1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, s.Int)
+
+	// Case 2: child parsed after its parent
+	replica := &childTestConfig{}
+	err = parent.Child("replica").ParseWithArgs(replica, []string{"-str", "flag", "-db-user", "flag"})
+	assert.NoError(t, err)
+	assert.Equal(t, "", replica.Host)
+
+	// Case 3: nested child
+	os.Setenv("GFC_A_B_HOST", "nested")
+	defer os.Unsetenv("GFC_A_B_HOST")
+	nested := &childTestConfig{}
+	err = New(ContinueOnError).Child("a").Child("b").ParseWithArgs(nested, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "", nested.Host)
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("GFC")
+	err = gf.Child("a").Child("b").ParseWithArgs(nested, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "nested", nested.Host)
+}
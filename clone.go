@@ -0,0 +1,81 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"reflect"
+)
+
+// Clone returns a deep copy of v, typically a pointer to a parsed configuration struct,
+// of the same type as v. Pointers, slices, maps and interfaces are copied recursively,
+// preserving nil values and pointer aliasing. Unexported fields are copied shallowly,
+// which is correct for value types such as time.Time. Channels and functions are shared.
+func Clone(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	return deepCopy(reflect.ValueOf(v), make(map[uintptr]reflect.Value)).Interface()
+}
+
+// deepCopy returns a deep copy of rv, copied maps the pointers already copied to
+// their copy.
+func deepCopy(rv reflect.Value, copied map[uintptr]reflect.Value) reflect.Value {
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return reflect.Zero(rv.Type())
+		}
+		if cp, ok := copied[rv.Pointer()]; ok && cp.Type() == rv.Type() {
+			return cp
+		}
+		cp := reflect.New(rv.Type().Elem())
+		copied[rv.Pointer()] = cp
+		cp.Elem().Set(deepCopy(rv.Elem(), copied))
+		return cp
+	case reflect.Interface:
+		if rv.IsNil() {
+			return reflect.Zero(rv.Type())
+		}
+		cp := reflect.New(rv.Type()).Elem()
+		cp.Set(deepCopy(rv.Elem(), copied))
+		return cp
+	case reflect.Slice:
+		if rv.IsNil() {
+			return reflect.Zero(rv.Type())
+		}
+		cp := reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			cp.Index(i).Set(deepCopy(rv.Index(i), copied))
+		}
+		return cp
+	case reflect.Array:
+		cp := reflect.New(rv.Type()).Elem()
+		for i := 0; i < rv.Len(); i++ {
+			cp.Index(i).Set(deepCopy(rv.Index(i), copied))
+		}
+		return cp
+	case reflect.Map:
+		if rv.IsNil() {
+			return reflect.Zero(rv.Type())
+		}
+		cp := reflect.MakeMapWithSize(rv.Type(), rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			cp.SetMapIndex(deepCopy(iter.Key(), copied), deepCopy(iter.Value(), copied))
+		}
+		return cp
+	case reflect.Struct:
+		cp := reflect.New(rv.Type()).Elem()
+		cp.Set(rv) // copies the unexported fields
+		for i := 0; i < rv.NumField(); i++ {
+			if cp.Field(i).CanSet() {
+				cp.Field(i).Set(deepCopy(rv.Field(i), copied))
+			}
+		}
+		return cp
+	}
+	return rv
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type cloneTestStruct struct {
+	TestStruct
+	Time    time.Time
+	Ptr     *SubTestStruct
+	Alias   *SubTestStruct
+	Nil     *SubTestStruct
+	Slice   []string
+	Nested  [][]int
+	Map     map[string]*SubTestStruct
+	Iface   interface{}
+	Array   [2][]int
+	private []int
+}
+
+func TestClone(t *testing.T) {
+	sub := &SubTestStruct{RenamedStr: "sub"}
+	s := &cloneTestStruct{
+		TestStruct: *buildTestStruct(),
+		Time:       time.Date(2019, 1, 2, 3, 4, 5, 6, time.UTC),
+		Ptr:        sub,
+		Alias:      sub,
+		Slice:      []string{"a", "b"},
+		Nested:     [][]int{{1}, {2, 3}},
+		Map:        map[string]*SubTestStruct{"a": {RenamedStr: "a"}},
+		Iface:      map[string]interface{}{"a": []interface{}{1}},
+		Array:      [2][]int{{1}, {2}},
+		private:    []int{1},
+	}
+
+	cp := Clone(s).(*cloneTestStruct)
+	assert.Equal(t, s, cp)
+
+	// the copy doesn't share memory with the original
+	cp.Ptr.RenamedStr = "changed"
+	cp.Slice[0] = "changed"
+	cp.Nested[1][0] = 0
+	cp.Map["a"].RenamedStr = "changed"
+	cp.Iface.(map[string]interface{})["a"].([]interface{})[0] = 0
+	cp.Array[0][0] = 0
+	assert.Equal(t, "sub", s.Ptr.RenamedStr)
+	assert.Equal(t, "a", s.Slice[0])
+	assert.Equal(t, 2, s.Nested[1][0])
+	assert.Equal(t, "a", s.Map["a"].RenamedStr)
+	assert.Equal(t, 1, s.Iface.(map[string]interface{})["a"].([]interface{})[0])
+	assert.Equal(t, 1, s.Array[0][0])
+
+	// aliasing is preserved
+	assert.True(t, cp.Ptr == cp.Alias)
+	assert.Nil(t, cp.Nil)
+
+	assert.Nil(t, Clone(nil))
+	assert.Equal(t, 1, Clone(1))
+}
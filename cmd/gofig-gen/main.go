@@ -0,0 +1,340 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// Command gofig-gen generates the field listing of a gofig configuration struct: the
+// names, tags and pointers of its leaf fields, so that gofig doesn't have to discover
+// them by walking the struct type with reflection at startup. The values are still
+// set through the field pointers with reflection. It also reports tag errors
+// (malformed tags, flag or environment variable collisions) at generation time instead
+// of at run time.
+//
+// Usage, next to the configuration struct definition:
+//
+//	//go:generate gofig-gen -type Config
+//
+// This generates a config_gofig.go file implementing gofig.FieldLister for *Config.
+// With -keys, it also generates a constant holding the key path of each field (as
+// accepted by gofig.Override), e.g. ConfigKeyDBPort = "db.port".
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	typeName := flag.String("type", "", "name of the configuration struct type (required)")
+	output := flag.String("output", "", "output file name (default <type>_gofig.go)")
+	keys := flag.Bool("keys", false, "generate the key path constants")
+	flag.Parse()
+
+	if *typeName == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+	if *output == "" {
+		*output = filepath.Join(dir, strings.ToLower(*typeName)+"_gofig.go")
+	}
+
+	src, err := generate(dir, *typeName, *keys)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gofig-gen: %v\n", err)
+		os.Exit(1)
+	}
+
+	err = os.WriteFile(*output, src, 0644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gofig-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// field is a leaf field of the configuration struct
+type field struct {
+	names    []string
+	tags     []string
+	access   string   // Go expression of the field, e.g. "v.Sub.Str"
+	nilCheck []string // pointers to check before taking the field address
+}
+
+// generate loads the package in dir and returns the field listing of typeName, with
+// the key path constants if keys is set.
+func generate(dir string, typeName string, keys bool) ([]byte, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go") && !strings.HasSuffix(fi.Name(), "_gofig.go")
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// type errors are ignored, e.g. when the package uses code that isn't generated yet
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error:    func(error) {},
+	}
+
+	var obj types.Object
+	for _, pkg := range pkgs {
+		var files []*ast.File
+		for _, f := range pkg.Files {
+			files = append(files, f)
+		}
+		tpkg, _ := conf.Check(pkg.Name, fset, files, nil)
+		if obj = tpkg.Scope().Lookup(typeName); obj != nil {
+			break
+		}
+	}
+	if obj == nil {
+		return nil, fmt.Errorf("type %v not found in %v", typeName, dir)
+	}
+	st, ok := obj.Type().Underlying().(*types.Struct)
+	if !ok {
+		return nil, fmt.Errorf("type %v is not a struct", typeName)
+	}
+
+	var fields []field
+	err = collectFields(st, nil, nil, "v", nil, &fields)
+	if err != nil {
+		return nil, err
+	}
+	err = checkCollisions(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return render(obj.Pkg().Name(), typeName, fields, keys)
+}
+
+// collectFields recursively collects the leaf fields of a struct, following the
+// same rules as gofig's reflection based walk.
+func collectFields(st *types.Struct, names []string, tags []string, access string, nilCheck []string, fields *[]field) error {
+	for i := 0; i < st.NumFields(); i++ {
+		v := st.Field(i)
+		if !v.Exported() {
+			continue
+		}
+
+		tag := st.Tag(i)
+		err := validateTag(tag)
+		if err != nil {
+			return fmt.Errorf("field %v: %v", strings.Join(append(names, v.Name()), "."), err)
+		}
+
+		fieldNames := append(names[:len(names):len(names)], v.Name())
+		fieldTags := append(tags[:len(tags):len(tags)], tag)
+		fieldAccess := access + "." + v.Name()
+
+		switch t := v.Type().Underlying().(type) {
+		case *types.Pointer:
+			if sub, ok := t.Elem().Underlying().(*types.Struct); ok && !isValue(t.Elem()) {
+				check := append(nilCheck[:len(nilCheck):len(nilCheck)], fieldAccess)
+				err = collectFields(sub, fieldNames, fieldTags, fieldAccess, check, fields)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+		case *types.Struct:
+			if isValue(v.Type()) {
+				break // a single value
+			}
+			err = collectFields(t, fieldNames, fieldTags, fieldAccess, nilCheck, fields)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		*fields = append(*fields, field{
+			names:    fieldNames,
+			tags:     fieldTags,
+			access:   fieldAccess,
+			nilCheck: nilCheck,
+		})
+	}
+	return nil
+}
+
+// isValue returns whether the struct type t is set by gofig as a single value, being
+// time.Time or implementing encoding.TextUnmarshaler or flag.Value.
+func isValue(t types.Type) bool {
+	methods := types.NewMethodSet(types.NewPointer(t))
+	return methods.Lookup(nil, "UnmarshalText") != nil ||
+		methods.Lookup(nil, "Set") != nil && methods.Lookup(nil, "String") != nil
+}
+
+// validateTag checks that a struct tag follows the key:"value" convention.
+func validateTag(tag string) error {
+	for tag != "" {
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		tag = tag[i:]
+		if tag == "" {
+			break
+		}
+
+		i = 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			return fmt.Errorf("malformed struct tag `%v`", tag)
+		}
+		tag = tag[i+1:]
+
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			return fmt.Errorf("malformed struct tag value %v", tag)
+		}
+		if _, err := strconv.Unquote(tag[:i+1]); err != nil {
+			return fmt.Errorf("malformed struct tag value %v", tag[:i+1])
+		}
+		tag = tag[i+1:]
+	}
+	return nil
+}
+
+// keyPath returns the key path of a field for a tag, nil if the field is skipped.
+func keyPath(f field, cfgTag string) []string {
+	path := make([]string, 0, len(f.names))
+	for i, name := range f.names {
+		key := strings.Split(reflect.StructTag(f.tags[i]).Get(cfgTag), ",")[0]
+		if key == "-" {
+			return nil
+		} else if key == "" {
+			key = name
+		}
+		path = append(path, strings.ToLower(key))
+	}
+	return path
+}
+
+// checkCollisions reports fields mapping to the same flag or environment variable.
+func checkCollisions(fields []field) error {
+	flags := make(map[string]string)
+	envs := make(map[string]string)
+	for _, f := range fields {
+		name := strings.Join(f.names, ".")
+		if path := keyPath(f, "flag"); path != nil {
+			key := strings.Join(path, "-")
+			if prev, ok := flags[key]; ok {
+				return fmt.Errorf("flag -%v is defined by both field %v and field %v", key, prev, name)
+			}
+			flags[key] = name
+		}
+		if path := keyPath(f, "env"); path != nil {
+			key := strings.ToUpper(strings.Join(path, "_"))
+			if prev, ok := envs[key]; ok {
+				return fmt.Errorf("environment variable '%v' is used by both field %v and field %v", key, prev, name)
+			}
+			envs[key] = name
+		}
+	}
+	return nil
+}
+
+// quoteTag returns a struct tag as a Go string literal, preferably a raw one.
+func quoteTag(tag string) string {
+	if strings.Contains(tag, "`") {
+		return strconv.Quote(tag)
+	}
+	return "`" + tag + "`"
+}
+
+// render returns the formatted field listing code.
+func render(pkgName string, typeName string, fields []field, keys bool) ([]byte, error) {
+	if len(fields) == 0 {
+		return nil, errors.New("no exported field found in type " + typeName)
+	}
+
+	varName := "gofig" + typeName + "Fields"
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by gofig-gen; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %v\n\n", pkgName)
+	fmt.Fprintf(&b, "import (\n\t\"reflect\"\n\n\t\"github.com/curvegrid/gofig\"\n)\n\n")
+
+	if keys {
+		renderKeys(&b, typeName, fields)
+	}
+
+	fmt.Fprintf(&b, "var %v = [...]struct {\n\tnames []string\n\ttags  []reflect.StructTag\n}{\n", varName)
+	for _, f := range fields {
+		fmt.Fprintf(&b, "\t{\n\t\tnames: []string{")
+		for i, name := range f.names {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(strconv.Quote(name))
+		}
+		fmt.Fprintf(&b, "},\n\t\ttags: []reflect.StructTag{")
+		for i, tag := range f.tags {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(quoteTag(tag))
+		}
+		fmt.Fprintf(&b, "},\n\t},\n")
+	}
+	fmt.Fprintf(&b, "}\n\n")
+
+	fmt.Fprintf(&b, "// GofigFields implements gofig.FieldLister.\n")
+	fmt.Fprintf(&b, "func (v *%v) GofigFields() []gofig.Field {\n", typeName)
+	fmt.Fprintf(&b, "\tfields := make([]gofig.Field, 0, %v)\n", len(fields))
+	for i, f := range fields {
+		indent := "\t"
+		if len(f.nilCheck) > 0 {
+			fmt.Fprintf(&b, "\tif %v != nil {\n", strings.Join(f.nilCheck, " != nil && "))
+			indent = "\t\t"
+		}
+		fmt.Fprintf(&b, "%vfields = append(fields, gofig.Field{Names: %v[%v].names, Tags: %v[%v].tags, Ptr: &%v})\n",
+			indent, varName, i, varName, i, f.access)
+		if len(f.nilCheck) > 0 {
+			fmt.Fprintf(&b, "\t}\n")
+		}
+	}
+	fmt.Fprintf(&b, "\treturn fields\n}\n")
+
+	return format.Source(b.Bytes())
+}
+
+// renderKeys writes a constant holding the key path of each field.
+func renderKeys(b *bytes.Buffer, typeName string, fields []field) {
+	fmt.Fprintf(b, "// Key paths of the %v fields, as accepted by gofig.Override.\n", typeName)
+	fmt.Fprintf(b, "const (\n")
+	for _, f := range fields {
+		if path := keyPath(f, "json"); path != nil {
+			fmt.Fprintf(b, "\t%vKey%v = %q\n", typeName, strings.Join(f.names, ""), strings.Join(path, "."))
+		}
+	}
+	fmt.Fprintf(b, ")\n\n")
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	src, err := generate("testdata/config", "Config", false)
+	assert.NoError(t, err)
+
+	code := string(src)
+	assert.Contains(t, code, "package config")
+	assert.Contains(t, code, "func (v *Config) GofigFields() []gofig.Field {")
+	assert.Contains(t, code, "tags:  []reflect.StructTag{`flag:\"d\"`},")
+	assert.Contains(t, code, "Ptr: &v.Debug})")
+	assert.Contains(t, code, "Ptr: &v.DB.Port})")
+	assert.Contains(t, code, "\tif v.Replica != nil {\n\t\tfields = append(fields, gofig.Field{Names: gofigConfigFields[2].names, Tags: gofigConfigFields[2].tags, Ptr: &v.Replica.Port})\n\t}")
+	assert.Contains(t, code, "Ptr: &v.Skipped})")
+	assert.Contains(t, code, "Ptr: &v.Started})")
+	assert.Contains(t, code, "Ptr: &v.Owner})")
+	assert.Contains(t, code, "Ptr: &v.Listen})")
+	assert.NotContains(t, code, "hidden")
+	assert.NotContains(t, code, "ConfigKey")
+
+	// key path constants
+	src, err = generate("testdata/config", "Config", true)
+	assert.NoError(t, err)
+
+	code = string(src)
+	assert.Contains(t, code, "\tConfigKeyDebug       = \"debug\"\n")
+	assert.Contains(t, code, "\tConfigKeyDBPort      = \"db.port\"\n")
+	assert.Contains(t, code, "\tConfigKeyReplicaPort = \"replica.port\"\n")
+	assert.Contains(t, code, "\tConfigKeySkipped     = \"skipped\"\n")
+}
+
+func TestGenerateErrors(t *testing.T) {
+	_, err := generate("testdata/collision", "Config", false)
+	assert.EqualError(t, err, "flag -name is defined by both field A and field B")
+
+	_, err = generate("testdata/badtag", "Config", false)
+	assert.EqualError(t, err, "field A: malformed struct tag `flag:name`")
+
+	_, err = generate("testdata/config", "Missing", false)
+	assert.EqualError(t, err, "type Missing not found in testdata/config")
+
+	_, err = generate("testdata/config", "DB", false)
+	assert.NoError(t, err)
+}
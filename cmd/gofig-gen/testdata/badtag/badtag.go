@@ -0,0 +1,5 @@
+package badtag
+
+type Config struct {
+	A string `flag:name`
+}
@@ -0,0 +1,6 @@
+package collision
+
+type Config struct {
+	A string `flag:"name"`
+	B string `flag:"name"`
+}
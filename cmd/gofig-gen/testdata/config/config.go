@@ -0,0 +1,41 @@
+package config
+
+import (
+	"strings"
+	"time"
+)
+
+type DB struct {
+	Port int `desc:"port"`
+}
+
+type Owner struct {
+	Team, Name string
+}
+
+func (o *Owner) UnmarshalText(text []byte) error {
+	o.Team, o.Name, _ = strings.Cut(string(text), "/")
+	return nil
+}
+
+type Endpoint struct {
+	Host, Port string
+}
+
+func (e *Endpoint) String() string { return e.Host + ":" + e.Port }
+
+func (e *Endpoint) Set(s string) error {
+	e.Host, e.Port, _ = strings.Cut(s, ":")
+	return nil
+}
+
+type Config struct {
+	Debug   bool `flag:"d"`
+	DB      DB
+	Replica *DB
+	Skipped string    `flag:"-" env:"-"`
+	Started time.Time `layout:"2006-01-02"`
+	Owner   Owner
+	Listen  Endpoint
+	hidden  string
+}
@@ -0,0 +1,401 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// Command gofig validates, converts, compares and documents config files. The
+// configuration struct is described by a JSON schema file, as generated by
+// gofig.HelmValuesSchema.
+//
+// Usage:
+//
+//	gofig validate -schema schema.json config.yaml...
+//	gofig convert [-schema schema.json] -to toml config.yaml
+//	gofig diff a.json b.yaml
+//	gofig docs -schema schema.json
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/curvegrid/gofig"
+)
+
+// errDiff is returned by the diff command when the files differ.
+var errDiff = errors.New("files differ")
+
+const usage = `usage:
+  gofig validate -schema schema.json config...
+  gofig convert [-schema schema.json] -to format config
+  gofig diff config1 config2
+  gofig docs -schema schema.json
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	err := run(os.Args[1], os.Args[2:], os.Stdout)
+	if err == errDiff {
+		os.Exit(1)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "gofig: %v\n", err)
+		os.Exit(2)
+	}
+}
+
+// run runs a command with its arguments, writing its output to w.
+func run(cmd string, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("gofig "+cmd, flag.ContinueOnError)
+	schemaPath := fs.String("schema", "", "JSON schema file of the configuration")
+	to := fs.String("to", "", "output format: json, toml or yaml")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	var schema map[string]interface{}
+	if *schemaPath != "" {
+		schema, err = loadSchema(*schemaPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch cmd {
+	case "validate":
+		if schema == nil || fs.NArg() == 0 {
+			return errors.New("validate requires a schema and at least one config file")
+		}
+		for _, path := range fs.Args() {
+			_, err = load(path, schema)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "%v: ok\n", path)
+		}
+		return nil
+	case "convert":
+		if *to == "" || fs.NArg() != 1 {
+			return errors.New("convert requires an output format and a config file")
+		}
+		tree, err := load(fs.Arg(0), schema)
+		if err != nil {
+			return err
+		}
+		return gofig.Encode(w, *to, tree)
+	case "diff":
+		if fs.NArg() != 2 {
+			return errors.New("diff requires two config files")
+		}
+		return diff(fs.Arg(0), fs.Arg(1), schema, w)
+	case "docs":
+		if schema == nil {
+			return errors.New("docs requires a schema")
+		}
+		return docs(schema, w)
+	}
+	return fmt.Errorf("unknown command %v\n%v", cmd, usage)
+}
+
+// loadSchema reads a JSON schema file.
+func loadSchema(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var schema map[string]interface{}
+	err = json.Unmarshal(data, &schema)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing schema %v: %v", path, err)
+	}
+	return schema, nil
+}
+
+// load decodes a config file and validates it against the schema, if not nil.
+func load(path string, schema map[string]interface{}) (map[string]interface{}, error) {
+	format := gofig.FormatFromPath(path)
+	if format == "" {
+		return nil, fmt.Errorf("%v: config file type not supported", path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tree := make(map[string]interface{})
+	err = gofig.Decode(f, format, &tree)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	if schema != nil {
+		err = validate(tree, schema, "")
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", path, err)
+		}
+	}
+	return tree, nil
+}
+
+// validate checks a decoded value against a JSON schema. Only the subset of JSON schema
+// generated by gofig is supported: type, properties, additionalProperties, items,
+// minimum, maximum and enum. Like gofig, object keys are matched case-insensitively, and
+// the zero values are allowed by an enum.
+func validate(v interface{}, schema map[string]interface{}, path string) error {
+	name := path
+	if name == "" {
+		name = "config"
+	}
+
+	typ, _ := schema["type"].(string)
+	switch typ {
+	case "object":
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%v: expected an object, got %v", name, describe(v))
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		additional, _ := schema["additionalProperties"].(map[string]interface{})
+		for k, val := range m {
+			sub := lookup(properties, k)
+			if sub == nil {
+				sub = additional
+			}
+			if sub == nil {
+				return fmt.Errorf("%v: unknown key", join(path, k))
+			}
+			err := validate(val, sub, join(path, k))
+			if err != nil {
+				return err
+			}
+		}
+	case "array":
+		a, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("%v: expected an array, got %v", name, describe(v))
+		}
+		items, _ := schema["items"].(map[string]interface{})
+		for i, val := range a {
+			if items == nil {
+				break
+			}
+			err := validate(val, items, fmt.Sprintf("%v[%v]", name, i))
+			if err != nil {
+				return err
+			}
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			return fmt.Errorf("%v: expected a string, got %v", name, describe(v))
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%v: expected a boolean, got %v", name, describe(v))
+		}
+	case "integer", "number":
+		n, ok := number(v)
+		if !ok || (typ == "integer" && n != float64(int64(n))) {
+			return fmt.Errorf("%v: expected %v, got %v", name, article(typ), describe(v))
+		}
+		if min, ok := schema["minimum"].(float64); ok && n < min {
+			return fmt.Errorf("%v: %v is less than the minimum %v", name, n, min)
+		}
+		if max, ok := schema["maximum"].(float64); ok && n > max {
+			return fmt.Errorf("%v: %v is greater than the maximum %v", name, n, max)
+		}
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && !isZero(v) && !inEnum(v, enum) {
+		values := make([]string, len(enum))
+		for i, val := range enum {
+			values[i] = fmt.Sprint(val)
+		}
+		return fmt.Errorf("%v: invalid value %v, it must be one of %v", name, v, strings.Join(values, ", "))
+	}
+	return nil
+}
+
+// inEnum returns whether a decoded value is one of the allowed values of an enum, the
+// numbers being compared whatever their type.
+func inEnum(v interface{}, enum []interface{}) bool {
+	n, isNumber := number(v)
+	for _, val := range enum {
+		if m, ok := number(val); ok && isNumber && m == n {
+			return true
+		}
+		if val == v {
+			return true
+		}
+	}
+	return false
+}
+
+// isZero returns whether a decoded scalar is the zero value of its type.
+func isZero(v interface{}) bool {
+	if n, ok := number(v); ok {
+		return n == 0
+	}
+	return v == "" || v == false
+}
+
+// lookup returns the schema of a property, matched case-insensitively.
+func lookup(properties map[string]interface{}, key string) map[string]interface{} {
+	if sub, ok := properties[key].(map[string]interface{}); ok {
+		return sub
+	}
+	for k, sub := range properties {
+		if strings.EqualFold(k, key) {
+			sub, _ := sub.(map[string]interface{})
+			return sub
+		}
+	}
+	return nil
+}
+
+// number converts the numbers decoded from any format to float64.
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func describe(v interface{}) string {
+	if v == nil {
+		return "null"
+	}
+	return fmt.Sprintf("%T %v", v, v)
+}
+
+func article(typ string) string {
+	if typ == "integer" {
+		return "an integer"
+	}
+	return "a number"
+}
+
+func join(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// flatten returns the leaf values of a decoded tree keyed by their lowercase
+// dot-separated key paths, as gofig matches keys case-insensitively.
+func flatten(v interface{}, path string, values map[string]string) {
+	if m, ok := v.(map[string]interface{}); ok {
+		for k, val := range m {
+			flatten(val, join(path, strings.ToLower(k)), values)
+		}
+		return
+	}
+	values[path] = fmt.Sprint(v)
+}
+
+// diff prints the keys whose values differ between two config files, in a unified diff
+// like format, and returns errDiff if there is any.
+func diff(path1 string, path2 string, schema map[string]interface{}, w io.Writer) error {
+	tree1, err := load(path1, schema)
+	if err != nil {
+		return err
+	}
+	tree2, err := load(path2, schema)
+	if err != nil {
+		return err
+	}
+
+	values1 := make(map[string]string)
+	values2 := make(map[string]string)
+	flatten(tree1, "", values1)
+	flatten(tree2, "", values2)
+
+	keys := make([]string, 0, len(values1)+len(values2))
+	for k := range values1 {
+		keys = append(keys, k)
+	}
+	for k := range values2 {
+		if _, ok := values1[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintf(w, "--- %v\n+++ %v\n", path1, path2)
+	differ := false
+	for _, k := range keys {
+		val1, ok1 := values1[k]
+		val2, ok2 := values2[k]
+		if ok1 && ok2 && val1 == val2 {
+			continue
+		}
+		differ = true
+		if ok1 {
+			fmt.Fprintf(w, "-%v: %v\n", k, val1)
+		}
+		if ok2 {
+			fmt.Fprintf(w, "+%v: %v\n", k, val2)
+		}
+	}
+	if differ {
+		return errDiff
+	}
+	return nil
+}
+
+// docs prints a Markdown table of the configuration keys described by the schema.
+func docs(schema map[string]interface{}, w io.Writer) error {
+	fmt.Fprintf(w, "|key|type|default|description|\n|-|-|-|-|\n")
+	return docsRows(schema, "", w)
+}
+
+func docsRows(schema map[string]interface{}, path string, w io.Writer) error {
+	properties, _ := schema["properties"].(map[string]interface{})
+	keys := make([]string, 0, len(properties))
+	for k := range properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		sub, _ := properties[k].(map[string]interface{})
+		if sub == nil {
+			continue
+		}
+		if _, ok := sub["properties"]; ok {
+			err := docsRows(sub, join(path, k), w)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		def := ""
+		if val, ok := sub["default"]; ok {
+			data, err := json.Marshal(val)
+			if err != nil {
+				return err
+			}
+			def = "`" + string(data) + "`"
+		}
+		desc, _ := sub["description"].(string)
+		fmt.Fprintf(w, "|%v|%v|%v|%v|\n", join(path, k), sub["type"], def, strings.ReplaceAll(desc, "|", `\|`))
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/curvegrid/gofig"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	var b bytes.Buffer
+	err := run("validate", []string{"-schema", "testdata/schema.json", "testdata/config.yaml", "testdata/config.json"}, &b)
+	assert.NoError(t, err)
+	assert.Equal(t, "testdata/config.yaml: ok\ntestdata/config.json: ok\n", b.String())
+
+	err = run("validate", []string{"-schema", "testdata/schema.json", "testdata/invalid.toml"}, &b)
+	assert.EqualError(t, err, "testdata/invalid.toml: db.port: -1 is less than the minimum 0")
+
+	err = validate(map[string]interface{}{"db": map[string]interface{}{"port": "5432"}}, mustLoadSchema(t), "")
+	assert.EqualError(t, err, "db.port: expected an integer, got string 5432")
+
+	err = validate(map[string]interface{}{"unknown": 1}, mustLoadSchema(t), "")
+	assert.EqualError(t, err, "unknown: unknown key")
+
+	err = validate(map[string]interface{}{"db": map[string]interface{}{"port": 70000}}, mustLoadSchema(t), "")
+	assert.EqualError(t, err, "db.port: 70000 is greater than the maximum 65535")
+
+	err = validate(map[string]interface{}{"tags": []interface{}{"a", "d"}}, mustLoadSchema(t), "")
+	assert.EqualError(t, err, "tags[1]: invalid value d, it must be one of a, b, c")
+
+	schema := map[string]interface{}{"type": "integer", "enum": []interface{}{float64(1), float64(2)}}
+	assert.NoError(t, validate(int64(2), schema, "level"))
+	assert.NoError(t, validate(int64(0), schema, "level"))
+	assert.EqualError(t, validate(int64(3), schema, "level"), "level: invalid value 3, it must be one of 1, 2")
+
+	// the rules of the tags, in the schema generated by gofig
+	var cfg struct {
+		Env     string `json:"env" enum:"dev,prod"`
+		Workers int    `json:"workers" min:"1" max:"8"`
+	}
+	data, err := gofig.HelmValuesSchema(&cfg)
+	assert.NoError(t, err)
+	schema = nil
+	assert.NoError(t, json.Unmarshal(data, &schema))
+	assert.NoError(t, validate(map[string]interface{}{"env": "prod", "workers": int64(8)}, schema, ""))
+	assert.EqualError(t, validate(map[string]interface{}{"env": "test"}, schema, ""), "env: invalid value test, it must be one of dev, prod")
+	assert.EqualError(t, validate(map[string]interface{}{"workers": int64(9)}, schema, ""), "workers: 9 is greater than the maximum 8")
+
+	err = run("validate", []string{"testdata/config.yaml"}, &b)
+	assert.Error(t, err)
+}
+
+func TestConvert(t *testing.T) {
+	var b bytes.Buffer
+	err := run("convert", []string{"-schema", "testdata/schema.json", "-to", "toml", "testdata/config.yaml"}, &b)
+	assert.NoError(t, err)
+	assert.Equal(t, "debug = true\ntags = [\"a\", \"b\"]\n\n[db]\n  host = \"db.example.com\"\n  port = 5432\n", b.String())
+
+	b.Reset()
+	err = run("convert", []string{"-to", "json", "testdata/invalid.toml"}, &b)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"debug": true, "db": {"port": -1}}`, b.String())
+
+	err = run("convert", []string{"-schema", "testdata/schema.json", "-to", "json", "testdata/invalid.toml"}, &b)
+	assert.Error(t, err)
+	err = run("convert", []string{"-to", "ini", "testdata/config.yaml"}, &b)
+	assert.Error(t, err)
+}
+
+func TestDiff(t *testing.T) {
+	var b bytes.Buffer
+	err := run("diff", []string{"testdata/config.yaml", "testdata/config.json"}, &b)
+	assert.Equal(t, errDiff, err)
+	assert.Equal(t, "--- testdata/config.yaml\n+++ testdata/config.json\n-db.port: 5432\n+db.port: 6543\n", b.String())
+
+	b.Reset()
+	err = run("diff", []string{"testdata/config.yaml", "testdata/config.yaml"}, &b)
+	assert.NoError(t, err)
+}
+
+func TestDocs(t *testing.T) {
+	var b bytes.Buffer
+	err := run("docs", []string{"-schema", "testdata/schema.json"}, &b)
+	assert.NoError(t, err)
+	assert.Equal(t, "|key|type|default|description|\n|-|-|-|-|\n"+
+		"|db.host|string|`\"localhost\"`||\n"+
+		"|db.port|integer|`5432`|database port|\n"+
+		"|debug|boolean||enable debug logs|\n"+
+		"|tags|array|||\n", b.String())
+
+	err = run("unknown", nil, &b)
+	assert.Error(t, err)
+}
+
+func mustLoadSchema(t *testing.T) map[string]interface{} {
+	schema, err := loadSchema("testdata/schema.json")
+	assert.NoError(t, err)
+	return schema
+}
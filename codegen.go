@@ -0,0 +1,61 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Field describes a leaf field of a configuration struct. It is emitted by the
+// gofig-gen code generator so that gofig doesn't have to walk the struct type with
+// reflection to find its fields; the value is still set through Ptr with reflection.
+type Field struct {
+	// Names holds the Go field names from the root struct to the field, e.g. ["Sub", "RenamedStr"].
+	Names []string
+	// Tags holds the struct tags of each field in Names.
+	Tags []reflect.StructTag
+	// Ptr is a pointer to the field value.
+	Ptr interface{}
+}
+
+// FieldLister is implemented by the configuration structs whose field listing was
+// generated with gofig-gen (see cmd/gofig-gen).
+type FieldLister interface {
+	GofigFields() []Field
+}
+
+// walkFields calls the parser function on each field listed by generated code,
+// following the same key rules as walkStruct.
+func walkFields(fields []Field, parser fieldParser, cfgTag string) error {
+	for _, field := range fields {
+		path := make([]string, 0, len(field.Names))
+		for i, name := range field.Names {
+			key := field.Tags[i].Get(cfgTag)
+			if i := strings.IndexByte(key, ','); i >= 0 {
+				key = key[:i]
+			}
+			if key == "-" {
+				path = nil
+				break
+			} else if key == "" {
+				key = name
+			}
+			path = append(path, strings.ToLower(key))
+		}
+		if path == nil {
+			continue
+		}
+
+		val := reflect.ValueOf(field.Ptr).Elem()
+		tags := field.Tags[len(field.Tags)-1]
+		err := parser(path, strings.Join(field.Names, "."), &val, &tags)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
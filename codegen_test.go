@@ -0,0 +1,49 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// generatedTestStruct mimics the code generated by gofig-gen
+type generatedTestStruct struct {
+	Str     string
+	Sub     SubTestStruct
+	Skipped string `flag:"-" env:"-"`
+}
+
+func (v *generatedTestStruct) GofigFields() []Field {
+	return []Field{
+		{Names: []string{"Str"}, Tags: []reflect.StructTag{``}, Ptr: &v.Str},
+		{Names: []string{"Sub", "RenamedStr"}, Tags: []reflect.StructTag{``, `env:"str" flag:"str"`}, Ptr: &v.Sub.RenamedStr},
+		{Names: []string{"Skipped"}, Tags: []reflect.StructTag{`flag:"-" env:"-"`}, Ptr: &v.Skipped},
+	}
+}
+
+func TestFieldLister(t *testing.T) {
+	os.Setenv("GF_STR", "env")
+	os.Setenv("GF_SKIPPED", "env")
+	defer os.Unsetenv("GF_STR")
+	defer os.Unsetenv("GF_SKIPPED")
+
+	s := &generatedTestStruct{}
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("GF")
+	err := gf.ParseWithArgs(s, []string{"-sub-str", "flag"})
+	assert.NoError(t, err)
+
+	expected := &generatedTestStruct{
+		Str: "env",
+		Sub: SubTestStruct{RenamedStr: "flag"},
+	}
+	assert.Equal(t, expected, s)
+	assert.Nil(t, gf.flagSet.Lookup("skipped"))
+}
@@ -0,0 +1,34 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import "reflect"
+
+// defaultTag is the struct tag holding the default value of a field, decoded like an
+// environment variable, e.g. default:"8080" or default:"a,b".
+const defaultTag = "default"
+
+// applyDefaults sets the fields of v still holding their zero value to the value of their
+// default tag, the fields having a parse method in hooks being set with it. The values
+// set in code before parsing take precedence over the tags.
+func applyDefaults(v interface{}, hooks map[string]parseHook) error {
+	return parseStruct(v, func(path []string, name string, f *reflect.Value, tags *reflect.StructTag) error {
+		def, ok := tags.Lookup(defaultTag)
+		if !ok || !f.IsZero() {
+			return nil
+		}
+		var err error
+		if hook, ok := hooks[name]; ok {
+			err = hook.call(def)
+		} else {
+			err = decodeField(f, def, tags)
+		}
+		if err != nil {
+			return errorf("invalid default value '%v' of field %v: %v", def, name, err)
+		}
+		return nil
+	}, "json")
+}
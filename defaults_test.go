@@ -0,0 +1,72 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type defaultsStruct struct {
+	Port    int      `json:"port" default:"8080" desc:"listen port"`
+	Host    string   `json:"host" default:"localhost"`
+	Timeout Duration `json:"timeout" default:"30s"`
+	Tags    []string `json:"tags" default:"a,b"`
+	Debug   *bool    `json:"debug" default:"true"`
+	Name    string   `json:"name" default:"tagged"`
+}
+
+func TestDefaultTags(t *testing.T) {
+	// Case 1: the tags set the zero values, the values set in code taking precedence
+	s := &defaultsStruct{Name: "code"}
+	gf := New(ContinueOnError)
+	err := gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, 8080, s.Port)
+	assert.Equal(t, "localhost", s.Host)
+	assert.Equal(t, Duration(30*time.Second), s.Timeout)
+	assert.Equal(t, []string{"a", "b"}, s.Tags)
+	if assert.NotNil(t, s.Debug) {
+		assert.True(t, *s.Debug)
+	}
+	assert.Equal(t, "code", s.Name)
+
+	// Case 2: overridden by the config documents, env variables and flags, and shown in
+	// the usage message
+	os.Setenv("GFD_HOST", "db")
+	defer os.Unsetenv("GFD_HOST")
+	s = &defaultsStruct{}
+	gf = New(ContinueOnError)
+	gf.SetEnvPrefix("GFD")
+	err = gf.ParseWithArgs(s, []string{"-port", "9090"})
+	assert.NoError(t, err)
+	assert.Equal(t, 9090, s.Port)
+	assert.Equal(t, "db", s.Host)
+
+	s = &defaultsStruct{}
+	err = decodeConfig(strings.NewReader(`{"port": 1}`), jsonExtention, s)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, s.Port)
+
+	var b bytes.Buffer
+	gf = New(ContinueOnError)
+	gf.flagSet.SetOutput(&b)
+	err = gf.ParseWithArgs(&defaultsStruct{}, []string{"-h"})
+	assert.Error(t, err)
+	assert.Contains(t, b.String(), "listen port (default 8080)")
+
+	// Case 3: invalid default value
+	gf = New(ContinueOnError)
+	err = gf.ParseWithArgs(&struct {
+		Port int `default:"http"`
+	}{}, []string{})
+	assert.EqualError(t, err, "invalid default value 'http' of field Port: strconv.ParseInt: parsing \"http\": invalid syntax")
+}
@@ -0,0 +1,192 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"flag"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// enabledByTag is the struct tag of a nested struct only used if a bool field is true,
+// named by its key path, e.g. `enabledBy:"tls.enabled"`.
+const enabledByTag = "enabledBy"
+
+// section is a nested struct enabled by a toggle field.
+type section struct {
+	path    []string // key path of the section, following the keys of a format
+	toggle  string   // dot-separated key path of the toggle field
+	enabled bool
+}
+
+// sections returns the nested structs of v tagged with enabledBy, with their key path
+// following cfgTag, and reports the toggles which aren't bool fields.
+func sections(v interface{}, cfgTag string) ([]section, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil, nil
+	}
+	var found []section
+	err := walkSections(rv.Elem(), nil, "", func(path []string, name string, toggle string) error {
+		toggle = strings.ToLower(toggle)
+		f, ok := fieldByKey(v, toggle)
+		if !ok || f.Kind() != reflect.Bool {
+			return errorf("field %v is enabled by '%v', which isn't a bool field", name, toggle)
+		}
+		found = append(found, section{path: path, toggle: toggle, enabled: f.Bool()})
+		return nil
+	}, cfgTag)
+	return found, err
+}
+
+// walkSections calls fn on each nested struct of rv tagged with enabledBy.
+func walkSections(rv reflect.Value, parents []string, parentName string, fn func(path []string, name string, toggle string) error, cfgTag string) error {
+	for i, field := range structFields(rv.Type(), cfgTag) {
+		if field.key == "" {
+			continue
+		}
+		f := structValue(rv.Field(i))
+		if !f.IsValid() {
+			continue
+		}
+		path := append(parents[:len(parents):len(parents)], field.key)
+		name := field.name
+		if parentName != "" {
+			name = parentName + "." + name
+		}
+		if toggle, ok := field.tags.Lookup(enabledByTag); ok {
+			err := fn(path, name, toggle)
+			if err != nil {
+				return err
+			}
+		}
+		err := walkSections(f, path, name, fn, cfgTag)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inactiveSection returns the toggle of the innermost disabled section containing the
+// key path, if any.
+func inactiveSection(sections []section, path []string) (string, bool) {
+	toggle, depth := "", 0
+	for _, s := range sections {
+		if !s.enabled && len(s.path) > depth && hasPathPrefix(path, s.path) {
+			toggle, depth = s.toggle, len(s.path)
+		}
+	}
+	return toggle, depth > 0
+}
+
+// hasPathPrefix returns whether the key path starts with prefix.
+func hasPathPrefix(path, prefix []string) bool {
+	if len(path) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if path[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// InactiveSections returns the sorted key paths of the nested structs of the parsed
+// configuration tagged with enabledBy, e.g. `enabledBy:"tls.enabled"`, whose toggle is
+// false. The fields of the inactive sections aren't validated, e.g. their secret
+// references aren't resolved, and are reported as such by -gofig-print-config.
+func InactiveSections() []string { return gf.InactiveSections() }
+
+// InactiveSections returns the sorted key paths of the nested structs of the parsed
+// configuration tagged with enabledBy, e.g. `enabledBy:"tls.enabled"`, whose toggle is
+// false. The fields of the inactive sections aren't validated, e.g. their secret
+// references aren't resolved, and are reported as such by -gofig-print-config.
+func (gf *Gofig) InactiveSections() []string {
+	gf.mu.Lock()
+	defer gf.mu.Unlock()
+	var inactive []string
+	for _, s := range gf.sections {
+		if !s.enabled {
+			inactive = append(inactive, strings.Join(s.path, "."))
+		}
+	}
+	sort.Strings(inactive)
+	return inactive
+}
+
+// isInactive returns whether the field at the key path, following the json tags, is in
+// an inactive section of the last parse.
+func (gf *Gofig) isInactive(path []string) bool {
+	_, inactive := inactiveSection(gf.sections, path)
+	return inactive
+}
+
+// annotateSectionFlags appends the toggle of their section to the usage of the flags of
+// the sections of v.
+func (gf *Gofig) annotateSectionFlags(fs *flag.FlagSet, v interface{}) error {
+	found, err := sections(v, "flag")
+	if err != nil || len(found) == 0 {
+		return err
+	}
+	for _, s := range found {
+		prefix := strings.Join(append(gf.scope[:len(gf.scope):len(gf.scope)], s.path...), flagSeparator) + flagSeparator
+		fs.VisitAll(func(f *flag.Flag) {
+			if strings.HasPrefix(f.Name, prefix) {
+				f.Usage += gf.translatef(" (used if %v is true)", s.toggle)
+			}
+		})
+	}
+	return nil
+}
+
+// encodeSections encodes v as YAML, commenting the inactive sections.
+func (gf *Gofig) encodeSections(w io.Writer, v interface{}) error {
+	found, err := sections(v, "yaml")
+	if err != nil || len(found) == 0 {
+		return Encode(w, "yaml", v)
+	}
+	var doc yaml.Node
+	err = doc.Encode(v)
+	if err != nil {
+		return err
+	}
+	for _, s := range found {
+		if s.enabled {
+			continue
+		}
+		if key := yamlKeyNode(&doc, s.path); key != nil {
+			key.HeadComment = gf.translatef("inactive: %v is false", s.toggle)
+		}
+	}
+	return Encode(w, "yaml", &doc)
+}
+
+// yamlKeyNode returns the key node at the key path of a YAML mapping node.
+func yamlKeyNode(node *yaml.Node, path []string) *yaml.Node {
+	var key *yaml.Node
+	for _, k := range path {
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		key = nil
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == k {
+				key, node = node.Content[i], node.Content[i+1]
+				break
+			}
+		}
+		if key == nil {
+			return nil
+		}
+	}
+	return key
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type tlsConfig struct {
+	Cert string `desc:"certificate"`
+	Key  string
+}
+
+type sectionStruct struct {
+	Host string
+	TLS  struct {
+		Enabled bool
+		Files   tlsConfig `enabledBy:"tls.enabled"`
+	}
+}
+
+func TestEnabledBy(t *testing.T) {
+	parse := func(args ...string) (*Gofig, string, error) {
+		var out bytes.Buffer
+		gf := New(ContinueOnError)
+		gf.SetFlagOutput(&out)
+		gf.SetSecretResolver("test", testResolver{})
+		s := &sectionStruct{}
+		s.TLS.Files.Key = "test://key"
+		err := gf.ParseWithArgs(s, args)
+		return gf, out.String(), err
+	}
+
+	// Case 1: inactive section
+	gf, out, err := parse("-gofig-print-config")
+	assert.Equal(t, ErrHandled, err)
+	assert.Equal(t, "host: \"\"\ntls:\n  enabled: false\n  # inactive: tls.enabled is false\n  files:\n    cert: \"\"\n    key: test://key\n", out)
+	assert.Equal(t, []string{"tls.files"}, gf.InactiveSections())
+	assert.Equal(t, "certificate (used if tls.enabled is true)", gf.flagSet.Lookup("tls-files-cert").Usage)
+
+	// Case 2: active section, its secret references resolved, and redacted
+	gf, out, err = parse("-tls-enabled", "-gofig-print-config")
+	assert.Equal(t, ErrHandled, err)
+	assert.Equal(t, "host: \"\"\ntls:\n  enabled: true\n  files:\n    cert: \"\"\n    key: <redacted>\n", out)
+	assert.Empty(t, gf.InactiveSections())
+
+	// Case 3: invalid toggle
+	type invalidStruct struct {
+		Host string
+		TLS  tlsConfig `enabledBy:"host"`
+	}
+	err = New(ContinueOnError).ParseWithArgs(&invalidStruct{}, []string{})
+	assert.EqualError(t, err, "field TLS is enabled by 'host', which isn't a bool field")
+}
+
+type testResolver struct{}
+
+func (testResolver) Resolve(ctx context.Context, ref string) (string, error) {
+	return "secret", nil
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// enumTag is the struct tag listing the comma-separated allowed values of a field, e.g.
+// enum:"dev,staging,prod".
+const enumTag = "enum"
+
+// enumValues returns the allowed values of an enum tag.
+func enumValues(enum string) []string {
+	values := strings.Split(enum, ",")
+	for i := range values {
+		values[i] = strings.TrimSpace(values[i])
+	}
+	return values
+}
+
+// checkEnums returns an error if a field of v tagged with enum holds a value which isn't
+// allowed, the items of the lists being checked one by one. The zero values are
+// accepted, so that an unset field is left to the required tag, as are the fields of
+// the inactive sections.
+func (gf *Gofig) checkEnums(v interface{}) error {
+	var invalid, value, allowed string
+	err := parseStruct(v, func(path []string, name string, f *reflect.Value, tags *reflect.StructTag) error {
+		enum, ok := tags.Lookup(enumTag)
+		if !ok || f.IsZero() || gf.isInactive(path) {
+			return nil
+		}
+		values := enumValues(enum)
+		items := []reflect.Value{*f}
+		if f.Kind() == reflect.Slice || f.Kind() == reflect.Array {
+			items = items[:0]
+			for i := 0; i < f.Len(); i++ {
+				items = append(items, f.Index(i))
+			}
+		}
+		for _, item := range items {
+			s, ok := encodeString(&item)
+			if !ok {
+				return errorf("field %v has an enum tag, but isn't a single value or a list", name)
+			}
+			if !containsString(values, s) {
+				invalid, value, allowed = name, s, strings.Join(values, ", ")
+				return errEnum
+			}
+		}
+		return nil
+	}, "json")
+	if err != errEnum {
+		return err
+	}
+	names := gf.fieldNames(v, "json", true)
+	return errorf("invalid value '%v' of field %v (%v), it must be one of %v", value, invalid, names[invalid].format(gf.translate), allowed)
+}
+
+// errEnum stops the walk of the fields at the first value not allowed.
+var errEnum = errorf("value not allowed")
+
+// containsString returns whether s is in list.
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// enumSchema returns the allowed values of an enum tag for the JSON schema of a field of
+// type rt, as numbers for the numeric fields.
+func enumSchema(rt reflect.Type, enum string) []interface{} {
+	for rt.Kind() == reflect.Ptr || rt.Kind() == reflect.Slice || rt.Kind() == reflect.Array {
+		rt = rt.Elem()
+	}
+	values := enumValues(enum)
+	schema := make([]interface{}, len(values))
+	for i, val := range values {
+		schema[i] = val
+		switch rt.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			if n, err := strconv.ParseFloat(val, 64); err == nil && !isTextType(rt) {
+				schema[i] = n
+			}
+		}
+	}
+	return schema
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type enumStruct struct {
+	Env     string   `json:"env" enum:"dev, staging, prod" desc:"deployment environment"`
+	Level   int      `json:"level" enum:"1,2,3"`
+	Regions []string `json:"regions" enum:"eu,us"`
+	Mode    *string  `json:"mode" enum:"fast,safe"`
+}
+
+func TestEnums(t *testing.T) {
+	for args, expected := range map[string]string{
+		"":                              "",
+		"-env prod -level 2 -mode safe": "",
+		"-regions eu,us":                "",
+		"-env test":                     "invalid value 'test' of field Env (flag -env, env ENV, key env), it must be one of dev, staging, prod",
+		"-level 4":                      "invalid value '4' of field Level (flag -level, env LEVEL, key level), it must be one of 1, 2, 3",
+		"-regions eu,ap":                "invalid value 'ap' of field Regions (flag -regions, env REGIONS, key regions), it must be one of eu, us",
+		"-mode slow":                    "invalid value 'slow' of field Mode (flag -mode, env MODE, key mode), it must be one of fast, safe",
+	} {
+		gf := New(ContinueOnError)
+		err := gf.ParseWithArgs(&enumStruct{}, strings.Fields(args))
+		if expected == "" {
+			assert.NoError(t, err, args)
+		} else {
+			assert.EqualError(t, err, expected, args)
+		}
+	}
+
+	// the allowed values are shown in the usage message and the JSON schema
+	var b bytes.Buffer
+	gf := New(ContinueOnError)
+	gf.flagSet.SetOutput(&b)
+	err := gf.ParseWithArgs(&enumStruct{}, []string{"-h"})
+	assert.Error(t, err)
+	assert.Contains(t, b.String(), "deployment environment (one of dev, staging, prod)")
+
+	schema, err := GenerateOpenAPISchema(&enumStruct{})
+	assert.NoError(t, err)
+	assert.Contains(t, string(schema), `"enum": [
+          "eu",
+          "us"
+        ]`)
+	assert.Contains(t, string(schema), `"enum": [
+        1,
+        2,
+        3
+      ]`)
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// EnvVarInfo describes the environment variable of a configuration field.
+type EnvVarInfo struct {
+	// Name is the environment variable name, including the prefix.
+	Name string
+	// Field is the struct field path, e.g. "Sub.RenamedStr".
+	Field string
+	// Value is the current value of the field, encoded like an environment variable.
+	Value string
+	// Desc is the field description, from the desc tag.
+	Desc string
+	// Secret is set for the fields tagged with secret:"true", the fields read from the
+	// keyring or a credential, and the fields set from a secret reference.
+	Secret bool
+}
+
+// isSecret returns whether a field is tagged as a secret, or read from the keyring or a
+// credential.
+func isSecret(tags *reflect.StructTag) bool {
+	return tags.Get("secret") == "true" || tags.Get(keyringTag) != "" || tags.Get(credentialTag) != ""
+}
+
+// EnvVars returns the environment variables of the fields of v, a pointer to a
+// configuration struct, with their current values. Fields of types which can't be set
+// from an environment variable are omitted.
+func EnvVars(v interface{}) []EnvVarInfo { return gf.EnvVars(v) }
+
+// EnvVars returns the environment variables of the fields of v, a pointer to a
+// configuration struct, with their current values. Fields of types which can't be set
+// from an environment variable are omitted.
+func (gf *Gofig) EnvVars(v interface{}) []EnvVarInfo {
+	var vars []EnvVarInfo
+	_ = parseStruct(v, gf.scoped(func(path []string, name string, f *reflect.Value, tags *reflect.StructTag) error {
+		val, ok := encodeField(f, tags)
+		if !ok {
+			return nil
+		}
+		vars = append(vars, EnvVarInfo{
+			Name:   gf.getEnvKey(path),
+			Field:  name,
+			Value:  val,
+			Desc:   tags.Get("desc"),
+			Secret: gf.isSecretField(name, tags),
+		})
+		return nil
+	}), "env")
+	return vars
+}
+
+// ExportEnv writes the environment variables of the fields of v, a pointer to a
+// configuration struct, as a shell script of export NAME='value' lines, which is also
+// understood by most .env file loaders. The values of the secret fields are redacted,
+// see CommandEnv to pass them to a subprocess.
+func ExportEnv(v interface{}, w io.Writer) error { return gf.ExportEnv(v, w) }
+
+// ExportEnv writes the environment variables of the fields of v, a pointer to a
+// configuration struct, as a shell script of export NAME='value' lines, which is also
+// understood by most .env file loaders. The values of the secret fields are redacted,
+// see CommandEnv to pass them to a subprocess.
+func (gf *Gofig) ExportEnv(v interface{}, w io.Writer) error {
+	for _, env := range gf.EnvVars(v) {
+		var err error
+		if env.Secret {
+			_, err = fmt.Fprintf(w, "# export %v=<redacted>\n", env.Name)
+		} else {
+			_, err = fmt.Fprintf(w, "export %v=%v\n", env.Name, shellQuote(env.Value))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CommandEnv returns the environment variables of the fields of v, a pointer to a
+// configuration struct, as NAME=value entries, secrets included, to configure a
+// subprocess sharing the configuration, e.g.:
+//
+//	cmd.Env = append(os.Environ(), gofig.CommandEnv(&cfg)...)
+func CommandEnv(v interface{}) []string { return gf.CommandEnv(v) }
+
+// CommandEnv returns the environment variables of the fields of v, a pointer to a
+// configuration struct, as NAME=value entries, secrets included, to configure a
+// subprocess sharing the configuration, e.g.:
+//
+//	cmd.Env = append(os.Environ(), gf.CommandEnv(&cfg)...)
+func (gf *Gofig) CommandEnv(v interface{}) []string {
+	vars := gf.EnvVars(v)
+	env := make([]string, len(vars))
+	for i, v := range vars {
+		env[i] = v.Name + "=" + v.Value
+	}
+	return env
+}
+
+// shellQuote quotes a value for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type envTestStruct struct {
+	Host     string `desc:"server host"`
+	Port     int
+	Password string `secret:"true"`
+	Sub      SubTestStruct
+	Skipped  string `env:"-"`
+	List     []string
+}
+
+func TestEnvVars(t *testing.T) {
+	s := &envTestStruct{Host: "it's", Port: 80, Password: "pass", Sub: SubTestStruct{RenamedStr: "sub"}, List: []string{"a", "b,c"}}
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("gf")
+
+	assert.Equal(t, []EnvVarInfo{
+		{Name: "GF_HOST", Field: "Host", Value: "it's", Desc: "server host"},
+		{Name: "GF_PORT", Field: "Port", Value: "80"},
+		{Name: "GF_PASSWORD", Field: "Password", Value: "pass", Secret: true},
+		{Name: "GF_SUB_STR", Field: "Sub.RenamedStr", Value: "sub"},
+		{Name: "GF_LIST", Field: "List", Value: `a,"b,c"`},
+	}, gf.EnvVars(s))
+
+	var b bytes.Buffer
+	err := gf.ExportEnv(s, &b)
+	assert.NoError(t, err)
+	assert.Equal(t, "export GF_HOST='it'\\''s'\n"+
+		"export GF_PORT='80'\n"+
+		"# export GF_PASSWORD=<redacted>\n"+
+		"export GF_SUB_STR='sub'\n"+
+		"export GF_LIST='a,\"b,c\"'\n", b.String())
+
+	// child
+	assert.Equal(t, "GF_DB_HOST", gf.Child("db").EnvVars(s)[0].Name)
+}
+
+func TestCommandEnv(t *testing.T) {
+	s := &envTestStruct{Host: "host", Password: "pass", List: []string{"x", "y"}}
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("GFE")
+	env := gf.CommandEnv(s)
+	assert.Equal(t, []string{"GFE_HOST=host", "GFE_PORT=0", "GFE_PASSWORD=pass", "GFE_SUB_STR=", "GFE_LIST=x,y"}, env)
+
+	// the subprocess parses the same configuration
+	for _, kv := range env {
+		kvs := strings.SplitN(kv, "=", 2)
+		os.Setenv(kvs[0], kvs[1])
+		defer os.Unsetenv(kvs[0])
+	}
+	u := &envTestStruct{Port: 1}
+	err := gf.ParseWithArgs(u, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, s, u)
+}
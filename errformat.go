@@ -0,0 +1,79 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ErrorFormat defines how the parsing errors are formatted
+type ErrorFormat int
+
+const (
+	// ErrorsText formats the errors as plain text messages
+	ErrorsText ErrorFormat = iota
+	// ErrorsJSON formats the errors as JSON objects, e.g. {"error": "...", "field":
+	// "DB.Port", "flag": "-db-port", "env": "GF_DB_PORT", "key": "db.port"}, the field
+	// names being set for the errors on a field
+	ErrorsJSON
+)
+
+// SetErrorFormat sets the format of the errors returned by Parse, and printed with
+// ExitOnError, e.g. ErrorsJSON for tooling parsing the container logs.
+func SetErrorFormat(format ErrorFormat) { gf.SetErrorFormat(format) }
+
+// SetErrorFormat sets the format of the errors returned by Parse, and printed with
+// ExitOnError, e.g. ErrorsJSON for tooling parsing the container logs.
+func (gf *Gofig) SetErrorFormat(format ErrorFormat) {
+	gf.errFormat = format
+}
+
+// formatError returns a parsing error in the error format.
+func (gf *Gofig) formatError(err error) error {
+	if errs, ok := err.(ParseErrors); ok {
+		formatted := make(ParseErrors, len(errs))
+		for i := range errs {
+			formatted[i] = gf.formatError(errs[i])
+		}
+		return formatted
+	}
+	err = gf.withTranslation(err)
+	if err == nil || err == ErrHandled || gf.errFormat != ErrorsJSON {
+		return err
+	}
+	return &jsonError{err: err}
+}
+
+// jsonError is an error formatted as a JSON object
+type jsonError struct {
+	err error
+}
+
+func (e *jsonError) Error() string {
+	obj := struct {
+		Error string `json:"error"`
+		Field string `json:"field,omitempty"`
+		Flag  string `json:"flag,omitempty"`
+		Env   string `json:"env,omitempty"`
+		Key   string `json:"key,omitempty"`
+	}{Error: e.err.Error()}
+	var fe *FieldError
+	if errors.As(e.err, &fe) {
+		obj.Field = fe.Field
+		if fe.Flag != "" {
+			obj.Flag = "-" + fe.Flag
+		}
+		obj.Env = fe.Env
+		obj.Key = fe.Path
+	}
+	b, _ := json.Marshal(obj)
+	return string(b)
+}
+
+func (e *jsonError) Unwrap() error {
+	return e.err
+}
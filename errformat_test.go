@@ -0,0 +1,52 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorFormat(t *testing.T) {
+	type Config struct {
+		DB struct {
+			Port int
+		}
+	}
+
+	// Case 1: errors on a field
+	gf := New(ContinueOnError)
+	gf.SetErrorFormat(ErrorsJSON)
+	gf.SetEnvPrefix("GFE")
+	gf.AddSource(&testSource{name: "yaml", doc: &Document{Format: "yaml", Data: []byte("db:\n  port: 1.5\n")}})
+	err := gf.ParseWithArgs(&Config{}, []string{})
+	assert.JSONEq(t, `{
+		"error": "error decoding source yaml: error parsing key 'db.port' with value '1.5' into int (flag -db-port, env GFE_DB_PORT, key db.port)",
+		"field": "DB.Port",
+		"flag": "-db-port",
+		"env": "GFE_DB_PORT",
+		"key": "db.port"
+	}`, err.Error())
+
+	// Case 2: other errors
+	gf = New(ContinueOnError)
+	gf.SetErrorFormat(ErrorsJSON)
+	gf.SetFlagOutput(io.Discard)
+	err = gf.ParseWithArgs(&Config{}, []string{"-unknown"})
+	assert.JSONEq(t, `{"error": "flag provided but not defined: -unknown"}`, err.Error())
+
+	// Case 3: the error is wrapped, and built-in flags aren't errors
+	err = errors.Unwrap(err)
+	assert.EqualError(t, err, "flag provided but not defined: -unknown")
+	gf = New(ContinueOnError)
+	gf.SetErrorFormat(ErrorsJSON)
+	gf.SetFlagOutput(io.Discard)
+	err = gf.ParseWithArgs(&Config{}, []string{"-gofig-check-config"})
+	assert.Equal(t, ErrHandled, err)
+}
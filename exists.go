@@ -0,0 +1,130 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// existsTag and modeTag are the struct tags of the path fields checked once parsed:
+// exists:"file" or exists:"dir" requires the path to exist and be a file or a directory,
+// and mode:"readable", mode:"writable" or mode:"readable,writable" requires it to be
+// opened with these permissions.
+const (
+	existsTag = "exists"
+	modeTag   = "mode"
+)
+
+// checkPaths checks the paths of the fields of v tagged with exists or mode, which may be
+// a path or a list of paths (e.g. a PathList). The empty paths and the fields of the
+// inactive sections aren't checked.
+func (gf *Gofig) checkPaths(v interface{}) error {
+	return parseStruct(v, func(path []string, name string, f *reflect.Value, tags *reflect.StructTag) error {
+		exists, hasExists := tags.Lookup(existsTag)
+		mode, hasMode := tags.Lookup(modeTag)
+		if !hasExists && !hasMode {
+			return nil
+		}
+		if hasExists && exists != "file" && exists != "dir" {
+			return errorf("invalid exists tag '%v' of field %v, it must be file or dir", exists, name)
+		}
+		var readable, writable bool
+		if hasMode {
+			for _, m := range strings.Split(mode, ",") {
+				switch strings.TrimSpace(m) {
+				case "readable":
+					readable = true
+				case "writable":
+					writable = true
+				default:
+					return errorf("invalid mode tag '%v' of field %v, it must be readable, writable or both", mode, name)
+				}
+			}
+		}
+
+		var paths []string
+		switch {
+		case f.Kind() == reflect.String:
+			paths = []string{f.String()}
+		case f.Kind() == reflect.Slice && f.Type().Elem().Kind() == reflect.String:
+			for i := 0; i < f.Len(); i++ {
+				paths = append(paths, f.Index(i).String())
+			}
+		default:
+			return errorf("field %v has an exists or mode tag, but isn't a path or a list of paths", name)
+		}
+		if gf.isInactive(path) {
+			return nil
+		}
+
+		for _, p := range paths {
+			if p == "" {
+				continue
+			}
+			err := checkPath(p, exists, readable, writable)
+			if err != nil {
+				return &fieldError{name: name, err: err}
+			}
+		}
+		return nil
+	}, "json")
+}
+
+// checkPath checks that a path exists as a file or a directory if exists is set, and
+// that it is readable or writable. A missing path which must be writable but not exist
+// is writable if its directory is, e.g. a file created by the application.
+func checkPath(path string, exists string, readable bool, writable bool) error {
+	fi, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		switch {
+		case exists == "dir":
+			return errorf("directory '%v' doesn't exist", path)
+		case exists != "" || readable:
+			return errorf("file '%v' doesn't exist", path)
+		case writable:
+			return checkPath(filepath.Dir(path), "dir", false, true)
+		}
+		return nil
+	} else if err != nil {
+		return errorf("error checking '%v': %v", path, err)
+	}
+
+	switch {
+	case exists == "file" && fi.IsDir():
+		return errorf("'%v' is a directory, not a file", path)
+	case exists == "dir" && !fi.IsDir():
+		return errorf("'%v' isn't a directory", path)
+	}
+
+	if readable {
+		file, err := os.Open(path)
+		if err != nil {
+			return errorf("'%v' isn't readable", path)
+		}
+		file.Close()
+	}
+	if writable {
+		if fi.IsDir() {
+			// a directory is writable if a file can be created in it
+			file, err := os.CreateTemp(path, ".gofig-*")
+			if err != nil {
+				return errorf("directory '%v' isn't writable", path)
+			}
+			file.Close()
+			os.Remove(file.Name())
+		} else {
+			file, err := os.OpenFile(path, os.O_WRONLY, 0)
+			if err != nil {
+				return errorf("'%v' isn't writable", path)
+			}
+			file.Close()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type existsStruct struct {
+	Cert    string   `json:"cert" exists:"file" mode:"readable"`
+	Data    string   `json:"data" exists:"dir" mode:"readable,writable"`
+	Log     string   `json:"log" mode:"writable"`
+	Plugins PathList `json:"plugins" exists:"dir"`
+	Backup  struct {
+		Enabled bool   `json:"enabled"`
+		Dir     string `json:"dir" exists:"dir"`
+	} `json:"backup"`
+	Archive struct {
+		Dir string `json:"dir" exists:"dir"`
+	} `json:"archive" enabledBy:"backup.enabled"`
+}
+
+func TestExistsTags(t *testing.T) {
+	dir := t.TempDir()
+	cert := filepath.Join(dir, "cert.pem")
+	assert.NoError(t, os.WriteFile(cert, []byte("cert"), 0600))
+	missing := filepath.Join(dir, "missing")
+
+	// Case 1: valid paths, the empty paths and the inactive sections being ignored
+	s := &existsStruct{}
+	gf := New(ContinueOnError)
+	err := gf.ParseWithArgs(s, []string{"-cert", cert, "-data", dir, "-log", filepath.Join(dir, "app.log"),
+		"-plugins", dir, "-archive-dir", missing})
+	assert.NoError(t, err)
+
+	// Case 2: invalid paths
+	for _, c := range []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"-cert", missing}, "file '" + missing + "' doesn't exist (flag -cert, env CERT, key cert)"},
+		{[]string{"-cert", dir}, "'" + dir + "' is a directory, not a file (flag -cert, env CERT, key cert)"},
+		{[]string{"-data", cert}, "'" + cert + "' isn't a directory (flag -data, env DATA, key data)"},
+		{[]string{"-log", filepath.Join(missing, "app.log")}, "directory '" + missing + "' doesn't exist (flag -log, env LOG, key log)"},
+		{[]string{"-plugins", dir + string(os.PathListSeparator) + missing}, "directory '" + missing + "' doesn't exist (flag -plugins, env PLUGINS, key plugins)"},
+		{[]string{"-backup-enabled", "-archive-dir", missing}, "directory '" + missing + "' doesn't exist (flag -archive-dir, env ARCHIVE_DIR, key archive.dir)"},
+	} {
+		s := &existsStruct{}
+		gf := New(ContinueOnError)
+		err := gf.ParseWithArgs(s, c.args)
+		assert.EqualError(t, err, c.expected, c.args)
+	}
+
+	// Case 3: invalid tags
+	bad := &struct {
+		Port int `json:"port" exists:"file"`
+	}{}
+	gf = New(ContinueOnError)
+	err = gf.ParseWithArgs(bad, []string{})
+	assert.EqualError(t, err, "field Port has an exists or mode tag, but isn't a path or a list of paths")
+}
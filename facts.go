@@ -0,0 +1,116 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// factsPrefix is the prefix of the facts in the key paths, variable references and
+// value selectors, e.g. ${facts.hostname}.
+const factsPrefix = "facts."
+
+// namespaceFile is the namespace of the service account mounted into the Kubernetes pods.
+var namespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// factEnvVars are the environment variables hinting at the facts not reported by the
+// runtime, by order of preference: the downward API variables of the Kubernetes pods
+// are usually named POD_NAME, POD_NAMESPACE and NODE_NAME.
+var factEnvVars = map[string][]string{
+	"pod_name":      {"POD_NAME"},
+	"pod_namespace": {"POD_NAMESPACE"},
+	"node_name":     {"NODE_NAME"},
+	"region":        {"REGION", "AWS_REGION", "AWS_DEFAULT_REGION", "CLOUDSDK_COMPUTE_REGION", "FLY_REGION"},
+	"zone":          {"ZONE", "AVAILABILITY_ZONE", "CLOUDSDK_COMPUTE_ZONE"},
+}
+
+// Facts returns the facts of the host and runtime: hostname, num_cpu, os and arch, and
+// when known, pod_name, pod_namespace and node_name (from the Kubernetes downward API
+// variables or the service account), region and zone (from the usual environment
+// variables of the cloud providers, e.g. AWS_REGION).
+func Facts() map[string]string {
+	facts := map[string]string{
+		"num_cpu": strconv.Itoa(runtime.NumCPU()),
+		"os":      runtime.GOOS,
+		"arch":    runtime.GOARCH,
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		facts["hostname"] = hostname
+	}
+	for fact, keys := range factEnvVars {
+		for _, key := range keys {
+			if val := os.Getenv(key); val != "" {
+				facts[fact] = val
+				break
+			}
+		}
+	}
+	if _, ok := facts["pod_namespace"]; !ok {
+		if data, err := os.ReadFile(namespaceFile); err == nil {
+			facts["pod_namespace"] = strings.TrimSpace(string(data))
+		}
+	}
+	if _, ok := facts["pod_name"]; !ok && os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		// the hostname of a pod is its name
+		facts["pod_name"] = facts["hostname"]
+	}
+	return facts
+}
+
+// FactsSource is a read-only source of the facts of the host and runtime (see Facts),
+// setting the fields at their key path prefixed with "facts.", e.g. "facts.hostname".
+type FactsSource struct {
+	facts map[string]string
+}
+
+// NewFactsSource returns a Source of the facts of the host and runtime.
+func NewFactsSource() *FactsSource {
+	return &FactsSource{facts: Facts()}
+}
+
+// Name returns "facts".
+func (s *FactsSource) Name() string {
+	return "facts"
+}
+
+// Load returns the facts.
+func (s *FactsSource) Load(ctx context.Context) (*Document, error) {
+	values := make(map[string]string, len(s.facts))
+	for k, v := range s.facts {
+		values[factsPrefix+k] = v
+	}
+	return &Document{Values: values}, nil
+}
+
+// AddFacts adds the source of the facts of the host and runtime (see Facts), which are
+// also available in the expansion of the environment variables (see SetEnvExpand), e.g.
+// GF_URL='http://${facts.hostname}:8080', and in the value selectors of the config
+// documents (see SetLabels), e.g. `replicas: {default: 2, "facts.region=eu-west-1": 4}`.
+func AddFacts() { gf.AddFacts() }
+
+// AddFacts adds the source of the facts of the host and runtime (see Facts), which are
+// also available in the expansion of the environment variables (see SetEnvExpand), e.g.
+// GF_URL='http://${facts.hostname}:8080', and in the value selectors of the config
+// documents (see SetLabels), e.g. `replicas: {default: 2, "facts.region=eu-west-1": 4}`.
+func (gf *Gofig) AddFacts() {
+	src := NewFactsSource()
+	gf.facts = src.facts
+	gf.AddSource(src)
+}
+
+// lookupFact returns the value of a fact referenced with the facts. prefix, if the facts
+// were added.
+func (gf *Gofig) lookupFact(name string) (string, bool) {
+	if gf.facts == nil || !strings.HasPrefix(name, factsPrefix) {
+		return "", false
+	}
+	val, ok := gf.facts[name[len(factsPrefix):]]
+	return val, ok
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type factsStruct struct {
+	URL      string `json:"url"`
+	Replicas int    `json:"replicas"`
+	Facts    struct {
+		Hostname     string `json:"hostname"`
+		NumCPU       int    `json:"num_cpu"`
+		PodNamespace string `json:"pod_namespace"`
+		Region       string `json:"region"`
+	} `json:"facts"`
+}
+
+func TestFacts(t *testing.T) {
+	dir := t.TempDir()
+	prevNamespaceFile := namespaceFile
+	namespaceFile = filepath.Join(dir, "namespace")
+	defer func() { namespaceFile = prevNamespaceFile }()
+	assert.NoError(t, os.WriteFile(namespaceFile, []byte("payments\n"), 0600))
+	os.Setenv("AWS_REGION", "eu-west-1")
+	defer os.Unsetenv("AWS_REGION")
+
+	facts := Facts()
+	hostname, _ := os.Hostname()
+	assert.Equal(t, hostname, facts["hostname"])
+	assert.Equal(t, strconv.Itoa(runtime.NumCPU()), facts["num_cpu"])
+	assert.Equal(t, "payments", facts["pod_namespace"])
+	assert.Equal(t, "eu-west-1", facts["region"])
+
+	cfgFile := filepath.Join(dir, "config.yaml")
+	assert.NoError(t, os.WriteFile(cfgFile, []byte(`replicas: {default: 2, "facts.region=eu-west-1": 4}`), 0600))
+	os.Setenv("FACTS_URL", "http://${facts.hostname}:${facts.port:-8080}")
+	defer os.Unsetenv("FACTS_URL")
+
+	s := &factsStruct{}
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("FACTS")
+	gf.SetEnvExpand(true)
+	gf.AddConfigFile(filepath.Join(dir, "config"))
+	gf.AddFacts()
+	err := gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "http://"+hostname+":8080", s.URL)
+	assert.Equal(t, 4, s.Replicas)
+	assert.Equal(t, hostname, s.Facts.Hostname)
+	assert.Equal(t, runtime.NumCPU(), s.Facts.NumCPU)
+	assert.Equal(t, "payments", s.Facts.PodNamespace)
+	assert.Equal(t, "eu-west-1", s.Facts.Region)
+}
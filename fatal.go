@@ -0,0 +1,84 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"fmt"
+	"os"
+)
+
+// FatalError is returned by the parse functions of ExitOnError instead of exiting when
+// SetReturnFatalErrors is enabled, leaving it to the application to exit, e.g. once
+// the deferred functions flushing the coverage or profiles have run.
+type FatalError struct {
+	// Err is the parse error, or ErrHandled once a built-in flag is handled.
+	Err error
+	// Code is the exit status: 0 once a built-in flag is handled, 2 otherwise.
+	Code int
+}
+
+func (e *FatalError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FatalError) Unwrap() error {
+	return e.Err
+}
+
+// Exit prints the error, unless the exit status is 0, and exits, as ExitOnError does.
+func (e *FatalError) Exit() {
+	if e.Code != 0 {
+		fmt.Println(e.Err)
+	}
+	os.Exit(e.Code)
+}
+
+// SetReturnFatalErrors makes the parse functions of ExitOnError return a *FatalError
+// instead of calling os.Exit, which skips the deferred functions and breaks the coverage
+// and profiling of the integration tests. The package-level Parse, which doesn't return
+// an error, panics with the *FatalError instead, as MustParse does once a built-in flag
+// is handled, so it can be recovered in main. Disabled by default.
+func SetReturnFatalErrors(enabled bool) { gf.SetReturnFatalErrors(enabled) }
+
+// SetReturnFatalErrors makes the parse functions of ExitOnError return a *FatalError
+// instead of calling os.Exit, which skips the deferred functions and breaks the coverage
+// and profiling of the integration tests. The package-level Parse, which doesn't return
+// an error, panics with the *FatalError instead, as MustParse does once a built-in flag
+// is handled, so it can be recovered in main. Disabled by default.
+func (gf *Gofig) SetReturnFatalErrors(enabled bool) {
+	gf.returnFatal = enabled
+}
+
+// handleError handles a parse error according to the error handling, returning it with
+// ContinueOnError.
+func (gf *Gofig) handleError(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch gf.errHandling {
+	case ExitOnError:
+		fatal := &FatalError{Err: err, Code: 2}
+		if err == ErrHandled {
+			fatal.Code = 0
+		}
+		if gf.returnFatal {
+			return fatal
+		}
+		fatal.Exit()
+	case PanicOnError:
+		panic(err)
+	}
+	return err // includes ContinueOnError
+}
+
+// exitHandled exits successfully once a built-in flag is handled by MustParse, or
+// panics with a *FatalError if SetReturnFatalErrors is enabled.
+func (gf *Gofig) exitHandled() {
+	if gf.returnFatal {
+		panic(&FatalError{Err: ErrHandled, Code: 0})
+	}
+	os.Exit(0)
+}
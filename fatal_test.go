@@ -0,0 +1,57 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetReturnFatalErrors(t *testing.T) {
+	gf := New(ExitOnError)
+	gf.SetReturnFatalErrors(true)
+	gf.SetFlagOutput(io.Discard)
+
+	// Case 1: parse error
+	err := gf.ParseWithArgs(buildTestStruct(), []string{"-int", "abc"})
+	var fatal *FatalError
+	assert.True(t, errors.As(err, &fatal))
+	assert.Equal(t, 2, fatal.Code)
+	assert.Contains(t, err.Error(), "-int")
+
+	// Case 2: built-in flag handled
+	gf = New(ExitOnError)
+	gf.SetReturnFatalErrors(true)
+	gf.SetFlagOutput(io.Discard)
+	err = gf.ParseWithArgs(buildTestStruct(), []string{"-gofig-print-config"})
+	assert.True(t, errors.As(err, &fatal))
+	assert.Equal(t, 0, fatal.Code)
+	assert.True(t, errors.Is(err, ErrHandled))
+
+	// Case 3: MustParse panics with the fatal error once a built-in flag is handled
+	b := NewBuilder().Flags([]string{"-gofig-check-config"})
+	b.Gofig().SetReturnFatalErrors(true)
+	b.Gofig().SetFlagOutput(io.Discard)
+	func() {
+		defer func() {
+			fatal, _ := recover().(*FatalError)
+			if assert.NotNil(t, fatal) {
+				assert.Equal(t, 0, fatal.Code)
+			}
+		}()
+		b.MustParse(buildTestStruct())
+	}()
+
+	// Case 4: no effect with ContinueOnError
+	gf = New(ContinueOnError)
+	gf.SetReturnFatalErrors(true)
+	gf.SetFlagOutput(io.Discard)
+	err = gf.ParseWithArgs(buildTestStruct(), []string{"-int", "abc"})
+	assert.False(t, errors.As(err, &fatal))
+}
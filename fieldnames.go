@@ -0,0 +1,238 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// fieldError is an error on the value of a field, identified by its name (e.g. DB.Port)
+// or by its config document key path (e.g. db.port), with the source and the raw text of
+// the value if known.
+type fieldError struct {
+	name   string
+	key    string
+	source string
+	raw    string
+	err    error
+}
+
+func (e *fieldError) Error() string {
+	return e.err.Error()
+}
+
+// fieldNames are the names of a field in each source
+type fieldNames struct {
+	flag string
+	env  string
+	key  string
+}
+
+// String returns the names of a field, e.g. "flag -db-port, env GF_DB_PORT, key db.port".
+func (n *fieldNames) String() string {
+	return n.format(func(s string) string { return s })
+}
+
+// format returns the names of a field, the labels being translated with translate.
+func (n *fieldNames) format(translate func(string) string) string {
+	var names []string
+	if n.flag != "" {
+		names = append(names, fmt.Sprintf(translate("flag -%v"), n.flag))
+	}
+	if n.env != "" {
+		names = append(names, fmt.Sprintf(translate("env %v"), n.env))
+	}
+	if n.key != "" {
+		names = append(names, fmt.Sprintf(translate("key %v"), n.key))
+	}
+	return strings.Join(names, ", ")
+}
+
+var (
+	// flagErrorName matches the name of the flag of a flag package parse error
+	flagErrorName = regexp.MustCompile(`^invalid (?:boolean )?value "(.*)" for (?:flag )?-([^:]+): `)
+	// tomlErrorKey matches the key of a TOML decoding error
+	tomlErrorKey = regexp.MustCompile(`\(last key "([^"]*)"\)`)
+	// yamlErrorLine matches the line of a YAML decoding error
+	yamlErrorLine = regexp.MustCompile(`line (\d+): `)
+)
+
+// fieldNames returns the names of the fields of the struct pointed to by v by field name,
+// the key paths following the keys of cfgTag. The names of a child are prefixed with its
+// scope if scoped is set.
+func (gf *Gofig) fieldNames(v interface{}, cfgTag string, scoped bool) map[string]*fieldNames {
+	names := make(map[string]*fieldNames)
+	walk := func(tag string, set func(n *fieldNames, path []string)) {
+		parser := func(path []string, name string, val *reflect.Value, tags *reflect.StructTag) error {
+			n, ok := names[name]
+			if !ok {
+				n = &fieldNames{}
+				names[name] = n
+			}
+			set(n, path)
+			return nil
+		}
+		if scoped {
+			parser = gf.scoped(parser)
+		}
+		_ = parseStruct(v, parser, tag)
+	}
+	walk("flag", func(n *fieldNames, path []string) { n.flag = strings.Join(path, flagSeparator) })
+	walk("env", func(n *fieldNames, path []string) { n.env = gf.getEnvKey(path) })
+	walk(cfgTag, func(n *fieldNames, path []string) { n.key = strings.Join(path, ".") })
+	return names
+}
+
+// withFieldNames adds the names of the field in each source to an error on a field of
+// the struct pointed to by v, so that it can be fixed in any of them. The key paths
+// follow the keys of cfgTag, and are prefixed with the scope of a child if scoped is set.
+func (gf *Gofig) withFieldNames(err error, v interface{}, cfgTag string, scoped bool) error {
+	if err == nil {
+		return nil
+	}
+	var name, key, flagName, source, raw string
+	var fe *fieldError
+	var te *json.UnmarshalTypeError
+	if errors.As(err, &fe) {
+		name, key, source, raw = fe.name, fe.key, fe.source, fe.raw
+	} else if errors.As(err, &te) {
+		key, source = te.Field, ProvenanceConfig
+	} else if m := tomlErrorKey.FindStringSubmatch(err.Error()); m != nil {
+		key, source = m[1], ProvenanceConfig
+	} else if m := flagErrorName.FindStringSubmatch(err.Error()); m != nil {
+		raw, flagName, source = m[1], m[2], ProvenanceFlag
+	} else {
+		return err
+	}
+
+	names := gf.fieldNames(v, cfgTag, scoped)
+	if name == "" {
+		key = fieldKey(names, strings.ToLower(key))
+		for fieldName, n := range names {
+			if (flagName != "" && n.flag == flagName) || (key != "" && n.key == key) {
+				name = fieldName
+				break
+			}
+		}
+	}
+	n, ok := names[name]
+	if !ok {
+		return err
+	}
+	return &FieldError{
+		Field:    name,
+		Path:     n.key,
+		Flag:     n.flag,
+		Env:      n.env,
+		Source:   source,
+		RawValue: raw,
+		Err:      err,
+		names:    n,
+	}
+}
+
+// FieldError is an error on the value of a field, returned by the parse functions
+// (possibly wrapped, see errors.As) with the names of the field in each source, so that
+// callers can tell which field failed and from which source, and render their own
+// messages. Its message is the message of Err followed by the names of the field.
+type FieldError struct {
+	// Field is the field name, e.g. DB.Port.
+	Field string
+	// Path is the dot-separated key path of the field, e.g. db.port.
+	Path string
+	// Flag is the flag name of the field, without the dash, e.g. db-port.
+	Flag string
+	// Env is the environment variable of the field, e.g. GF_DB_PORT.
+	Env string
+	// Source is the source of the invalid value, ProvenanceFlag, ProvenanceEnv,
+	// ProvenanceConfig, ProvenanceOverride, ProvenanceOverrideFlag or ProvenanceSecret,
+	// or empty if the error isn't on a value of a source, e.g. a missing directory.
+	Source string
+	// RawValue is the text of the invalid value, if known.
+	RawValue string
+	// Err is the error on the value.
+	Err error
+
+	names *fieldNames
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%v (%v)", e.Err, e.names)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// fieldKey returns the key path of the field holding the value at key, e.g. "ports" for
+// "ports[1]" or "labels" for "labels.a", or an empty string if there's none.
+func fieldKey(names map[string]*fieldNames, key string) string {
+	keys := make(map[string]bool, len(names))
+	for _, n := range names {
+		keys[n.key] = true
+	}
+	for key != "" {
+		if keys[key] {
+			return key
+		}
+		i := strings.LastIndexAny(key, ".[")
+		if i < 0 {
+			break
+		}
+		key = key[:i]
+	}
+	return ""
+}
+
+// yamlErrorKey returns a fieldError with the key path of the value of a YAML decoding
+// error, if a single key is found at the line of the error.
+func yamlErrorKey(err error, data []byte) error {
+	var te *yaml.TypeError
+	if !errors.As(err, &te) || len(te.Errors) == 0 {
+		return err
+	}
+	m := yamlErrorLine.FindStringSubmatch(te.Errors[0])
+	if m == nil {
+		return err
+	}
+	positions, perr := yamlPositions(data)
+	if perr != nil {
+		return err
+	}
+	key := ""
+	for k, pos := range positions {
+		if fmt.Sprint(pos.Line) != m[1] {
+			continue
+		}
+		if key != "" && !strings.HasPrefix(k, key+".") {
+			if strings.HasPrefix(key, k+".") {
+				continue // the parent of key, on the same line
+			}
+			return err // several values on the line
+		}
+		key = k
+	}
+	if key == "" {
+		return err
+	}
+	return &fieldError{key: key, source: ProvenanceConfig, err: err}
+}
+
+// withSource sets the source of err if it is a fieldError without one.
+func withSource(err error, source string) error {
+	var fe *fieldError
+	if errors.As(err, &fe) && fe.source == "" {
+		fe.source = source
+	}
+	return err
+}
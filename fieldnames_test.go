@@ -0,0 +1,118 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldNamesInErrors(t *testing.T) {
+	type DB struct {
+		Port  int `json:"port" toml:"port" yaml:"port" env:"PORT_NUMBER"`
+		Hosts []string
+	}
+	type Config struct {
+		DB DB `json:"db" toml:"db" yaml:"db" flag:"database"`
+	}
+	parse := func(format string, doc string, args ...string) error {
+		gf := New(ContinueOnError)
+		gf.SetEnvPrefix("GFN")
+		if doc != "" {
+			gf.AddSource(&testSource{name: format, doc: &Document{Format: format, Data: []byte(doc)}})
+		}
+		return gf.ParseWithArgs(&Config{}, args)
+	}
+	names := "(flag -database-port, env GFN_DB_PORT_NUMBER, key db.port)"
+
+	// Case 1: config documents
+	err := parse("json", `{"db": {"port": "x"}}`)
+	assert.EqualError(t, err, "error decoding source json: json: cannot unmarshal string into Go struct field Config.db.port of type int "+names)
+	err = parse("json", `{"db": {"port": 1.5}}`)
+	assert.EqualError(t, err, "error decoding source json: error parsing key 'db.port' with value '1.5' into int "+names)
+	err = parse("toml", "[db]\nport = \"x\"\n")
+	assert.EqualError(t, err, `error decoding source toml: toml: line 2 (last key "db.port"): incompatible types: TOML value has type string; destination has type integer `+names)
+	err = parse("yaml", "db:\n  port: x\n")
+	assert.EqualError(t, err, "error decoding source yaml: yaml: unmarshal errors:\n  line 2: cannot unmarshal !!str `x` into int "+names)
+	err = parse("yaml", "db: {port: x}\n")
+	assert.EqualError(t, err, "error decoding source yaml: yaml: unmarshal errors:\n  line 1: cannot unmarshal !!str `x` into int "+names)
+	err = parse("json", `{"db": {"hosts": [1]}}`)
+	assert.EqualError(t, err, "error decoding source json: json: cannot unmarshal number into Config.db.hosts.0 of type string (flag -database-hosts, env GFN_DB_HOSTS, key db.hosts)")
+
+	// Case 2: environment variables
+	os.Setenv("GFN_DB_PORT_NUMBER", "x")
+	err = parse("", "")
+	os.Unsetenv("GFN_DB_PORT_NUMBER")
+	assert.EqualError(t, err, "error parsing environment variable 'GFN_DB_PORT_NUMBER' with value 'x' into int "+names)
+
+	// Case 3: flags
+	err = parse("", "", "-database-port", "x")
+	assert.EqualError(t, err, `invalid value "x" for flag -database-port: parse error `+names)
+
+	// Case 4: children
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("GFN")
+	gf.AddSource(&testSource{name: "yaml", doc: &Document{Format: "yaml", Data: []byte("db:\n  port: x\n")}})
+	err = gf.Child("db").ParseWithArgs(&DB{}, []string{})
+	assert.EqualError(t, err, "error decoding source yaml: yaml: unmarshal errors:\n  line 2: cannot unmarshal !!str `x` into int (flag -db-port, env GFN_DB_PORT_NUMBER, key db.port)")
+}
+
+func TestFieldError(t *testing.T) {
+	type Config struct {
+		DB struct {
+			Port int `json:"port"`
+		} `json:"db"`
+	}
+	parse := func(setup func(gf *Gofig), args ...string) *FieldError {
+		gf := New(ContinueOnError)
+		gf.SetEnvPrefix("GFE")
+		gf.SetOverrideFlag("set", "override a value")
+		setup(gf)
+		err := gf.ParseWithArgs(&Config{}, args)
+		var fe *FieldError
+		if !assert.True(t, errors.As(err, &fe), "%v", err) {
+			return &FieldError{}
+		}
+		assert.Equal(t, "DB.Port", fe.Field)
+		assert.Equal(t, "db.port", fe.Path)
+		assert.Equal(t, "db-port", fe.Flag)
+		assert.Equal(t, "GFE_DB_PORT", fe.Env)
+		assert.NotNil(t, fe.Err)
+		return fe
+	}
+
+	// Case 1: the source and raw value of each source
+	fe := parse(func(gf *Gofig) {
+		gf.AddSource(&testSource{name: "yaml", doc: &Document{Format: "yaml", Values: map[string]string{"db.port": "x"}}})
+	})
+	assert.Equal(t, ProvenanceConfig, fe.Source)
+	assert.Equal(t, "x", fe.RawValue)
+	fe = parse(func(gf *Gofig) {
+		gf.AddSource(&testSource{name: "json", doc: &Document{Format: "json", Data: []byte(`{"db": {"port": 1.5}}`)}})
+	})
+	assert.Equal(t, ProvenanceConfig, fe.Source)
+	assert.Equal(t, "1.5", fe.RawValue)
+	os.Setenv("GFE_DB_PORT", "y")
+	fe = parse(func(gf *Gofig) {})
+	os.Unsetenv("GFE_DB_PORT")
+	assert.Equal(t, ProvenanceEnv, fe.Source)
+	assert.Equal(t, "y", fe.RawValue)
+	fe = parse(func(gf *Gofig) {}, "-db-port", "z")
+	assert.Equal(t, ProvenanceFlag, fe.Source)
+	assert.Equal(t, "z", fe.RawValue)
+	fe = parse(func(gf *Gofig) { assert.NoError(t, gf.Override("db.port", "o")) })
+	assert.Equal(t, ProvenanceOverride, fe.Source)
+	assert.Equal(t, "o", fe.RawValue)
+	fe = parse(func(gf *Gofig) {}, "-set", "db.port=s")
+	assert.Equal(t, ProvenanceOverrideFlag, fe.Source)
+	assert.Equal(t, "s", fe.RawValue)
+
+	// Case 2: the message is unchanged
+	assert.Equal(t, fe.Err.Error()+" (flag -db-port, env GFE_DB_PORT, key db.port)", fe.Error())
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileSource loads a local config file, its format being detected from its extension.
+// When watching (see Gofig.Watch), the file is polled every PollInterval, and changes
+// are only reported once the file content has been stable for QuietPeriod, so that rapid
+// successive writes (e.g. editors writing temporary files) trigger a single reload. The
+// file may briefly disappear, as with the atomic symlink swap Kubernetes uses to update
+// ConfigMap volumes: the change is reported once the file is back and stable. Batched
+// reloads (see SetWatchQuietPeriod) wait for the changing files to be stable.
+type FileSource struct {
+	// Path of the config file.
+	Path string
+	// PollInterval is the polling interval when watching, 0 to disable polling.
+	PollInterval time.Duration
+	// QuietPeriod is the time the file content must stay unchanged before a change is
+	// reported, 0 to report changes as soon as they are detected.
+	QuietPeriod time.Duration
+
+	mu      sync.Mutex
+	sum     [sha256.Size]byte // checksum of the loaded or last reported content
+	pending bool              // a change is detected but not stable yet
+}
+
+// NewFileSource returns a Source loading the config file at path.
+func NewFileSource(path string) *FileSource {
+	return &FileSource{Path: path}
+}
+
+// Name returns the file path.
+func (s *FileSource) Name() string {
+	return s.Path
+}
+
+// Load reads the file.
+func (s *FileSource) Load(ctx context.Context) (*Document, error) {
+	format := FormatFromPath(s.Path)
+	if format == "" {
+		return nil, fmt.Errorf("unable to detect the config format")
+	}
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.sum = sha256.Sum256(data)
+	s.mu.Unlock()
+	return &Document{Format: format, Data: data}, nil
+}
+
+// Watch polls the file until its content changes and stays stable for the quiet period.
+func (s *FileSource) Watch(ctx context.Context) error {
+	if s.PollInterval <= 0 {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	s.mu.Lock()
+	loaded := s.sum
+	s.mu.Unlock()
+
+	ticker := time.NewTicker(s.PollInterval)
+	defer ticker.Stop()
+	defer s.setPending(false)
+
+	var last [sha256.Size]byte
+	var lastExists bool
+	var lastChange time.Time
+	pending := false
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		sum, exists, err := s.checksum()
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if !pending || sum != last || exists != lastExists {
+			if !pending && exists && sum == loaded {
+				continue
+			}
+			pending = true
+			s.setPending(true)
+			last, lastExists, lastChange = sum, exists, now
+		}
+		if !lastExists || now.Sub(lastChange) < s.QuietPeriod {
+			continue // wait for the file to settle
+		}
+		if last == loaded {
+			pending = false // reverted to the loaded content
+			s.setPending(false)
+			continue
+		}
+
+		s.mu.Lock()
+		s.sum = last
+		s.pending = false
+		s.mu.Unlock()
+		return nil
+	}
+}
+
+// setPending sets whether a change is detected but not stable yet.
+func (s *FileSource) setPending(pending bool) {
+	s.mu.Lock()
+	s.pending = pending
+	s.mu.Unlock()
+}
+
+// settling returns whether the file is changing, so that the configuration isn't
+// reloaded from a partial update.
+func (s *FileSource) settling() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pending
+}
+
+// checksum returns the checksum of the file content, and whether the file exists.
+func (s *FileSource) checksum() ([sha256.Size]byte, bool, error) {
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return [sha256.Size]byte{}, false, nil
+	} else if err != nil {
+		return [sha256.Size]byte{}, false, err
+	}
+	return sha256.Sum256(data), true, nil
+}
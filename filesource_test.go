@@ -0,0 +1,120 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("int: 1\n"), 0644))
+
+	s := buildTestStruct()
+	gf := New(ContinueOnError)
+	src := NewFileSource(path)
+	src.PollInterval = 5 * time.Millisecond
+	src.QuietPeriod = 100 * time.Millisecond
+	gf.AddSource(src)
+	err := gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, s.Int)
+
+	// a burst of writes triggers a single reload
+	var reloads int32
+	changed := make(chan struct{}, 10)
+	gf.OnChange(func() {
+		atomic.AddInt32(&reloads, 1)
+		changed <- struct{}{}
+	})
+	w, err := gf.StartWatching(context.Background())
+	assert.NoError(t, err)
+	defer w.Stop()
+
+	for i := 2; i <= 6; i++ {
+		assert.NoError(t, os.WriteFile(path, []byte(fmt.Sprintf("int: %v\n", i)), 0644))
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("configuration not reloaded")
+	}
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&reloads))
+	assert.NoError(t, w.Stop())
+	assert.Equal(t, 6, s.Int)
+}
+
+func TestFileSourceWatch(t *testing.T) {
+	watch := func(src *FileSource) chan error {
+		done := make(chan error, 1)
+		go func() { done <- src.Watch(context.Background()) }()
+		return done
+	}
+	waitFor := func(done chan error) {
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("change not detected")
+		}
+	}
+
+	// Case 1: Kubernetes ConfigMap volume, updated by swapping the ..data symlink
+	dir := t.TempDir()
+	for _, v := range []string{"1", "2"} {
+		assert.NoError(t, os.Mkdir(filepath.Join(dir, "..v"+v), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "..v"+v, "config.yaml"), []byte("int: "+v+"\n"), 0644))
+	}
+	assert.NoError(t, os.Symlink("..v1", filepath.Join(dir, "..data")))
+	assert.NoError(t, os.Symlink(filepath.Join("..data", "config.yaml"), filepath.Join(dir, "config.yaml")))
+
+	src := NewFileSource(filepath.Join(dir, "config.yaml"))
+	src.PollInterval = 5 * time.Millisecond
+	src.QuietPeriod = 20 * time.Millisecond
+	doc, err := src.Load(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "yaml", doc.Format)
+
+	done := watch(src)
+	assert.NoError(t, os.Symlink("..v2", filepath.Join(dir, "..data_tmp")))
+	assert.NoError(t, os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+	waitFor(done)
+	doc, err = src.Load(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "int: 2\n", string(doc.Data))
+
+	// Case 2: the file is removed then written back
+	path := filepath.Join(dir, "..v2", "config.yaml")
+	src = NewFileSource(path)
+	src.PollInterval = 5 * time.Millisecond
+	_, err = src.Load(context.Background())
+	assert.NoError(t, err)
+
+	done = watch(src)
+	assert.NoError(t, os.Remove(path))
+	time.Sleep(30 * time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("change reported while the file is missing")
+	default:
+	}
+	assert.NoError(t, os.WriteFile(path, []byte("int: 3\n"), 0644))
+	waitFor(done)
+
+	// Case 3: unknown format
+	_, err = NewFileSource(filepath.Join(dir, "config.txt")).Load(context.Background())
+	assert.EqualError(t, err, "unable to detect the config format")
+}
@@ -0,0 +1,206 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	listUsage = " (comma-separated `list`, quoted as CSV, or a JSON array; repeatable)"
+	mapUsage  = " (comma-separated `key=value` pairs, quoted as CSV, or a JSON object; repeatable)"
+)
+
+var flagValueType = reflect.TypeOf((*flag.Value)(nil)).Elem()
+
+// isFlagValueType returns whether the pointers to type t implement flag.Value, the values
+// of type t being set from their text with Set.
+func isFlagValueType(t reflect.Type) bool {
+	return reflect.PtrTo(t).Implements(flagValueType)
+}
+
+// flagValue returns the flag.Value of the field f, if its type is a flag.Value type.
+func flagValue(f *reflect.Value) (flag.Value, bool) {
+	if !f.CanAddr() || !isFlagValueType(f.Type()) {
+		return nil, false
+	}
+	return f.Addr().Interface().(flag.Value), true
+}
+
+// listFlag is a flag.Value setting a slice field from a comma-separated list, quoted as
+// CSV (e.g. -tags 'a,"b,c"'), or from a JSON array (e.g. -servers '[{"host": "x"}]').
+// The first value replaces the default value, the next ones are appended.
+type listFlag struct {
+	val reflect.Value
+	set bool
+}
+
+func (f *listFlag) String() string {
+	if !f.val.IsValid() {
+		return ""
+	}
+	items := make([]string, f.val.Len())
+	for i := range items {
+		elem := f.val.Index(i)
+		s, ok := encodeString(&elem)
+		if !ok {
+			data, _ := json.Marshal(f.val.Interface())
+			return string(data)
+		}
+		items[i] = s
+	}
+	return joinCSV(items)
+}
+
+func (f *listFlag) Set(s string) error {
+	elems, err := parseList(f.val.Type(), s)
+	if err != nil {
+		return err
+	}
+	if !f.set {
+		f.val.Set(reflect.MakeSlice(f.val.Type(), 0, elems.Len()))
+		f.set = true
+	}
+	f.val.Set(reflect.AppendSlice(f.val, elems))
+	return nil
+}
+
+// parseList parses a comma-separated list, quoted as CSV, or a JSON array into a slice of type t.
+func parseList(t reflect.Type, s string) (reflect.Value, error) {
+	list := reflect.New(t)
+	if strings.HasPrefix(strings.TrimSpace(s), "[") {
+		err := json.Unmarshal([]byte(s), list.Interface())
+		return list.Elem(), err
+	}
+
+	items, err := splitCSV(s)
+	if err != nil {
+		return list.Elem(), err
+	}
+	list.Elem().Set(reflect.MakeSlice(t, len(items), len(items)))
+	for i, item := range items {
+		elem := list.Elem().Index(i)
+		err = decodeItem(&elem, item)
+		if err != nil {
+			return list.Elem(), err
+		}
+	}
+	return list.Elem(), nil
+}
+
+// mapFlag is a flag.Value setting a map field from comma-separated key=value pairs,
+// quoted as CSV (e.g. -labels 'a=b,"c=d,e"'), or from a JSON object. The first value
+// replaces the default value, the next ones are added.
+type mapFlag struct {
+	val reflect.Value
+	set bool
+}
+
+func (f *mapFlag) String() string {
+	if !f.val.IsValid() {
+		return ""
+	}
+	items := make([]string, 0, f.val.Len())
+	iter := f.val.MapRange()
+	for iter.Next() {
+		k, v := iter.Key(), iter.Value()
+		ks, ok1 := encodeString(&k)
+		vs, ok2 := encodeString(&v)
+		if !ok1 || !ok2 {
+			data, _ := json.Marshal(f.val.Interface())
+			return string(data)
+		}
+		items = append(items, ks+"="+vs)
+	}
+	sort.Strings(items)
+	return joinCSV(items)
+}
+
+func (f *mapFlag) Set(s string) error {
+	m, err := parseMap(f.val.Type(), s)
+	if err != nil {
+		return err
+	}
+	if !f.set || f.val.IsNil() {
+		f.val.Set(reflect.MakeMapWithSize(f.val.Type(), m.Len()))
+		f.set = true
+	}
+	iter := m.MapRange()
+	for iter.Next() {
+		f.val.SetMapIndex(iter.Key(), iter.Value())
+	}
+	return nil
+}
+
+// parseMap parses comma-separated key=value pairs, quoted as CSV, or a JSON object into
+// a map of type t.
+func parseMap(t reflect.Type, s string) (reflect.Value, error) {
+	m := reflect.New(t)
+	if strings.HasPrefix(strings.TrimSpace(s), "{") {
+		err := json.Unmarshal([]byte(s), m.Interface())
+		return m.Elem(), err
+	}
+
+	items, err := splitCSV(s)
+	if err != nil {
+		return m.Elem(), err
+	}
+	m.Elem().Set(reflect.MakeMapWithSize(t, len(items)))
+	for _, item := range items {
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			return m.Elem(), fmt.Errorf("invalid key=value pair '%v'", item)
+		}
+		key := reflect.New(t.Key()).Elem()
+		err = decodeItem(&key, kv[0])
+		if err != nil {
+			return m.Elem(), err
+		}
+		val := reflect.New(t.Elem()).Elem()
+		err = decodeItem(&val, kv[1])
+		if err != nil {
+			return m.Elem(), err
+		}
+		m.Elem().SetMapIndex(key, val)
+	}
+	return m.Elem(), nil
+}
+
+// decodeItem decodes a list item or a map key or value, which must be of a type
+// decodeString supports.
+func decodeItem(f *reflect.Value, s string) error {
+	if _, ok := encodeString(f); !ok && !isValueType(f.Type()) {
+		return fmt.Errorf("%v values must be set with JSON", f.Type())
+	}
+	return decodeString(f, s)
+}
+
+// splitCSV splits a comma-separated line, quoted as CSV.
+func splitCSV(s string) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	r := csv.NewReader(strings.NewReader(s))
+	r.LazyQuotes = true
+	r.TrimLeadingSpace = true
+	return r.Read()
+}
+
+// joinCSV joins items into a comma-separated line, quoted as CSV.
+func joinCSV(items []string) string {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	_ = w.Write(items)
+	w.Flush()
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,206 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type server struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+type listConfig struct {
+	Tags    []string          `desc:"server tags"`
+	Ports   []int             `desc:"ports"`
+	Labels  map[string]string `desc:"labels"`
+	Weights map[string]float64
+	Servers []server `desc:"servers"`
+}
+
+func TestListAndMapFlags(t *testing.T) {
+	// Case 1: comma-separated values, CSV quoting, repeated flags and JSON literals
+	s := &listConfig{Tags: []string{"default"}, Labels: map[string]string{"default": "x"}}
+	gf := New(ContinueOnError)
+	err := gf.ParseWithArgs(s, []string{
+		"-tags", `a, "b,c"`, "-tags", "d",
+		"-ports", "[80, 443]",
+		"-labels", "k=v", "-labels", `k2=v2,"k3=v3,v4"`,
+		"-weights", `{"a": 0.5}`,
+		"-servers", `[{"host": "x", "port": 1}]`,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b,c", "d"}, s.Tags)
+	assert.Equal(t, []int{80, 443}, s.Ports)
+	assert.Equal(t, map[string]string{"k": "v", "k2": "v2", "k3": "v3,v4"}, s.Labels)
+	assert.Equal(t, map[string]float64{"a": 0.5}, s.Weights)
+	assert.Equal(t, []server{{Host: "x", Port: 1}}, s.Servers)
+
+	// Case 2: the default value is kept without flags, and shown in the usage
+	s = &listConfig{Tags: []string{"a", "b,c"}, Labels: map[string]string{"b": "2", "a": "1"}}
+	gf = New(ContinueOnError)
+	err = gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b,c"}, s.Tags)
+
+	var usage bytes.Buffer
+	gf.flagSet.SetOutput(&usage)
+	gf.flagSet.PrintDefaults()
+	assert.Contains(t, usage.String(), "  -tags list\n    \tserver tags (comma-separated list, quoted as CSV, or a JSON array; repeatable) (default a,\"b,c\")\n")
+	assert.Contains(t, usage.String(), "  -labels key=value\n    \tlabels (comma-separated key=value pairs, quoted as CSV, or a JSON object; repeatable) (default a=1,b=2)\n")
+
+	// Case 3: errors
+	for _, args := range [][]string{
+		{"-ports", "a"},
+		{"-ports", "[1,"},
+		{"-labels", "k"},
+		{"-servers", "x"},
+	} {
+		gf = New(ContinueOnError)
+		gf.flagSet.SetOutput(&usage)
+		err = gf.ParseWithArgs(&listConfig{}, args)
+		assert.Error(t, err, args)
+	}
+	assert.Contains(t, usage.String(), "invalid value \"x\" for flag -servers: gofig.server values must be set with JSON")
+}
+
+func TestSliceSources(t *testing.T) {
+	os.Setenv("GFL_TAGS", `a, "b,c"`)
+	os.Setenv("GFL_PORTS", "80,443")
+	os.Setenv("GFL_SERVERS", `[{"host": "x", "port": 1}]`)
+	defer os.Unsetenv("GFL_TAGS")
+	defer os.Unsetenv("GFL_PORTS")
+	defer os.Unsetenv("GFL_SERVERS")
+
+	// Case 1: environment variables, a flag taking precedence
+	s := &listConfig{Ports: []int{1}}
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("GFL")
+	err := gf.ParseWithArgs(s, []string{"-ports", "8080"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b,c"}, s.Tags)
+	assert.Equal(t, []int{8080}, s.Ports)
+	assert.Equal(t, []server{{Host: "x", Port: 1}}, s.Servers)
+
+	// Case 2: config document, overridden by the environment variables
+	os.Unsetenv("GFL_TAGS")
+	s = &listConfig{}
+	gf = New(ContinueOnError)
+	gf.SetEnvPrefix("GFL")
+	gf.AddSource(&testSource{name: "test", doc: &Document{Format: "yaml", Data: []byte("tags: [x, y]\nports: [1, 2]\n")}})
+	err = gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"x", "y"}, s.Tags)
+	assert.Equal(t, []int{80, 443}, s.Ports)
+
+	// Case 3: runtime override, and invalid item
+	assert.NoError(t, gf.Override("tags", "z"))
+	assert.Equal(t, []string{"z"}, s.Tags)
+	err = gf.Override("ports", "80,abc")
+	assert.EqualError(t, err, "error parsing key 'ports' with value '80,abc' into []int (flag -ports, env GFL_PORTS, key ports)")
+}
+
+func TestMapSources(t *testing.T) {
+	os.Setenv("GFM_LABELS", "a=1,b=2")
+	os.Setenv("GFM_LABELS_FOO", "bar")
+	os.Setenv("GFM_WEIGHTS_HEAVY", "2.5")
+	defer os.Unsetenv("GFM_LABELS")
+	defer os.Unsetenv("GFM_LABELS_FOO")
+	defer os.Unsetenv("GFM_WEIGHTS_HEAVY")
+
+	// Case 1: environment variables, the prefixed ones adding entries to the config
+	// document and whole variable values
+	s := &listConfig{}
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("GFM")
+	gf.AddSource(&testSource{name: "test", doc: &Document{Format: "yaml", Data: []byte("weights: {light: 0.5}\n")}})
+	err := gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "1", "b": "2", "foo": "bar"}, s.Labels)
+	assert.Equal(t, map[string]float64{"light": 0.5, "heavy": 2.5}, s.Weights)
+
+	// Case 2: runtime override, and a flag taking precedence
+	assert.NoError(t, gf.Override("labels", "x=y"))
+	assert.Equal(t, map[string]string{"x": "y"}, s.Labels)
+	s = &listConfig{}
+	gf = New(ContinueOnError)
+	gf.SetEnvPrefix("GFM")
+	err = gf.ParseWithArgs(s, []string{"-labels", "k=v"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"k": "v"}, s.Labels)
+
+	// Case 3: invalid entry
+	os.Setenv("GFM_WEIGHTS_BAD", "abc")
+	defer os.Unsetenv("GFM_WEIGHTS_BAD")
+	gf = New(ContinueOnError)
+	gf.SetEnvPrefix("GFM")
+	err = gf.ParseWithArgs(&listConfig{}, []string{})
+	assert.EqualError(t, err, "error parsing environment variable 'GFM_WEIGHTS_BAD' with value 'abc' into map[string]float64 (flag -weights, env GFM_WEIGHTS, key weights)")
+}
+
+type hostPort struct {
+	Host string
+	Port int
+}
+
+func (h *hostPort) String() string {
+	if h.Host == "" {
+		return ""
+	}
+	return fmt.Sprintf("%v:%v", h.Host, h.Port)
+}
+
+func (h *hostPort) Set(s string) error {
+	i := strings.LastIndexByte(s, ':')
+	if i < 0 {
+		return fmt.Errorf("missing port in %v", s)
+	}
+	port, err := strconv.Atoi(s[i+1:])
+	if err != nil {
+		return err
+	}
+	h.Host, h.Port = s[:i], port
+	return nil
+}
+
+func TestFlagValueFields(t *testing.T) {
+	type config struct {
+		Listen hostPort `json:"listen"`
+		Wait   Duration `json:"wait"`
+	}
+
+	// Case 1: flags
+	s := &config{}
+	gf := New(ContinueOnError)
+	err := gf.ParseWithArgs(s, []string{"-listen", "localhost:8080", "-wait", "5s"})
+	assert.NoError(t, err)
+	assert.Equal(t, hostPort{Host: "localhost", Port: 8080}, s.Listen)
+	assert.Equal(t, Duration(5*time.Second), s.Wait)
+	assert.Equal(t, map[string]string{"listen": "localhost:8080", "wait": "5s"}, Flatten(s))
+
+	gf = New(ContinueOnError)
+	err = gf.ParseWithArgs(&config{}, []string{"-listen", "localhost"})
+	assert.Error(t, err)
+
+	// Case 2: environment variables
+	os.Setenv("GFV_LISTEN", "0.0.0.0:9090")
+	defer os.Unsetenv("GFV_LISTEN")
+	s = &config{}
+	gf = New(ContinueOnError)
+	gf.SetEnvPrefix("GFV")
+	err = gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, hostPort{Host: "0.0.0.0", Port: 9090}, s.Listen)
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Flatten returns the values of v, a pointer to a struct, keyed by their dot-separated
+// key path (which follows the json tags, e.g. "sub.str"). The values are encoded so that
+// Unflatten, Override and environment variables decode them back to the same value.
+// Fields of unsupported types are omitted.
+func Flatten(v interface{}) map[string]string {
+	values := make(map[string]string)
+	_ = parseStruct(v, func(path []string, name string, f *reflect.Value, tags *reflect.StructTag) error {
+		if val, ok := encodeField(f, tags); ok {
+			values[strings.Join(path, ".")] = val
+		}
+		return nil
+	}, "json")
+	return values
+}
+
+// Unflatten decodes values keyed by their dot-separated key path (which follows the json
+// tags, e.g. "sub.str") into v, a pointer to a struct. Keys are case-insensitive and keys
+// which don't map to a field are ignored.
+func Unflatten(values map[string]string, v interface{}) error {
+	return decodeValues(values, v)
+}
+
+// encodeValue is the inverse of decodeString, it encodes the values of encodeString, the
+// slices, as a comma-separated list quoted as CSV or a JSON array, and the maps, as
+// comma-separated key=value pairs or a JSON object, and returns false if the field type
+// isn't supported.
+func encodeValue(f *reflect.Value) (string, bool) {
+	if isScalarPointer(f.Type()) {
+		if f.IsNil() {
+			return "", false // not set
+		}
+		elem := f.Elem()
+		return encodeValue(&elem)
+	}
+	if _, ok := textMarshaler(f); ok || isFlagValueType(f.Type()) {
+		return encodeString(f)
+	}
+	switch f.Kind() {
+	case reflect.Slice:
+		return (&listFlag{val: *f}).String(), true
+	case reflect.Map:
+		return (&mapFlag{val: *f}).String(), true
+	}
+	return encodeString(f)
+}
+
+// encodeString is the inverse of decodeString for the scalar values, it returns false if
+// the field type isn't a supported scalar type.
+func encodeString(f *reflect.Value) (string, bool) {
+	if isScalarPointer(f.Type()) {
+		if f.IsNil() {
+			return "", true
+		}
+		elem := f.Elem()
+		return encodeString(&elem)
+	}
+	if m, ok := textMarshaler(f); ok {
+		text, err := m.MarshalText()
+		return string(text), err == nil
+	}
+	if fv, ok := flagValue(f); ok {
+		return fv.String(), true
+	}
+	switch f.Kind() {
+	case reflect.String:
+		return f.String(), true
+	case reflect.Bool:
+		return strconv.FormatBool(f.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if f.Type() == durationType || f.Type() == timeDurationType {
+			return time.Duration(f.Int()).String(), true
+		}
+		return strconv.FormatInt(f.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(f.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(f.Float(), 'g', -1, f.Type().Bits()), true
+	case reflect.Struct:
+		if f.Type() == timeType {
+			return formatTime(f.Interface().(time.Time), time.RFC3339), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlatten(t *testing.T) {
+	s := buildTestStruct()
+	s.Float = 1.5
+	s.Duration = Duration(90 * time.Second)
+
+	values := Flatten(s)
+	assert.Equal(t, map[string]string{
+		"str":      s.Str,
+		"bool":     "false",
+		"int":      "-99",
+		"int64":    "-99",
+		"uint":     "99",
+		"uint64":   "99",
+		"float":    "1.5",
+		"duration": "1m30s",
+		"sub.str":  s.Sub.RenamedStr,
+	}, values)
+
+	// round-trip
+	u := &TestStruct{}
+	err := Unflatten(values, u)
+	assert.NoError(t, err)
+	assert.Equal(t, s, u)
+
+	// invalid value
+	err = Unflatten(map[string]string{"int": "one"}, u)
+	assert.Error(t, err)
+
+	// invalid target
+	assert.Empty(t, Flatten(*s))
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// FormatFromPath returns the config format of a file from its extension: "json",
+// "toml" or "yaml" ("yml" is accepted), or an empty string if it isn't supported.
+func FormatFromPath(path string) string {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case jsonExtention, tomlExtention, yamlExtention:
+		return ext[1:]
+	case ".yml":
+		return "yaml"
+	}
+	return ""
+}
+
+// Decode decodes a config document in format ("json", "toml" or "yaml") into v, following
+// the same rules as config files. When v is a *map[string]interface{} or an *interface{},
+// YAML maps are decoded as map[string]interface{} like with the other formats.
+func Decode(r io.Reader, format string, v interface{}) error {
+	if format == "yml" {
+		format = "yaml"
+	}
+	if format != "yaml" {
+		return decodeConfig(r, "."+format, v)
+	}
+
+	switch ptr := v.(type) {
+	case *interface{}:
+		var tree interface{}
+		err := decodeConfig(r, yamlExtention, &tree)
+		*ptr = stringKeys(tree)
+		return err
+	case *map[string]interface{}:
+		var tree map[interface{}]interface{}
+		err := decodeConfig(r, yamlExtention, &tree)
+		if tree != nil {
+			*ptr = stringKeys(tree).(map[string]interface{})
+		}
+		return err
+	}
+	return decodeConfig(r, yamlExtention, v)
+}
+
+// Encode encodes v into a config document in format ("json", "toml" or "yaml").
+func Encode(w io.Writer, format string, v interface{}) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case "toml":
+		return toml.NewEncoder(w).Encode(v)
+	case "yaml", "yml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		defer enc.Close()
+		return enc.Encode(v)
+	}
+	return fmt.Errorf("config format %v not supported", format)
+}
+
+// Convert converts a config document from inFormat to outFormat ("json", "toml" or
+// "yaml") through the configuration struct type of schema, a pointer to struct, so that
+// values which don't fit the struct are reported. schema itself isn't modified.
+func Convert(in io.Reader, inFormat string, out io.Writer, outFormat string, schema interface{}) error {
+	rv := reflect.ValueOf(schema)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errInvalidValue
+	}
+
+	v := reflect.New(rv.Elem().Type()).Interface()
+	err := Decode(in, inFormat, v)
+	if err != nil {
+		return fmt.Errorf("error decoding %v config: %v", inFormat, err)
+	}
+	return Encode(out, outFormat, v)
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatFromPath(t *testing.T) {
+	assert.Equal(t, "json", FormatFromPath("config.json"))
+	assert.Equal(t, "toml", FormatFromPath("dir/config.TOML"))
+	assert.Equal(t, "yaml", FormatFromPath("config.yaml"))
+	assert.Equal(t, "yaml", FormatFromPath("config.yml"))
+	assert.Equal(t, "", FormatFromPath("config.ini"))
+}
+
+func TestEncodeDecode(t *testing.T) {
+	for _, format := range []string{"json", "toml", "yaml"} {
+		t.Run(format, func(t *testing.T) {
+			s := buildTestStruct()
+			var b bytes.Buffer
+			err := Encode(&b, format, s)
+			assert.NoError(t, err)
+
+			u := &TestStruct{}
+			err = Decode(bytes.NewReader(b.Bytes()), format, u)
+			assert.NoError(t, err)
+			assert.Equal(t, s, u)
+
+			// generic tree
+			var tree map[string]interface{}
+			err = Decode(bytes.NewReader(b.Bytes()), format, &tree)
+			assert.NoError(t, err)
+			assert.IsType(t, map[string]interface{}{}, tree[findKey(tree, "sub")])
+		})
+	}
+
+	err := Encode(&bytes.Buffer{}, "ini", buildTestStruct())
+	assert.Error(t, err)
+	err = Decode(strings.NewReader(""), "ini", &TestStruct{})
+	assert.Error(t, err)
+}
+
+// findKey returns the key of m matching key case-insensitively.
+func findKey(m map[string]interface{}, key string) string {
+	for k := range m {
+		if strings.EqualFold(k, key) {
+			return k
+		}
+	}
+	return ""
+}
+
+func TestConvert(t *testing.T) {
+	// Case 1: yaml to json
+	var b bytes.Buffer
+	in := "str: converted\nint: 3\nduration: 1m0s\nsub:\n  str: sub\n"
+	err := Convert(strings.NewReader(in), "yaml", &b, "json", &TestStruct{})
+	assert.NoError(t, err)
+
+	s := &TestStruct{}
+	err = Decode(&b, "json", s)
+	assert.NoError(t, err)
+	assert.Equal(t, &TestStruct{Str: "converted", Int: 3, Duration: Duration(time.Minute), Sub: SubTestStruct{RenamedStr: "sub"}}, s)
+
+	// Case 2: value not matching the struct
+	err = Convert(strings.NewReader("int: three"), "yaml", &b, "json", &TestStruct{})
+	assert.Error(t, err)
+
+	// Case 3: invalid schema
+	err = Convert(strings.NewReader(in), "yaml", &b, "json", TestStruct{})
+	assert.Equal(t, errInvalidValue, err)
+}
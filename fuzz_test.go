@@ -0,0 +1,108 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// FuzzStruct holds fields of many kinds, including kinds gofig doesn't support, which
+// must be ignored without panicking.
+type FuzzStruct struct {
+	TestStruct
+	Mode     Mode
+	Timeout  time.Duration
+	Ptr      *int
+	Sub      *SubTestStruct
+	Nil      *SubTestStruct
+	Slice    []string
+	Map      map[string]int
+	Iface    interface{}
+	Int8     int8
+	Float32  float32
+	Array    [2]int
+	Chan     chan int `json:"-" yaml:"-" toml:"-"`
+	Func     func()   `json:"-" yaml:"-" toml:"-"`
+	private  string
+	embedded SubTestStruct
+}
+
+// Mode is a named string type
+type Mode string
+
+func newFuzzStruct() *FuzzStruct {
+	return &FuzzStruct{TestStruct: *buildTestStruct(), Sub: &SubTestStruct{}}
+}
+
+func FuzzDecodeConfig(f *testing.F) {
+	for _, ext := range cfgFileExt {
+		data, err := os.ReadFile("gofig_test_" + ext[1:] + ext)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data, uint8(0))
+		f.Add(data, uint8(1))
+		f.Add(data, uint8(2))
+	}
+	f.Add([]byte("a: &a [*a, *a]\nb: *a"), uint8(2))
+	f.Add([]byte(`{"map": {"a": 1}, "slice": ["a"], "iface": {"a": [1]}}`), uint8(0))
+
+	f.Fuzz(func(t *testing.T, data []byte, format uint8) {
+		ext := cfgFileExt[int(format)%len(cfgFileExt)]
+		_ = decodeConfig(bytes.NewReader(data), ext, newFuzzStruct())
+		_ = decodeConfig(bytes.NewReader(data), ext, &map[string]interface{}{})
+	})
+}
+
+func FuzzDecodeEnv(f *testing.F) {
+	f.Add("1")
+	f.Add("-1")
+	f.Add("1.5")
+	f.Add("1h30m")
+	f.Add("true")
+	f.Add("99999999999999999999999")
+	f.Add("NaN")
+	f.Add("")
+
+	gf := New(ContinueOnError)
+	f.Fuzz(func(t *testing.T, val string) {
+		v := newFuzzStruct()
+		_ = parseStruct(v, func(path []string, name string, f *reflect.Value, tags *reflect.StructTag) error {
+			_ = decodeString(f, val)
+			return nil
+		}, "env")
+		_ = decodeValues(map[string]string{"int": val, "mode": val, "sub.str": val, "nil.str": val}, v)
+		_ = gf.decodeDocument(&Document{Env: map[string]string{"INT8": val, "TIMEOUT": val}}, v)
+	})
+}
+
+func FuzzBuildFlag(f *testing.F) {
+	f.Add("-int", "1")
+	f.Add("-mode", "debug")
+	f.Add("-timeout", "1s")
+	f.Add("-bool", "false")
+	f.Add("-sub-str", "")
+	f.Add("--float", "x")
+
+	f.Fuzz(func(t *testing.T, name string, val string) {
+		fs := flag.NewFlagSet("fuzz", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		v := newFuzzStruct()
+		err := parseStruct(v, New(ContinueOnError).flagBuilder(fs, nil), "flag")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = fs.Parse([]string{name, val})
+		_ = fs.Parse([]string{name + "=" + val})
+		fs.PrintDefaults()
+	})
+}
@@ -0,0 +1,183 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GitSource loads a config file from a git repository, GitOps style. The repository
+// is fetched into a local directory with the git command. When watching (see
+// Gofig.Watch), the ref is fetched every PollInterval and the configuration reloaded
+// when it points to a new commit.
+type GitSource struct {
+	// URL of the repository.
+	URL string
+	// Ref is the branch, tag or commit to check out, "HEAD" by default.
+	Ref string
+	// Path of the config file in the repository, its extension giving its format.
+	Path string
+	// Dir is the local directory the repository is fetched into. It is created if needed.
+	Dir string
+	// PollInterval is the polling interval when watching, 0 to disable polling.
+	PollInterval time.Duration
+
+	mu     sync.Mutex // serializes the git commands
+	commit string     // last checked out commit, or fetched by Watch
+}
+
+// NewGitSource returns a Source loading the config file at path from the git
+// repository at url, fetched into dir.
+func NewGitSource(url string, ref string, path string, dir string) *GitSource {
+	return &GitSource{URL: url, Ref: ref, Path: path, Dir: dir}
+}
+
+// Name returns the repository URL and config file path.
+func (s *GitSource) Name() string {
+	return s.URL + "//" + s.Path
+}
+
+// Load fetches the ref, checks it out and reads the config file.
+func (s *GitSource) Load(ctx context.Context) (*Document, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	commit, err := s.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.git(ctx, "checkout", "--quiet", "--force", "--detach", commit)
+	if err != nil {
+		return nil, err
+	}
+	s.commit = commit
+
+	path, err := s.file()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Document{Format: strings.TrimPrefix(filepath.Ext(s.Path), "."), Data: data}, nil
+}
+
+// Watch polls the ref until it points to a different commit than the checked out one,
+// or the one the last call returned on.
+func (s *GitSource) Watch(ctx context.Context) error {
+	if s.PollInterval <= 0 {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	ticker := time.NewTicker(s.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		s.mu.Lock()
+		commit, err := s.fetch(ctx)
+		changed := err == nil && commit != s.commit
+		if changed {
+			s.commit = commit // reported once, until the next change
+		}
+		s.mu.Unlock()
+		if err != nil {
+			return err
+		}
+		if changed {
+			return nil
+		}
+	}
+}
+
+// check returns an error if the URL or the ref would be taken for an option of the git
+// command, or if the path isn't a relative path inside the repository.
+func (s *GitSource) check() error {
+	if strings.HasPrefix(s.URL, "-") {
+		return fmt.Errorf("invalid repository URL '%v'", s.URL)
+	}
+	if strings.HasPrefix(s.Ref, "-") {
+		return fmt.Errorf("invalid ref '%v'", s.Ref)
+	}
+	path := filepath.Clean(filepath.FromSlash(s.Path))
+	if filepath.IsAbs(path) || path == ".." || strings.HasPrefix(path, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("invalid path '%v', it must be inside the repository", s.Path)
+	}
+	return nil
+}
+
+// file returns the path of the config file in the local repository, once checked out,
+// after checking that it doesn't escape the repository through a symbolic link.
+func (s *GitSource) file() (string, error) {
+	dir, err := filepath.EvalSymlinks(s.Dir)
+	if err != nil {
+		return "", err
+	}
+	path, err := filepath.EvalSymlinks(filepath.Join(dir, filepath.FromSlash(s.Path)))
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid path '%v', it must be inside the repository", s.Path)
+	}
+	return path, nil
+}
+
+// fetch initializes the local repository if needed, fetches the ref and returns its commit.
+func (s *GitSource) fetch(ctx context.Context) (string, error) {
+	err := s.check()
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(filepath.Join(s.Dir, ".git")); err != nil {
+		err = os.MkdirAll(s.Dir, 0755)
+		if err != nil {
+			return "", err
+		}
+		_, err = s.git(ctx, "init", "--quiet")
+		if err != nil {
+			return "", err
+		}
+	}
+
+	ref := s.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	_, err = s.git(ctx, "fetch", "--quiet", "--depth", "1", "--", s.URL, ref)
+	if err != nil {
+		return "", err
+	}
+	return s.git(ctx, "rev-parse", "FETCH_HEAD")
+}
+
+// git runs a git command in the local repository and returns its trimmed output.
+func (s *GitSource) git(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = s.Dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("git %v: %v: %v", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
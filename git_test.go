@@ -0,0 +1,99 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGitSource(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	// upstream repository
+	upstream := t.TempDir()
+	commit := func(content string) {
+		assert.NoError(t, os.WriteFile(filepath.Join(upstream, "config.yaml"), []byte(content), 0644))
+		for _, args := range [][]string{
+			{"add", "config.yaml"},
+			{"-c", "user.name=gofig", "-c", "user.email=gofig@example.com", "commit", "--quiet", "-m", "update"},
+		} {
+			cmd := exec.Command("git", args...)
+			cmd.Dir = upstream
+			out, err := cmd.CombinedOutput()
+			assert.NoError(t, err, string(out))
+		}
+	}
+	cmd := exec.Command("git", "init", "--quiet")
+	cmd.Dir = upstream
+	assert.NoError(t, cmd.Run())
+	commit("str: git\nint: 5\n")
+
+	s := buildTestStruct()
+	gf := New(ContinueOnError)
+	src := NewGitSource(upstream, "", "config.yaml", filepath.Join(t.TempDir(), "clone"))
+	src.PollInterval = 10 * time.Millisecond
+	gf.AddSource(src)
+	err := gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "git", s.Str)
+	assert.Equal(t, 5, s.Int)
+
+	// polling
+	gf.SetWatchQuietPeriod(50 * time.Millisecond) // longer than the poll interval
+	changed := make(chan struct{}, 1)
+	gf.OnChange(func() { changed <- struct{}{} })
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- gf.Watch(ctx) }()
+
+	commit("str: git\nint: 6\n")
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("configuration not reloaded")
+	}
+	assert.Equal(t, 6, s.Int)
+
+	cancel()
+	assert.NoError(t, <-done)
+
+	// unknown ref
+	gf = New(ContinueOnError)
+	gf.AddSource(NewGitSource(upstream, "missing", "config.yaml", filepath.Join(t.TempDir(), "clone")))
+	err = gf.ParseWithArgs(buildTestStruct(), []string{})
+	assert.Error(t, err)
+
+	// options and paths outside of the repository
+	secret := filepath.Join(t.TempDir(), "secret.yaml")
+	assert.NoError(t, os.WriteFile(secret, []byte("str: secret\n"), 0644))
+	assert.NoError(t, os.Symlink(secret, filepath.Join(upstream, "link.yaml")))
+	cmd = exec.Command("git", "add", "link.yaml")
+	cmd.Dir = upstream
+	assert.NoError(t, cmd.Run())
+	commit("str: git\nint: 7\n")
+	for _, c := range []struct {
+		url, ref, path, expected string
+	}{
+		{"--upload-pack=touch /tmp/pwned", "", "config.yaml", "invalid repository URL '--upload-pack=touch /tmp/pwned'"},
+		{upstream, "--upload-pack=touch /tmp/pwned", "config.yaml", "invalid ref '--upload-pack=touch /tmp/pwned'"},
+		{upstream, "", "../secret.yaml", "invalid path '../secret.yaml', it must be inside the repository"},
+		{upstream, "", "/etc/passwd", "invalid path '/etc/passwd', it must be inside the repository"},
+		{upstream, "", "link.yaml", "invalid path 'link.yaml', it must be inside the repository"},
+	} {
+		src := NewGitSource(c.url, c.ref, c.path, filepath.Join(t.TempDir(), "clone"))
+		_, err = src.Load(context.Background())
+		assert.EqualError(t, err, c.expected)
+	}
+}
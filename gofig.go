@@ -6,20 +6,19 @@
 package gofig
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
+	"encoding/base64"
 	"errors"
 	"flag"
-	"fmt"
+	"io"
 	"io/fs"
 	"os"
-	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
-
-	"github.com/BurntSushi/toml"
-	yaml "gopkg.in/yaml.v2"
 )
 
 // Duration wraps time.Duration so we can augment it with encoding.TextMarshaler and
@@ -33,6 +32,28 @@ func (d *Duration) UnmarshalText(text []byte) error {
 	return err
 }
 
+// UnmarshalTOML unmarshals a TOML string, e.g. "1m30s", or a TOML local time, e.g.
+// 00:01:30, being the duration since midnight, into a Duration value.
+func (d *Duration) UnmarshalTOML(value interface{}) error {
+	switch value := value.(type) {
+	case string:
+		return d.UnmarshalText([]byte(value))
+	case time.Time:
+		if isTOMLLocal(value) && value.Year() == 0 {
+			h, m, s := value.Clock()
+			*d = Duration(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute +
+				time.Duration(s)*time.Second + time.Duration(value.Nanosecond()))
+			return nil
+		}
+	}
+	return errorf("invalid duration %v", value)
+}
+
+// MarshalText marshals a Duration into a byte slice, e.g. "1m30s".
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
 // String returns a Duration as a string.
 func (d *Duration) String() string {
 	if d != nil {
@@ -60,7 +81,8 @@ type ErrHandling int
 const (
 	// ContinueOnError will return an err from Parse() if an error is found
 	ContinueOnError ErrHandling = iota
-	// ExitOnError will call os.Exit(2) if an error is found when parsing
+	// ExitOnError will call os.Exit(2) if an error is found when parsing, or return a
+	// *FatalError (see SetReturnFatalErrors)
 	ExitOnError
 	// PanicOnError will panic() if an error is found when parsing flags
 	PanicOnError
@@ -72,6 +94,7 @@ const (
 	jsonExtention = ".json"
 	tomlExtention = ".toml"
 	yamlExtention = ".yaml"
+	cfgB64EnvKey  = "CONFIG_B64"
 )
 
 var cfgFileExt = []string{jsonExtention, tomlExtention, yamlExtention}
@@ -85,18 +108,89 @@ func init() {
 // Gofig is the main gofig structure
 type Gofig struct {
 	envPrefix   string
+	envExpand   bool
+	argsFiles   bool
+	envNoCase   bool
+	lenient     bool
+	strict      bool
+	returnFatal bool
 	cfgFlagName string
+	setFlagName string
+	setJSONFlag string
+	setFileFlag string
+	sumFlagName string
 	cfgFiles    []string
 	errHandling ErrHandling
+	errFormat   ErrorFormat
+	translator  Translator
 	flagSet     *flag.FlagSet
+	flags       *flagUsage
+	builtins    builtinFlags
+	limits      Limits
+	keyring     Keyring
+	resolvers   map[string]SecretResolver
+	verifier    Verifier
+	sigPolicy   SignaturePolicy
+	labels      map[string]string // labels of the instance, resolving the value selectors
+	middlewares []Middleware
+	transforms  []transform
+	validation  bool
+	jsonnet     bool
+	extVarFlag  string
+	checks      []Check
+	aggregate   bool
+	facts       map[string]string // facts of the host and runtime, if added
+
+	sources           []Source
+	urlSources        map[Source]bool // the sources added with AddConfigURL
+	sourceConcurrency int
+	scope             []string // key path of a child instance
+	claimed           []string // key paths decoded by the children and the tenants
+	parseTimeout      time.Duration
+	watchQuietPeriod  time.Duration
+
+	readsMu sync.Mutex
+	reads   map[string]struct{} // key paths of the fields read with the accessors, if recorded
+
+	healthMu sync.Mutex
+	health   []sourceHealth // fetch status of each source
+
+	mu          sync.Mutex
+	target      interface{}       // the parsed struct
+	defaults    reflect.Value     // copy of the user-defined values
+	args        []string          // the parsed arguments
+	overrides   map[string]string // runtime overrides by key path
+	pushed      *Document         // pushed config document
+	patched     *Document         // patches applied on top of the pushed document
+	listeners   []func()
+	changes     []Change        // changes applied by the last reload
+	changed     bool            // whether the last reload changed the configuration
+	sections    []section       // sections enabled by a toggle, as of the last parse
+	resolved    map[string]bool // fields set from a secret reference, as of the last parse
+	rollout     *rolloutPolicy
+	rolloutHeld bool // whether the rollout policy holds a change back
+
+	unused     map[string]struct{} // unused keys collected during a parse
+	unusedKeys []string
+	warns      []string // warnings collected during a parse
+	warnings   []string
+	decoded    map[string]bool // key paths set by the documents and the keyring during a parse
+	visited    map[string]bool // flags set by the arguments during a parse
+	loaded     []*Document     // documents of the sources loaded for the next parse
 }
 
 // New returns an initialized Gofig instance.
 func New(errHandling ErrHandling) *Gofig {
-	return &Gofig{
+	gf := &Gofig{
 		errHandling: errHandling,
 		flagSet:     flag.NewFlagSet(os.Args[0], flag.ContinueOnError),
+		flags:       &flagUsage{},
+
+		sourceConcurrency: defaultSourceConcurrency,
 	}
+	gf.flagSet.SetOutput(&flagOutput{gf: gf})
+	gf.flagSet.Usage = gf.defaultUsage
+	return gf
 }
 
 // SetConfigFileFlag adds a config file flag
@@ -107,7 +201,8 @@ func SetConfigFileFlag(name string, desc string) {
 // SetConfigFileFlag adds a config file flag
 func (gf *Gofig) SetConfigFileFlag(name string, desc string) {
 	gf.cfgFlagName = name
-	gf.flagSet.String(gf.cfgFlagName, "", desc)
+	gf.flagSet.String(gf.cfgFlagName, "", gf.translate(desc))
+	gf.declareFlag(gf.flagSet, gf.cfgFlagName)
 }
 
 // AddConfigFile adds one or more config file(s) (WITHOUT THE FILE EXTENTION) to try to load a startup.
@@ -132,9 +227,38 @@ func (gf *Gofig) SetEnvPrefix(prefix string) {
 	gf.envPrefix = prefix
 }
 
+// SetEnvExpand enables the expansion of $VAR, ${VAR} and ${VAR:-default} references
+// inside environment variable values, e.g. GF_URL='http://$HOST:${PORT:-8080}'.
+// Use $$ for a literal $. Only the ${VAR} references are expanded in the values starting
+// with a Windows path, e.g. C:\$Recycle.Bin or \\server\c$\app. Disabled by default.
+func SetEnvExpand(enabled bool) { gf.SetEnvExpand(enabled) }
+
+// SetEnvExpand enables the expansion of $VAR, ${VAR} and ${VAR:-default} references
+// inside environment variable values, e.g. GF_URL='http://$HOST:${PORT:-8080}'.
+// Use $$ for a literal $. Only the ${VAR} references are expanded in the values starting
+// with a Windows path, e.g. C:\$Recycle.Bin or \\server\c$\app. Disabled by default.
+func (gf *Gofig) SetEnvExpand(enabled bool) {
+	gf.envExpand = enabled
+}
+
+// SetEnvCaseInsensitive makes environment variable lookups case-insensitive, as
+// environment variable names are on Windows. An exact match is still preferred.
+func SetEnvCaseInsensitive(enabled bool) { gf.SetEnvCaseInsensitive(enabled) }
+
+// SetEnvCaseInsensitive makes environment variable lookups case-insensitive, as
+// environment variable names are on Windows. An exact match is still preferred.
+func (gf *Gofig) SetEnvCaseInsensitive(enabled bool) {
+	gf.envNoCase = enabled
+}
+
 // Parse parses the struct to build the flags, parse/decode the optional config file,
 // decode the environment variables and finally parse the arguments.
-func Parse(v interface{}) { _ = gf.Parse(v) }
+func Parse(v interface{}) {
+	// only returns a *FatalError (see SetReturnFatalErrors)
+	if err := gf.Parse(v); err != nil {
+		panic(err)
+	}
+}
 
 // Parse parses the struct to build the flags, parse/decode the optional config file,
 // decode the environment variables and finally parse the arguments.
@@ -146,47 +270,297 @@ func (gf *Gofig) Parse(v interface{}) error {
 // decode the environment variables and finally parse the arguments.
 func (gf *Gofig) ParseWithArgs(v interface{}, args []string) error {
 	err := gf.parse(v, args)
+	if err == nil {
+		err = gf.runBuiltinFlags(v)
+	}
+	return gf.handleError(gf.formatError(err))
+}
+
+func (gf *Gofig) parse(v interface{}, args []string) (err error) {
+	// keep what's needed to recompute the configuration on changes
+	gf.mu.Lock()
+	defer gf.mu.Unlock()
+	args, err = gf.expandArgs(args)
 	if err != nil {
-		switch gf.errHandling {
-		case ExitOnError:
-			fmt.Println(err)
-			os.Exit(2)
-		case PanicOnError:
-			panic(err)
-		default: // includes ContinueOnError
+		return err
+	}
+	gf.defaults = snapshot(v)
+	gf.args = args
+
+	fs := gf.flagSet
+	if gf.scope != nil && fs.Parsed() {
+		fs = gf.newFlagSet() // a child parsed after its parent parses the arguments itself
+	}
+	gf.declareBuiltinFlags()
+	err = gf.parseInto(v, fs, args)
+	if err != nil {
+		return err
+	}
+	gf.target = v
+	return nil
+}
+
+// parseInto runs all the parsing stages on v, registering the flags into fs.
+func (gf *Gofig) parseInto(v interface{}, fs *flag.FlagSet, args []string) (err error) {
+	// resolve the parse methods of the fields
+	hooks, err := fieldHooks(v)
+	if err != nil {
+		return err
+	}
+	// collect the errors of the stages, stopping at the first one unless aggregated
+	errs := errorList{aggregate: gf.aggregate}
+	withNames := func(err error) error { return gf.withFieldNames(err, v, "json", true) }
+	// set the default values of the tags, before the flags are built so that the usage
+	// shows them
+	if errs.add(applyDefaults(v, hooks)) {
+		return errs.err()
+	}
+	// keep the user-defined values of the structs inheriting from a sibling
+	var defaults reflect.Value
+	if hasInheritTags(reflect.TypeOf(v), make(map[reflect.Type]bool)) {
+		defaults = snapshot(v)
+	}
+	// build the flag list from the struct
+	err = parseStruct(v, errs.collect(gf.scoped(gf.flagBuilder(fs, hooks)), nil), "flag")
+	if errs.add(err) || errs.add(gf.annotateSectionFlags(fs, v)) {
+		return errs.err()
+	}
+	if fs != gf.flagSet {
+		// declare the other flags of the flag set (config file, tenants, parent or
+		// children flags) so that the arguments can be parsed, ignoring their values
+		gf.flagSet.VisitAll(func(f *flag.Flag) {
+			if fs.Lookup(f.Name) == nil {
+				fs.Var(ignoredFlag{f.Value}, f.Name, f.Usage)
+			}
+		})
+	}
+	// fetch the optional sources
+	ctx, cancel := gf.parseContext()
+	defer cancel()
+	var docs []*Document
+	err = gf.runStage(ctx, StageSources, v, func(ctx context.Context, name string, v interface{}) (err error) {
+		if gf.loaded != nil {
+			docs, gf.loaded = gf.loaded, nil // already loaded for the tenants
+			return nil
+		}
+		docs, err = gf.loadSources(ctx, args)
+		return err
+	})
+	if errs.add(err) {
+		return errs.err()
+	}
+	// decode the config documents (override user-defined values)
+	gf.unused = make(map[string]struct{})
+	gf.warns = nil
+	gf.decoded = make(map[string]bool)
+	defer func() { gf.decoded, gf.visited = nil, nil }()
+	err = gf.runStage(ctx, StageDocuments, v, func(ctx context.Context, name string, v interface{}) error {
+		return gf.decodeDocuments(ctx, v, args, docs)
+	})
+	unused, warnings := gf.unused, gf.warns
+	gf.unused, gf.warns = nil, nil
+	prefix := ""
+	if len(gf.scope) > 0 {
+		prefix = strings.Join(gf.scope, ".") + "." // the documents of a child are shared
+	}
+	if errs.add(err) || errs.add(gf.checkUnknownKeys(unused, prefix)) {
+		return errs.err()
+	}
+	// decode the env variables (override config file, sources and pushed values)
+	err = gf.runStage(ctx, StageEnv, v, func(ctx context.Context, name string, v interface{}) error {
+		err := parseStruct(v, errs.collect(gf.scoped(gf.envDecoder(hooks)), withNames), "env")
+		return withNames(err)
+	})
+	if errs.add(err) {
+		return errs.err()
+	}
+	// parse the flags (override the env variables values), the flags of a child are
+	// parsed with its parent flag set
+	if fs != gf.flagSet || gf.scope == nil {
+		err = gf.runStage(ctx, StageFlags, v, func(ctx context.Context, name string, v interface{}) error {
+			return withNames(fs.Parse(args))
+		})
+		if errors.Is(err, flag.ErrHelp) {
 			return err
 		}
+		if errs.add(err) {
+			return errs.err()
+		}
+	}
+	gf.visited = make(map[string]bool)
+	if fs.Parsed() {
+		fs.Visit(func(f *flag.Flag) { gf.visited[f.Name] = true })
+	}
+	// apply the runtime overrides (override the flags values), then the override flags
+	err = gf.runStage(ctx, StageOverrides, v, func(ctx context.Context, name string, v interface{}) error {
+		err := withSource(decodeValues(gf.overrides, v), ProvenanceOverride)
+		if err == nil {
+			err = gf.applySetFlags(ctx, v, args)
+		}
+		return withNames(err)
+	})
+	if errs.add(err) {
+		return errs.err()
+	}
+	// find the sections disabled by their toggle
+	gf.sections, err = sections(v, "json")
+	if errs.add(err) {
+		return errs.err()
+	}
+	// inherit the values of the siblings of the structs not overridden
+	if defaults.IsValid() {
+		if errs.add(inheritDefaults(reflect.ValueOf(v).Elem(), defaults, "", gf.setFields(v))) {
+			return errs.err()
+		}
 	}
+	// resolve the secret references of all the sources
+	err = gf.runStage(ctx, StageSecrets, v, func(ctx context.Context, name string, v interface{}) error {
+		resolved, err := gf.resolveSecrets(ctx, v)
+		gf.resolved = resolved
+		return withNames(err)
+	})
+	if errs.add(err) {
+		return errs.err()
+	}
+	// check the required fields, the enums and the ranges, resolve the scratch
+	// directories and check the paths of the fields tagged with exists or mode, then
+	// check the validation rules of the tags, the invariants of the structs implementing
+	// Validator and the added checks
+	for _, check := range []func(v interface{}) error{
+		gf.checkRequired,
+		gf.checkEnums,
+		gf.checkRanges,
+		func(v interface{}) error { return withNames(gf.resolveScratchDirs(v)) },
+		func(v interface{}) error { return withNames(gf.checkPaths(v)) },
+		gf.checkValidateTags,
+		gf.validate,
+		gf.runChecks,
+	} {
+		if errs.add(check(v)) {
+			return errs.err()
+		}
+	}
+	if err := errs.err(); err != nil {
+		return err
+	}
+	gf.setUnusedKeys(unused, prefix)
+	gf.warnings = warnings
 	return nil
 }
 
-func (gf *Gofig) parse(v interface{}, args []string) (err error) {
-	// build the flag list from the struct
-	err = parseStruct(v, gf.flagBuilder, "flag")
+// decodeDocuments decodes the config file and the config documents (including the
+// sources documents docs) into v, each overriding the previous ones.
+func (gf *Gofig) decodeDocuments(ctx context.Context, v interface{}, args []string, docs []*Document) (err error) {
+	if len(gf.scope) > 0 {
+		// the documents are decoded at the scope path of a child
+		wrapper, copyBack := gf.scopeWrapper(v)
+		defer func() {
+			if err == nil {
+				copyBack()
+			}
+		}()
+		v = wrapper
+	}
+
+	// parse the optional config file (override user-defined values)
+	err = gf.parseConfigFile(ctx, v, args)
 	if err != nil {
 		return err
 	}
-	// parse the optional config file (override user-defined values)
-	err = gf.parseConfigFile(v, args)
+	// decode the optional base64-encoded config env variable (override config file values)
+	err = gf.parseConfigEnv(v)
 	if err != nil {
 		return err
 	}
-	// decode the env variables (override config file values)
-	err = parseStruct(v, gf.envDecoder, "env")
+	// decode the optional sources (override config file values)
+	err = gf.decodeSources(docs, v)
 	if err != nil {
 		return err
 	}
-	// parse the flags (override the env variables values)
-	return gf.flagSet.Parse(args)
+	// decode the optional keyring secrets (override sources values)
+	err = gf.decodeKeyring(ctx, v)
+	if err != nil {
+		return gf.withFieldNames(err, v, "json", false)
+	}
+	// decode the pushed document (override sources values)
+	err = gf.decodeDocument(gf.pushed, v)
+	if err != nil {
+		return errorf("error decoding pushed config: %v", err)
+	}
+	// decode the patches (override pushed values)
+	err = gf.decodeDocument(gf.patched, v)
+	if err != nil {
+		return errorf("error decoding patched config: %v", err)
+	}
+	return nil
 }
 
-type fieldParser = func(path []string, val *reflect.Value, tags *reflect.StructTag) error
+// fieldParser is called for each leaf field with its key path, its struct field path
+// (e.g. "Sub.RenamedStr", used in error messages), its value and its tags.
+// The path slice is not shared with any other field and may be retained.
+type fieldParser = func(path []string, name string, val *reflect.Value, tags *reflect.StructTag) error
+
+// errOverflow is returned when a value doesn't fit in the field type.
+var errOverflow = errors.New("value out of range")
 
 // errInvalidValue is returned by DecodeEnv when the target provided is not a non-nil pointer to struct.
 var errInvalidValue = errors.New("invalid interface value, it must be a non-nil pointer to struct")
 
+// fieldMeta holds the metadata of a struct field for a given key tag.
+type fieldMeta struct {
+	key  string // lowercased key, empty if the field is skipped
+	name string
+	tags reflect.StructTag
+}
+
+type fieldMetaKey struct {
+	typ    reflect.Type
+	cfgTag string
+}
+
+// fieldMetaCache caches the []fieldMeta of each struct type and key tag, so the
+// tags are parsed only once per type.
+var fieldMetaCache sync.Map
+
+// structFields returns the cached field metadata of a struct type for a key tag.
+func structFields(rt reflect.Type, cfgTag string) []fieldMeta {
+	cacheKey := fieldMetaKey{typ: rt, cfgTag: cfgTag}
+	if fields, ok := fieldMetaCache.Load(cacheKey); ok {
+		return fields.([]fieldMeta)
+	}
+
+	fields := make([]fieldMeta, rt.NumField())
+	for i := range fields {
+		sf := rt.Field(i)
+		fields[i].name = sf.Name
+		fields[i].tags = sf.Tag
+		if sf.PkgPath != "" {
+			continue // unexported fields can't be set
+		}
+
+		// support field key renaming/skipping
+		key := sf.Tag.Get(cfgTag)
+		if i := strings.IndexByte(key, ','); i >= 0 {
+			key = key[:i]
+		}
+		if key == "-" {
+			continue
+		} else if key == "" {
+			key = sf.Name
+		}
+		fields[i].key = strings.ToLower(key)
+	}
+
+	fieldMetaCache.Store(cacheKey, fields)
+	return fields
+}
+
 // parseStruct recursively parse a struct and call the parser function on each field
-func parseStruct(v interface{}, parser fieldParser, cfgTag string, parents ...string) (err error) {
+func parseStruct(v interface{}, parser fieldParser, cfgTag string) error {
+	if fl, ok := v.(FieldLister); ok {
+		return walkFields(fl.GofigFields(), parser, cfgTag)
+	}
+
 	rv := reflect.ValueOf(v)
 	if rv.Kind() != reflect.Ptr || rv.IsNil() {
 		return errInvalidValue
@@ -197,75 +571,162 @@ func parseStruct(v interface{}, parser fieldParser, cfgTag string, parents ...st
 		return errInvalidValue
 	}
 
-	rt := rv.Type()
-	for i := 0; i < rv.NumField(); i++ {
-		f := rv.Field(i)
-		tags := rt.Field(i).Tag
+	return walkStruct(rv, parser, cfgTag, nil, "")
+}
 
-		// support field key renaming/skipping
-		key := strings.Split(tags.Get(cfgTag), ",")[0]
-		if key == "-" {
+// walkStruct calls the parser function on each field of the struct value rv, recursing
+// into nested structs. All the paths of a struct are carved out of a single buffer,
+// capped so that appending to one of them never overwrites a sibling path.
+func walkStruct(rv reflect.Value, parser fieldParser, cfgTag string, parents []string, parentName string) error {
+	fields := structFields(rv.Type(), cfgTag)
+	buf := make([]string, 0, len(fields)*(len(parents)+1))
+
+	var f reflect.Value
+	var tags reflect.StructTag
+	for i := range fields {
+		if fields[i].key == "" {
 			continue
-		} else if key == "" {
-			key = rt.Field(i).Name
 		}
-		path := append(parents, strings.ToLower(key))
+		f = rv.Field(i)
+		tags = fields[i].tags
+
+		start := len(buf)
+		buf = append(buf, parents...)
+		buf = append(buf, fields[i].key)
+		path := buf[start:len(buf):len(buf)]
+
+		name := fields[i].name
+		if parentName != "" {
+			name = parentName + "." + name
+		}
 
-		// check if it's a struct and if yes we call ourself recursively
+		// check if it's a struct and if yes we call ourself recursively, the times, text
+		// types and flag.Value types being single values
 		switch f.Kind() {
 		case reflect.Ptr:
-			if f.Elem().Kind() != reflect.Struct {
+			if f.Elem().Kind() != reflect.Struct || isValueType(f.Elem().Type()) {
 				break
 			}
 			f = f.Elem()
 			fallthrough
 		case reflect.Struct:
-			si := f.Addr().Interface()
-			err = parseStruct(si, parser, cfgTag, path...)
+			if isValueType(f.Type()) {
+				break
+			}
+			err := walkStruct(f, parser, cfgTag, path, name)
 			if err != nil {
 				return err
 			}
 			continue
 		}
 
-		err = parser(path, &f, &tags)
+		err := parser(path, name, &f, &tags)
 		if err != nil {
 			return err
 		}
 	}
-	return
+	return nil
+}
+
+// flagBuilder returns a fieldParser registering a flag for each field into fs, the
+// fields having a parse method in hooks being set with it.
+func (gf *Gofig) flagBuilder(fs *flag.FlagSet, hooks map[string]parseHook) fieldParser {
+	fields := make(map[string]string) // flag name -> struct field path
+	return func(path []string, name string, val *reflect.Value, tags *reflect.StructTag) error {
+		return gf.buildFlag(fs, fields, hooks, path, name, val, tags)
+	}
 }
 
-func (gf *Gofig) flagBuilder(path []string, val *reflect.Value, tags *reflect.StructTag) error {
+func (gf *Gofig) buildFlag(fs *flag.FlagSet, fields map[string]string, hooks map[string]parseHook, path []string, name string, val *reflect.Value, tags *reflect.StructTag) error {
 	key := strings.Join(path, flagSeparator)
-	desc := tags.Get("desc")
+	desc := gf.translate(tags.Get("desc"))
+	if enum, ok := tags.Lookup(enumTag); ok {
+		desc += gf.translatef(" (one of %v)", strings.Join(enumValues(enum), ", "))
+	}
 
-	v := val.Interface()
-	pv := val.Addr().Interface()
-	switch val.Kind() {
-	case reflect.String:
-		gf.flagSet.StringVar(pv.(*string), key, v.(string), desc)
-	case reflect.Bool:
-		gf.flagSet.BoolVar(pv.(*bool), key, v.(bool), desc)
-	case reflect.Int:
-		gf.flagSet.IntVar(pv.(*int), key, v.(int), desc)
-	case reflect.Int64:
-		durationPtr, ok := pv.(*Duration)
-		if ok {
-			gf.flagSet.Var(durationPtr, key, desc)
-		} else {
-			gf.flagSet.Int64Var(pv.(*int64), key, v.(int64), desc)
+	// the flag package panics on redefined flags, report a meaningful error instead
+	if prev, ok := fields[key]; ok {
+		return errorf("flag -%v is defined by both field %v and field %v", key, prev, name)
+	}
+	if fs.Lookup(key) != nil {
+		return errorf("flag -%v of field %v is already defined", key, name)
+	}
+	if isBuiltinFlag(key) {
+		return errorf("flag -%v of field %v uses the %v prefix reserved for the built-in flags", key, name, builtinFlagPrefix)
+	}
+	fields[key] = name
+
+	defer gf.declareFlag(fs, key)
+	if hook, ok := hooks[name]; ok {
+		fs.Var(&hookFlag{val: *val, hook: hook}, key, desc)
+		return nil
+	}
+	switch pv := val.Addr().Interface().(type) {
+	case *string:
+		fs.StringVar(pv, key, *pv, desc)
+	case *bool:
+		fs.BoolVar(pv, key, *pv, desc)
+	case *int:
+		fs.IntVar(pv, key, *pv, desc)
+	case *int64:
+		fs.Int64Var(pv, key, *pv, desc)
+	case *time.Duration:
+		fs.DurationVar(pv, key, *pv, desc)
+	case flag.Value:
+		// e.g. Duration, PathList or a user-defined type
+		fs.Var(pv, key, desc)
+	case *time.Time:
+		fs.Var(&timeFlag{t: pv, layout: timeLayout(tags)}, key, desc)
+	case *uint:
+		fs.UintVar(pv, key, *pv, desc)
+	case *uint64:
+		fs.Uint64Var(pv, key, *pv, desc)
+	case *float64:
+		fs.Float64Var(pv, key, *pv, desc)
+	default:
+		if _, ok := encodeString(val); ok || isTextType(val.Type()) {
+			// named types of the supported kinds, e.g. type Mode string, and the types
+			// implementing encoding.TextUnmarshaler
+			fs.Var(&valueFlag{val: *val, tags: *tags}, key, desc)
+		} else if val.Kind() == reflect.Slice {
+			fs.Var(&listFlag{val: *val}, key, desc+gf.translate(listUsage))
+		} else if val.Kind() == reflect.Map {
+			fs.Var(&mapFlag{val: *val}, key, desc+gf.translate(mapUsage))
 		}
-	case reflect.Uint:
-		gf.flagSet.UintVar(pv.(*uint), key, v.(uint), desc)
-	case reflect.Uint64:
-		gf.flagSet.Uint64Var(pv.(*uint64), key, v.(uint64), desc)
-	case reflect.Float64:
-		gf.flagSet.Float64Var(pv.(*float64), key, v.(float64), desc)
 	}
 	return nil
 }
 
+// valueFlag is a flag.Value setting a field of a named type or a pointer to a scalar
+// type through reflection.
+type valueFlag struct {
+	val  reflect.Value
+	tags reflect.StructTag
+}
+
+func (f *valueFlag) String() string {
+	if !f.val.IsValid() {
+		return "" // zero valueFlag created by flag.PrintDefaults
+	}
+	s, _ := encodeField(&f.val, &f.tags)
+	return s
+}
+
+func (f *valueFlag) Set(s string) error {
+	return decodeField(&f.val, s, &f.tags)
+}
+
+func (f *valueFlag) IsBoolFlag() bool {
+	if !f.val.IsValid() {
+		return false
+	}
+	t := f.val.Type()
+	if isScalarPointer(t) {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Bool
+}
+
 func (gf *Gofig) getEnvKey(path []string) string {
 	// build the env key
 	if gf.envPrefix != "" {
@@ -274,13 +735,102 @@ func (gf *Gofig) getEnvKey(path []string) string {
 	return strings.ToUpper(strings.Join(path, envSeparator))
 }
 
-func (gf *Gofig) envDecoder(path []string, f *reflect.Value, tags *reflect.StructTag) error {
+// envDecoder returns a fieldParser decoding the environment variable of each field, the
+// fields having a parse method in hooks being set with it.
+func (gf *Gofig) envDecoder(hooks map[string]parseHook) fieldParser {
+	fields := make(map[string]string) // env key -> struct field path
+	return func(path []string, name string, f *reflect.Value, tags *reflect.StructTag) error {
+		return gf.decodeEnv(fields, hooks, path, name, f, tags)
+	}
+}
+
+func (gf *Gofig) decodeEnv(fields map[string]string, hooks map[string]parseHook, path []string, name string, f *reflect.Value, tags *reflect.StructTag) error {
 	key := gf.getEnvKey(path)
-	val, ok := os.LookupEnv(key)
+	if prev, ok := fields[key]; ok {
+		return errorf("environment variable '%v' is used by both field %v and field %v", key, prev, name)
+	}
+	fields[key] = name
+
+	val, ok := gf.lookupEnv(key)
 	if !ok {
+		if _, hooked := hooks[name]; !hooked && f.Kind() == reflect.Map {
+			return gf.decodeEnvMap(key, name, f)
+		}
 		return nil
 	}
+	if gf.envExpand {
+		val = gf.expand(val)
+	}
 
+	if hook, ok := hooks[name]; ok {
+		err := hook.call(val)
+		if err != nil {
+			return &fieldError{name: name, source: ProvenanceEnv, raw: val, err: errorf("error parsing environment variable '%v' with %v: %v", key, hook.method, err)}
+		}
+		return nil
+	}
+	err := decodeField(f, val, tags)
+	if err != nil {
+		return &fieldError{name: name, source: ProvenanceEnv, raw: val, err: errorf("error parsing environment variable '%v' with value '%v' into %v", key, val, f.Type())}
+	}
+	if f.Kind() == reflect.Map {
+		return gf.decodeEnvMap(key, name, f)
+	}
+	return nil
+}
+
+// decodeEnvMap sets the entries of a map field from the environment variables prefixed
+// with its own, e.g. PREFIX_LABELS_FOO=bar sets the "foo" entry of the labels field, the
+// keys being lowercased.
+func (gf *Gofig) decodeEnvMap(key string, name string, f *reflect.Value) error {
+	prefix := key + envSeparator
+	var m reflect.Value
+	for _, kv := range os.Environ() {
+		kvs := strings.SplitN(kv, "=", 2)
+		if len(kvs) != 2 || len(kvs[0]) <= len(prefix) {
+			continue
+		}
+		if kvs[0][:len(prefix)] != prefix && !(gf.envNoCase && strings.EqualFold(kvs[0][:len(prefix)], prefix)) {
+			continue
+		}
+
+		val := kvs[1]
+		if gf.envExpand {
+			val = gf.expand(val)
+		}
+		if !m.IsValid() {
+			// don't modify the map of the previous sources
+			m = reflect.MakeMap(f.Type())
+			iter := f.MapRange()
+			for iter.Next() {
+				m.SetMapIndex(iter.Key(), iter.Value())
+			}
+		}
+		k := reflect.New(f.Type().Key()).Elem()
+		elem := reflect.New(f.Type().Elem()).Elem()
+		if decodeItem(&k, strings.ToLower(kvs[0][len(prefix):])) != nil || decodeItem(&elem, val) != nil {
+			return &fieldError{name: name, source: ProvenanceEnv, raw: val, err: errorf("error parsing environment variable '%v' with value '%v' into %v", kvs[0], val, f.Type())}
+		}
+		m.SetMapIndex(k, elem)
+	}
+	if m.IsValid() {
+		f.Set(m)
+	}
+	return nil
+}
+
+// decodeString decodes a string value into the field f, the same way for every source
+// of string values (environment variables, flat key/value sources, etc.).
+func decodeString(f *reflect.Value, val string) error {
+	if isScalarPointer(f.Type()) {
+		return decodePointer(f, func(elem *reflect.Value) error { return decodeString(elem, val) })
+	}
+	if u, ok := textUnmarshaler(f); ok {
+		return u.UnmarshalText([]byte(val))
+	}
+	if fv, ok := flagValue(f); ok {
+		return fv.Set(val)
+	}
 	switch f.Kind() {
 	case reflect.String:
 		f.SetString(val)
@@ -290,87 +840,244 @@ func (gf *Gofig) envDecoder(path []string, f *reflect.Value, tags *reflect.Struc
 			return err
 		}
 		f.SetBool(b)
-	case reflect.Int, reflect.Int64:
-		if f.Type() == reflect.TypeOf(Duration(0)) {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if f.Type() == durationType || f.Type() == timeDurationType {
 			d, err := time.ParseDuration(val)
 			if err != nil {
-				return fmt.Errorf("error parsing environment variable '%v' with value '%v' into %v", key, val, f.Type())
+				return err
 			}
 			f.SetInt(d.Nanoseconds())
 		} else {
 			n, err := strconv.ParseInt(val, 10, 64)
-			if err != nil || f.OverflowInt(n) {
-				return fmt.Errorf("error parsing environment variable '%v' with value '%v' into %v", key, val, f.Kind())
+			if err != nil {
+				return err
+			}
+			if f.OverflowInt(n) {
+				return errOverflow
 			}
 			f.SetInt(n)
 		}
-	case reflect.Uint, reflect.Uint64:
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 		n, err := strconv.ParseUint(val, 10, 64)
-		if err != nil || f.OverflowUint(n) {
-			return fmt.Errorf("error parsing environment variable '%v' with value '%v' into %v", key, val, f.Kind())
+		if err != nil {
+			return err
+		}
+		if f.OverflowUint(n) {
+			return errOverflow
 		}
 		f.SetUint(n)
-	case reflect.Float64:
+	case reflect.Float32, reflect.Float64:
 		n, err := strconv.ParseFloat(val, f.Type().Bits())
-		if err != nil || f.OverflowFloat(n) {
-			return fmt.Errorf("error parsing environment variable '%v' with value '%v' into %v", key, val, f.Kind())
+		if err != nil {
+			return err
+		}
+		if f.OverflowFloat(n) {
+			return errOverflow
 		}
 		f.SetFloat(n)
+	case reflect.Map:
+		if val == "" {
+			f.Set(reflect.Zero(f.Type()))
+			return nil
+		}
+		m, err := parseMap(f.Type(), val)
+		if err != nil {
+			return err
+		}
+		f.Set(m)
+	case reflect.Slice:
+		if val == "" {
+			f.Set(reflect.Zero(f.Type()))
+			return nil
+		}
+		list, err := parseList(f.Type(), val)
+		if err != nil {
+			return err
+		}
+		f.Set(list)
+	case reflect.Struct:
+		if f.Type() == timeType {
+			t, err := parseTime(val, time.RFC3339)
+			if err != nil {
+				return err
+			}
+			f.Set(reflect.ValueOf(t))
+		}
 	}
 	return nil
 }
 
+// lookupEnv retrieves the value of an environment variable, ignoring the case of its
+// name if SetEnvCaseInsensitive is enabled.
+func (gf *Gofig) lookupEnv(key string) (string, bool) {
+	val, ok := os.LookupEnv(key)
+	if ok || !gf.envNoCase {
+		return val, ok
+	}
+	for _, kv := range os.Environ() {
+		kvs := strings.SplitN(kv, "=", 2)
+		if len(kvs) == 2 && strings.EqualFold(kvs[0], key) {
+			return kvs[1], true
+		}
+	}
+	return "", false
+}
+
+// expandEnvVar resolves a variable reference found by os.Expand, supporting the
+// ${VAR:-default} syntax and $$ as an escaped $.
+func (gf *Gofig) expandEnvVar(name string) string {
+	if name == "$" {
+		return "$"
+	}
+	if i := strings.Index(name, ":-"); i >= 0 {
+		if val := gf.lookupVar(name[:i]); val != "" {
+			return val
+		}
+		return name[i+2:]
+	}
+	return gf.lookupVar(name)
+}
+
+// lookupVar returns the value of a variable reference: a fact (see AddFacts) or an
+// environment variable.
+func (gf *Gofig) lookupVar(name string) string {
+	if val, ok := gf.lookupFact(name); ok {
+		return val
+	}
+	val, _ := gf.lookupEnv(name)
+	return val
+}
+
 func (gf *Gofig) parseConfigFlag(args []string) string {
-	name := "-" + gf.cfgFlagName
-	for i, a := range args {
-		if a == name && len(os.Args) > i+1 {
-			return args[i+1]
+	return flagArg(args, gf.cfgFlagName)
+}
+
+// flagArg returns the value of a flag in args, parsed ahead of the flag set.
+func flagArg(args []string, flagName string) string {
+	if vals := flagArgs(args, flagName); len(vals) > 0 {
+		return vals[0]
+	}
+	return ""
+}
+
+// flagArgs returns the values of a repeatable flag in args, in order, parsed ahead of
+// the flag set.
+func flagArgs(args []string, flagName string) []string {
+	name := "-" + flagName
+	var vals []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == name && len(args) > i+1 {
+			vals = append(vals, args[i+1])
+			i++
+			continue
 		}
 		as := strings.SplitN(a, "=", 2)
 		if as[0] == name && len(as) > 1 {
-			return as[1]
+			vals = append(vals, as[1])
 		}
 	}
-	return ""
+	return vals
 }
 
-func (gf *Gofig) parseConfigFile(v interface{}, args []string) error {
+func (gf *Gofig) parseConfigFile(ctx context.Context, v interface{}, args []string) error {
 	cfgFlag := gf.parseConfigFlag(args)
-
-	var f *os.File
 	if cfgFlag != "" {
-		f, err := os.Open(cfgFlag)
-		if err != nil {
-			return err
-		}
-		return gf.decodeConfigFile(f, v)
+		return gf.decodeConfigFile(ctx, cfgFlag, v)
 	}
 
 	for _, cfgFile := range gf.cfgFiles {
-		for _, ext := range cfgFileExt {
-			var err error
-			f, err = os.Open(cfgFile + ext)
-			if err != nil {
-				if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
-					continue
-				}
-				return err
+		for _, ext := range gf.cfgFileExts() {
+			err := gf.decodeConfigFile(ctx, cfgFile+ext, v)
+			if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+				continue
 			}
-			return gf.decodeConfigFile(f, v)
+			return err
 		}
 	}
 	return nil
 }
 
-func (gf *Gofig) decodeConfigFile(f *os.File, v interface{}) error {
-	switch filepath.Ext(f.Name()) {
-	case jsonExtention:
-		return json.NewDecoder(f).Decode(v)
-	case tomlExtention:
-		_, err := toml.DecodeReader(f, v)
+// parseConfigEnv decodes the base64-encoded JSON or YAML document found in the
+// PREFIX_CONFIG_B64 environment variable, if set.
+func (gf *Gofig) parseConfigEnv(v interface{}) error {
+	key := gf.getEnvKey([]string{cfgB64EnvKey})
+	val, ok := gf.lookupEnv(key)
+	if !ok || val == "" {
+		return nil
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(val))
+	if err != nil {
+		return errorf("error decoding environment variable '%v': %v", key, err)
+	}
+	// the variable can't be signed, it's rejected if the documents must be
+	err = gf.verifyAttached("environment variable '"+key+"'", data, nil)
+	if err != nil {
+		return err
+	}
+	ext := yamlExtention
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		ext = jsonExtention
+	}
+	err = gf.decodeConfig(bytes.NewReader(data), ext, v)
+	if err != nil {
+		return errorf("error decoding environment variable '%v': %v", key, err)
+	}
+	return nil
+}
+
+func (gf *Gofig) decodeConfigFile(ctx context.Context, path string, v interface{}) error {
+	data, err := gf.readConfigFile(ctx, path)
+	if err != nil {
+		return err
+	}
+	err = gf.verifyConfigFile(path, data)
+	if err != nil {
+		return err
+	}
+	data, ext, err := gf.transformFile(ctx, path, data)
+	if err != nil {
+		return err
+	}
+	return gf.decodeConfig(bytes.NewReader(data), ext, v)
+}
+
+// decodeConfig decodes a config document based on its file extension.
+func decodeConfig(r io.Reader, ext string, v interface{}) error {
+	switch ext {
+	case jsonExtention, tomlExtention, yamlExtention:
+	default:
+		return errorf("config file type not supported")
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return decodeConfigTree(parseTree(data, ext), v)
+}
+
+// decodeConfigTree decodes a config document tree, setting the fields having a parse
+// method once the other ones are decoded.
+func decodeConfigTree(t *docTree, v interface{}) (err error) {
+	// the decoders may panic on malformed documents, which may be user-edited
+	defer func() {
+		if p := recover(); p != nil {
+			err = errorf("error decoding %v config: %v", strings.TrimPrefix(t.ext, "."), p)
+		}
+	}()
+
+	hooked, err := extractHooked(t, v)
+	if err != nil {
+		return err
+	}
+	err = coerceNumbers(t, v)
+	if err != nil {
+		return err
+	}
+	err = t.decode(v)
+	if err != nil {
 		return err
-	case yamlExtention:
-		return yaml.NewDecoder(f).Decode(v)
 	}
-	return fmt.Errorf("config file type not supported")
+	return applyHooked(hooked)
 }
@@ -6,8 +6,11 @@
 package gofig
 
 import (
+	"encoding/base64"
 	"fmt"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -266,3 +269,250 @@ func testParseConfigEnvFlag(cfgFile string) func(t *testing.T) {
 		assert.Equal(t, expected, s)
 	}
 }
+
+func TestCollisions(t *testing.T) {
+	// Case 1: two fields renamed to the same flag
+	t.Run("flag", func(t *testing.T) {
+		s := &struct {
+			A string `flag:"name"`
+			B string `flag:"name"`
+		}{}
+		gf := New(ContinueOnError)
+		err := gf.ParseWithArgs(s, []string{})
+		assert.EqualError(t, err, "flag -name is defined by both field A and field B")
+	})
+
+	// Case 2: a field colliding with the config file flag
+	t.Run("config-flag", func(t *testing.T) {
+		s := &struct {
+			C string
+		}{}
+		gf := New(ContinueOnError)
+		gf.SetConfigFileFlag("c", "My test config file")
+		err := gf.ParseWithArgs(s, []string{})
+		assert.EqualError(t, err, "flag -c of field C is already defined")
+	})
+
+	// Case 3: two fields mapping to the same env key
+	t.Run("env", func(t *testing.T) {
+		s := &struct {
+			Sub struct {
+				Port int
+			}
+			SubPort int `env:"sub_port"`
+		}{}
+		gf := New(ContinueOnError)
+		err := gf.ParseWithArgs(s, []string{})
+		assert.EqualError(t, err, "environment variable 'SUB_PORT' is used by both field Sub.Port and field SubPort")
+	})
+}
+
+func TestConfigEnvB64(t *testing.T) {
+	defer os.Unsetenv("B64_CONFIG_B64")
+	defer os.Unsetenv("B64_INT")
+
+	// Case 1: YAML document
+	os.Setenv("B64_CONFIG_B64", base64.StdEncoding.EncodeToString([]byte("str: yaml\nsub:\n  str: sub-yaml\n")))
+	s := &TestStruct{}
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("B64")
+	err := gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "yaml", s.Str)
+	assert.Equal(t, "sub-yaml", s.Sub.RenamedStr)
+
+	// Case 2: JSON document, overridden by an env variable
+	os.Setenv("B64_CONFIG_B64", base64.StdEncoding.EncodeToString([]byte(`{"str": "json", "int": 1}`)))
+	os.Setenv("B64_INT", "2")
+	s = &TestStruct{}
+	gf = New(ContinueOnError)
+	gf.SetEnvPrefix("B64")
+	err = gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "json", s.Str)
+	assert.Equal(t, 2, s.Int)
+
+	// Case 3: invalid base64
+	os.Setenv("B64_CONFIG_B64", "not base64!")
+	gf = New(ContinueOnError)
+	gf.SetEnvPrefix("B64")
+	err = gf.ParseWithArgs(&TestStruct{}, []string{})
+	assert.Error(t, err)
+}
+
+func TestSetEnvExpand(t *testing.T) {
+	os.Setenv("GF_HOST", "example.com")
+	os.Setenv("GF_STR", "http://$GF_HOST:${GF_PORT:-8080}/$$")
+	defer os.Unsetenv("GF_HOST")
+	defer os.Unsetenv("GF_STR")
+
+	// Case 1: expansion disabled (default)
+	s := &TestStruct{}
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("GF")
+	err := gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "http://$GF_HOST:${GF_PORT:-8080}/$$", s.Str)
+
+	// Case 2: expansion enabled, with default value
+	s = &TestStruct{}
+	gf = New(ContinueOnError)
+	gf.SetEnvPrefix("GF")
+	gf.SetEnvExpand(true)
+	err = gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com:8080/$", s.Str)
+
+	// Case 3: expansion enabled, referenced variable set
+	os.Setenv("GF_PORT", "9090")
+	defer os.Unsetenv("GF_PORT")
+	s = &TestStruct{}
+	gf = New(ContinueOnError)
+	gf.SetEnvPrefix("GF")
+	gf.SetEnvExpand(true)
+	err = gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com:9090/$", s.Str)
+}
+
+func TestSetEnvCaseInsensitive(t *testing.T) {
+	os.Setenv("Gf_Str", "env")
+	defer os.Unsetenv("Gf_Str")
+
+	// Case 1: case-sensitive (default)
+	s := &TestStruct{}
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("GF")
+	err := gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "", s.Str)
+
+	// Case 2: case-insensitive
+	s = &TestStruct{}
+	gf = New(ContinueOnError)
+	gf.SetEnvPrefix("GF")
+	gf.SetEnvCaseInsensitive(true)
+	err = gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "env", s.Str)
+}
+
+type deepTestStruct struct {
+	A struct {
+		B struct {
+			C struct {
+				X string
+				Y string
+				Z string
+			}
+			W string
+		}
+	}
+	V string
+}
+
+func TestParseStructPaths(t *testing.T) {
+	// paths are retained by the parser, they must not alias each other
+	var paths []string
+	var retained [][]string
+	var names []string
+	parser := func(path []string, name string, val *reflect.Value, tags *reflect.StructTag) error {
+		retained = append(retained, path)
+		names = append(names, name)
+		return nil
+	}
+	err := parseStruct(&deepTestStruct{}, parser, "flag")
+	assert.NoError(t, err)
+
+	for _, path := range retained {
+		paths = append(paths, strings.Join(path, "."))
+	}
+	assert.Equal(t, []string{"a.b.c.x", "a.b.c.y", "a.b.c.z", "a.b.w", "v"}, paths)
+	assert.Equal(t, []string{"A.B.C.X", "A.B.C.Y", "A.B.C.Z", "A.B.W", "V"}, names)
+}
+
+func TestParseStructAllocs(t *testing.T) {
+	s := &deepTestStruct{}
+	parser := func(path []string, name string, val *reflect.Value, tags *reflect.StructTag) error { return nil }
+
+	// one path buffer and one reflect.Value/StructTag pair per struct, plus one
+	// name per nested field: 4 structs and 6 nested fields (A.B, A.B.C, ...)
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = parseStruct(s, parser, "flag")
+	})
+	assert.True(t, allocs <= 4*3+6, "too many allocations: %v", allocs)
+}
+
+func BenchmarkParseStruct(b *testing.B) {
+	s := &struct {
+		TestStruct
+		Deep  deepTestStruct
+		Other TestStruct
+	}{}
+	parser := func(path []string, name string, val *reflect.Value, tags *reflect.StructTag) error { return nil }
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = parseStruct(s, parser, "flag")
+	}
+}
+
+func TestNamedTypesAndUnexportedFields(t *testing.T) {
+	type Config struct {
+		Mode    Mode
+		Verbose Verbose
+		private string
+	}
+
+	s := &Config{Mode: "release", private: "private"}
+	gf := New(ContinueOnError)
+	err := gf.ParseWithArgs(s, []string{"-mode", "debug", "-verbose"})
+	assert.NoError(t, err)
+	assert.Equal(t, Mode("debug"), s.Mode)
+	assert.Equal(t, Verbose(true), s.Verbose)
+	assert.Equal(t, "private", s.private)
+	assert.Nil(t, gf.flagSet.Lookup("private"))
+
+	// decoder panics are reported as errors
+	err = decodeConfig(strings.NewReader(`{"panic": 1}`), jsonExtention, &struct{ Panic panicUnmarshaler }{})
+	assert.EqualError(t, err, "error decoding json config: boom")
+}
+
+func TestSmallNumericKinds(t *testing.T) {
+	type Config struct {
+		I8  int8
+		I16 int16
+		I32 int32
+		U8  uint8
+		U16 uint16
+		U32 uint32
+		F32 float32
+	}
+
+	os.Setenv("GFN_I16", "-32768")
+	os.Setenv("GFN_U32", "4294967295")
+	defer os.Unsetenv("GFN_I16")
+	defer os.Unsetenv("GFN_U32")
+	s := &Config{}
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("GFN")
+	err := gf.ParseWithArgs(s, []string{"-i8", "-128", "-i32", "7", "-u8", "255", "-u16", "65535", "-f32", "1.5"})
+	assert.NoError(t, err)
+	assert.Equal(t, Config{I8: -128, I16: -32768, I32: 7, U8: 255, U16: 65535, U32: 4294967295, F32: 1.5}, *s)
+	assert.Equal(t, "1.5", Flatten(s)["f32"])
+
+	// overflows
+	for _, args := range [][]string{{"-i8", "128"}, {"-u8", "256"}, {"-u16", "-1"}, {"-f32", "1e39"}} {
+		gf := New(ContinueOnError)
+		err := gf.ParseWithArgs(&Config{}, args)
+		assert.Error(t, err, args)
+	}
+}
+
+// panicUnmarshaler panics when unmarshaled
+type panicUnmarshaler struct{}
+
+func (p *panicUnmarshaler) UnmarshalJSON([]byte) error { panic("boom") }
+
+// Verbose is a named bool type
+type Verbose bool
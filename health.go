@@ -0,0 +1,101 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"time"
+)
+
+// SourceStatus is the health of a configuration source.
+type SourceStatus struct {
+	// Name is the source name.
+	Name string
+	// Optional is set for the sources marked with Optional.
+	Optional bool
+	// LastFetch is the time of the last successful fetch, zero if the source was never
+	// fetched successfully.
+	LastFetch time.Time
+	// LastError is the error of the last failed fetch or watch, nil if the source was
+	// fetched successfully since.
+	LastError error
+	// LastErrorTime is the time of the last error.
+	LastErrorTime time.Time
+	// Staleness is the time elapsed since the last successful fetch, zero if the source
+	// was never fetched successfully.
+	Staleness time.Duration
+}
+
+// Stale returns whether the source wasn't fetched successfully for longer than max, or
+// never was.
+func (s SourceStatus) Stale(max time.Duration) bool {
+	return s.LastFetch.IsZero() || s.Staleness > max
+}
+
+// sourceHealth is the fetch status recorded for a source
+type sourceHealth struct {
+	lastFetch     time.Time
+	lastError     error
+	lastErrorTime time.Time
+}
+
+// SourcesHealth returns the health of each source, in the order they were added, so that
+// readiness probes can refuse traffic when critical configuration is stale.
+func SourcesHealth() []SourceStatus { return gf.SourcesHealth() }
+
+// SourcesHealth returns the health of each source, in the order they were added, so that
+// readiness probes can refuse traffic when critical configuration is stale.
+func (gf *Gofig) SourcesHealth() []SourceStatus {
+	gf.healthMu.Lock()
+	defer gf.healthMu.Unlock()
+
+	now := time.Now()
+	statuses := make([]SourceStatus, len(gf.sources))
+	for i, src := range gf.sources {
+		statuses[i] = SourceStatus{Name: src.Name(), Optional: isOptional(src)}
+		if i >= len(gf.health) {
+			continue
+		}
+		h := gf.health[i]
+		statuses[i].LastFetch = h.lastFetch
+		statuses[i].LastError = h.lastError
+		statuses[i].LastErrorTime = h.lastErrorTime
+		if !h.lastFetch.IsZero() {
+			statuses[i].Staleness = now.Sub(h.lastFetch)
+		}
+	}
+	return statuses
+}
+
+// recordFetch records the result of fetching the i-th source.
+func (gf *Gofig) recordFetch(i int, err error) {
+	if err != nil {
+		gf.recordError(i, err)
+		return
+	}
+	gf.healthMu.Lock()
+	defer gf.healthMu.Unlock()
+	h := gf.sourceHealth(i)
+	h.lastFetch = time.Now()
+	h.lastError = nil
+}
+
+// recordError records an error fetching or watching the i-th source.
+func (gf *Gofig) recordError(i int, err error) {
+	gf.healthMu.Lock()
+	defer gf.healthMu.Unlock()
+	h := gf.sourceHealth(i)
+	h.lastError = err
+	h.lastErrorTime = time.Now()
+}
+
+// sourceHealth returns the recorded status of the i-th source. It must be called with
+// the health lock held.
+func (gf *Gofig) sourceHealth(i int) *sourceHealth {
+	for len(gf.health) <= i {
+		gf.health = append(gf.health, sourceHealth{})
+	}
+	return &gf.health[i]
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSourcesHealth(t *testing.T) {
+	flaky := &flakySource{failures: 1}
+	gf := New(ContinueOnError)
+	gf.AddSource(
+		&testSource{name: "ok", doc: &Document{Values: map[string]string{"str": "ok"}}},
+		Optional(flaky),
+	)
+
+	// Case 1: never fetched
+	health := gf.SourcesHealth()
+	assert.Len(t, health, 2)
+	assert.Equal(t, SourceStatus{Name: "ok"}, health[0])
+	assert.True(t, health[0].Stale(time.Hour))
+
+	// Case 2: failed optional source
+	start := time.Now()
+	err := gf.ParseWithArgs(buildTestStruct(), []string{})
+	assert.NoError(t, err)
+	health = gf.SourcesHealth()
+	assert.Equal(t, "ok", health[0].Name)
+	assert.False(t, health[0].Optional)
+	assert.True(t, !health[0].LastFetch.Before(start))
+	assert.NoError(t, health[0].LastError)
+	assert.False(t, health[0].Stale(time.Hour))
+	assert.True(t, health[0].Stale(0))
+
+	assert.Equal(t, "flaky", health[1].Name)
+	assert.True(t, health[1].Optional)
+	assert.True(t, health[1].LastFetch.IsZero())
+	assert.Equal(t, errors.New("unavailable"), health[1].LastError)
+	assert.True(t, !health[1].LastErrorTime.Before(start))
+	assert.True(t, health[1].Stale(time.Hour))
+
+	// Case 3: recovered on reload
+	err = gf.Reload()
+	assert.NoError(t, err)
+	health = gf.SourcesHealth()
+	assert.NoError(t, health[1].LastError)
+	assert.False(t, health[1].Stale(time.Hour))
+}
@@ -0,0 +1,269 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// HelmValuesSource loads a Helm values file following the usual Helm conventions:
+// camelCase keys (matched case-insensitively against the json tags or field names) and
+// an optional top-level "env" list of {name, value} entries, applied as environment
+// variables (e.g. {name: GF_DB_PORT, value: "5432"}).
+type HelmValuesSource struct {
+	// Path of the values file.
+	Path string
+}
+
+// NewHelmValuesSource returns a Source loading the Helm values file at path.
+func NewHelmValuesSource(path string) *HelmValuesSource {
+	return &HelmValuesSource{Path: path}
+}
+
+// AddHelmValues adds a Helm values file as a source, see HelmValuesSource.
+func AddHelmValues(path string) { gf.AddHelmValues(path) }
+
+// AddHelmValues adds a Helm values file as a source, see HelmValuesSource.
+func (gf *Gofig) AddHelmValues(path string) {
+	gf.AddSource(NewHelmValuesSource(path))
+}
+
+// Name returns the values file path.
+func (s *HelmValuesSource) Name() string {
+	return s.Path
+}
+
+// Load reads the values file.
+func (s *HelmValuesSource) Load(ctx context.Context) (*Document, error) {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	return parseHelmValues(data)
+}
+
+// parseHelmValues converts Helm values into a document.
+func parseHelmValues(data []byte) (*Document, error) {
+	var values interface{}
+	err := yaml.Unmarshal(data, &values)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, ok := stringKeys(values).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("the values file must be a YAML mapping")
+	}
+
+	doc := &Document{Format: "json"}
+	if env, ok := helmEnv(tree["env"]); ok {
+		delete(tree, "env")
+		doc.Env = env
+	}
+
+	// JSON keys are matched case-insensitively, so camelCase keys match the fields
+	doc.Data, err = json.Marshal(tree)
+	return doc, err
+}
+
+// helmEnv converts a Helm env list of {name, value} entries into a map.
+func helmEnv(v interface{}) (map[string]string, bool) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	env := make(map[string]string, len(list))
+	for _, item := range list {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		name, ok := entry["name"].(string)
+		if !ok {
+			return nil, false
+		}
+		if value, ok := entry["value"]; ok && value != nil {
+			env[name] = fmt.Sprint(value)
+		}
+	}
+	return env, true
+}
+
+// stringKeys converts the map[interface{}]interface{} decoded by YAML for maps with
+// non-string keys into map[string]interface{}, recursively.
+func stringKeys(v interface{}) interface{} {
+	switch node := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(node))
+		for k, val := range node {
+			m[fmt.Sprint(k)] = stringKeys(val)
+		}
+		return m
+	case map[string]interface{}:
+		for k, val := range node {
+			node[k] = stringKeys(val)
+		}
+	case []interface{}:
+		for i, val := range node {
+			node[i] = stringKeys(val)
+		}
+	}
+	return v
+}
+
+// HelmValuesSchema returns the JSON schema of the configuration struct v, to be used
+// as the values.schema.json file of a Helm chart. Keys follow the Helm camelCase
+// convention unless renamed with a json tag, descriptions come from the desc tags and
+// defaults from the current values of v.
+func HelmValuesSchema(v interface{}) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil, errInvalidValue
+	}
+
+	schema := jsonSchema(rv.Elem(), "", schemaOptions{key: helmKey})
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// schemaOptions are the options of the JSON schema of a struct
+type schemaOptions struct {
+	key     func(sf reflect.StructField) string // key of a field, "-" to skip it
+	secrets bool                                // whether the secret fields are write-only
+	parents map[reflect.Type]bool               // struct types being described, to stop on a cycle
+}
+
+// helmKey returns the key of a field in Helm values, following the Helm camelCase
+// convention unless renamed with a json tag.
+func helmKey(sf reflect.StructField) string {
+	key := strings.Split(sf.Tag.Get("json"), ",")[0]
+	if key == "" {
+		key = lowerCamel(sf.Name)
+	}
+	return key
+}
+
+// jsonSchema returns the JSON schema of a value. The struct types nested in themselves,
+// e.g. type Node struct{ Next *Node }, accept any value at the point they recur.
+func jsonSchema(rv reflect.Value, desc string, opts schemaOptions) map[string]interface{} {
+	schema := make(map[string]interface{})
+	if desc != "" {
+		schema["description"] = desc
+	}
+
+	if rv.Type() == durationType || rv.Type() == timeDurationType {
+		schema["type"] = "string" // a duration string, e.g. "1m30s"
+		if d := time.Duration(rv.Int()); d != 0 {
+			schema["default"] = d.String()
+		}
+		return schema
+	}
+	if rv.Type() == timeType || isTextType(rv.Type()) {
+		schema["type"] = "string" // in the layout of the field, or text
+		return schema
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return jsonSchema(reflect.Zero(rv.Type().Elem()), desc, opts)
+		}
+		return jsonSchema(rv.Elem(), desc, opts)
+	case reflect.Struct:
+		rt := rv.Type()
+		if opts.parents[rt] {
+			return schema // any value
+		}
+		if opts.parents == nil {
+			opts.parents = make(map[reflect.Type]bool)
+		}
+		opts.parents[rt] = true
+		defer delete(opts.parents, rt)
+
+		schema["type"] = "object"
+		properties := make(map[string]interface{})
+		for i := 0; i < rt.NumField(); i++ {
+			sf := rt.Field(i)
+			if sf.PkgPath != "" {
+				continue // unexported
+			}
+			key := opts.key(sf)
+			if key == "-" {
+				continue
+			}
+			field := rv.Field(i)
+			if opts.secrets && isSecret(&sf.Tag) {
+				field = reflect.Zero(sf.Type) // no default
+			}
+			properties[key] = jsonSchema(field, sf.Tag.Get("desc"), opts)
+			if opts.secrets && isSecret(&sf.Tag) {
+				properties[key].(map[string]interface{})["writeOnly"] = true
+			}
+			if enum, ok := sf.Tag.Lookup(enumTag); ok {
+				prop := properties[key].(map[string]interface{})
+				if items, ok := prop["items"].(map[string]interface{}); ok {
+					prop = items // the allowed values of the items of a list
+				}
+				prop["enum"] = enumSchema(sf.Type, enum)
+			}
+			if n, ok := rangeSchema(sf.Type, sf.Tag.Get(minTag)); ok {
+				properties[key].(map[string]interface{})["minimum"] = n
+			}
+			if n, ok := rangeSchema(sf.Type, sf.Tag.Get(maxTag)); ok {
+				properties[key].(map[string]interface{})["maximum"] = n
+			}
+		}
+		schema["properties"] = properties
+		return schema
+	case reflect.String:
+		schema["type"] = "string"
+	case reflect.Bool:
+		schema["type"] = "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		schema["type"] = "integer"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		schema["type"] = "integer"
+		schema["minimum"] = 0
+	case reflect.Float32, reflect.Float64:
+		schema["type"] = "number"
+	case reflect.Slice, reflect.Array:
+		schema["type"] = "array"
+		schema["items"] = jsonSchema(reflect.Zero(rv.Type().Elem()), "", opts)
+	case reflect.Map:
+		schema["type"] = "object"
+		schema["additionalProperties"] = jsonSchema(reflect.Zero(rv.Type().Elem()), "", opts)
+	}
+
+	if !rv.IsZero() {
+		schema["default"] = rv.Interface()
+	}
+	return schema
+}
+
+// lowerCamel converts a Go field name to lower camel case, e.g. "HTTPPort" to "httpPort".
+func lowerCamel(name string) string {
+	runes := []rune(name)
+	n := 0
+	for n < len(runes) && unicode.IsUpper(runes[n]) {
+		n++
+	}
+	if n > 1 && n < len(runes) {
+		n-- // keep the start of the next word, e.g. the P of "HTTPPort"
+	}
+	for i := 0; i < n; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
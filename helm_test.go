@@ -0,0 +1,115 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type helmTestStruct struct {
+	MaxConns int    `desc:"maximum number of connections"`
+	LogLevel string `json:"log_level"`
+	DB       struct {
+		HTTPPort uint
+		Timeout  Duration
+	}
+	Skipped string `json:"-"`
+}
+
+func TestAddHelmValues(t *testing.T) {
+	values := `
+maxConns: 10
+log_level: debug
+db:
+  httpPort: 8080
+  timeout: 5s
+env:
+  - name: GF_DB_HTTPPORT
+    value: "9090"
+  - name: OTHER
+    value: ignored
+`
+	path := filepath.Join(t.TempDir(), "values.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(values), 0644))
+
+	s := &helmTestStruct{}
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("GF")
+	gf.AddHelmValues(path)
+	err := gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 10, s.MaxConns)
+	assert.Equal(t, "debug", s.LogLevel)
+	assert.Equal(t, uint(9090), s.DB.HTTPPort)
+	assert.Equal(t, Duration(5*time.Second), s.DB.Timeout)
+}
+
+func TestHelmValuesSchema(t *testing.T) {
+	s := &helmTestStruct{MaxConns: 5}
+	s.DB.Timeout = Duration(time.Minute)
+
+	schema, err := HelmValuesSchema(s)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"type": "object",
+		"properties": {
+			"maxConns": {"type": "integer", "description": "maximum number of connections", "default": 5},
+			"log_level": {"type": "string"},
+			"db": {
+				"type": "object",
+				"properties": {
+					"httpPort": {"type": "integer", "minimum": 0},
+					"timeout": {"type": "string", "default": "1m0s"}
+				}
+			}
+		}
+	}`, string(schema))
+
+	_, err = HelmValuesSchema(*s)
+	assert.Error(t, err)
+
+	// self-referential types
+	type node struct {
+		Name     string
+		Next     *node
+		Children []node
+	}
+	schema, err = HelmValuesSchema(&struct{ Root node }{Root: node{Next: &node{Name: "b"}}})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"type": "object",
+		"properties": {
+			"root": {
+				"type": "object",
+				"properties": {
+					"name": {"type": "string"},
+					"next": {},
+					"children": {"type": "array", "items": {}}
+				}
+			}
+		}
+	}`, string(schema))
+}
+
+func TestLowerCamel(t *testing.T) {
+	for name, expected := range map[string]string{
+		"MaxConns": "maxConns",
+		"DB":       "db",
+		"HTTPPort": "httpPort",
+		"ID":       "id",
+		"url":      "url",
+	} {
+		assert.Equal(t, expected, lowerCamel(name))
+	}
+}
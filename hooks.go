@@ -0,0 +1,213 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// parseWithTag is the struct tag naming the method of the parent struct which parses the
+// raw values of a field, e.g. `parseWith:"ParseListenAddrs"`. The method must be a
+// func(string) error, receiving the environment variable, flag or config document value
+// as text, or a func(interface{}) error, receiving the text of the environment variables
+// and flags and the decoded value of the config documents (a string, bool, int64,
+// float64, time.Time, []interface{} or map[string]interface{}).
+const parseWithTag = "parseWith"
+
+var (
+	stringType    = reflect.TypeOf("")
+	interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+	errorType     = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// parseHook is the parse method of a field
+type parseHook struct {
+	method string
+	fn     reflect.Value
+	raw    bool // whether fn receives the decoded config document values
+}
+
+// call calls the parse method with a raw value.
+func (h parseHook) call(raw interface{}) error {
+	arg := reflect.ValueOf(&raw).Elem()
+	if !h.raw {
+		switch val := raw.(type) {
+		case string:
+			arg = reflect.ValueOf(val)
+		case map[string]interface{}, []interface{}:
+			return errorf("%v expects a single value", h.method)
+		default:
+			arg = reflect.ValueOf(fmt.Sprint(val))
+		}
+	}
+	out := h.fn.Call([]reflect.Value{arg})
+	err, _ := out[0].Interface().(error)
+	return err
+}
+
+// fieldHooks returns the parse methods of the fields of the struct pointed to by v
+// having a parseWith tag, by field name.
+func fieldHooks(v interface{}) (map[string]parseHook, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil, nil
+	}
+	hooks := make(map[string]parseHook)
+	err := walkHooks(rv.Elem(), "", hooks)
+	return hooks, err
+}
+
+// walkHooks adds the parse methods of the fields of the struct value rv to hooks,
+// recursing into nested structs.
+func walkHooks(rv reflect.Value, parentName string, hooks map[string]parseHook) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		name := sf.Name
+		if parentName != "" {
+			name = parentName + "." + name
+		}
+
+		f := rv.Field(i)
+		if f.Kind() == reflect.Ptr && f.Elem().Kind() == reflect.Struct {
+			f = f.Elem()
+		}
+		if f.Kind() == reflect.Struct {
+			err := walkHooks(f, name, hooks)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		method, ok := sf.Tag.Lookup(parseWithTag)
+		if !ok {
+			continue
+		}
+		fn := rv.Addr().MethodByName(method)
+		if !fn.IsValid() {
+			return errorf("parse method %v of field %v not found", method, name)
+		}
+		ft := fn.Type()
+		if ft.NumIn() != 1 || ft.NumOut() != 1 || ft.Out(0) != errorType ||
+			(ft.In(0) != stringType && ft.In(0) != interfaceType) {
+			return errorf("parse method %v of field %v must be a func(string) error or a func(interface{}) error", method, name)
+		}
+		hooks[name] = parseHook{method: method, fn: fn, raw: ft.In(0) == interfaceType}
+	}
+	return nil
+}
+
+// hookedValue is a config document value set into a field by its parse method
+type hookedValue struct {
+	key  string
+	raw  interface{}
+	hook parseHook
+}
+
+// extractHooked removes the values of the fields having a parse method from a config
+// document tree, so that they can be set by calling the method once the tree is decoded
+// into the struct pointed to by v.
+func extractHooked(t *docTree, v interface{}) ([]hookedValue, error) {
+	hooks, err := fieldHooks(v)
+	if err != nil || len(hooks) == 0 || t.root == nil {
+		return nil, err // syntax errors are left to the decoder
+	}
+	keys := make(map[string]parseHook)
+	_ = parseStruct(v, func(path []string, name string, val *reflect.Value, tags *reflect.StructTag) error {
+		if hook, ok := hooks[name]; ok {
+			keys[strings.Join(path, ".")] = hook
+		}
+		return nil
+	}, strings.TrimPrefix(t.ext, "."))
+
+	var hooked []hookedValue
+	removeHooked(t.root, "", keys, &hooked)
+	t.changed = t.changed || len(hooked) > 0
+	return hooked, nil
+}
+
+// removeHooked removes the values of a document tree mapped to the keys of the fields
+// having a parse method, adding them to hooked.
+func removeHooked(tree map[string]interface{}, path string, keys map[string]parseHook, hooked *[]hookedValue) {
+	for k, val := range tree {
+		key := strings.ToLower(k)
+		if path != "" {
+			key = path + "." + key
+		}
+		if hook, ok := keys[key]; ok {
+			*hooked = append(*hooked, hookedValue{key: key, raw: rawValue(val), hook: hook})
+			delete(tree, k)
+		} else if sub, ok := val.(map[string]interface{}); ok {
+			removeHooked(sub, key, keys, hooked)
+		}
+	}
+}
+
+// applyHooked sets the values removed by extractHooked by calling the parse methods.
+func applyHooked(hooked []hookedValue) error {
+	for _, h := range hooked {
+		err := h.hook.call(h.raw)
+		if err != nil {
+			return &fieldError{key: h.key, source: ProvenanceConfig, raw: fmt.Sprint(h.raw), err: errorf("error parsing key '%v' with %v: %v", h.key, h.hook.method, err)}
+		}
+	}
+	return nil
+}
+
+// rawValue returns a decoded value with the same types whatever the config format, the
+// integers being int64 and the other numbers float64.
+func rawValue(val interface{}) interface{} {
+	switch val := val.(type) {
+	case json.Number:
+		if n, err := val.Int64(); err == nil {
+			return n
+		}
+		f, _ := val.Float64()
+		return f
+	case int:
+		return int64(val)
+	case uint64:
+		return float64(val)
+	case []interface{}:
+		list := make([]interface{}, len(val))
+		for i := range val {
+			list[i] = rawValue(val[i])
+		}
+		return list
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k := range val {
+			m[k] = rawValue(val[k])
+		}
+		return m
+	}
+	return val
+}
+
+// hookFlag is a flag.Value setting a field with its parse method.
+type hookFlag struct {
+	val  reflect.Value
+	hook parseHook
+}
+
+func (f *hookFlag) String() string {
+	if !f.val.IsValid() {
+		return "" // zero hookFlag created by flag.PrintDefaults
+	}
+	s, _ := encodeString(&f.val)
+	return s
+}
+
+func (f *hookFlag) Set(s string) error {
+	return f.hook.call(s)
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type hookServer struct {
+	Listen []string `parseWith:"ParseListenAddrs"`
+	Mode   int      `parseWith:"ParseMode"`
+}
+
+// ParseListenAddrs parses a space-separated list of addresses, or a list of ports
+func (s *hookServer) ParseListenAddrs(raw interface{}) error {
+	s.Listen = nil
+	switch raw := raw.(type) {
+	case string:
+		s.Listen = strings.Fields(raw)
+	case []interface{}:
+		for _, port := range raw {
+			s.Listen = append(s.Listen, fmt.Sprintf(":%v", port))
+		}
+	default:
+		return fmt.Errorf("unexpected %T", raw)
+	}
+	return nil
+}
+
+// ParseMode parses a mode name
+func (s *hookServer) ParseMode(raw string) error {
+	switch raw {
+	case "fast":
+		s.Mode = 1
+	case "safe":
+		s.Mode = 2
+	default:
+		return fmt.Errorf("unknown mode %q", raw)
+	}
+	return nil
+}
+
+type hookInvalid struct {
+	Port int `parseWith:"ParsePort"`
+}
+
+// ParsePort has an invalid parse method signature
+func (s *hookInvalid) ParsePort(raw string) bool {
+	return false
+}
+
+func TestParseWith(t *testing.T) {
+	type Config struct {
+		Server hookServer
+	}
+
+	// Case 1: config document values
+	for format, doc := range map[string]string{
+		"json": `{"server": {"listen": [80, 443], "mode": "safe"}}`,
+		"toml": "[server]\nlisten = [80, 443]\nmode = \"safe\"\n",
+		"yaml": "server: {listen: [80, 443], mode: safe}\n",
+	} {
+		s := &Config{}
+		err := decodeConfig(strings.NewReader(doc), "."+format, s)
+		assert.NoError(t, err, format)
+		assert.Equal(t, hookServer{Listen: []string{":80", ":443"}, Mode: 2}, s.Server, format)
+	}
+
+	// Case 2: environment variables and flags
+	os.Setenv("GFHOOK_SERVER_LISTEN", "a:1 b:2")
+	defer os.Unsetenv("GFHOOK_SERVER_LISTEN")
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("GFHOOK")
+	s := &Config{}
+	err := gf.ParseWithArgs(s, []string{"-server-mode", "fast"})
+	assert.NoError(t, err)
+	assert.Equal(t, hookServer{Listen: []string{"a:1", "b:2"}, Mode: 1}, s.Server)
+
+	// Case 3: parse method errors
+	err = decodeConfig(strings.NewReader(`{"server": {"mode": "slow"}}`), jsonExtention, &Config{})
+	assert.EqualError(t, err, `error parsing key 'server.mode' with ParseMode: unknown mode "slow"`)
+	err = decodeConfig(strings.NewReader(`{"server": {"mode": ["fast"]}}`), jsonExtention, &Config{})
+	assert.EqualError(t, err, "error parsing key 'server.mode' with ParseMode: ParseMode expects a single value")
+	type Ported struct {
+		Server hookServer
+		Port   int
+	}
+	err = decodeConfig(strings.NewReader("server:\n  mode: safe\n\nport: abc\n"), yamlExtention, &Ported{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "line 4:")
+	os.Setenv("GFHOOK_SERVER_MODE", "slow")
+	defer os.Unsetenv("GFHOOK_SERVER_MODE")
+	gf = New(ContinueOnError)
+	gf.SetEnvPrefix("GFHOOK")
+	err = gf.ParseWithArgs(&Config{}, []string{})
+	assert.EqualError(t, err, `error parsing environment variable 'GFHOOK_SERVER_MODE' with ParseMode: unknown mode "slow" (flag -server-mode, env GFHOOK_SERVER_MODE, key server.mode)`)
+
+	// Case 4: invalid parse methods
+	type Missing struct {
+		Port int `parseWith:"ParsePort"`
+	}
+	err = New(ContinueOnError).ParseWithArgs(&Missing{}, []string{})
+	assert.EqualError(t, err, "parse method ParsePort of field Port not found")
+	err = New(ContinueOnError).ParseWithArgs(&hookInvalid{}, []string{})
+	assert.EqualError(t, err, "parse method ParsePort of field Port must be a func(string) error or a func(interface{}) error")
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"reflect"
+	"strings"
+)
+
+// inheritTag is the struct tag of a nested struct falling back to the values of a
+// sibling struct of the same type, e.g. `inheritDefaults:"primary"`. The sibling is
+// named by its key.
+const inheritTag = "inheritDefaults"
+
+// hasInheritTags returns whether the struct type rt, or a nested struct, has fields
+// tagged with inheritDefaults.
+func hasInheritTags(rt reflect.Type, visited map[reflect.Type]bool) bool {
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if rt.Kind() != reflect.Struct || visited[rt] {
+		return false
+	}
+	visited[rt] = true
+	for i, f := range structFields(rt, "json") {
+		if f.key == "" {
+			continue
+		}
+		if _, ok := f.tags.Lookup(inheritTag); ok {
+			return true
+		}
+		if hasInheritTags(rt.Field(i).Type, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// inheritDefaults sets the fields of the structs tagged with inheritDefaults which
+// weren't set by a source, according to set, and still hold their default value in
+// defaults, to the value of the field of the sibling struct they inherit from. A field
+// explicitly set to its default value isn't inherited.
+func inheritDefaults(rv, defaults reflect.Value, parentName string, set func(name string) bool) error {
+	rt := rv.Type()
+	fields := structFields(rt, "json")
+	for i, field := range fields {
+		if field.key == "" {
+			continue
+		}
+		name := field.name
+		if parentName != "" {
+			name = parentName + "." + name
+		}
+		f, def := structValue(rv.Field(i)), structValue(defaults.Field(i))
+		if !f.IsValid() {
+			continue
+		}
+
+		if key, ok := field.tags.Lookup(inheritTag); ok {
+			key = strings.ToLower(key)
+			var src reflect.Value
+			for j := range fields {
+				if j != i && fields[j].key == key {
+					src = structValue(rv.Field(j))
+				}
+			}
+			if !src.IsValid() || src.Type() != f.Type() {
+				return errorf("field %v inherits from '%v', which isn't a sibling struct of the same type", name, key)
+			}
+			inheritValues(f, src, def, name, set)
+		}
+		if def.IsValid() {
+			err := inheritDefaults(f, def, name, set)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// inheritValues sets the leaf fields of dst, named after parentName, which weren't set
+// and still hold their default value, in def, to the value of the field of src.
+func inheritValues(dst, src, def reflect.Value, parentName string, set func(name string) bool) {
+	for i, field := range structFields(dst.Type(), "json") {
+		if field.key == "" {
+			continue
+		}
+		name := parentName + "." + field.name
+		f, s := dst.Field(i), src.Field(i)
+		var d reflect.Value
+		if def.IsValid() {
+			d = def.Field(i)
+		}
+		if fs, ss := structValue(f), structValue(s); fs.IsValid() && ss.IsValid() {
+			inheritValues(fs, ss, structValue(d), name, set)
+			continue
+		}
+		if set(name) {
+			continue
+		}
+		if !d.IsValid() || reflect.DeepEqual(f.Interface(), d.Interface()) {
+			f.Set(deepCopy(s, make(map[uintptr]reflect.Value)))
+		}
+	}
+}
+
+// structValue returns the struct value of a nested struct field, or of the struct it
+// points to, or an invalid value if it isn't one.
+func structValue(f reflect.Value) reflect.Value {
+	if !f.IsValid() {
+		return f
+	}
+	if f.Kind() == reflect.Ptr {
+		if f.IsNil() {
+			return reflect.Value{}
+		}
+		f = f.Elem()
+	}
+	if f.Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+	return f
+}
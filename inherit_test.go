@@ -0,0 +1,87 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type dbConfig struct {
+	Host    string
+	Port    int
+	Options struct {
+		SSL bool
+	}
+}
+
+type inheritStruct struct {
+	Primary dbConfig
+	Replica dbConfig `inheritDefaults:"primary"`
+}
+
+func TestInheritDefaults(t *testing.T) {
+	os.Setenv("INHERIT_PRIMARY_PORT", "5433")
+	defer os.Unsetenv("INHERIT_PRIMARY_PORT")
+
+	s := &inheritStruct{}
+	s.Primary.Host = "db"
+	s.Primary.Port = 5432
+	s.Replica.Port = 5432
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("INHERIT")
+	err := gf.ParseWithArgs(s, []string{"-replica-host", "db-replica", "-primary-options-ssl"})
+	assert.NoError(t, err)
+	assert.Equal(t, "db", s.Primary.Host)
+	assert.Equal(t, 5433, s.Primary.Port)
+	assert.True(t, s.Primary.Options.SSL)
+	// distinct namespaces, the replica falling back to the primary values
+	assert.Equal(t, "db-replica", s.Replica.Host)
+	assert.Equal(t, 5433, s.Replica.Port)
+	assert.True(t, s.Replica.Options.SSL)
+
+	// the values are inherited again on reload
+	assert.NoError(t, gf.Override("primary.host", "db2"))
+	assert.Equal(t, "db-replica", s.Replica.Host)
+	assert.NoError(t, gf.ClearOverride("primary.host"))
+	assert.NoError(t, gf.Override("primary.port", "6000"))
+	assert.Equal(t, 6000, s.Replica.Port)
+
+	// a value explicitly set to the default isn't inherited
+	for _, args := range [][]string{
+		{"-primary-port", "1", "-replica-port", "5432"},
+		{"-primary-port", "1", "-set", "replica.port=5432"},
+	} {
+		s = &inheritStruct{}
+		s.Replica.Port = 5432
+		gf = New(ContinueOnError)
+		gf.SetOverrideFlag("set", "override a `key=value`")
+		err = gf.ParseWithArgs(s, args)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, s.Primary.Port)
+		assert.Equal(t, 5432, s.Replica.Port, args)
+	}
+	os.Setenv("INHERIT_REPLICA_PORT", "5432")
+	s = &inheritStruct{}
+	s.Replica.Port = 5432
+	gf = New(ContinueOnError)
+	gf.SetEnvPrefix("INHERIT")
+	err = gf.ParseWithArgs(s, []string{})
+	os.Unsetenv("INHERIT_REPLICA_PORT")
+	assert.NoError(t, err)
+	assert.Equal(t, 5433, s.Primary.Port)
+	assert.Equal(t, 5432, s.Replica.Port)
+
+	// unknown sibling
+	type invalidStruct struct {
+		Primary dbConfig
+		Replica dbConfig `inheritDefaults:"main"`
+	}
+	err = New(ContinueOnError).ParseWithArgs(&invalidStruct{}, []string{})
+	assert.EqualError(t, err, "field Replica inherits from 'main', which isn't a sibling struct of the same type")
+}
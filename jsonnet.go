@@ -0,0 +1,109 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// jsonnetCommand is the command evaluating the Jsonnet config files, either the C++ or
+// the Go implementation.
+const jsonnetCommand = "jsonnet"
+
+// EnableJsonnet enables the Jsonnet config files (.jsonnet and .libsonnet), evaluated
+// into JSON with the jsonnet command, their imports being resolved relative to their
+// directory. If extVarFlag isn't empty, their external variables (std.extVar) are set
+// with this repeatable flag, e.g. -ext-str region=eu, and with the PREFIX_EXT_STR
+// environment variable holding comma-separated name=value pairs, e.g.
+// GF_EXT_STR=region=eu,env=prod, the flag taking precedence.
+func EnableJsonnet(extVarFlag string, desc string) { gf.EnableJsonnet(extVarFlag, desc) }
+
+// EnableJsonnet enables the Jsonnet config files (.jsonnet and .libsonnet), evaluated
+// into JSON with the jsonnet command, their imports being resolved relative to their
+// directory. If extVarFlag isn't empty, their external variables (std.extVar) are set
+// with this repeatable flag, e.g. -ext-str region=eu, and with the PREFIX_EXT_STR
+// environment variable holding comma-separated name=value pairs, e.g.
+// GF_EXT_STR=region=eu,env=prod, the flag taking precedence.
+func (gf *Gofig) EnableJsonnet(extVarFlag string, desc string) {
+	gf.jsonnet = true
+	gf.extVarFlag = extVarFlag
+	if extVarFlag != "" {
+		gf.flagSet.Var(&setFlag{}, extVarFlag, gf.translate(desc))
+		gf.declareFlag(gf.flagSet, extVarFlag)
+	}
+}
+
+// allTransforms returns the transformers of the config files, including the Jsonnet
+// evaluation if enabled.
+func (gf *Gofig) allTransforms() []transform {
+	if !gf.jsonnet {
+		return gf.transforms
+	}
+	return append(gf.transforms[:len(gf.transforms):len(gf.transforms)],
+		transform{ext: ".jsonnet", format: "json", fn: gf.evalJsonnet},
+		transform{ext: ".libsonnet", format: "json", fn: gf.evalJsonnet})
+}
+
+// jsonnetExtVars returns the external variables of the Jsonnet config files, from the
+// environment variable then the flag of the parsed arguments.
+func (gf *Gofig) jsonnetExtVars() (map[string]string, error) {
+	vars := make(map[string]string)
+	if gf.extVarFlag == "" {
+		return vars, nil
+	}
+	key := gf.getEnvKey([]string{strings.ReplaceAll(gf.extVarFlag, flagSeparator, envSeparator)})
+	var pairs []string
+	if val, ok := gf.lookupEnv(key); ok && val != "" {
+		pairs = strings.Split(val, ",")
+	}
+	pairs = append(pairs, flagArgs(gf.args, gf.extVarFlag)...)
+	for _, pair := range pairs {
+		i := strings.IndexByte(pair, '=')
+		if i <= 0 {
+			return nil, errorf("invalid Jsonnet external variable '%v', it must be name=value", pair)
+		}
+		vars[strings.TrimSpace(pair[:i])] = pair[i+1:]
+	}
+	return vars, nil
+}
+
+// evalJsonnet evaluates a Jsonnet config file with the jsonnet command, its data being
+// read from the standard input.
+func (gf *Gofig) evalJsonnet(ctx context.Context, path string, data []byte) ([]byte, error) {
+	vars, err := gf.jsonnetExtVars()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	args := []string{"-J", filepath.Dir(path)}
+	for _, name := range names {
+		args = append(args, "--ext-str", name+"="+vars[name])
+	}
+	args = append(args, "-")
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, jsonnetCommand, args...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%v: %v", err, msg)
+		}
+		return nil, err
+	}
+	return out, nil
+}
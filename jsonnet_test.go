@@ -0,0 +1,84 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeJsonnet is a jsonnet command printing its external variables and its input
+const fakeJsonnet = `#!/bin/sh
+vars=""
+while [ $# -gt 0 ]; do
+	case "$1" in
+	--ext-str) vars="$vars $2"; shift;;
+	-J) [ -d "$2" ] || exit 1; shift;;
+	esac
+	shift
+done
+input=$(cat)
+if [ "$input" = "error" ]; then
+	echo "RUNTIME ERROR: boom" >&2
+	exit 1
+fi
+printf '{"str": "%s", "int": %s}' "$(echo $vars)" "$input"
+`
+
+func TestJsonnet(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake jsonnet command is a shell script")
+	}
+	bin := t.TempDir()
+	err := os.WriteFile(filepath.Join(bin, jsonnetCommand), []byte(fakeJsonnet), 0755)
+	assert.NoError(t, err)
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "config.jsonnet"), []byte("3"), 0600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "lib.libsonnet"), []byte("4"), 0600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "broken.jsonnet"), []byte("error"), 0600))
+
+	// Case 1: searched and evaluated with the external variables of the env variable and
+	// the flag
+	t.Setenv("GFJ_EXT_STR", "env=prod,region=us")
+	s := &TestStruct{}
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("GFJ")
+	gf.EnableJsonnet("ext-str", "set a Jsonnet external `variable`")
+	gf.AddConfigFile(filepath.Join(dir, "config"))
+	err = gf.ParseWithArgs(s, []string{"-ext-str", "region=eu"})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, s.Int)
+	assert.Equal(t, "env=prod region=eu", s.Str)
+
+	// Case 2: libsonnet file, without external variables
+	s = &TestStruct{}
+	gf = New(ContinueOnError)
+	gf.SetConfigFileFlag("config", "config file")
+	gf.EnableJsonnet("", "")
+	err = gf.ParseWithArgs(s, []string{"-config", filepath.Join(dir, "lib.libsonnet")})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, s.Int)
+	assert.Equal(t, "", s.Str)
+
+	// Case 3: errors
+	gf = New(ContinueOnError)
+	gf.SetConfigFileFlag("config", "config file")
+	gf.EnableJsonnet("", "")
+	err = gf.ParseWithArgs(&TestStruct{}, []string{"-config", filepath.Join(dir, "broken.jsonnet")})
+	assert.EqualError(t, err, "error transforming config file '"+filepath.Join(dir, "broken.jsonnet")+"': exit status 1: RUNTIME ERROR: boom")
+
+	gf = New(ContinueOnError)
+	gf.SetConfigFileFlag("config", "config file")
+	gf.EnableJsonnet("ext-str", "set a Jsonnet external `variable`")
+	err = gf.ParseWithArgs(&TestStruct{}, []string{"-config", filepath.Join(dir, "config.jsonnet"), "-ext-str", "region"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// keyringTag is the struct tag naming the keyring secret of a field, e.g.
+// `keyring:"mycli/token"`.
+const keyringTag = "keyring"
+
+// ErrKeyringNotFound is returned by a Keyring when a secret doesn't exist.
+var ErrKeyringNotFound = errors.New("secret not found in keyring")
+
+// Keyring is a store of secrets identified by a service and an account name.
+type Keyring interface {
+	// Get returns the secret of an account of a service, or ErrKeyringNotFound.
+	Get(ctx context.Context, service, account string) (string, error)
+}
+
+// OSKeyring returns the keyring of the operating system: the macOS Keychain (through the
+// security command), the Secret Service of Linux and the BSDs, e.g. GNOME Keyring or
+// KWallet (through the secret-tool command of libsecret), or the Windows Credential
+// Manager. The secrets are looked up as stored by the usual Go keyring libraries: the
+// generic passwords of a service and an account on macOS, the items having service and
+// username attributes with the Secret Service, and the generic credentials named
+// "service:account" on Windows.
+func OSKeyring() Keyring {
+	return osKeyring{}
+}
+
+// osKeyring is the keyring of the operating system, its Get method being defined by OS.
+type osKeyring struct{}
+
+// SetKeyring sets the keyring of the secrets of the fields tagged with a service and an
+// account name, e.g. `keyring:"mycli/token"`, such as OSKeyring() for the CLIs storing
+// tokens on the developer machines (none by default). The keyring secrets override the
+// config documents, and are overridden by the environment variables and flags. The
+// fields whose secret doesn't exist are left unchanged.
+func SetKeyring(kr Keyring) { gf.SetKeyring(kr) }
+
+// SetKeyring sets the keyring of the secrets of the fields tagged with a service and an
+// account name, e.g. `keyring:"mycli/token"`, such as OSKeyring() for the CLIs storing
+// tokens on the developer machines (none by default). The keyring secrets override the
+// config documents, and are overridden by the environment variables and flags. The
+// fields whose secret doesn't exist are left unchanged.
+func (gf *Gofig) SetKeyring(kr Keyring) {
+	gf.keyring = kr
+}
+
+// decodeKeyring decodes the keyring secrets into the fields of v having a keyring tag.
+func (gf *Gofig) decodeKeyring(ctx context.Context, v interface{}) error {
+	if gf.keyring == nil {
+		return nil
+	}
+	return parseStruct(v, func(path []string, name string, f *reflect.Value, tags *reflect.StructTag) error {
+		ref := tags.Get(keyringTag)
+		if ref == "" {
+			return nil
+		}
+		i := strings.IndexByte(ref, '/')
+		if i < 0 {
+			return errorf("invalid keyring secret '%v' of field %v, it must be service/account", ref, name)
+		}
+		val, err := gf.keyring.Get(ctx, ref[:i], ref[i+1:])
+		if err == ErrKeyringNotFound {
+			return nil
+		} else if err != nil {
+			return &fieldError{name: name, source: ProvenanceSecret, err: errorf("error reading keyring secret '%v': %v", ref, err)}
+		}
+		err = decodeField(f, val, tags)
+		if err != nil {
+			// the value of a secret isn't reported
+			return &fieldError{name: name, source: ProvenanceSecret, err: errorf("error parsing keyring secret '%v' into %v", ref, f.Type())}
+		}
+		if gf.decoded != nil {
+			gf.decoded[strings.Join(path, ".")] = true
+		}
+		return nil
+	}, "json")
+}
@@ -0,0 +1,28 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// securityNotFound is the exit status of the security command when an item isn't found.
+const securityNotFound = 44
+
+// Get returns a generic password of the macOS Keychain.
+func (osKeyring) Get(ctx context.Context, service, account string) (string, error) {
+	out, err := exec.CommandContext(ctx, "security", "find-generic-password", "-s", service, "-a", account, "-w").Output()
+	var ee *exec.ExitError
+	if errors.As(err, &ee) && ee.ExitCode() == securityNotFound {
+		return "", ErrKeyringNotFound
+	} else if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
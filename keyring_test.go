@@ -0,0 +1,94 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testKeyring is a Keyring of secrets keyed by service/account
+type testKeyring map[string]string
+
+func (kr testKeyring) Get(ctx context.Context, service, account string) (string, error) {
+	val, ok := kr[service+"/"+account]
+	if !ok {
+		return "", ErrKeyringNotFound
+	}
+	if val == "error" {
+		return "", errors.New("keyring locked")
+	}
+	return val, nil
+}
+
+func TestSetKeyring(t *testing.T) {
+	type Config struct {
+		Token   string `keyring:"mycli/token"`
+		Port    int    `keyring:"mycli/port"`
+		Missing string `keyring:"mycli/missing"`
+	}
+
+	// Case 1: secrets, overridden by the environment variables
+	os.Setenv("GFK_PORT", "8080")
+	defer os.Unsetenv("GFK_PORT")
+	s := &Config{Missing: "default"}
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("GFK")
+	gf.SetKeyring(testKeyring{"mycli/token": "t0k3n", "mycli/port": "1234"})
+	err := gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, &Config{Token: "t0k3n", Port: 8080, Missing: "default"}, s)
+
+	// Case 2: errors, the secrets not being reported
+	for kr, expected := range map[string]string{
+		"error": "error reading keyring secret 'mycli/token': keyring locked (flag -token, env GFK_TOKEN, key token)",
+		"1.5":   "error parsing keyring secret 'mycli/port' into int (flag -port, env GFK_PORT, key port)",
+	} {
+		gf = New(ContinueOnError)
+		gf.SetEnvPrefix("GFK")
+		if kr == "error" {
+			gf.SetKeyring(testKeyring{"mycli/token": kr})
+		} else {
+			gf.SetKeyring(testKeyring{"mycli/port": kr})
+		}
+		err = gf.ParseWithArgs(&Config{}, []string{})
+		assert.EqualError(t, err, expected)
+	}
+	gf = New(ContinueOnError)
+	gf.SetKeyring(testKeyring{})
+	err = gf.ParseWithArgs(&struct {
+		Token string `keyring:"token"`
+	}{}, []string{})
+	assert.EqualError(t, err, "invalid keyring secret 'token' of field Token, it must be service/account")
+}
+
+func TestOSKeyring(t *testing.T) {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skip("secret-tool is used on the other systems")
+	}
+	// fake secret-tool command
+	dir := t.TempDir()
+	script := `#!/bin/sh
+if [ "$3" = "mycli" ] && [ "$5" = "alice" ]; then printf 's3cret'; exit 0; fi
+exit 1
+`
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "secret-tool"), []byte(script), 0755))
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+	defer os.Setenv("PATH", path)
+
+	val, err := OSKeyring().Get(context.Background(), "mycli", "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cret", val)
+	_, err = OSKeyring().Get(context.Background(), "mycli", "bob")
+	assert.Equal(t, ErrKeyringNotFound, err)
+}
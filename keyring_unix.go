@@ -0,0 +1,26 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build !darwin && !windows
+
+package gofig
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+)
+
+// Get returns a secret of the Secret Service, through the secret-tool command.
+func (osKeyring) Get(ctx context.Context, service, account string) (string, error) {
+	out, err := exec.CommandContext(ctx, "secret-tool", "lookup", "service", service, "username", account).Output()
+	var ee *exec.ExitError
+	if errors.As(err, &ee) && len(ee.Stderr) == 0 {
+		return "", ErrKeyringNotFound // secret-tool exits with 1 and no message
+	} else if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
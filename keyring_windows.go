@@ -0,0 +1,61 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	advapi32      = syscall.NewLazyDLL("advapi32.dll")
+	procCredReadW = advapi32.NewProc("CredReadW")
+	procCredFree  = advapi32.NewProc("CredFree")
+)
+
+const (
+	credTypeGeneric = 1
+	errorNotFound   = syscall.Errno(1168)
+)
+
+// credential is the CREDENTIALW structure of the Windows Credential Manager
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// Get returns the generic credential "service:account" of the Windows Credential
+// Manager.
+func (osKeyring) Get(ctx context.Context, service, account string) (string, error) {
+	target, err := syscall.UTF16PtrFromString(service + ":" + account)
+	if err != nil {
+		return "", err
+	}
+	var cred *credential
+	ok, _, err := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if ok == 0 {
+		if err == errorNotFound {
+			return "", ErrKeyringNotFound
+		}
+		return "", err
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+	if cred.CredentialBlobSize == 0 {
+		return "", nil
+	}
+	return string(unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)), nil
+}
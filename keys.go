@@ -0,0 +1,82 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// KeyFor returns the dot-separated key path (which follows the json tags, e.g. "sub.str")
+// of the parsed configuration field pointed to by fieldPtr, as accepted by Override.
+func KeyFor(fieldPtr interface{}) (string, error) { return gf.KeyFor(fieldPtr) }
+
+// KeyFor returns the dot-separated key path (which follows the json tags, e.g. "sub.str")
+// of the parsed configuration field pointed to by fieldPtr, as accepted by Override.
+func (gf *Gofig) KeyFor(fieldPtr interface{}) (string, error) {
+	path, err := gf.pathFor(fieldPtr, "json")
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(path, "."), nil
+}
+
+// EnvFor returns the environment variable name of the parsed configuration field
+// pointed to by fieldPtr, including the prefix.
+func EnvFor(fieldPtr interface{}) (string, error) { return gf.EnvFor(fieldPtr) }
+
+// EnvFor returns the environment variable name of the parsed configuration field
+// pointed to by fieldPtr, including the prefix.
+func (gf *Gofig) EnvFor(fieldPtr interface{}) (string, error) {
+	path, err := gf.pathFor(fieldPtr, "env")
+	if err != nil {
+		return "", err
+	}
+	return gf.getEnvKey(append(gf.scope[:len(gf.scope):len(gf.scope)], path...)), nil
+}
+
+// FlagFor returns the flag name (without the leading dash) of the parsed configuration
+// field pointed to by fieldPtr.
+func FlagFor(fieldPtr interface{}) (string, error) { return gf.FlagFor(fieldPtr) }
+
+// FlagFor returns the flag name (without the leading dash) of the parsed configuration
+// field pointed to by fieldPtr.
+func (gf *Gofig) FlagFor(fieldPtr interface{}) (string, error) {
+	path, err := gf.pathFor(fieldPtr, "flag")
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(append(gf.scope[:len(gf.scope):len(gf.scope)], path...), flagSeparator), nil
+}
+
+// pathFor returns the key path for the cfgTag of the field of the parsed configuration
+// pointed to by fieldPtr.
+func (gf *Gofig) pathFor(fieldPtr interface{}, cfgTag string) ([]string, error) {
+	ptr := reflect.ValueOf(fieldPtr)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() {
+		return nil, fmt.Errorf("%T is not a pointer to a configuration field", fieldPtr)
+	}
+
+	gf.mu.Lock()
+	target := gf.target
+	gf.mu.Unlock()
+	if target == nil {
+		return nil, errNotParsed
+	}
+
+	var path []string
+	_ = parseStruct(target, func(p []string, name string, f *reflect.Value, tags *reflect.StructTag) error {
+		if f.CanAddr() && f.Addr().Pointer() == ptr.Pointer() && f.Type() == ptr.Elem().Type() {
+			path = p
+		}
+		return nil
+	}, cfgTag)
+	if path == nil {
+		return nil, fmt.Errorf("%T is not a pointer to a configuration field, or the field isn't mapped to a %v key", fieldPtr, cfgTag)
+	}
+	return path, nil
+}
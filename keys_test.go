@@ -0,0 +1,54 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyFor(t *testing.T) {
+	s := &TestStruct{}
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("GF")
+
+	// Case 1: not parsed
+	_, err := gf.KeyFor(&s.Str)
+	assert.Equal(t, errNotParsed, err)
+
+	err = gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+
+	// Case 2: nested field
+	key, err := gf.KeyFor(&s.Sub.RenamedStr)
+	assert.NoError(t, err)
+	assert.Equal(t, "sub.str", key)
+	env, err := gf.EnvFor(&s.Sub.RenamedStr)
+	assert.NoError(t, err)
+	assert.Equal(t, "GF_SUB_STR", env)
+	flag, err := gf.FlagFor(&s.Sub.RenamedStr)
+	assert.NoError(t, err)
+	assert.Equal(t, "sub-str", flag)
+
+	// Case 3: the key is accepted by Override
+	key, err = gf.KeyFor(&s.Int)
+	assert.NoError(t, err)
+	assert.Equal(t, "int", key)
+	assert.NoError(t, gf.Override(key, "7"))
+	assert.Equal(t, 7, s.Int)
+
+	// Case 4: skipped field
+	_, err = gf.KeyFor(&s.Skipped)
+	assert.Error(t, err)
+
+	// Case 5: not a field of the configuration
+	other := 1
+	_, err = gf.KeyFor(&other)
+	assert.Error(t, err)
+	_, err = gf.KeyFor(other)
+	assert.Error(t, err)
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// LabelSource maps container labels onto the configuration fields. Only the labels
+// starting with Prefix are used, the rest of the label being the dot-separated key path
+// of the field: with the "com.example.app" prefix, the label "com.example.app.db.port"
+// sets the field at the key path "db.port".
+type LabelSource struct {
+	// Prefix of the labels, without the trailing dot.
+	Prefix string
+	// Labels holds the labels, used if Path is empty.
+	Labels map[string]string
+	// Path is a file holding the labels, either a JSON object (e.g. the Labels of
+	// "docker inspect") or lines of key="value" (e.g. a Kubernetes downward API volume).
+	Path string
+}
+
+// NewLabelSource returns a Source mapping the labels with the given prefix onto fields.
+func NewLabelSource(prefix string, labels map[string]string) *LabelSource {
+	return &LabelSource{Prefix: prefix, Labels: labels}
+}
+
+// NewLabelFileSource returns a Source mapping the labels with the given prefix read
+// from a file onto fields.
+func NewLabelFileSource(prefix string, path string) *LabelSource {
+	return &LabelSource{Prefix: prefix, Path: path}
+}
+
+// Name returns the labels prefix.
+func (s *LabelSource) Name() string {
+	if s.Path != "" {
+		return s.Path
+	}
+	return "labels " + s.Prefix
+}
+
+// Load reads the labels.
+func (s *LabelSource) Load(ctx context.Context) (*Document, error) {
+	labels := s.Labels
+	if s.Path != "" {
+		data, err := os.ReadFile(s.Path)
+		if err != nil {
+			return nil, err
+		}
+		labels, err = parseLabels(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	prefix := s.Prefix + "."
+	values := make(map[string]string)
+	for k, v := range labels {
+		if strings.HasPrefix(k, prefix) {
+			values[k[len(prefix):]] = v
+		}
+	}
+	return &Document{Values: values}, nil
+}
+
+// parseLabels parses a labels file.
+func parseLabels(data []byte) (map[string]string, error) {
+	labels := make(map[string]string)
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		err := json.Unmarshal(trimmed, &labels)
+		return labels, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid label on line %v", n)
+		}
+		value := kv[1]
+		if strings.HasPrefix(value, `"`) {
+			var err error
+			value, err = strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid label value on line %v", n)
+			}
+		}
+		labels[kv[0]] = value
+	}
+	return labels, scanner.Err()
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabelSource(t *testing.T) {
+	// Case 1: provided labels
+	s := buildTestStruct()
+	gf := New(ContinueOnError)
+	gf.AddSource(NewLabelSource("com.example.app", map[string]string{
+		"com.example.app.str":     "label",
+		"com.example.app.sub.str": "renamed-label",
+		"com.example.other.int":   "5",
+	}))
+	err := gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "label", s.Str)
+	assert.Equal(t, "renamed-label", s.Sub.RenamedStr)
+	assert.Equal(t, -99, s.Int)
+
+	// Case 2: labels files
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"labels.json": `{"com.example.app.int": "5", "com.example.app.str": "json"}`,
+		"labels":      "# downward API\ncom.example.app.int=\"5\"\ncom.example.app.str=\"downward \\\"api\\\"\"\n",
+	} {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+		s := buildTestStruct()
+		gf := New(ContinueOnError)
+		gf.AddSource(NewLabelFileSource("com.example.app", path))
+		err := gf.ParseWithArgs(s, []string{})
+		assert.NoError(t, err, name)
+		assert.Equal(t, 5, s.Int, name)
+	}
+	assert.Equal(t, "downward \"api\"", func() string {
+		s := buildTestStruct()
+		gf := New(ContinueOnError)
+		gf.AddSource(NewLabelFileSource("com.example.app", filepath.Join(dir, "labels")))
+		_ = gf.ParseWithArgs(s, []string{})
+		return s.Str
+	}())
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SetLenient enables or disables the lenient mode, where the quoted numbers and booleans
+// of the config documents (e.g. "8080" or "true", as written by templating systems
+// quoting every value) are accepted for the numeric, Duration and bool fields. Each
+// quoted value coerced is reported by Warnings.
+func SetLenient(enabled bool) { gf.SetLenient(enabled) }
+
+// SetLenient enables or disables the lenient mode, where the quoted numbers and booleans
+// of the config documents (e.g. "8080" or "true", as written by templating systems
+// quoting every value) are accepted for the numeric, Duration and bool fields. Each
+// quoted value coerced is reported by Warnings.
+func (gf *Gofig) SetLenient(enabled bool) {
+	gf.lenient = enabled
+}
+
+// Warnings returns the warnings of the last parse or reload, such as the quoted values
+// coerced in lenient mode.
+func Warnings() []string { return gf.Warnings() }
+
+// Warnings returns the warnings of the last parse or reload, such as the quoted values
+// coerced in lenient mode.
+func (gf *Gofig) Warnings() []string {
+	gf.mu.Lock()
+	defer gf.mu.Unlock()
+	return gf.warnings
+}
+
+// unquoteScalars converts the quoted numbers and booleans of a document set into the
+// numeric, Duration and bool fields of the struct pointed to by v, collecting a warning
+// for each of them during a parse.
+func (gf *Gofig) unquoteScalars(t *docTree, v interface{}) {
+	if t.root == nil {
+		return
+	}
+	fields, ok := numberFields(v, t.ext)
+	if !ok {
+		return
+	}
+
+	var warns []string
+	warn := func(key string, s string, t reflect.Type) {
+		warns = append(warns, fmt.Sprintf("coerced quoted value '%v' of key '%v' into %v", s, key, t))
+	}
+	if !unquoteTree(t.root, "", fields, warn) {
+		return
+	}
+	t.changed = true
+	sort.Strings(warns)
+	gf.warns = append(gf.warns, warns...)
+}
+
+// unquoteTree converts the quoted scalars of a document tree mapped to the fields, and
+// returns whether some were converted.
+func unquoteTree(tree map[string]interface{}, path string, fields map[string]numberField, warn func(string, string, reflect.Type)) bool {
+	converted := false
+	for k, val := range tree {
+		key := strings.ToLower(k)
+		if path != "" {
+			key = path + "." + key
+		}
+
+		c := false
+		if f, ok := fields[key]; ok {
+			c = unquoteValue(&val, f.typ, key, warn)
+		} else if sub, ok := val.(map[string]interface{}); ok {
+			c = unquoteTree(sub, key, fields, warn)
+		}
+		if c {
+			tree[k] = val
+			converted = true
+		}
+	}
+	return converted
+}
+
+// unquoteValue converts the quoted scalars of a value decoded for a field of type t, and
+// returns whether some were converted.
+func unquoteValue(val *interface{}, t reflect.Type, key string, warn func(string, string, reflect.Type)) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t != durationType {
+		pt := reflect.PtrTo(t)
+		if pt.Implements(textUnmarshalerType) || pt.Implements(jsonUnmarshalerType) ||
+			pt.Implements(yamlUnmarshalerType) || pt.Implements(tomlUnmarshalerType) {
+			return false
+		}
+	}
+
+	converted := false
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		list, ok := (*val).([]interface{})
+		if !ok {
+			return false
+		}
+		for i := range list {
+			c := unquoteValue(&list[i], t.Elem(), fmt.Sprintf("%v[%v]", key, i), warn)
+			converted = converted || c
+		}
+		return converted
+	case reflect.Map:
+		m, ok := (*val).(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for k := range m {
+			elem := m[k]
+			if unquoteValue(&elem, t.Elem(), key+"."+strings.ToLower(k), warn) {
+				m[k] = elem
+				converted = true
+			}
+		}
+		return converted
+	}
+
+	s, ok := (*val).(string)
+	if !ok {
+		return false
+	}
+	trimmed := strings.TrimSpace(s)
+	switch t.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(trimmed)
+		if err != nil {
+			return false
+		}
+		*val = b
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		if t == durationType {
+			if _, err := strconv.ParseFloat(trimmed, 64); err != nil {
+				return false // a duration string, e.g. "1m30s"
+			}
+		}
+		n, ok := parseNumber(trimmed)
+		if !ok {
+			return false
+		}
+		*val = n
+	default:
+		return false
+	}
+	warn(key, s, t)
+	return true
+}
+
+// parseNumber parses a decimal number into an int64, a uint64 or a float64, the numeric
+// rules being applied afterwards.
+func parseNumber(s string) (interface{}, bool) {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, true
+	}
+	if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return u, true
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, false
+	}
+	return f, true
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLenient(t *testing.T) {
+	type Server struct {
+		Port    int
+		Debug   bool
+		Ratio   float64
+		Timeout Duration
+		Delay   Duration `unit:"ms"`
+		Name    string
+		Ports   []uint16
+		Limits  map[string]int
+	}
+	type Config struct {
+		Server Server
+	}
+	docs := map[string]string{
+		"json": `{"server": {"port": "8080", "debug": "true", "ratio": " 0.5 ", "timeout": "1m30s", "delay": "250",
+			"name": "42", "ports": ["80", 443], "limits": {"a": "1"}}}`,
+		"toml": `[server]
+port = "8080"
+debug = "true"
+ratio = " 0.5 "
+timeout = "1m30s"
+delay = "250"
+name = "42"
+ports = ["80", 443]
+limits = { a = "1" }
+`,
+		"yaml": `server:
+  port: "8080"
+  debug: "true"
+  ratio: " 0.5 "
+  timeout: "1m30s"
+  delay: "250"
+  name: "42"
+  ports: ["80", 443]
+  limits: {a: "1"}
+`,
+	}
+	want := Server{
+		Port:    8080,
+		Debug:   true,
+		Ratio:   0.5,
+		Timeout: Duration(90 * time.Second),
+		Delay:   Duration(250 * time.Millisecond),
+		Name:    "42",
+		Ports:   []uint16{80, 443},
+		Limits:  map[string]int{"a": 1},
+	}
+
+	for format, doc := range docs {
+		// Case 1: quoted values are rejected by default
+		gf := New(ContinueOnError)
+		gf.AddSource(&testSource{name: format, doc: &Document{Format: format, Data: []byte(doc)}})
+		err := gf.ParseWithArgs(&Config{}, []string{})
+		assert.Error(t, err, format)
+
+		// Case 2: quoted values are coerced in lenient mode, with a warning
+		gf = New(ContinueOnError)
+		gf.SetLenient(true)
+		gf.AddSource(&testSource{name: format, doc: &Document{Format: format, Data: []byte(doc)}})
+		s := &Config{}
+		err = gf.ParseWithArgs(s, []string{})
+		assert.NoError(t, err, format)
+		assert.Equal(t, want, s.Server, format)
+		assert.Equal(t, []string{
+			"coerced quoted value ' 0.5 ' of key 'server.ratio' into float64",
+			"coerced quoted value '1' of key 'server.limits.a' into int",
+			"coerced quoted value '250' of key 'server.delay' into gofig.Duration",
+			"coerced quoted value '80' of key 'server.ports[0]' into uint16",
+			"coerced quoted value '8080' of key 'server.port' into int",
+			"coerced quoted value 'true' of key 'server.debug' into bool",
+		}, gf.Warnings(), format)
+	}
+
+	// Case 3: the numeric rules still apply to the coerced values
+	gf := New(ContinueOnError)
+	gf.SetLenient(true)
+	gf.AddSource(&testSource{name: "yaml", doc: &Document{Format: "yaml", Data: []byte("server: {port: \"1.5\"}\n")}})
+	err := gf.ParseWithArgs(&Config{}, []string{})
+	assert.EqualError(t, err, "error decoding source yaml: error parsing key 'server.port' with value '1.5' into int (flag -server-port, env SERVER_PORT, key server.port)")
+
+	// Case 4: values which aren't numbers or booleans are left to the decoder
+	gf = New(ContinueOnError)
+	gf.SetLenient(true)
+	gf.AddSource(&testSource{name: "yaml", doc: &Document{Format: "yaml", Data: []byte("server: {debug: \"yes please\"}\n")}})
+	err = gf.ParseWithArgs(&Config{}, []string{})
+	assert.Error(t, err)
+	assert.Empty(t, gf.Warnings())
+
+	// Case 5: the errors keep the line of the document
+	gf = New(ContinueOnError)
+	gf.SetLenient(true)
+	gf.AddSource(&testSource{name: "yaml", doc: &Document{Format: "yaml", Data: []byte("server:\n  port: \"8080\"\n\n  ratio: abc\n")}})
+	err = gf.ParseWithArgs(&Config{}, []string{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "line 4:")
+}
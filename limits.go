@@ -0,0 +1,171 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Limits bounds the config documents decoded by gofig (config files, sources, pushed
+// documents), to protect services accepting config from semi-trusted sources.
+// A zero value means no limit.
+type Limits struct {
+	// MaxSize is the maximum size of a config document, in bytes.
+	MaxSize int64
+	// MaxDepth is the maximum nesting depth of the maps and lists of a config document,
+	// the top-level map having a depth of 1.
+	MaxDepth int
+	// MaxLength is the maximum number of elements of a map or list.
+	MaxLength int
+}
+
+// SetLimits sets the limits enforced when decoding config documents.
+func SetLimits(limits Limits) { gf.SetLimits(limits) }
+
+// SetLimits sets the limits enforced when decoding config documents.
+func (gf *Gofig) SetLimits(limits Limits) {
+	gf.limits = limits
+}
+
+// maxSizeKey is the context key of the maximum size of the documents read by the sources.
+type maxSizeKey struct{}
+
+// withMaxSize returns a context bounding the documents and responses read by the
+// sources to size bytes, if set, see readLimited.
+func withMaxSize(ctx context.Context, size int64) context.Context {
+	if size <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, maxSizeKey{}, size)
+}
+
+// readLimited reads a document or a response of a source, failing as soon as it exceeds
+// the maximum size of ctx, rather than once fully read.
+func readLimited(ctx context.Context, r io.Reader) ([]byte, error) {
+	size, _ := ctx.Value(maxSizeKey{}).(int64)
+	if size <= 0 {
+		return io.ReadAll(r)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, size+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > size {
+		return nil, errorf("config document exceeds the maximum size of %v bytes", size)
+	}
+	return data, nil
+}
+
+// decodeConfig decodes a config document based on its file extension, enforcing the limits,
+// coercing the quoted scalars in lenient mode and collecting the unused keys during a parse.
+// The errors on a field are reported with the names of the field in each source.
+func (gf *Gofig) decodeConfig(r io.Reader, ext string, v interface{}) error {
+	err := gf.decodeDocumentData(r, ext, v)
+	if err != nil {
+		return gf.withFieldNames(err, v, strings.TrimPrefix(ext, "."), false)
+	}
+	return nil
+}
+
+// decodeDocumentData decodes a config document for decodeConfig.
+func (gf *Gofig) decodeDocumentData(r io.Reader, ext string, v interface{}) error {
+	limits := gf.limits
+	if limits == (Limits{}) && gf.unused == nil && gf.decoded == nil && !gf.lenient && gf.labels == nil && gf.facts == nil {
+		return decodeConfig(r, ext, v)
+	}
+
+	if limits.MaxSize > 0 {
+		r = io.LimitReader(r, limits.MaxSize+1)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if limits.MaxSize > 0 && int64(len(data)) > limits.MaxSize {
+		return errorf("config document exceeds the maximum size of %v bytes", limits.MaxSize)
+	}
+	switch ext {
+	case jsonExtention, tomlExtention, yamlExtention:
+	default:
+		return errorf("config file type not supported")
+	}
+
+	t := parseTree(data, ext)
+	if gf.labels != nil || gf.facts != nil {
+		err = gf.selectValues(t)
+		if err != nil {
+			return err
+		}
+	}
+	if t.root != nil && (limits.MaxDepth > 0 || limits.MaxLength > 0) {
+		err = limits.check(t.root, "", 1)
+		if err != nil {
+			return err
+		}
+	}
+	if gf.lenient {
+		gf.unquoteScalars(t, v)
+	}
+	if gf.decoded != nil && t.root != nil {
+		// before the values set by a parse method are removed from the tree
+		collectDecodedKeys(t.root, v, strings.TrimPrefix(ext, "."), gf.decoded)
+	}
+	err = decodeConfigTree(t, v)
+	if err != nil {
+		return err
+	}
+	if gf.unused != nil && t.root != nil {
+		collectUnusedKeys(t.root, v, strings.TrimPrefix(ext, "."), gf.unused)
+	}
+	return nil
+}
+
+// check checks the nesting depth and the length of the maps and lists of a decoded
+// document tree.
+func (l *Limits) check(node interface{}, path string, depth int) error {
+	var children []interface{}
+	var keys []string
+	switch node := node.(type) {
+	case map[string]interface{}:
+		for k, val := range node {
+			keys = append(keys, k)
+			children = append(children, val)
+		}
+	case []interface{}:
+		children = node
+	default:
+		return nil
+	}
+
+	name := path
+	if name == "" {
+		name = "the config document"
+	}
+	if l.MaxDepth > 0 && depth > l.MaxDepth {
+		return errorf("%v exceeds the maximum nesting depth of %v", name, l.MaxDepth)
+	}
+	if l.MaxLength > 0 && len(children) > l.MaxLength {
+		return errorf("%v has %v elements, exceeding the maximum of %v", name, len(children), l.MaxLength)
+	}
+
+	for i, child := range children {
+		childPath := fmt.Sprintf("%v[%v]", path, i)
+		if keys != nil {
+			childPath = keys[i]
+			if path != "" {
+				childPath = path + "." + keys[i]
+			}
+		}
+		err := l.check(child, childPath, depth+1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetLimits(t *testing.T) {
+	gf := New(ContinueOnError)
+	decode := func(doc string) error {
+		return gf.decodeConfig(strings.NewReader(doc), yamlExtention, &TestStruct{})
+	}
+
+	// Case 1: no limits
+	assert.NoError(t, decode("str: "+strings.Repeat("a", 1000)+"\nsub: {str: a}"))
+
+	// Case 2: size
+	gf.SetLimits(Limits{MaxSize: 100})
+	assert.NoError(t, decode("str: a"))
+	assert.EqualError(t, decode("str: "+strings.Repeat("a", 100)), "config document exceeds the maximum size of 100 bytes")
+
+	// Case 3: depth
+	gf.SetLimits(Limits{MaxDepth: 2})
+	assert.NoError(t, decode("sub: {str: a}"))
+	assert.EqualError(t, decode("sub: {str: a, deep: {a: b}}"), "sub.deep exceeds the maximum nesting depth of 2")
+	assert.EqualError(t, decode("list: [[a]]"), "list[0] exceeds the maximum nesting depth of 2")
+
+	// Case 4: length
+	gf.SetLimits(Limits{MaxLength: 3})
+	assert.NoError(t, decode("str: a\nint: 1\nsub: {str: a}"))
+	assert.EqualError(t, decode("str: a\nint: 1\nbool: true\nfloat: 1.1"), "the config document has 4 elements, exceeding the maximum of 3")
+	assert.EqualError(t, decode("sub: {list: [1, 2, 3, 4]}"), "sub.list has 4 elements, exceeding the maximum of 3")
+
+	// Case 5: enforced when parsing
+	gf.SetLimits(Limits{MaxSize: 10})
+	gf.AddConfigFile("gofig_test_yaml")
+	err := gf.ParseWithArgs(&TestStruct{}, []string{})
+	assert.EqualError(t, err, "config document exceeds the maximum size of 10 bytes")
+
+	// Case 6: enforced while reading the body of an HTTP source, which never ends
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		chunk := []byte("# " + strings.Repeat("a", 1000) + "\n")
+		for r.Context().Err() == nil {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+	gf = New(ContinueOnError)
+	gf.SetLimits(Limits{MaxSize: 100})
+	gf.AddSource(NewHTTPSource(server.URL))
+	err = gf.ParseWithArgs(&TestStruct{}, []string{})
+	assert.EqualError(t, err, "error loading source "+server.URL+": config document exceeds the maximum size of 100 bytes")
+
+	// Case 7: enforced on the pushed documents
+	gf = New(ContinueOnError)
+	gf.SetLimits(Limits{MaxSize: 10})
+	assert.NoError(t, gf.ParseWithArgs(&TestStruct{}, []string{}))
+	req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"str": "`+strings.Repeat("a", 100)+`"}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	gf.PushHandler("secret").ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "config document exceeds the maximum size of 10 bytes\n", rec.Body.String())
+}
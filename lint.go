@@ -0,0 +1,127 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// deprecatedTag is the struct tag of the deprecated fields, holding what to use instead,
+// e.g. deprecated:"use tls.cert instead".
+const deprecatedTag = "deprecated"
+
+// LintField is a field of a configuration struct checked by the lint rules.
+type LintField struct {
+	// Name is the dot-separated field name, e.g. DB.Port.
+	Name string
+	// Key is the dot-separated key path of the config documents, e.g. db.port.
+	Key string
+	// Tags are the struct tags of the field.
+	Tags reflect.StructTag
+	// Value is the current value of the field.
+	Value reflect.Value
+}
+
+// LintRule is a named rule of the config linter. Check returns the message of the issue
+// of a field, or an empty string if it complies with the rule.
+type LintRule struct {
+	Name  string
+	Check func(f *LintField) string
+}
+
+// LintIssue is an issue found by a lint rule on a field.
+type LintIssue struct {
+	Rule    string
+	Field   string
+	Key     string
+	Message string
+}
+
+func (i LintIssue) String() string {
+	return fmt.Sprintf("%v (key %v): %v [%v]", i.Field, i.Key, i.Message, i.Rule)
+}
+
+var (
+	// LintUntaggedSecrets reports the fields whose name suggests a secret (password,
+	// secret, token, API key, private key or credentials) without the secret tag, which
+	// would be printed and exported in plain text.
+	LintUntaggedSecrets = LintRule{Name: "untagged-secret", Check: func(f *LintField) string {
+		if isSecret(&f.Tags) {
+			return ""
+		}
+		name := strings.ToLower(f.Name[strings.LastIndexByte(f.Name, '.')+1:])
+		for _, word := range []string{"password", "passwd", "secret", "token", "apikey", "privatekey", "credential"} {
+			if strings.Contains(name, word) {
+				return `looks like a secret, but has no secret:"true" tag`
+			}
+		}
+		return ""
+	}}
+
+	// LintMissingDesc reports the fields without a desc tag, which have no usage.
+	LintMissingDesc = LintRule{Name: "missing-desc", Check: func(f *LintField) string {
+		if f.Tags.Get("desc") == "" {
+			return "has no desc tag"
+		}
+		return ""
+	}}
+
+	// LintUnboundedNumbers reports the numeric and duration fields with neither a min,
+	// a max nor an enum tag.
+	LintUnboundedNumbers = LintRule{Name: "unbounded-number", Check: func(f *LintField) string {
+		rt := f.Value.Type()
+		for rt.Kind() == reflect.Ptr {
+			rt = rt.Elem()
+		}
+		if !isRangeType(rt) {
+			return ""
+		}
+		for _, tag := range []string{minTag, maxTag, enumTag} {
+			if _, ok := f.Tags.Lookup(tag); ok {
+				return ""
+			}
+		}
+		return "has neither a min, a max nor an enum tag"
+	}}
+
+	// LintDeprecatedUsage reports the fields tagged with deprecated which are set.
+	LintDeprecatedUsage = LintRule{Name: "deprecated-usage", Check: func(f *LintField) string {
+		msg, ok := f.Tags.Lookup(deprecatedTag)
+		if !ok || f.Value.IsZero() {
+			return ""
+		}
+		if msg == "" {
+			return "is deprecated"
+		}
+		return "is deprecated: " + msg
+	}}
+
+	// DefaultLintRules are the built-in lint rules, used by Lint if no rule is given.
+	DefaultLintRules = []LintRule{LintUntaggedSecrets, LintMissingDesc, LintUnboundedNumbers, LintDeprecatedUsage}
+)
+
+// Lint checks the fields of v, a pointer to a configuration struct, with the rules, or
+// DefaultLintRules if none is given, so that CI can enforce the hygiene of the config
+// structs. The issues are returned in the order of the fields, then of the rules. It
+// returns nil if v isn't a pointer to a struct.
+func Lint(v interface{}, rules ...LintRule) []LintIssue {
+	if len(rules) == 0 {
+		rules = DefaultLintRules
+	}
+	var issues []LintIssue
+	_ = parseStruct(v, func(path []string, name string, val *reflect.Value, tags *reflect.StructTag) error {
+		f := &LintField{Name: name, Key: strings.Join(path, "."), Tags: *tags, Value: *val}
+		for _, rule := range rules {
+			if msg := rule.Check(f); msg != "" {
+				issues = append(issues, LintIssue{Rule: rule.Name, Field: f.Name, Key: f.Key, Message: msg})
+			}
+		}
+		return nil
+	}, "json")
+	return issues
+}
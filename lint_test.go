@@ -0,0 +1,58 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type lintStruct struct {
+	Port     int      `json:"port" desc:"listen port" min:"1" max:"65535"`
+	Workers  int      `json:"workers" desc:"number of workers"`
+	Level    int      `json:"level" desc:"log level" enum:"1,2,3"`
+	Timeout  Duration `json:"timeout" desc:"timeout" max:"1m"`
+	Password string   `json:"password" desc:"password" secret:"true"`
+	DB       struct {
+		APIToken string `json:"apitoken" desc:"API token"`
+	} `json:"db"`
+	Name    string `json:"name"`
+	OldHost string `json:"oldhost" desc:"old host" deprecated:"use host instead"`
+	OldPort string `json:"oldport" desc:"old port" deprecated:""`
+}
+
+func TestLint(t *testing.T) {
+	// Case 1: built-in rules
+	s := &lintStruct{OldHost: "db", OldPort: "5432"}
+	assert.Equal(t, []LintIssue{
+		{Rule: "unbounded-number", Field: "Workers", Key: "workers", Message: "has neither a min, a max nor an enum tag"},
+		{Rule: "untagged-secret", Field: "DB.APIToken", Key: "db.apitoken", Message: `looks like a secret, but has no secret:"true" tag`},
+		{Rule: "missing-desc", Field: "Name", Key: "name", Message: "has no desc tag"},
+		{Rule: "deprecated-usage", Field: "OldHost", Key: "oldhost", Message: "is deprecated: use host instead"},
+		{Rule: "deprecated-usage", Field: "OldPort", Key: "oldport", Message: "is deprecated"},
+	}, Lint(s))
+	assert.Equal(t, "DB.APIToken (key db.apitoken): looks like a secret, but has no secret:\"true\" tag [untagged-secret]", Lint(s)[1].String())
+
+	// Case 2: selected and custom rules
+	jsonTags := LintRule{Name: "json-tag", Check: func(f *LintField) string {
+		if f.Tags.Get("json") == "" {
+			return "has no json tag"
+		}
+		return ""
+	}}
+	issues := Lint(&struct {
+		Host string `json:"host"`
+		Port int
+	}{}, jsonTags, LintMissingDesc)
+	assert.Equal(t, []LintIssue{
+		{Rule: "missing-desc", Field: "Host", Key: "host", Message: "has no desc tag"},
+		{Rule: "json-tag", Field: "Port", Key: "port", Message: "has no json tag"},
+		{Rule: "missing-desc", Field: "Port", Key: "port", Message: "has no desc tag"},
+	}, issues)
+
+	assert.Nil(t, Lint(lintStruct{}))
+}
@@ -0,0 +1,185 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Translator translates a user-facing message of gofig. The message is its English text,
+// a fmt format for the messages having arguments, e.g. "error parsing key '%v' with
+// value '%v' into %v", and the translation must have the same verbs (indexed verbs such
+// as %[2]v can reorder the arguments). The backquoted words of the flag descriptions
+// name the flag values in the usage message, and should be kept backquoted. The
+// messages having no translation are returned unchanged.
+type Translator func(message string) string
+
+// Catalog is a message catalog mapping the English messages of gofig to their
+// translations, its Translate method being a Translator.
+type Catalog map[string]string
+
+// Translate returns the translation of a message, or the message if it has none.
+func (c Catalog) Translate(message string) string {
+	if t, ok := c[message]; ok {
+		return t
+	}
+	return message
+}
+
+// SetTranslator sets the translator of the user-facing messages: the usage message and
+// the descriptions of the flags (the desc tags included), the messages of the built-in
+// flags and the errors returned by Parse. It must be set before Parse, which declares
+// the flags.
+func SetTranslator(t Translator) { gf.SetTranslator(t) }
+
+// SetTranslator sets the translator of the user-facing messages: the usage message and
+// the descriptions of the flags (the desc tags included), the messages of the built-in
+// flags and the errors returned by Parse. It must be set before Parse, which declares
+// the flags.
+func (gf *Gofig) SetTranslator(t Translator) {
+	gf.translator = t
+}
+
+// translate returns the translation of a message.
+func (gf *Gofig) translate(message string) string {
+	if gf.translator == nil {
+		return message
+	}
+	return gf.translator(message)
+}
+
+// translatef returns the translation of a message formatted with args.
+func (gf *Gofig) translatef(format string, args ...interface{}) string {
+	return fmt.Sprintf(gf.translate(format), args...)
+}
+
+// messageError is an error keeping its message format and arguments, to be translated.
+type messageError struct {
+	format string
+	args   []interface{}
+}
+
+// errorf returns an error formatted like fmt.Errorf, which can be translated. The error
+// arguments are formatted with %v, and the last one is wrapped.
+func errorf(format string, args ...interface{}) error {
+	return &messageError{format: format, args: args}
+}
+
+func (e *messageError) Error() string {
+	return fmt.Sprintf(e.format, e.args...)
+}
+
+func (e *messageError) Unwrap() error {
+	for i := len(e.args) - 1; i >= 0; i-- {
+		if err, ok := e.args[i].(error); ok {
+			return err
+		}
+	}
+	return nil
+}
+
+// flagErrors are the errors of the flag package, by message format
+var flagErrors = []struct {
+	format string
+	re     *regexp.Regexp
+	nested bool // whether the last argument is the error of the flag value
+}{
+	{"flag provided but not defined: -%v", regexp.MustCompile(`^flag provided but not defined: -(.*)$`), false},
+	{"invalid value %v for flag -%v: %v", regexp.MustCompile(`^invalid value (".*") for flag -([^:]+): (.*)$`), true},
+	{"invalid boolean value %v for -%v: %v", regexp.MustCompile(`^invalid boolean value (".*") for -([^:]+): (.*)$`), true},
+	{"invalid boolean flag %v: %v", regexp.MustCompile(`^invalid boolean flag ([^:]+): (.*)$`), true},
+	{"flag needs an argument: -%v", regexp.MustCompile(`^flag needs an argument: -(.*)$`), false},
+	{"bad flag syntax: %v", regexp.MustCompile(`^bad flag syntax: (.*)$`), false},
+}
+
+// translateError returns the translation of the message of an error.
+func (gf *Gofig) translateError(err error) string {
+	switch e := err.(type) {
+	case *messageError:
+		args := make([]interface{}, len(e.args))
+		for i, arg := range e.args {
+			if argErr, ok := arg.(error); ok {
+				arg = gf.translateError(argErr)
+			}
+			args[i] = arg
+		}
+		return gf.translatef(e.format, args...)
+	case *fieldError:
+		return gf.translateError(e.err)
+	case *FieldError:
+		return gf.translatef("%v (%v)", gf.translateError(e.Err), e.names.format(gf.translate))
+	}
+	msg := err.Error()
+	for _, fe := range flagErrors {
+		m := fe.re.FindStringSubmatch(msg)
+		if m == nil {
+			continue
+		}
+		args := make([]interface{}, len(m)-1)
+		for i := range args {
+			args[i] = m[i+1]
+		}
+		if fe.nested {
+			args[len(args)-1] = gf.translate(m[len(m)-1])
+		}
+		return gf.translatef(fe.format, args...)
+	}
+	return gf.translate(msg)
+}
+
+// translatedError is an error with a translated message
+type translatedError struct {
+	err error
+	msg string
+}
+
+func (e *translatedError) Error() string {
+	return e.msg
+}
+
+func (e *translatedError) Unwrap() error {
+	return e.err
+}
+
+// withTranslation returns a parsing error with a translated message, if a translator is
+// set. ErrHandled and flag.ErrHelp are returned as is.
+func (gf *Gofig) withTranslation(err error) error {
+	if err == nil || err == ErrHandled || err == flag.ErrHelp || gf.translator == nil {
+		return err
+	}
+	return &translatedError{err: err, msg: gf.translateError(err)}
+}
+
+// flagOutput is the output of a flag set translating the errors printed by the flag
+// package.
+type flagOutput struct {
+	gf *Gofig
+	w  io.Writer
+}
+
+func (o *flagOutput) Write(p []byte) (int, error) {
+	w := o.w
+	if w == nil {
+		w = os.Stderr
+	}
+	if o.gf.translator == nil {
+		return w.Write(p)
+	}
+	msg := strings.TrimSuffix(string(p), "\n")
+	for _, fe := range flagErrors {
+		if fe.re.MatchString(msg) {
+			_, err := io.WriteString(w, o.gf.translateError(errors.New(msg))+"\n")
+			return len(p), err
+		}
+	}
+	return w.Write(p)
+}
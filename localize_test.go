@@ -0,0 +1,86 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranslator(t *testing.T) {
+	type Config struct {
+		DB struct {
+			Port int `desc:"database port"`
+		}
+	}
+	catalog := Catalog{
+		"Usage of %v:":                 "Utilisation de %v :",
+		"database port":                "port de la base de données",
+		"error decoding source %v: %v": "erreur de décodage de la source %v : %v",
+		"error parsing key '%v' with value '%v' into %v": "valeur '%[2]v' de la clé '%[1]v' invalide pour %[3]v",
+		"%v (%v)":                            "%v (%v)",
+		"flag -%v":                           "option -%v",
+		"env %v":                             "variable %v",
+		"key %v":                             "clé %v",
+		"flag provided but not defined: -%v": "option non définie : -%v",
+		"invalid value %v for flag -%v: %v":  "valeur %v invalide pour l'option -%v : %v",
+		"parse error":                        "erreur de syntaxe",
+		"print the effective configuration and exit":                       "affiche la configuration effective et quitte",
+		"print the `shell` (bash, zsh or fish) completion script and exit": "affiche le script de complétion du `shell` et quitte",
+	}
+
+	// Case 1: usage message and flag descriptions
+	var out bytes.Buffer
+	gf := New(ContinueOnError)
+	gf.SetFlagOutput(&out)
+	gf.SetTranslator(catalog.Translate)
+	err := gf.ParseWithArgs(&Config{}, []string{"-h"})
+	assert.Equal(t, flag.ErrHelp, err)
+	assert.Equal(t, `Utilisation de `+gf.flagSet.Name()+` :
+  -db-port int
+    	port de la base de données
+`, out.String())
+
+	// Case 2: errors, the arguments being reordered
+	gf = New(ContinueOnError)
+	gf.SetTranslator(catalog.Translate)
+	gf.SetEnvPrefix("GFT")
+	gf.AddSource(&testSource{name: "yaml", doc: &Document{Format: "yaml", Data: []byte("db:\n  port: 1.5\n")}})
+	err = gf.ParseWithArgs(&Config{}, []string{})
+	assert.EqualError(t, err, "erreur de décodage de la source yaml : valeur '1.5' de la clé 'db.port' invalide pour int (option -db-port, variable GFT_DB_PORT, clé db.port)")
+	var fe *FieldError
+	assert.True(t, errors.As(err, &fe))
+
+	// Case 3: errors of the flag package, printed and returned
+	out.Reset()
+	gf = New(ContinueOnError)
+	gf.SetFlagOutput(&out)
+	gf.SetUsage(func() {})
+	gf.SetTranslator(catalog.Translate)
+	err = gf.ParseWithArgs(&Config{}, []string{"-unknown"})
+	assert.EqualError(t, err, "option non définie : -unknown")
+	assert.Equal(t, "option non définie : -unknown\n", out.String())
+	gf = New(ContinueOnError)
+	gf.SetFlagOutput(&out)
+	gf.SetUsage(func() {})
+	gf.SetTranslator(catalog.Translate)
+	err = gf.ParseWithArgs(&Config{}, []string{"-db-port", "x"})
+	assert.EqualError(t, err, `valeur "x" invalide pour l'option -db-port : erreur de syntaxe (option -db-port, variable DB_PORT, clé db.port)`)
+
+	// Case 4: a JSON error, and messages without translation are unchanged
+	gf = New(ContinueOnError)
+	gf.SetTranslator(catalog.Translate)
+	gf.SetErrorFormat(ErrorsJSON)
+	gf.SetFlagOutput(&out)
+	gf.SetUsage(func() {})
+	err = gf.ParseWithArgs(&Config{}, []string{"-unknown"})
+	assert.JSONEq(t, `{"error": "option non définie : -unknown"}`, err.Error())
+	assert.Equal(t, "configuration is valid", catalog.Translate("configuration is valid"))
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"io"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// secretNamePlaceholder is the name of the Kubernetes secret holding the secret fields,
+// to be replaced in the generated manifests.
+const secretNamePlaceholder = "<secret-name>"
+
+// KubernetesEnv writes the env: list of a Kubernetes container spec setting every
+// environment variable of v, a pointer to a configuration struct, to its current value,
+// with the field descriptions as comments. The secret fields are read from a Secret,
+// whose name is the <secret-name> placeholder and key the lowercased variable name.
+func KubernetesEnv(v interface{}, w io.Writer) error { return gf.KubernetesEnv(v, w) }
+
+// KubernetesEnv writes the env: list of a Kubernetes container spec setting every
+// environment variable of v, a pointer to a configuration struct, to its current value,
+// with the field descriptions as comments. The secret fields are read from a Secret,
+// whose name is the <secret-name> placeholder and key the lowercased variable name.
+func (gf *Gofig) KubernetesEnv(v interface{}, w io.Writer) error {
+	list := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, env := range gf.EnvVars(v) {
+		entry := mappingNode("name", scalarNode(env.Name, false))
+		if env.Secret {
+			ref := mappingNode("name", scalarNode(secretNamePlaceholder, false), "key", scalarNode(strings.ToLower(env.Name), false))
+			entry.Content = append(entry.Content, mappingNode("valueFrom", mappingNode("secretKeyRef", ref)).Content...)
+		} else {
+			val := strings.ReplaceAll(env.Value, "$(", "$$(") // escape the variable references
+			entry.Content = append(entry.Content, mappingNode("value", scalarNode(val, true)).Content...)
+		}
+		entry.HeadComment = env.Desc
+		list.Content = append(list.Content, entry)
+	}
+	return encodeManifest(w, mappingNode("env", list))
+}
+
+// ComposeEnv writes the environment: block of a docker-compose service setting every
+// environment variable of v, a pointer to a configuration struct, to its current value,
+// with the field descriptions as comments. The secret fields are interpolated from the
+// environment of docker-compose, e.g. GF_DB_PASSWORD: ${GF_DB_PASSWORD}.
+func ComposeEnv(v interface{}, w io.Writer) error { return gf.ComposeEnv(v, w) }
+
+// ComposeEnv writes the environment: block of a docker-compose service setting every
+// environment variable of v, a pointer to a configuration struct, to its current value,
+// with the field descriptions as comments. The secret fields are interpolated from the
+// environment of docker-compose, e.g. GF_DB_PASSWORD: ${GF_DB_PASSWORD}.
+func (gf *Gofig) ComposeEnv(v interface{}, w io.Writer) error {
+	block := &yaml.Node{Kind: yaml.MappingNode}
+	for _, env := range gf.EnvVars(v) {
+		val := scalarNode(strings.ReplaceAll(env.Value, "$", "$$"), true) // escape the interpolation
+		if env.Secret {
+			val = scalarNode("${"+env.Name+"}", false)
+		}
+		key := scalarNode(env.Name, false)
+		key.HeadComment = env.Desc
+		block.Content = append(block.Content, key, val)
+	}
+	return encodeManifest(w, mappingNode("environment", block))
+}
+
+// mappingNode returns a YAML mapping of key and value node pairs.
+func mappingNode(kvs ...interface{}) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for i := 0; i < len(kvs); i += 2 {
+		node.Content = append(node.Content, scalarNode(kvs[i].(string), false), kvs[i+1].(*yaml.Node))
+	}
+	return node
+}
+
+// scalarNode returns a YAML string, double-quoted if quoted is set, e.g. the environment
+// variable values which must be strings.
+func scalarNode(s string, quoted bool) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}
+	if quoted {
+		node.Style = yaml.DoubleQuotedStyle
+	}
+	return node
+}
+
+// encodeManifest writes a YAML manifest snippet indented with two spaces.
+func encodeManifest(w io.Writer, node *yaml.Node) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	err := enc.Encode(node)
+	if err != nil {
+		return err
+	}
+	return enc.Close()
+}
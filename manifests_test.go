@@ -0,0 +1,53 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type manifestStruct struct {
+	Port     int    `json:"port" desc:"listen port"`
+	Password string `json:"password" secret:"true" desc:"database password"`
+	Greeting string `json:"greeting"`
+}
+
+func TestManifestEnv(t *testing.T) {
+	s := &manifestStruct{Port: 8080, Password: "hunter2", Greeting: "$(USER) costs $5"}
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("APP")
+
+	var b bytes.Buffer
+	err := gf.KubernetesEnv(s, &b)
+	assert.NoError(t, err)
+	assert.Equal(t, `env:
+  # listen port
+  - name: APP_PORT
+    value: "8080"
+  # database password
+  - name: APP_PASSWORD
+    valueFrom:
+      secretKeyRef:
+        name: <secret-name>
+        key: app_password
+  - name: APP_GREETING
+    value: "$$(USER) costs $5"
+`, b.String())
+
+	b.Reset()
+	err = gf.ComposeEnv(s, &b)
+	assert.NoError(t, err)
+	assert.Equal(t, `environment:
+  # listen port
+  APP_PORT: "8080"
+  # database password
+  APP_PASSWORD: ${APP_PASSWORD}
+  APP_GREETING: "$$(USER) costs $$5"
+`, b.String())
+}
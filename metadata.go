@@ -0,0 +1,141 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// CloudProvider identifies a cloud instance metadata service.
+type CloudProvider int
+
+const (
+	// AWS is the Amazon EC2 instance metadata service (IMDSv2)
+	AWS CloudProvider = iota
+	// GCE is the Google Compute Engine metadata server
+	GCE
+	// Azure is the Azure Instance Metadata Service
+	Azure
+)
+
+// default metadata service endpoints and user-data paths
+var metadataDefaults = map[CloudProvider]struct {
+	name     string
+	endpoint string
+	path     string
+}{
+	AWS:   {"aws", "http://169.254.169.254", "/latest/user-data"},
+	GCE:   {"gce", "http://metadata.google.internal", "/computeMetadata/v1/instance/attributes/user-data"},
+	Azure: {"azure", "http://169.254.169.254", "/metadata/instance/compute/userData?api-version=2021-01-01&format=text"},
+}
+
+// MetadataSource loads a config document from a cloud instance metadata service, by
+// default from the instance user-data, so bootstrap config doesn't have to be baked
+// into images.
+type MetadataSource struct {
+	// Provider is the cloud provider.
+	Provider CloudProvider
+	// Format of the document: "json", "toml" or "yaml".
+	Format string
+	// Endpoint overrides the metadata service base URL.
+	Endpoint string
+	// Path overrides the path of the document, e.g. a GCE custom attribute
+	// ("/computeMetadata/v1/instance/attributes/my-config").
+	Path string
+	// Client is the HTTP client used for the requests, http.DefaultClient if nil.
+	Client *http.Client
+}
+
+// NewMetadataSource returns a Source loading the instance user-data of the cloud provider.
+func NewMetadataSource(provider CloudProvider, format string) *MetadataSource {
+	return &MetadataSource{Provider: provider, Format: format}
+}
+
+// Name returns the cloud provider name.
+func (s *MetadataSource) Name() string {
+	return metadataDefaults[s.Provider].name + " metadata"
+}
+
+// Load fetches the document from the metadata service.
+func (s *MetadataSource) Load(ctx context.Context) (*Document, error) {
+	defaults, ok := metadataDefaults[s.Provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown cloud provider %v", s.Provider)
+	}
+	endpoint := strings.TrimSuffix(s.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = defaults.endpoint
+	}
+	path := s.Path
+	if path == "" {
+		path = defaults.path
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	switch s.Provider {
+	case AWS:
+		token, err := s.awsToken(ctx, endpoint)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("X-aws-ec2-metadata-token", token)
+	case GCE:
+		req.Header.Set("Metadata-Flavor", "Google")
+	case Azure:
+		req.Header.Set("Metadata", "true")
+	}
+
+	data, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Azure user data is base64 encoded
+	if s.Provider == Azure && s.Path == "" {
+		data, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Document{Format: s.Format, Data: data}, nil
+}
+
+// awsToken requests an IMDSv2 session token.
+func (s *MetadataSource) awsToken(ctx context.Context, endpoint string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+	token, err := s.do(req)
+	return string(token), err
+}
+
+// do sends a request to the metadata service and returns the response body.
+func (s *MetadataSource) do(req *http.Request) ([]byte, error) {
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected HTTP status %v", resp.Status)
+	}
+	return readLimited(req.Context(), resp.Body)
+}
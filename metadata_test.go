@@ -0,0 +1,54 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetadataSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/latest/api/token":
+			_, _ = w.Write([]byte("token"))
+		case r.URL.Path == "/latest/user-data" && r.Header.Get("X-aws-ec2-metadata-token") == "token":
+			_, _ = w.Write([]byte(`{"str": "aws"}`))
+		case r.URL.Path == "/computeMetadata/v1/instance/attributes/user-data" && r.Header.Get("Metadata-Flavor") == "Google":
+			_, _ = w.Write([]byte(`str: gce`))
+		case r.URL.Path == "/metadata/instance/compute/userData" && r.Header.Get("Metadata") == "true":
+			_, _ = w.Write([]byte(base64.StdEncoding.EncodeToString([]byte(`str = "azure"`))))
+		default:
+			http.Error(w, "forbidden", http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	for provider, expected := range map[CloudProvider]*MetadataSource{
+		AWS:   {Provider: AWS, Format: "json"},
+		GCE:   {Provider: GCE, Format: "yaml"},
+		Azure: {Provider: Azure, Format: "toml"},
+	} {
+		src := expected
+		src.Endpoint = server.URL
+		s := buildTestStruct()
+		gf := New(ContinueOnError)
+		gf.AddSource(src)
+		err := gf.ParseWithArgs(s, []string{})
+		assert.NoError(t, err, src.Name())
+		assert.Equal(t, metadataDefaults[provider].name, s.Str)
+	}
+
+	// custom path
+	gf := New(ContinueOnError)
+	gf.AddSource(&MetadataSource{Provider: GCE, Format: "yaml", Endpoint: server.URL, Path: "/missing"})
+	err := gf.ParseWithArgs(buildTestStruct(), []string{})
+	assert.EqualError(t, err, "error loading source gce metadata: unexpected HTTP status 403 Forbidden")
+}
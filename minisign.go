@@ -0,0 +1,102 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	// minisignAlgorithm is the signature algorithm of the minisign keys
+	minisignAlgorithm = "Ed"
+	// minisignHashedAlgorithm is the algorithm of the signatures of BLAKE2b-512 hashes
+	minisignHashedAlgorithm = "ED"
+	// minisignKeyIDSize is the size of the minisign key IDs
+	minisignKeyIDSize = 8
+	// trustedCommentPrefix is the prefix of the trusted comment of the signatures
+	trustedCommentPrefix = "trusted comment: "
+)
+
+// minisignVerifier verifies minisign signatures
+type minisignVerifier struct {
+	keyID [minisignKeyIDSize]byte
+	key   ed25519.PublicKey
+}
+
+// MinisignVerifier returns a Verifier of the minisign signatures, stored in .minisig
+// files, with a minisign public key: the content of its .pub file, or its base64-encoded
+// key line (e.g. "RWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3"). The
+// signatures of the file content (legacy) and of its BLAKE2b-512 hash (the default of
+// the recent minisign versions) are supported, as well as their trusted comment.
+func MinisignVerifier(publicKey string) (Verifier, error) {
+	lines := strings.Split(strings.TrimSpace(publicKey), "\n")
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[len(lines)-1]))
+	if err != nil || len(data) != 2+minisignKeyIDSize+ed25519.PublicKeySize || string(data[:2]) != minisignAlgorithm {
+		return nil, errors.New("invalid minisign public key")
+	}
+	v := &minisignVerifier{key: ed25519.PublicKey(data[2+minisignKeyIDSize:])}
+	copy(v.keyID[:], data[2:])
+	return v, nil
+}
+
+func (v *minisignVerifier) SignatureSuffix() string {
+	return ".minisig"
+}
+
+// Verify verifies a minisign signature file: an untrusted comment line, the signature
+// line, the trusted comment line and the global signature line, signing the signature
+// and the trusted comment.
+func (v *minisignVerifier) Verify(data, signature []byte) error {
+	lines := strings.Split(strings.TrimSpace(string(signature)), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[2], trustedCommentPrefix) {
+		return errors.New("invalid minisign signature file")
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(sig) != 2+minisignKeyIDSize+ed25519.SignatureSize {
+		return errors.New("invalid minisign signature")
+	}
+	if !bytes.Equal(sig[2:2+minisignKeyIDSize], v.keyID[:]) {
+		return fmt.Errorf("signed with another key (key ID %X)", reverseBytes(sig[2:2+minisignKeyIDSize]))
+	}
+
+	msg := data
+	switch string(sig[:2]) {
+	case minisignAlgorithm:
+	case minisignHashedAlgorithm:
+		sum := blake2b.Sum512(data)
+		msg = sum[:]
+	default:
+		return fmt.Errorf("unsupported minisign signature algorithm %q", sig[:2])
+	}
+	if !ed25519.Verify(v.key, msg, sig[2+minisignKeyIDSize:]) {
+		return errors.New("signature mismatch")
+	}
+
+	comment := strings.TrimSuffix(strings.TrimPrefix(lines[2], trustedCommentPrefix), "\r")
+	globalSig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	signed := append(append([]byte{}, sig[2+minisignKeyIDSize:]...), comment...)
+	if err != nil || !ed25519.Verify(v.key, signed, globalSig) {
+		return errors.New("trusted comment signature mismatch")
+	}
+	return nil
+}
+
+// reverseBytes returns the bytes of b in reverse order, the minisign key IDs being
+// displayed as little-endian numbers.
+func reverseBytes(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
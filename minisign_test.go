@@ -0,0 +1,87 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/blake2b"
+)
+
+func TestMinisignReference(t *testing.T) {
+	// signatures of "test" by the minisign tool, of the content (legacy) and of its hash
+	v, err := MinisignVerifier("RWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3")
+	assert.NoError(t, err)
+	for _, sig := range []string{
+		"untrusted comment: signature from minisign secret key\n" +
+			"RWQf6LRCGA9i59SLOFxz6NxvASXDJeRtuZykwQepbDEGt87ig1BNpWaVWuNrm73YiIiJbq71Wi+dP9eKL8OC351vwIasSSbXxwA=\n" +
+			"trusted comment: timestamp:1635442742\tfile:test\n" +
+			"0YteLgV960ia80vnA/fHbvkyjl/IoP/HNOCaZfrF0CdhAlp7ok+Tpkya+VpWPX5C/Is3q8a/kEDSY7fBmmgJCg==\n",
+		"untrusted comment: signature from minisign secret key\n" +
+			"RUQf6LRCGA9i559r3g7V1qNyJDApGip8MfqcadIgT9CuhV3EMhHoN1mGTkUidF/z7SrlQgXdy8ofjb7bNJJylDOocrCo8KLzZwo=\n" +
+			"trusted comment: timestamp:1635443258\tfile:test\thashed\n" +
+			"/cj37GK60vryibFn+ftOgbCvW9NKhKYgjVpFFQUcWPAnjO23wrvVDTt7cloNC06maoBli9q6qwZDXXoaxweICQ==\n",
+	} {
+		assert.NoError(t, v.Verify([]byte("test"), []byte(sig)))
+		assert.EqualError(t, v.Verify([]byte("tests"), []byte(sig)), "signature mismatch")
+	}
+}
+
+// minisignTestKey generates a minisign public key, and a function signing data like
+// minisign, hashed or not.
+func minisignTestKey(t *testing.T) (string, func(data []byte, hashed bool) []byte) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	keyID := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	pubKey := "untrusted comment: minisign public key\n" +
+		base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyID...), pub...)) + "\n"
+	sign := func(data []byte, hashed bool) []byte {
+		alg, msg := "Ed", data
+		if hashed {
+			sum := blake2b.Sum512(data)
+			alg, msg = "ED", sum[:]
+		}
+		sig := ed25519.Sign(priv, msg)
+		comment := "timestamp:1700000000"
+		global := ed25519.Sign(priv, append(append([]byte{}, sig...), comment...))
+		return []byte("untrusted comment: signature\n" +
+			base64.StdEncoding.EncodeToString(append(append([]byte(alg), keyID...), sig...)) + "\n" +
+			trustedCommentPrefix + comment + "\n" +
+			base64.StdEncoding.EncodeToString(global) + "\n")
+	}
+	return pubKey, sign
+}
+
+func TestMinisignVerifier(t *testing.T) {
+	pubKey, sign := minisignTestKey(t)
+	v, err := MinisignVerifier(pubKey)
+	assert.NoError(t, err)
+	assert.Equal(t, ".minisig", v.SignatureSuffix())
+
+	data := []byte("port: 8080\n")
+	for _, hashed := range []bool{false, true} {
+		sig := sign(data, hashed)
+		assert.NoError(t, v.Verify(data, sig))
+		assert.EqualError(t, v.Verify([]byte("port: 8081\n"), sig), "signature mismatch")
+
+		// tampered trusted comment
+		tampered := bytes.Replace(sig, []byte("timestamp"), []byte("Timestamp"), 1)
+		assert.EqualError(t, v.Verify(data, tampered), "trusted comment signature mismatch")
+	}
+
+	otherKey, _ := minisignTestKey(t)
+	other, err := MinisignVerifier(otherKey)
+	assert.NoError(t, err)
+	assert.EqualError(t, other.Verify(data, sign(data, true)), "signature mismatch")
+	assert.EqualError(t, v.Verify(data, []byte("garbage")), "invalid minisign signature file")
+	_, err = MinisignVerifier("RWQ")
+	assert.EqualError(t, err, "invalid minisign public key")
+}
@@ -0,0 +1,301 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// unitTag is the struct tag holding the unit of the numbers set into a Duration field.
+const unitTag = "unit"
+
+// durationUnits are the units of the numbers set into Duration fields
+var durationUnits = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"µs": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+}
+
+var (
+	durationType        = reflect.TypeOf(Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	yamlUnmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+	tomlUnmarshalerType = reflect.TypeOf((*toml.Unmarshaler)(nil)).Elem()
+)
+
+// numberField is a field which may be set from a number
+type numberField struct {
+	typ    reflect.Type
+	unit   string // unit of the numbers set into a Duration field
+	layout string // layout of the strings set into a time.Time field
+	ext    string // format of the document
+}
+
+// coerceNumbers applies the same numeric rules to all the config formats before a
+// document is decoded into the struct pointed to by v: integer fields accept integral
+// numbers only, including floats such as 1.0 or 1e3 (but not 1.5), and numbers must be
+// in the range of their field type. Numbers set into Duration fields are durations in
+// the unit of the unit tag (seconds by default), and strings set into time.Time fields
+// are times in the layout of the layout tag (RFC 3339 by default). Values which need to
+// be converted are converted in the document tree.
+func coerceNumbers(t *docTree, v interface{}) error {
+	if t.root == nil {
+		return nil // syntax errors are left to the decoder
+	}
+	fields, ok := numberFields(v, t.ext)
+	if !ok {
+		return nil // not a configuration struct
+	}
+
+	converted, err := coerceTree(t.root, "", fields)
+	t.changed = t.changed || converted
+	return err
+}
+
+// numberFields returns the fields of the struct pointed to by v which may be set from a
+// number, or a scalar, by key path, following the keys of the format of ext, and whether
+// v is a configuration struct.
+func numberFields(v interface{}, ext string) (map[string]numberField, bool) {
+	fields := make(map[string]numberField)
+	err := parseStruct(v, func(path []string, name string, val *reflect.Value, tags *reflect.StructTag) error {
+		if _, ok := tags.Lookup(parseWithTag); ok {
+			return nil // set by its parse method
+		}
+		fields[strings.Join(path, ".")] = numberField{typ: val.Type(), unit: tags.Get(unitTag), layout: tags.Get(layoutTag), ext: ext}
+		return nil
+	}, strings.TrimPrefix(ext, "."))
+	return fields, err == nil
+}
+
+// coerceTree checks and converts the numbers of a document tree mapped to the numeric
+// fields, and returns whether some were converted.
+func coerceTree(tree map[string]interface{}, path string, fields map[string]numberField) (bool, error) {
+	converted := false
+	for k, val := range tree {
+		key := strings.ToLower(k)
+		if path != "" {
+			key = path + "." + key
+		}
+
+		var err error
+		var c bool
+		if f, ok := fields[key]; ok {
+			c, err = coerceValue(&val, f, key)
+			if err != nil {
+				return false, &fieldError{key: key, source: ProvenanceConfig, raw: fmt.Sprint(val), err: err}
+			}
+		} else if sub, ok := val.(map[string]interface{}); ok {
+			c, err = coerceTree(sub, key, fields)
+			if err != nil {
+				return false, err
+			}
+		}
+		if c {
+			tree[k] = val
+			converted = true
+		}
+	}
+	return converted, nil
+}
+
+// coerceValue checks and converts the numbers of a value decoded for a field, and the
+// times written in the layout of a time.Time field, and returns whether some were
+// converted.
+func coerceValue(val *interface{}, f numberField, key string) (bool, error) {
+	t := f.typ
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == durationType {
+		return coerceDuration(val, f.unit, key)
+	}
+	if t == timeDurationType {
+		return coerceTimeDuration(val, f.ext, key)
+	}
+	if t == timeType {
+		return coerceTime(val, f.layout, key)
+	}
+	pt := reflect.PtrTo(t)
+	if pt.Implements(textUnmarshalerType) || pt.Implements(jsonUnmarshalerType) ||
+		pt.Implements(yamlUnmarshalerType) || pt.Implements(tomlUnmarshalerType) {
+		return false, nil
+	}
+
+	converted := false
+	elem := f
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		list, ok := (*val).([]interface{})
+		if !ok {
+			return false, nil
+		}
+		elem.typ = t.Elem()
+		for i := range list {
+			c, err := coerceValue(&list[i], elem, fmt.Sprintf("%v[%v]", key, i))
+			if err != nil {
+				return false, err
+			}
+			converted = converted || c
+		}
+		return converted, nil
+	case reflect.Map:
+		m, ok := (*val).(map[string]interface{})
+		if !ok {
+			return false, nil
+		}
+		elem.typ = t.Elem()
+		for k := range m {
+			v := m[k]
+			c, err := coerceValue(&v, elem, key+"."+strings.ToLower(k))
+			if err != nil {
+				return false, err
+			}
+			if c {
+				m[k] = v
+				converted = true
+			}
+		}
+		return converted, nil
+	}
+
+	s, num, isFloat := number(*val)
+	if num == nil {
+		return false, nil
+	}
+	invalid := errorf("error parsing key '%v' with value '%v' into %v", key, s, t)
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if !num.IsInt() {
+			return false, invalid
+		}
+		n := num.Num()
+		if !n.IsInt64() || reflect.Zero(t).OverflowInt(n.Int64()) {
+			return false, invalid
+		}
+		if isFloat {
+			*val = n.Int64()
+			return true, nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if !num.IsInt() {
+			return false, invalid
+		}
+		n := num.Num()
+		if !n.IsUint64() || reflect.Zero(t).OverflowUint(n.Uint64()) {
+			return false, invalid
+		}
+		if isFloat {
+			// the TOML integers are int64
+			if n.IsInt64() {
+				*val = n.Int64()
+			} else {
+				*val = n.Uint64()
+			}
+			return true, nil
+		}
+	case reflect.Float32:
+		if f, _ := num.Float64(); math.Abs(f) > math.MaxFloat32 {
+			return false, invalid
+		}
+	}
+	return false, nil
+}
+
+// coerceDuration converts a number decoded for a Duration field into a duration string,
+// the number being in unit ("ns", "us", "ms", "s", "m" or "h", "s" if empty).
+func coerceDuration(val *interface{}, unit string, key string) (bool, error) {
+	s, num, _ := number(*val)
+	if num == nil {
+		return false, nil
+	}
+	if unit == "" {
+		unit = "s"
+	}
+	scale, ok := durationUnits[unit]
+	if !ok {
+		return false, errorf("invalid unit '%v' for key '%v'", unit, key)
+	}
+
+	d := new(big.Rat).Mul(num, new(big.Rat).SetInt64(int64(scale)))
+	if !d.IsInt() || !d.Num().IsInt64() {
+		return false, errorf("error parsing key '%v' with value '%v' into %v", key, s, durationType)
+	}
+	*val = time.Duration(d.Num().Int64()).String()
+	return true, nil
+}
+
+// coerceTimeDuration converts a value decoded for a time.Duration field, either a
+// duration string, e.g. "1m30s", or a number of nanoseconds, into the one the decoder of
+// the format of ext sets: a number for JSON, a string for YAML, either for TOML.
+func coerceTimeDuration(val *interface{}, ext string, key string) (bool, error) {
+	if s, ok := (*val).(string); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(s))
+		if err != nil {
+			return false, errorf("error parsing key '%v' with value '%v' into %v", key, s, timeDurationType)
+		}
+		if ext != jsonExtention {
+			return false, nil
+		}
+		*val = int64(d)
+		return true, nil
+	}
+	s, num, isFloat := number(*val)
+	if num == nil || ext != yamlExtention {
+		return false, nil
+	}
+	if isFloat || !num.IsInt() || !num.Num().IsInt64() {
+		return false, errorf("error parsing key '%v' with value '%v' into %v", key, s, timeDurationType)
+	}
+	*val = time.Duration(num.Num().Int64()).String()
+	return true, nil
+}
+
+// number returns the text, formatted the same way whatever the format, and the exact
+// value of a decoded number, and whether it was written as a float, or a nil value if it
+// isn't a finite number.
+func number(val interface{}) (string, *big.Rat, bool) {
+	switch n := val.(type) {
+	case json.Number:
+		r, ok := new(big.Rat).SetString(string(n))
+		if !ok {
+			return "", nil, false
+		}
+		if strings.ContainsAny(string(n), ".eE") {
+			f, _ := r.Float64()
+			return strconv.FormatFloat(f, 'g', -1, 64), r, true
+		}
+		return string(n), r, false
+	case int:
+		return strconv.Itoa(n), new(big.Rat).SetInt64(int64(n)), false
+	case int64:
+		return strconv.FormatInt(n, 10), new(big.Rat).SetInt64(n), false
+	case uint64:
+		return strconv.FormatUint(n, 10), new(big.Rat).SetInt(new(big.Int).SetUint64(n)), false
+	case float64:
+		if math.IsInf(n, 0) || math.IsNaN(n) {
+			return "", nil, false
+		}
+		return strconv.FormatFloat(n, 'g', -1, 64), new(big.Rat).SetFloat64(n), true
+	}
+	return "", nil, false
+}
@@ -0,0 +1,226 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type numbersConfig struct {
+	Int     int
+	Int8    int8
+	Uint    uint
+	Uint16  uint16
+	Float   float64
+	Float32 float32
+	Ptr     *int
+	Ports   []int
+	Weights map[string]uint8
+	Sub     struct {
+		Int64 int64
+	}
+}
+
+// numberDocument returns a document setting key to the literal value in format
+func numberDocument(format string, key string, value string) string {
+	path := strings.Split(key, ".")
+	switch format {
+	case "json":
+		doc := value
+		for i := len(path) - 1; i >= 0; i-- {
+			doc = `{"` + path[i] + `": ` + doc + `}`
+		}
+		return doc
+	case "toml":
+		return key + " = " + value + "\n"
+	}
+	doc := value
+	for i := len(path) - 1; i >= 0; i-- {
+		doc = "{" + path[i] + ": " + doc + "}"
+	}
+	return doc + "\n"
+}
+
+func TestNumbersConformance(t *testing.T) {
+	one := 1
+	cases := []struct {
+		key   string
+		value string
+		want  numbersConfig
+		err   string
+	}{
+		// integral numbers, including floats, into integer fields
+		{key: "int", value: "42", want: numbersConfig{Int: 42}},
+		{key: "int", value: "1.0", want: numbersConfig{Int: 1}},
+		{key: "int", value: "1e3", want: numbersConfig{Int: 1000}},
+		{key: "int", value: "-2.0", want: numbersConfig{Int: -2}},
+		{key: "uint", value: "1E2", want: numbersConfig{Uint: 100}},
+		{key: "ptr", value: "1.0", want: numbersConfig{Ptr: &one}},
+		{key: "sub.int64", value: "5e0", want: numbersConfig{Sub: struct{ Int64 int64 }{5}}},
+		{key: "ports", value: "[80, 4.43e2]", want: numbersConfig{Ports: []int{80, 443}}},
+
+		// integer numbers into float fields
+		{key: "float", value: "5", want: numbersConfig{Float: 5}},
+		{key: "float32", value: "1.5", want: numbersConfig{Float32: 1.5}},
+
+		// fractional numbers into integer fields
+		{key: "int", value: "1.5", err: "error parsing key 'int' with value '1.5' into int"},
+		{key: "ports", value: "[80, 0.5]", err: "error parsing key 'ports[1]' with value '0.5' into int"},
+
+		// out of range numbers
+		{key: "int8", value: "300", err: "error parsing key 'int8' with value '300' into int8"},
+		{key: "int8", value: "-129", err: "error parsing key 'int8' with value '-129' into int8"},
+		{key: "uint", value: "-1", err: "error parsing key 'uint' with value '-1' into uint"},
+		{key: "uint16", value: "7e4", err: "error parsing key 'uint16' with value '70000' into uint16"},
+		{key: "float32", value: "1e39", err: "error parsing key 'float32' with value '1e+39' into float32"},
+	}
+
+	for _, format := range []string{"json", "toml", "yaml"} {
+		for _, c := range cases {
+			doc := numberDocument(format, c.key, c.value)
+			s := numbersConfig{}
+			err := decodeConfig(strings.NewReader(doc), "."+format, &s)
+			if c.err != "" {
+				assert.EqualError(t, err, c.err, "%v: %v", format, doc)
+				continue
+			}
+			assert.NoError(t, err, "%v: %v", format, doc)
+			assert.Equal(t, c.want, s, "%v: %v", format, doc)
+		}
+	}
+
+	// maps of numbers
+	for _, format := range []string{"json", "yaml"} {
+		s := numbersConfig{}
+		err := decodeConfig(strings.NewReader(numberDocument(format, "weights", `{"A": 1.0, "b": 2}`)), "."+format, &s)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]uint8{"A": 1, "b": 2}, s.Weights)
+
+		err = decodeConfig(strings.NewReader(numberDocument(format, "weights", `{"A": 256}`)), "."+format, &s)
+		assert.EqualError(t, err, "error parsing key 'weights.a' with value '256' into uint8")
+	}
+}
+
+func TestDurationNumbers(t *testing.T) {
+	type Config struct {
+		Timeout  Duration
+		Interval Duration   `unit:"ms"`
+		Backoff  []Duration `unit:"m"`
+		Bad      Duration   `unit:"days"`
+	}
+
+	for _, format := range []string{"json", "toml", "yaml"} {
+		// Case 1: numbers in the unit of the field, strings as usual
+		s := Config{}
+		doc := map[string]string{
+			"json": `{"timeout": 30, "interval": 1.5, "backoff": [1, "90s", 0.5]}`,
+			"toml": "timeout = 30\ninterval = 1.5\nbackoff = [1, \"90s\", 0.5]\n",
+			"yaml": "timeout: 30\ninterval: 1.5\nbackoff: [1, 90s, 0.5]\n",
+		}[format]
+		err := decodeConfig(strings.NewReader(doc), "."+format, &s)
+		assert.NoError(t, err, format)
+		assert.Equal(t, Duration(30*time.Second), s.Timeout, format)
+		assert.Equal(t, Duration(1500*time.Microsecond), s.Interval, format)
+		assert.Equal(t, []Duration{Duration(time.Minute), Duration(90 * time.Second), Duration(30 * time.Second)}, s.Backoff, format)
+
+		// Case 2: strings
+		err = decodeConfig(strings.NewReader(numberDocument(format, "timeout", `"1m"`)), "."+format, &s)
+		assert.NoError(t, err, format)
+		assert.Equal(t, Duration(time.Minute), s.Timeout, format)
+
+		// Case 3: errors
+		err = decodeConfig(strings.NewReader(numberDocument(format, "bad", "1")), "."+format, &s)
+		assert.EqualError(t, err, "invalid unit 'days' for key 'bad'", format)
+		err = decodeConfig(strings.NewReader(numberDocument(format, "timeout", "1e-10")), "."+format, &s)
+		assert.EqualError(t, err, "error parsing key 'timeout' with value '1e-10' into gofig.Duration", format)
+	}
+}
+
+func TestTimeDurations(t *testing.T) {
+	type Config struct {
+		Timeout time.Duration
+		Retry   *time.Duration
+		Backoff []time.Duration
+	}
+
+	// Case 1: flags and env variables, as duration strings
+	os.Setenv("GFD_RETRY", "1m30s")
+	os.Setenv("GFD_BACKOFF", "1s,2s")
+	defer os.Unsetenv("GFD_RETRY")
+	defer os.Unsetenv("GFD_BACKOFF")
+	s := Config{}
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("GFD")
+	err := gf.ParseWithArgs(&s, []string{"-timeout", "5s"})
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Second, s.Timeout)
+	assert.Equal(t, 90*time.Second, *s.Retry)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, s.Backoff)
+	assert.Equal(t, "5s", gf.flagSet.Lookup("timeout").Value.String())
+
+	err = New(ContinueOnError).ParseWithArgs(&Config{}, []string{"-timeout", "5"})
+	assert.EqualError(t, err, `invalid value "5" for flag -timeout: parse error (flag -timeout, env TIMEOUT, key timeout)`)
+	os.Setenv("GFD_RETRY", "soon")
+	gf = New(ContinueOnError)
+	gf.SetEnvPrefix("GFD")
+	err = gf.ParseWithArgs(&Config{}, []string{})
+	assert.EqualError(t, err, "error parsing environment variable 'GFD_RETRY' with value 'soon' into *time.Duration (flag -retry, env GFD_RETRY, key retry)")
+
+	// Case 2: documents, duration strings or numbers of nanoseconds
+	for _, format := range []string{"json", "toml", "yaml"} {
+		s = Config{}
+		err = decodeConfig(strings.NewReader(numberDocument(format, "timeout", `"2m"`)), "."+format, &s)
+		assert.NoError(t, err, format)
+		assert.Equal(t, 2*time.Minute, s.Timeout, format)
+		err = decodeConfig(strings.NewReader(numberDocument(format, "timeout", "1000")), "."+format, &s)
+		assert.NoError(t, err, format)
+		assert.Equal(t, time.Microsecond, s.Timeout, format)
+		err = decodeConfig(strings.NewReader(numberDocument(format, "timeout", `"soon"`)), "."+format, &s)
+		assert.EqualError(t, err, "error parsing key 'timeout' with value 'soon' into time.Duration", format)
+	}
+
+	// Case 3: JSON schema, a duration string
+	schema := jsonSchema(reflect.ValueOf(Config{Timeout: time.Minute}), "", schemaOptions{key: helmKey})
+	props := schema["properties"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"type": "string", "default": "1m0s"}, props["timeout"])
+	assert.Equal(t, map[string]interface{}{"type": "string"}, props["retry"])
+	assert.Equal(t, map[string]interface{}{"type": "string"}, props["backoff"].(map[string]interface{})["items"])
+}
+
+func TestCoercedDocumentPositions(t *testing.T) {
+	type Server struct {
+		Timeout Duration `yaml:"timeout" toml:"timeout"`
+		Port    int      `yaml:"port" toml:"port"`
+	}
+	type Config struct {
+		Name    string `yaml:"name" toml:"name"`
+		Default Server `yaml:"default" toml:"default"`
+		Server  Server `yaml:"server" toml:"server"`
+	}
+
+	// Case 1: the errors keep the line of the document once a value is converted
+	yamlDoc := "# servers\ndefault: &default\n  timeout: 90 # seconds\n\nname: api\n\n# the server overrides the port\nserver:\n  <<: *default\n\n  port: abc\n"
+	err := decodeConfig(strings.NewReader(yamlDoc), ".yaml", &Config{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "line 11:")
+	tomlDoc := "# servers\nname = \"api\"\n\n[default]\ntimeout = 90 # seconds\n\n# the server overrides the port\n[server]\ntimeout = 1.5\n\nport = \"abc\"\n"
+	err = decodeConfig(strings.NewReader(tomlDoc), ".toml", &Config{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "line 11 ")
+
+	// Case 2: the converted values of the anchors and of the merged keys
+	s := Config{}
+	err = decodeConfig(strings.NewReader(strings.Replace(yamlDoc, "abc", "8080", 1)), ".yaml", &s)
+	assert.NoError(t, err)
+	want := Config{Name: "api", Default: Server{Timeout: Duration(90 * time.Second)}, Server: Server{Timeout: Duration(90 * time.Second), Port: 8080}}
+	assert.Equal(t, want, s)
+}
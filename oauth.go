@@ -0,0 +1,227 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// tokenExpiryMargin is how long before their expiry the access tokens are renewed.
+	tokenExpiryMargin = 30 * time.Second
+
+	grantTypeClientCredentials = "client_credentials"
+	grantTypeTokenExchange     = "urn:ietf:params:oauth:grant-type:token-exchange"
+	tokenTypeJWT               = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// TokenSource returns the access tokens authenticating the requests of an HTTPSource.
+type TokenSource interface {
+	// Token returns a valid access token.
+	Token(ctx context.Context) (string, error)
+}
+
+// tokenInvalidator is a TokenSource whose token can be dropped when it's rejected.
+type tokenInvalidator interface {
+	invalidate(token string)
+}
+
+// ClientCredentials is a TokenSource getting access tokens with the OAuth2 client
+// credentials grant. The tokens are cached until shortly before they expire.
+type ClientCredentials struct {
+	// TokenURL is the token endpoint of the authorization server.
+	TokenURL string
+	// ClientID and ClientSecret authenticate the client.
+	ClientID     string
+	ClientSecret string
+	// Scopes are the optional scopes requested.
+	Scopes []string
+	// Audience is the optional audience of the token, as required by some servers.
+	Audience string
+	// Client is the HTTP client of the token requests, http.DefaultClient if nil.
+	Client *http.Client
+
+	cache tokenCache
+}
+
+// Token returns the cached access token, or requests a new one.
+func (c *ClientCredentials) Token(ctx context.Context) (string, error) {
+	return c.cache.get(ctx, func(ctx context.Context) (*tokenResponse, error) {
+		form := url.Values{"grant_type": {grantTypeClientCredentials}}
+		if len(c.Scopes) > 0 {
+			form.Set("scope", strings.Join(c.Scopes, " "))
+		}
+		if c.Audience != "" {
+			form.Set("audience", c.Audience)
+		}
+		return requestToken(ctx, c.Client, c.TokenURL, c.ClientID, c.ClientSecret, form)
+	})
+}
+
+func (c *ClientCredentials) invalidate(token string) {
+	c.cache.invalidate(token)
+}
+
+// TokenExchange is a TokenSource getting access tokens with the OAuth2 token exchange
+// grant (RFC 8693), exchanging an OIDC identity token, e.g. the service account token of
+// a Kubernetes pod, for an access token of an identity-aware proxy. The tokens are
+// cached until shortly before they expire.
+type TokenExchange struct {
+	// TokenURL is the token endpoint of the authorization server.
+	TokenURL string
+	// SubjectToken returns the token to exchange. If nil, it is read from
+	// SubjectTokenFile at each exchange, so the rotated tokens are used.
+	SubjectToken     func(ctx context.Context) (string, error)
+	SubjectTokenFile string
+	// SubjectTokenType is the type of the subject token, a JWT by default.
+	SubjectTokenType string
+	// Audience and Scopes are the optional audience and scopes requested.
+	Audience string
+	Scopes   []string
+	// ClientID and ClientSecret optionally authenticate the client.
+	ClientID     string
+	ClientSecret string
+	// Client is the HTTP client of the token requests, http.DefaultClient if nil.
+	Client *http.Client
+
+	cache tokenCache
+}
+
+// Token returns the cached access token, or exchanges the subject token for a new one.
+func (e *TokenExchange) Token(ctx context.Context) (string, error) {
+	return e.cache.get(ctx, func(ctx context.Context) (*tokenResponse, error) {
+		subject, err := e.subjectToken(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error reading the subject token: %v", err)
+		}
+		tokenType := e.SubjectTokenType
+		if tokenType == "" {
+			tokenType = tokenTypeJWT
+		}
+		form := url.Values{
+			"grant_type":         {grantTypeTokenExchange},
+			"subject_token":      {subject},
+			"subject_token_type": {tokenType},
+		}
+		if len(e.Scopes) > 0 {
+			form.Set("scope", strings.Join(e.Scopes, " "))
+		}
+		if e.Audience != "" {
+			form.Set("audience", e.Audience)
+		}
+		return requestToken(ctx, e.Client, e.TokenURL, e.ClientID, e.ClientSecret, form)
+	})
+}
+
+func (e *TokenExchange) invalidate(token string) {
+	e.cache.invalidate(token)
+}
+
+// subjectToken returns the token to exchange.
+func (e *TokenExchange) subjectToken(ctx context.Context) (string, error) {
+	if e.SubjectToken != nil {
+		return e.SubjectToken(ctx)
+	}
+	data, err := os.ReadFile(e.SubjectTokenFile)
+	return strings.TrimSpace(string(data)), err
+}
+
+// tokenCache caches an access token until shortly before it expires.
+type tokenCache struct {
+	mu     sync.Mutex
+	token  string
+	expiry time.Time // zero if the token doesn't expire
+}
+
+// get returns the cached token, or the token fetched if it's missing or expiring.
+func (c *tokenCache) get(ctx context.Context, fetch func(ctx context.Context) (*tokenResponse, error)) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && (c.expiry.IsZero() || time.Now().Add(tokenExpiryMargin).Before(c.expiry)) {
+		return c.token, nil
+	}
+	resp, err := fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.token = resp.AccessToken
+	c.expiry = time.Time{}
+	if resp.ExpiresIn > 0 {
+		c.expiry = time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second)
+	}
+	return c.token, nil
+}
+
+// invalidate drops the cached token if it's token, e.g. rejected before its expiry.
+func (c *tokenCache) invalidate(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token == token {
+		c.token = ""
+	}
+}
+
+// tokenResponse is the response of a token endpoint
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// requestToken requests an access token from a token endpoint, authenticating the client
+// with HTTP basic authentication if clientID is set.
+func requestToken(ctx context.Context, client *http.Client, tokenURL, clientID, clientSecret string, form url.Values) (*tokenResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if clientID != "" {
+		req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := readLimited(ctx, resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var token tokenResponse
+	jsonErr := json.Unmarshal(data, &token)
+	if resp.StatusCode != http.StatusOK {
+		if jsonErr == nil && token.Error != "" {
+			if token.ErrorDescription != "" {
+				return nil, fmt.Errorf("token request failed: %v: %v", token.Error, token.ErrorDescription)
+			}
+			return nil, fmt.Errorf("token request failed: %v", token.Error)
+		}
+		return nil, fmt.Errorf("token request failed: unexpected HTTP status %v", resp.Status)
+	}
+	if jsonErr != nil {
+		return nil, fmt.Errorf("invalid token response: %v", jsonErr)
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("invalid token response: no access token")
+	}
+	return &token, nil
+}
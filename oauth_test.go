@@ -0,0 +1,117 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newOAuthTestServer returns a server issuing the tokens "token-1", "token-2", ...
+// expiring in expiresIn seconds, and serving a config document to the bearer of the
+// valid token.
+func newOAuthTestServer(t *testing.T, expiresIn int, check func(r *http.Request)) (*httptest.Server, *int32) {
+	var issued int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		check(r)
+		n := atomic.AddInt32(&issued, 1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token": "token-%v", "token_type": "Bearer", "expires_in": %v}`, n, expiresIn)
+	})
+	mux.HandleFunc("/config.json", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != fmt.Sprintf("Bearer token-%v", atomic.LoadInt32(&issued)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"port": 8080}`)
+	})
+	return httptest.NewServer(mux), &issued
+}
+
+func TestClientCredentials(t *testing.T) {
+	type Config struct {
+		Port int
+	}
+	server, issued := newOAuthTestServer(t, 3600, func(r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		assert.Equal(t, "app", id)
+		assert.Equal(t, "s3cret", secret)
+		assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "config:read", r.PostForm.Get("scope"))
+	})
+	defer server.Close()
+
+	// Case 1: the token is cached
+	creds := &ClientCredentials{TokenURL: server.URL + "/token", ClientID: "app", ClientSecret: "s3cret", Scopes: []string{"config:read"}}
+	src := &HTTPSource{URL: server.URL + "/config.json", TokenSource: creds}
+	for i := 0; i < 2; i++ {
+		s := &Config{}
+		gf := New(ContinueOnError)
+		gf.AddSource(src)
+		assert.NoError(t, gf.ParseWithArgs(s, []string{}))
+		assert.Equal(t, 8080, s.Port)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(issued))
+
+	// Case 2: a rejected token is renewed
+	atomic.AddInt32(issued, 1) // token-2 is the valid one, as if token-1 was revoked
+	_, err := src.Load(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(issued))
+
+	// Case 3: expiring tokens are renewed
+	server2, issued2 := newOAuthTestServer(t, 10, func(r *http.Request) {})
+	defer server2.Close()
+	creds = &ClientCredentials{TokenURL: server2.URL + "/token"}
+	for i := 0; i < 2; i++ {
+		token, err := creds.Token(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("token-%v", i+1), token)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(issued2))
+
+	// Case 4: token errors
+	errServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error": "invalid_client", "error_description": "unknown client"}`)
+	}))
+	defer errServer.Close()
+	gf := New(ContinueOnError)
+	gf.AddSource(&HTTPSource{URL: server.URL + "/config.json", TokenSource: &ClientCredentials{TokenURL: errServer.URL}})
+	err = gf.ParseWithArgs(&Config{}, []string{})
+	assert.EqualError(t, err, "error loading source "+server.URL+"/config.json: token request failed: invalid_client: unknown client")
+}
+
+func TestTokenExchange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	assert.NoError(t, os.WriteFile(path, []byte("id-token\n"), 0600))
+	server, _ := newOAuthTestServer(t, 3600, func(r *http.Request) {
+		assert.Equal(t, "urn:ietf:params:oauth:grant-type:token-exchange", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "id-token", r.PostForm.Get("subject_token"))
+		assert.Equal(t, "urn:ietf:params:oauth:token-type:jwt", r.PostForm.Get("subject_token_type"))
+		assert.Equal(t, "config-service", r.PostForm.Get("audience"))
+	})
+	defer server.Close()
+
+	s := &struct{ Port int }{}
+	gf := New(ContinueOnError)
+	gf.AddSource(&HTTPSource{
+		URL:         server.URL + "/config.json",
+		TokenSource: &TokenExchange{TokenURL: server.URL + "/token", SubjectTokenFile: path, Audience: "config-service"},
+	})
+	assert.NoError(t, gf.ParseWithArgs(s, []string{}))
+	assert.Equal(t, 8080, s.Port)
+}
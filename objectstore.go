@@ -0,0 +1,153 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotModified is returned by ObjectStore.Get when the object still has the given ETag.
+var ErrNotModified = errors.New("not modified")
+
+// ObjectStore fetches objects from an object storage service. It is typically a thin
+// adapter around the client of the cloud provider SDK (AWS S3, Google Cloud Storage,
+// Azure Blob Storage), which resolves the credentials with the standard SDK chain.
+type ObjectStore interface {
+	// Get returns the content and the ETag of the object key in bucket. If etag is not
+	// empty and matches the current ETag of the object, it returns ErrNotModified.
+	Get(ctx context.Context, bucket string, key string, etag string) ([]byte, string, error)
+}
+
+var (
+	objectStoresMu sync.RWMutex
+	objectStores   = make(map[string]ObjectStore)
+)
+
+// RegisterObjectStore registers the object store serving the URLs of a scheme (e.g.
+// "s3", "gs" or "az") for AddConfigURL.
+func RegisterObjectStore(scheme string, store ObjectStore) {
+	objectStoresMu.Lock()
+	defer objectStoresMu.Unlock()
+	objectStores[scheme] = store
+}
+
+// objectStore returns the object store registered for a scheme.
+func objectStore(scheme string) (ObjectStore, bool) {
+	objectStoresMu.RLock()
+	defer objectStoresMu.RUnlock()
+	store, ok := objectStores[scheme]
+	return store, ok
+}
+
+// ObjectSource loads a config file from an object storage service, its format being
+// detected from the key extension. Unchanged objects are detected with their ETag, and
+// when watching (see Gofig.Watch), the object is polled every PollInterval and the
+// configuration reloaded when its ETag changes.
+type ObjectSource struct {
+	// URL of the object, e.g. "s3://bucket/app/config.yaml".
+	URL string
+	// Store fetches the object, the store registered for the URL scheme if nil.
+	Store ObjectStore
+	// PollInterval is the polling interval when watching, 0 to disable polling.
+	PollInterval time.Duration
+
+	mu   sync.Mutex
+	etag string // ETag of doc
+	seen string // last ETag loaded, or polled by Watch
+	doc  *Document
+}
+
+// NewObjectSource returns a Source loading the config file at the object URL.
+func NewObjectSource(url string) *ObjectSource {
+	return &ObjectSource{URL: url}
+}
+
+// Name returns the object URL.
+func (s *ObjectSource) Name() string {
+	return s.URL
+}
+
+// Load fetches the object if it changed since the last load.
+func (s *ObjectSource) Load(ctx context.Context) (*Document, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, etag, err := s.get(ctx, s.etag)
+	if errors.Is(err, ErrNotModified) && s.doc != nil {
+		return s.doc, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	format := formatFromURL(s.URL)
+	if format == "" {
+		return nil, fmt.Errorf("unable to detect the config format")
+	}
+	s.etag, s.seen = etag, etag
+	s.doc = &Document{Format: format, Data: data}
+	return s.doc, nil
+}
+
+// Watch polls the object until its ETag differs from the last loaded one, or the one the
+// last call returned on.
+func (s *ObjectSource) Watch(ctx context.Context) error {
+	if s.PollInterval <= 0 {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	ticker := time.NewTicker(s.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		s.mu.Lock()
+		etag := s.seen
+		s.mu.Unlock()
+
+		_, newETag, err := s.get(ctx, etag)
+		if errors.Is(err, ErrNotModified) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if newETag != etag || etag == "" {
+			s.mu.Lock()
+			s.seen = newETag // reported once, until the next change
+			s.mu.Unlock()
+			return nil
+		}
+	}
+}
+
+// get fetches the object from its store.
+func (s *ObjectSource) get(ctx context.Context, etag string) ([]byte, string, error) {
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		return nil, "", err
+	}
+	store := s.Store
+	if store == nil {
+		var ok bool
+		store, ok = objectStore(u.Scheme)
+		if !ok {
+			return nil, "", fmt.Errorf("no object store registered for the %v scheme", u.Scheme)
+		}
+	}
+	key := strings.TrimPrefix(path.Clean(u.Path), "/")
+	return store.Get(ctx, u.Host, key, etag)
+}
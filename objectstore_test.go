@@ -0,0 +1,91 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeObjectStore is an in-memory object store
+type fakeObjectStore struct {
+	mu      sync.Mutex
+	objects map[string]string
+	version int
+	gets    int
+}
+
+func (s *fakeObjectStore) Get(ctx context.Context, bucket string, key string, etag string) ([]byte, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gets++
+	data, ok := s.objects[bucket+"/"+key]
+	if !ok {
+		return nil, "", errors.New("no such key")
+	}
+	current := fmt.Sprint(s.version)
+	if etag == current {
+		return nil, "", ErrNotModified
+	}
+	return []byte(data), current, nil
+}
+
+func (s *fakeObjectStore) put(key string, data string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = data
+	s.version++
+}
+
+func TestObjectSource(t *testing.T) {
+	store := &fakeObjectStore{objects: map[string]string{"bucket/app/config.yaml": "str: s3\nint: 5"}}
+	RegisterObjectStore("s3", store)
+
+	s := buildTestStruct()
+	gf := New(ContinueOnError)
+	gf.AddConfigURL("s3://bucket/app/config.yaml")
+	err := gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "s3", s.Str)
+	assert.Equal(t, 5, s.Int)
+
+	// unchanged object, the cached document is used
+	err = gf.Reload()
+	assert.NoError(t, err)
+	assert.Equal(t, 5, s.Int)
+
+	// polling
+	gf.sources[0].(*ObjectSource).PollInterval = 5 * time.Millisecond
+	gf.SetWatchQuietPeriod(20 * time.Millisecond) // longer than the poll interval
+	changed := make(chan struct{}, 1)
+	gf.OnChange(func() { changed <- struct{}{} })
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- gf.Watch(ctx) }()
+
+	store.put("bucket/app/config.yaml", "str: s3\nint: 6")
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("configuration not reloaded")
+	}
+	assert.Equal(t, 6, s.Int)
+
+	cancel()
+	assert.NoError(t, <-done)
+
+	// unknown scheme
+	gf = New(ContinueOnError)
+	gf.AddConfigURL("gs://bucket/config.yaml")
+	err = gf.ParseWithArgs(buildTestStruct(), []string{})
+	assert.EqualError(t, err, "error loading source gs://bucket/config.yaml: no object store registered for the gs scheme")
+}
@@ -0,0 +1,36 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// GenerateOpenAPISchema returns an OpenAPI 3.1 schema object of the configuration struct
+// v, e.g. to be added to the components/schemas of an API rendering and validating
+// config forms. Keys are the config document keys, descriptions come from the desc
+// tags and defaults from the current values of v. The secret fields are write-only and
+// have no default.
+func GenerateOpenAPISchema(v interface{}) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil, errInvalidValue
+	}
+
+	schema := jsonSchema(rv.Elem(), "", schemaOptions{key: configKey, secrets: true})
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// configKey returns the key of a field in the config documents, following its json tag.
+func configKey(sf reflect.StructField) string {
+	key := strings.Split(sf.Tag.Get("json"), ",")[0]
+	if key == "" {
+		key = sf.Name
+	}
+	return strings.ToLower(key)
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateOpenAPISchema(t *testing.T) {
+	type Config struct {
+		MaxConns int    `desc:"maximum number of connections"`
+		LogLevel string `json:"log_level"`
+		Internal string `json:"-"`
+		DB       struct {
+			Password string `secret:"true"`
+			Timeout  Duration
+			Hosts    []string
+		}
+	}
+	s := &Config{MaxConns: 5}
+	s.DB.Password = "hunter2"
+	s.DB.Timeout = Duration(time.Minute)
+
+	schema, err := GenerateOpenAPISchema(s)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "object",
+		"properties": {
+			"maxconns": {"type": "integer", "description": "maximum number of connections", "default": 5},
+			"log_level": {"type": "string"},
+			"db": {
+				"type": "object",
+				"properties": {
+					"password": {"type": "string", "writeOnly": true},
+					"timeout": {"type": "string", "default": "1m0s"},
+					"hosts": {"type": "array", "items": {"type": "string"}}
+				}
+			}
+		}
+	}`, string(schema))
+
+	_, err = GenerateOpenAPISchema(*s)
+	assert.Error(t, err)
+}
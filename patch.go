@@ -0,0 +1,504 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// PatchFormat is the format of a patch applied with ApplyPatch.
+type PatchFormat int
+
+const (
+	// MergePatch is a JSON Merge Patch (RFC 7386)
+	MergePatch PatchFormat = iota
+	// JSONPatch is a JSON Patch (RFC 6902)
+	JSONPatch
+)
+
+// ApplyPatch applies a patch to the effective configuration, as seen in JSON, the keys
+// being matched regardless of their case. Only the values set by the patches are kept, in
+// a layer on top of the pushed document (see Push), so that the other values still
+// follow their sources; a value removed by a patch falls back on them. The patched
+// configuration is validated by recomputing it, then swapped in and the listeners are
+// notified.
+func ApplyPatch(patch []byte, format PatchFormat) error { return gf.ApplyPatch(patch, format) }
+
+// ApplyPatch applies a patch to the effective configuration, as seen in JSON, the keys
+// being matched regardless of their case. Only the values set by the patches are kept, in
+// a layer on top of the pushed document (see Push), so that the other values still
+// follow their sources; a value removed by a patch falls back on them. The patched
+// configuration is validated by recomputing it, then swapped in and the listeners are
+// notified.
+func (gf *Gofig) ApplyPatch(patch []byte, format PatchFormat) error {
+	return gf.update(func() (func(), error) {
+		if gf.target == nil {
+			return nil, errNotParsed
+		}
+
+		effective, err := configTree(gf.target)
+		if err != nil {
+			return nil, err
+		}
+		var layer interface{} = map[string]interface{}{}
+		if gf.patched != nil {
+			err = unmarshalJSON(gf.patched.Data, &layer)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		switch format {
+		case MergePatch:
+			var p interface{}
+			err = unmarshalJSON(patch, &p)
+			if err == nil {
+				layer = mergePatch(layer, matchKeys(p, effective))
+			}
+		case JSONPatch:
+			var ops []patchOp
+			err = json.Unmarshal(patch, &ops)
+			if err == nil {
+				for i := range ops {
+					ops[i].Path = matchPointer(ops[i].Path, effective)
+					ops[i].From = matchPointer(ops[i].From, effective)
+				}
+				var before, after interface{}
+				before, err = toJSONTree(effective)
+				if err == nil {
+					after, err = applyJSONPatch(before, ops)
+				}
+				if err == nil {
+					// keep the changes of the effective configuration in the layer
+					layer = mergePatch(layer, diffTrees(effective, after))
+				}
+			}
+		default:
+			err = fmt.Errorf("unknown patch format %v", format)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error applying patch: %v", err)
+		}
+		if _, ok := layer.(map[string]interface{}); !ok {
+			return nil, errorf("error applying patch: the configuration must be an object")
+		}
+
+		data, err := json.Marshal(layer)
+		if err != nil {
+			return nil, err
+		}
+		prev := gf.patched
+		gf.patched = &Document{Format: "json", Data: data}
+		return func() { gf.patched = prev }, nil
+	})
+}
+
+// matchKeys returns the patch whose object keys are renamed to the keys of the target
+// tree matching them regardless of their case, e.g. "Port" to "port".
+func matchKeys(patch interface{}, target interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, _ := target.(map[string]interface{})
+	matched := make(map[string]interface{}, len(p))
+	for k, v := range p {
+		key := matchKey(k, t)
+		matched[key] = matchKeys(v, t[key])
+	}
+	return matched
+}
+
+// matchKey returns the key of the object matching k regardless of its case, or k.
+func matchKey(k string, object map[string]interface{}) string {
+	if _, ok := object[k]; ok {
+		return k
+	}
+	for key := range object {
+		if strings.EqualFold(key, k) {
+			return key
+		}
+	}
+	return k
+}
+
+// matchPointer returns the JSON Pointer whose reference tokens are renamed to the keys of
+// the tree matching them regardless of their case.
+func matchPointer(pointer string, tree interface{}) string {
+	tokens, err := parsePointer(pointer)
+	if err != nil || len(tokens) == 0 {
+		return pointer
+	}
+	var b strings.Builder
+	for _, t := range tokens {
+		switch node := tree.(type) {
+		case map[string]interface{}:
+			t = matchKey(t, node)
+			tree = node[t]
+		case []interface{}:
+			if i, err := arrayIndex(t, len(node), false); err == nil {
+				tree = node[i]
+			} else {
+				tree = nil
+			}
+		default:
+			tree = nil
+		}
+		b.WriteString("/" + strings.ReplaceAll(strings.ReplaceAll(t, "~", "~0"), "/", "~1"))
+	}
+	return b.String()
+}
+
+// diffTrees returns the JSON Merge Patch turning the object before into after, the
+// values removed being null.
+func diffTrees(before map[string]interface{}, after interface{}) interface{} {
+	a, ok := after.(map[string]interface{})
+	if !ok {
+		return after
+	}
+	diff := make(map[string]interface{})
+	for k, v := range a {
+		prev, existed := before[k]
+		switch {
+		case !existed:
+			diff[k] = v
+		case jsonEqual(prev, v):
+		default:
+			if b, ok := prev.(map[string]interface{}); ok {
+				if _, ok := v.(map[string]interface{}); ok {
+					diff[k] = diffTrees(b, v)
+					continue
+				}
+			}
+			diff[k] = v
+		}
+	}
+	for k := range before {
+		if _, ok := a[k]; !ok {
+			diff[k] = nil
+		}
+	}
+	return diff
+}
+
+// configTree returns the configuration v as a generic JSON tree keyed by the lowercased
+// key paths, following the json tags.
+func configTree(v interface{}) (map[string]interface{}, error) {
+	tree := make(map[string]interface{})
+	err := parseStruct(v, func(path []string, name string, val *reflect.Value, tags *reflect.StructTag) error {
+		leaf, err := toJSONTree(val.Interface())
+		if err != nil {
+			return err
+		}
+		node := tree
+		for _, key := range path[:len(path)-1] {
+			child, ok := node[key].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				node[key] = child
+			}
+			node = child
+		}
+		node[path[len(path)-1]] = leaf
+		return nil
+	}, "json")
+	return tree, err
+}
+
+// toJSONTree returns v as a generic JSON tree.
+func toJSONTree(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var tree interface{}
+	err = unmarshalJSON(data, &tree)
+	return tree, err
+}
+
+// unmarshalJSON unmarshals JSON data keeping the numbers as json.Number.
+func unmarshalJSON(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// mergePatch applies a JSON Merge Patch to the target, see RFC 7386 section 2.
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = make(map[string]interface{})
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+		} else {
+			t[k] = mergePatch(t[k], v)
+		}
+	}
+	return t
+}
+
+// patchOp is a JSON Patch operation.
+type patchOp struct {
+	Op    string           `json:"op"`
+	Path  string           `json:"path"`
+	From  string           `json:"from"`
+	Value *json.RawMessage `json:"value"`
+}
+
+// applyJSONPatch applies the JSON Patch operations to doc, see RFC 6902 section 4.
+func applyJSONPatch(doc interface{}, ops []patchOp) (interface{}, error) {
+	var err error
+	for i, op := range ops {
+		var value interface{}
+		if op.Value != nil {
+			err = unmarshalJSON(*op.Value, &value)
+			if err != nil {
+				return nil, fmt.Errorf("operation %v: %v", i, err)
+			}
+		} else if op.Op == "add" || op.Op == "replace" || op.Op == "test" {
+			return nil, fmt.Errorf("operation %v: missing value", i)
+		}
+
+		switch op.Op {
+		case "add":
+			doc, err = pointerAdd(doc, op.Path, value)
+		case "remove":
+			doc, _, err = pointerRemove(doc, op.Path)
+		case "replace":
+			doc, _, err = pointerRemove(doc, op.Path)
+			if err == nil {
+				doc, err = pointerAdd(doc, op.Path, value)
+			}
+		case "move":
+			if strings.HasPrefix(op.Path, op.From+"/") {
+				err = fmt.Errorf("cannot move %v into one of its children", op.From)
+				break
+			}
+			doc, value, err = pointerRemove(doc, op.From)
+			if err == nil {
+				doc, err = pointerAdd(doc, op.Path, value)
+			}
+		case "copy":
+			value, err = pointerGet(doc, op.From)
+			if err == nil {
+				// deep copy so further operations don't alias the source
+				value, err = toJSONTree(value)
+			}
+			if err == nil {
+				doc, err = pointerAdd(doc, op.Path, value)
+			}
+		case "test":
+			var actual interface{}
+			actual, err = pointerGet(doc, op.Path)
+			if err == nil && !jsonEqual(actual, value) {
+				err = fmt.Errorf("test failed for %v", op.Path)
+			}
+		default:
+			err = fmt.Errorf("unknown operation '%v'", op.Op)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("operation %v: %v", i, err)
+		}
+	}
+	return doc, nil
+}
+
+// jsonEqual compares two JSON trees, numbers being compared by value.
+func jsonEqual(a, b interface{}) bool {
+	na, aok := a.(json.Number)
+	nb, bok := b.(json.Number)
+	if aok && bok {
+		fa, erra := na.Float64()
+		fb, errb := nb.Float64()
+		return erra == nil && errb == nil && fa == fb
+	}
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, v := range av {
+			if w, ok := bv[k]; !ok || !jsonEqual(v, w) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !jsonEqual(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its unescaped reference tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("invalid JSON pointer '%v'", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// arrayIndex parses an array index token, "-" meaning past the last element.
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if token == "-" && allowEnd {
+		return length, nil
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > length || (i == length && !allowEnd) || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index '%v'", token)
+	}
+	return i, nil
+}
+
+// pointerGet returns the value referenced by the pointer.
+func pointerGet(doc interface{}, pointer string) (interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tokens {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			v, ok := node[t]
+			if !ok {
+				return nil, fmt.Errorf("path %v not found", pointer)
+			}
+			doc = v
+		case []interface{}:
+			i, err := arrayIndex(t, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, fmt.Errorf("path %v not found", pointer)
+		}
+	}
+	return doc, nil
+}
+
+// pointerAdd adds a value at the pointer location and returns the updated document.
+func pointerAdd(doc interface{}, pointer string, value interface{}) (interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	parent, err := pointerGet(doc, pointer[:strings.LastIndex(pointer, "/")])
+	if err != nil {
+		return nil, err
+	}
+	last := tokens[len(tokens)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+	case []interface{}:
+		i, err := arrayIndex(last, len(node), true)
+		if err != nil {
+			return nil, err
+		}
+		node = append(node, nil)
+		copy(node[i+1:], node[i:])
+		node[i] = value
+		return replaceParent(doc, tokens[:len(tokens)-1], node), nil
+	default:
+		return nil, fmt.Errorf("path %v not found", pointer)
+	}
+	return doc, nil
+}
+
+// pointerRemove removes the value at the pointer location and returns the updated
+// document and the removed value.
+func pointerRemove(doc interface{}, pointer string) (interface{}, interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, doc, nil
+	}
+
+	parent, err := pointerGet(doc, pointer[:strings.LastIndex(pointer, "/")])
+	if err != nil {
+		return nil, nil, err
+	}
+	last := tokens[len(tokens)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		v, ok := node[last]
+		if !ok {
+			return nil, nil, fmt.Errorf("path %v not found", pointer)
+		}
+		delete(node, last)
+		return doc, v, nil
+	case []interface{}:
+		i, err := arrayIndex(last, len(node), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		v := node[i]
+		node = append(node[:i:i], node[i+1:]...)
+		return replaceParent(doc, tokens[:len(tokens)-1], node), v, nil
+	}
+	return nil, nil, fmt.Errorf("path %v not found", pointer)
+}
+
+// replaceParent replaces the array at the tokens location, as appending to or removing
+// from a slice may reallocate it.
+func replaceParent(doc interface{}, tokens []string, array []interface{}) interface{} {
+	if len(tokens) == 0 {
+		return array
+	}
+	node := doc
+	for _, t := range tokens[:len(tokens)-1] {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			node = n[t]
+		case []interface{}:
+			i, _ := strconv.Atoi(t)
+			node = n[i]
+		}
+	}
+	last := tokens[len(tokens)-1]
+	switch n := node.(type) {
+	case map[string]interface{}:
+		n[last] = array
+	case []interface{}:
+		i, _ := strconv.Atoi(last)
+		n[i] = array
+	}
+	return doc
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyPatch(t *testing.T) {
+	s := buildTestStruct()
+	gf := New(ContinueOnError)
+
+	// Case 1: not parsed yet
+	err := gf.ApplyPatch([]byte(`{"str": "patched"}`), MergePatch)
+	assert.Error(t, err)
+
+	err = gf.ParseWithArgs(s, []string{"-int", "3"})
+	assert.NoError(t, err)
+
+	// Case 2: merge patch, flags still take precedence
+	err = gf.ApplyPatch([]byte(`{"str": "merged", "int": 5, "duration": "1m", "sub": {"str": "renamed-merged"}}`), MergePatch)
+	assert.NoError(t, err)
+	assert.Equal(t, "merged", s.Str)
+	assert.Equal(t, 3, s.Int)
+	assert.Equal(t, Duration(time.Minute), s.Duration)
+	assert.Equal(t, "renamed-merged", s.Sub.RenamedStr)
+
+	// Case 3: JSON patch
+	err = gf.ApplyPatch([]byte(`[
+		{"op": "test", "path": "/str", "value": "merged"},
+		{"op": "replace", "path": "/str", "value": "json-patched"},
+		{"op": "copy", "from": "/str", "path": "/sub/str"}
+	]`), JSONPatch)
+	assert.NoError(t, err)
+	assert.Equal(t, "json-patched", s.Str)
+	assert.Equal(t, "json-patched", s.Sub.RenamedStr)
+
+	// Case 4: failed test or invalid value, nothing is applied
+	err = gf.ApplyPatch([]byte(`[{"op": "test", "path": "/str", "value": "other"}, {"op": "replace", "path": "/str", "value": "x"}]`), JSONPatch)
+	assert.EqualError(t, err, "error applying patch: operation 0: test failed for /str")
+	err = gf.ApplyPatch([]byte(`{"uint": -1}`), MergePatch)
+	assert.Error(t, err)
+	assert.Equal(t, "json-patched", s.Str)
+	assert.Equal(t, uint(99), s.Uint)
+}
+
+func TestApplyPatchLayer(t *testing.T) {
+	type Config struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+		Name string `json:"name"`
+	}
+	src := &testSource{name: "src", doc: &Document{Values: map[string]string{"host": "a", "port": "1", "name": "app"}}}
+	gf := New(ContinueOnError)
+	gf.AddSource(src)
+	cfg := &Config{}
+	err := gf.ParseWithArgs(cfg, []string{})
+	assert.NoError(t, err)
+
+	// Case 1: the keys not patched still follow their source, the keys match regardless
+	// of their case
+	err = gf.ApplyPatch([]byte(`{"Port": 2}`), MergePatch)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, cfg.Port)
+	src.doc = &Document{Values: map[string]string{"host": "b", "port": "3", "name": "app"}}
+	assert.NoError(t, gf.Reload())
+	assert.Equal(t, Config{Host: "b", Port: 2, Name: "app"}, *cfg)
+
+	// Case 2: JSON Patch, only the changed values are kept
+	err = gf.ApplyPatch([]byte(`[{"op": "test", "path": "/Host", "value": "b"}, {"op": "replace", "path": "/NAME", "value": "api"}]`), JSONPatch)
+	assert.NoError(t, err)
+	src.doc = &Document{Values: map[string]string{"host": "c", "port": "3", "name": "app"}}
+	assert.NoError(t, gf.Reload())
+	assert.Equal(t, Config{Host: "c", Port: 2, Name: "api"}, *cfg)
+
+	// Case 3: a removed value falls back on its source
+	err = gf.ApplyPatch([]byte(`{"port": null}`), MergePatch)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, cfg.Port)
+	err = gf.ApplyPatch([]byte(`[{"op": "remove", "path": "/name"}]`), JSONPatch)
+	assert.NoError(t, err)
+	assert.Equal(t, "app", cfg.Name)
+
+	// Case 4: a pushed document replaces the patches
+	assert.NoError(t, gf.ApplyPatch([]byte(`{"port": 4}`), MergePatch))
+	assert.NoError(t, gf.Push(&Document{Format: "json", Data: []byte(`{"host": "pushed"}`)}))
+	assert.Equal(t, Config{Host: "pushed", Port: 3, Name: "app"}, *cfg)
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	tests := []struct {
+		doc      string
+		patch    string
+		expected string
+		err      string
+	}{
+		{`{"foo": "bar"}`, `[{"op": "add", "path": "/baz", "value": "qux"}]`, `{"baz": "qux", "foo": "bar"}`, ""},
+		{`{"foo": ["bar", "baz"]}`, `[{"op": "add", "path": "/foo/1", "value": "qux"}]`, `{"foo": ["bar", "qux", "baz"]}`, ""},
+		{`{"foo": ["bar"]}`, `[{"op": "add", "path": "/foo/-", "value": "qux"}]`, `{"foo": ["bar", "qux"]}`, ""},
+		{`{"baz": "qux", "foo": "bar"}`, `[{"op": "remove", "path": "/baz"}]`, `{"foo": "bar"}`, ""},
+		{`{"foo": ["bar", "qux", "baz"]}`, `[{"op": "remove", "path": "/foo/1"}]`, `{"foo": ["bar", "baz"]}`, ""},
+		{`{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`, `[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`, `{"foo": {"bar": "baz"}, "qux": {"corge": "grault", "thud": "fred"}}`, ""},
+		{`{"foo": ["all", "grass", "cows", "eat"]}`, `[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`, `{"foo": ["all", "cows", "eat", "grass"]}`, ""},
+		{`{"a/b": 1, "m~n": 2}`, `[{"op": "test", "path": "/a~1b", "value": 1.0}, {"op": "replace", "path": "/m~0n", "value": 3}]`, `{"a/b": 1, "m~n": 3}`, ""},
+		{`{"foo": "bar"}`, `[{"op": "add", "path": "/baz/bat", "value": "qux"}]`, ``, "operation 0: path /baz not found"},
+		{`{"foo": ["bar"]}`, `[{"op": "add", "path": "/foo/2", "value": "qux"}]`, ``, "operation 0: invalid array index '2'"},
+		{`{"foo": "bar"}`, `[{"op": "remove", "path": "/baz"}]`, ``, "operation 0: path /baz not found"},
+		{`{"foo": "bar"}`, `[{"op": "frobnicate", "path": "/foo"}]`, ``, "operation 0: unknown operation 'frobnicate'"},
+	}
+
+	for _, tt := range tests {
+		var doc interface{}
+		var ops []patchOp
+		assert.NoError(t, unmarshalJSON([]byte(tt.doc), &doc))
+		assert.NoError(t, json.Unmarshal([]byte(tt.patch), &ops))
+
+		result, err := applyJSONPatch(doc, ops)
+		if tt.err != "" {
+			assert.EqualError(t, err, tt.err, tt.patch)
+			continue
+		}
+		assert.NoError(t, err, tt.patch)
+		actual, _ := json.Marshal(result)
+		assert.JSONEq(t, tt.expected, string(actual), tt.patch)
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	// examples from RFC 7386 appendix A
+	tests := []struct {
+		target   string
+		patch    string
+		expected string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, tt := range tests {
+		var target, patch interface{}
+		assert.NoError(t, unmarshalJSON([]byte(tt.target), &target))
+		assert.NoError(t, unmarshalJSON([]byte(tt.patch), &patch))
+		actual, _ := json.Marshal(mergePatch(target, patch))
+		assert.JSONEq(t, tt.expected, string(actual), tt.patch)
+	}
+}
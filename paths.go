@@ -0,0 +1,106 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// PathList is a list of paths, e.g. a search path, set from flags and environment
+// variables as a single value separated by the list separator of the OS (: on Unix, ;
+// on Windows), like PATH, and from config documents as a list or such a string.
+type PathList []string
+
+// String returns the paths separated by the OS list separator.
+func (l *PathList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, string(os.PathListSeparator))
+}
+
+// Set sets the paths separated by the OS list separator.
+func (l *PathList) Set(s string) error {
+	if s == "" {
+		*l = nil
+		return nil
+	}
+	*l = filepath.SplitList(s)
+	return nil
+}
+
+// UnmarshalJSON unmarshals a JSON array of paths, or a string of paths separated by the
+// OS list separator.
+func (l *PathList) UnmarshalJSON(data []byte) error {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		return l.Set(s)
+	}
+	return json.Unmarshal(data, (*[]string)(l))
+}
+
+// UnmarshalYAML unmarshals a YAML sequence of paths, or a string of paths separated by
+// the OS list separator.
+func (l *PathList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return l.Set(node.Value)
+	}
+	return node.Decode((*[]string)(l))
+}
+
+// UnmarshalTOML unmarshals a TOML array of paths, or a string of paths separated by the
+// OS list separator.
+func (l *PathList) UnmarshalTOML(value interface{}) error {
+	switch value := value.(type) {
+	case string:
+		return l.Set(value)
+	case []interface{}:
+		paths := make(PathList, len(value))
+		for i, v := range value {
+			s, ok := v.(string)
+			if !ok {
+				return errorf("invalid path %v", v)
+			}
+			paths[i] = s
+		}
+		*l = paths
+		return nil
+	}
+	return errorf("invalid path list %v", value)
+}
+
+// windowsPath matches the values starting with a Windows drive letter path, e.g.
+// C:\data, or a UNC path, e.g. \\server\share.
+var windowsPath = regexp.MustCompile(`^(?:[A-Za-z]:[\\/]|\\\\)`)
+
+// expand expands the variable references of an environment variable value (see
+// SetEnvExpand). Only the ${VAR} references are expanded in the Windows paths, so that
+// their $ characters, e.g. C:\$Recycle.Bin or \\server\c$\app, are kept.
+func (gf *Gofig) expand(val string) string {
+	if windowsPath.MatchString(val) {
+		var b strings.Builder
+		for i := 0; i < len(val); i++ {
+			b.WriteByte(val[i])
+			if val[i] != '$' {
+				continue
+			}
+			if i+1 < len(val) && val[i+1] == '$' {
+				b.WriteByte('$')
+				i++
+			} else if i+1 >= len(val) || val[i+1] != '{' {
+				b.WriteByte('$') // escaped
+			}
+		}
+		val = b.String()
+	}
+	return os.Expand(val, gf.expandEnvVar)
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type pathsStruct struct {
+	Data    string   `json:"data" yaml:"data" toml:"data"`
+	Plugins PathList `json:"plugins" yaml:"plugins" toml:"plugins"`
+}
+
+func TestPathList(t *testing.T) {
+	sep := string(os.PathListSeparator)
+	joined := strings.Join([]string{"/usr/lib/app", "/opt/app"}, sep)
+	expected := PathList{"/usr/lib/app", "/opt/app"}
+
+	// Case 1: config documents, as a list or a string
+	for ext, doc := range map[string]string{
+		jsonExtention: `{"plugins": ["/usr/lib/app", "/opt/app"]}`,
+		yamlExtention: "plugins: " + joined,
+		tomlExtention: `plugins = ["/usr/lib/app", "/opt/app"]`,
+	} {
+		s := &pathsStruct{}
+		err := decodeConfig(bytes.NewReader([]byte(doc)), ext, s)
+		assert.NoError(t, err, ext)
+		assert.Equal(t, expected, s.Plugins, ext)
+	}
+
+	// Case 2: environment variable and flag
+	os.Setenv("GFP_PLUGINS", joined)
+	defer os.Unsetenv("GFP_PLUGINS")
+	s := &pathsStruct{}
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("GFP")
+	err := gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, s.Plugins)
+	assert.Equal(t, joined, Flatten(s)["plugins"])
+
+	s = &pathsStruct{}
+	gf = New(ContinueOnError)
+	err = gf.ParseWithArgs(s, []string{"-plugins", "/a" + sep + "/b"})
+	assert.NoError(t, err)
+	assert.Equal(t, PathList{"/a", "/b"}, s.Plugins)
+}
+
+func TestExpandWindowsPaths(t *testing.T) {
+	os.Setenv("GFW_USER", "alice")
+	defer os.Unsetenv("GFW_USER")
+
+	gf := New(ContinueOnError)
+	for val, expected := range map[string]string{
+		`C:\data\app`:                 `C:\data\app`,
+		`C:\$Recycle.Bin`:             `C:\$Recycle.Bin`,
+		`\\server\c$\app`:             `\\server\c$\app`,
+		`D:/users/${GFW_USER}/$cache`: `D:/users/alice/$cache`,
+		`C:\${GFW_MISSING:-C:\tmp}`:   `C:\C:\tmp`,
+		`C:\costs$$`:                  `C:\costs$`,
+		`/home/$GFW_USER`:             `/home/alice`,
+	} {
+		assert.Equal(t, expected, gf.expand(val), val)
+	}
+}
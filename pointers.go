@@ -0,0 +1,41 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import "reflect"
+
+// isScalarType returns whether the values of type t are set from a single string: the
+// scalar kinds, and the times, text types and flag.Value types.
+func isScalarType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return isValueType(t)
+}
+
+// isScalarPointer returns whether t is a pointer to a scalar type, e.g. *int. Such a
+// field is only allocated when a source sets it, so that a nil pointer tells that the
+// field wasn't set, rather than set to the zero value.
+func isScalarPointer(t reflect.Type) bool {
+	return t.Kind() == reflect.Ptr && isScalarType(t.Elem())
+}
+
+// decodePointer sets the pointer field f to a new value decoded by decode, leaving it
+// unchanged if decode fails.
+func decodePointer(f *reflect.Value, decode func(elem *reflect.Value) error) error {
+	ptr := reflect.New(f.Type().Elem())
+	elem := ptr.Elem()
+	err := decode(&elem)
+	if err != nil {
+		return err
+	}
+	f.Set(ptr)
+	return nil
+}
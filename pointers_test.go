@@ -0,0 +1,70 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type pointersStruct struct {
+	Port    *int       `json:"port" yaml:"port" toml:"port"`
+	Debug   *bool      `json:"debug" yaml:"debug" toml:"debug"`
+	Name    *string    `json:"name" yaml:"name" toml:"name"`
+	Expires *time.Time `json:"expires" yaml:"expires" toml:"expires" layout:"2006-01-02"`
+}
+
+func TestScalarPointers(t *testing.T) {
+	// Case 1: unset fields stay nil
+	s := &pointersStruct{}
+	gf := New(ContinueOnError)
+	err := gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, &pointersStruct{}, s)
+	assert.Empty(t, Flatten(s))
+
+	// Case 2: zero values set by flags and environment variables
+	os.Setenv("GFPTR_PORT", "0")
+	defer os.Unsetenv("GFPTR_PORT")
+	s = &pointersStruct{}
+	gf = New(ContinueOnError)
+	gf.SetEnvPrefix("GFPTR")
+	err = gf.ParseWithArgs(s, []string{"-debug", "-name", "", "-expires", "2025-01-31"})
+	assert.NoError(t, err)
+	if assert.NotNil(t, s.Port) && assert.NotNil(t, s.Debug) && assert.NotNil(t, s.Name) && assert.NotNil(t, s.Expires) {
+		assert.Equal(t, 0, *s.Port)
+		assert.True(t, *s.Debug)
+		assert.Equal(t, "", *s.Name)
+		assert.True(t, time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC).Equal(*s.Expires))
+	}
+	assert.Equal(t, map[string]string{"port": "0", "debug": "true", "name": "", "expires": "2025-01-31"}, Flatten(s))
+
+	gf = New(ContinueOnError)
+	err = gf.ParseWithArgs(&pointersStruct{}, []string{"-port", "x"})
+	assert.Error(t, err)
+
+	// Case 3: config documents and overrides
+	s = &pointersStruct{}
+	err = decodeConfig(bytes.NewReader([]byte("port: 0\nexpires: \"2025-01-31\"")), yamlExtention, s)
+	assert.NoError(t, err)
+	if assert.NotNil(t, s.Port) && assert.NotNil(t, s.Expires) {
+		assert.Equal(t, 0, *s.Port)
+		assert.Equal(t, 2025, s.Expires.Year())
+	}
+	assert.Nil(t, s.Debug)
+
+	s = &pointersStruct{}
+	err = Unflatten(map[string]string{"debug": "false"}, s)
+	assert.NoError(t, err)
+	if assert.NotNil(t, s.Debug) {
+		assert.False(t, *s.Debug)
+	}
+	assert.Nil(t, s.Port)
+}
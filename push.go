@@ -0,0 +1,207 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// maxPushSize is the maximum size of a pushed config document, unless bounded by the
+// MaxSize limit.
+const maxPushSize = 10 << 20
+
+// signatureHeader is the header holding the base64-encoded detached signature of a
+// pushed document or patch
+const signatureHeader = "X-Signature"
+
+// errUnsupportedContentType is returned when pushing content of an unknown MIME type.
+var errUnsupportedContentType = errors.New("unsupported content type")
+
+// Push applies a pushed config document on top of the sources (and below the environment
+// variables and flags), replacing any previously pushed document and patches (see
+// ApplyPatch). The configuration is recomputed, swapped in and the listeners are
+// notified. A nil document clears the pushed document and patches.
+func Push(doc *Document) error { return gf.Push(doc) }
+
+// Push applies a pushed config document on top of the sources (and below the environment
+// variables and flags), replacing any previously pushed document and patches (see
+// ApplyPatch). The configuration is recomputed, swapped in and the listeners are
+// notified. A nil document clears the pushed document and patches.
+func (gf *Gofig) Push(doc *Document) error {
+	if doc != nil {
+		err := gf.verifyAttached("pushed document", doc.Data, doc.Signature)
+		if err != nil {
+			return err
+		}
+	}
+	return gf.update(func() (func(), error) {
+		if gf.target == nil {
+			return nil, errNotParsed
+		}
+		prev, prevPatched := gf.pushed, gf.patched
+		gf.pushed, gf.patched = doc, nil
+		return func() { gf.pushed, gf.patched = prev, prevPatched }, nil
+	})
+}
+
+// Serve listens on the TCP address addr and serves the config push endpoint of the Gofig
+// instance of store (see PushHandler), authenticated with the bearer token. The pushed
+// documents and patches are validated, then the readers of store get the new
+// configuration atomically. It always returns a non-nil error.
+func Serve[T any](addr string, store *Store[T], token string) error {
+	return http.ListenAndServe(addr, store.gf.PushHandler(token))
+}
+
+// PushHandler returns an HTTP handler to push config documents, authenticated with the
+// "Authorization: Bearer <token>" header:
+//   - PUT replaces the pushed document, its format is taken from the Content-Type
+//     header (application/json, application/toml or application/yaml);
+//   - PATCH applies a patch to the effective configuration (see ApplyPatch), as a JSON
+//     Merge Patch (application/merge-patch+json) or a JSON Patch (application/json-patch+json);
+//   - DELETE clears the pushed document and patches.
+//
+// The detached signature of a pushed document or patch, verified by the signature
+// verifier (see SetSignatureVerifier), is sent base64-encoded in the X-Signature header.
+//
+// Invalid documents are rejected with a 400 status and leave the configuration untouched.
+// The parsed struct is updated in place: read it through a Store (see Serve) or the
+// accessors when it is pushed concurrently.
+func (gf *Gofig) PushHandler(token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if token == "" || !strings.HasPrefix(auth, "Bearer ") ||
+			subtle.ConstantTimeCompare([]byte(auth[len("Bearer "):]), []byte(token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var err error
+		switch r.Method {
+		case http.MethodPut, http.MethodPatch:
+			var data []byte
+			contentType := r.Header.Get("Content-Type")
+			if isPatch(contentType) != (r.Method == http.MethodPatch) {
+				http.Error(w, errUnsupportedContentType.Error(), http.StatusUnsupportedMediaType)
+				return
+			}
+			var sig []byte
+			if h := r.Header.Get(signatureHeader); h != "" {
+				sig, err = base64.StdEncoding.DecodeString(h)
+				if err != nil {
+					err = errorf("invalid %v header: %v", signatureHeader, err)
+					break
+				}
+			}
+			size := int64(maxPushSize)
+			if gf.limits.MaxSize > 0 {
+				size = gf.limits.MaxSize
+			}
+			data, err = readLimited(withMaxSize(r.Context(), size), http.MaxBytesReader(w, r.Body, size+1))
+			if err == nil {
+				err = gf.pushContent(contentType, data, sig)
+			}
+		case http.MethodDelete:
+			err = gf.Push(nil)
+		default:
+			w.Header().Set("Allow", "PUT, PATCH, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if errors.Is(err, errUnsupportedContentType) {
+			http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// isPatch returns whether the MIME type is a patch type.
+func isPatch(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "application/merge-patch+json" || mediaType == "application/json-patch+json"
+}
+
+// pushContent pushes a config document or applies a patch, depending on its MIME type,
+// verifying its signature sig (nil if it isn't signed).
+func (gf *Gofig) pushContent(contentType string, data []byte, sig []byte) error {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/merge-patch+json", "application/json-patch+json":
+		err := gf.verifyAttached("pushed patch", data, sig)
+		if err != nil {
+			return err
+		}
+		if mediaType == "application/merge-patch+json" {
+			return gf.ApplyPatch(data, MergePatch)
+		}
+		return gf.ApplyPatch(data, JSONPatch)
+	}
+
+	format := formatFromContentType(contentType)
+	if format == "" {
+		return errUnsupportedContentType
+	}
+	return gf.Push(&Document{Format: format, Data: data, Signature: sig})
+}
+
+// Message is a config update message received from a messaging system.
+type Message struct {
+	// ContentType is the MIME type of Data: a config document (application/json,
+	// application/toml or application/yaml) or a patch (application/merge-patch+json or
+	// application/json-patch+json).
+	ContentType string
+	// Data is the config document or patch.
+	Data []byte
+	// Signature is the detached signature of Data, verified by the signature verifier
+	// (see SetSignatureVerifier), or nil if it isn't signed.
+	Signature []byte
+}
+
+// Subscriber receives config update messages, e.g. a thin adapter around a NATS
+// subscription or a Kafka consumer.
+type Subscriber interface {
+	// Next blocks until the next message is received, or ctx is done.
+	Next(ctx context.Context) (*Message, error)
+}
+
+// Subscribe applies the config update messages received by sub, pushing the config
+// documents (see Push) and applying the patches (see ApplyPatch). A message which can't
+// be applied leaves the configuration untouched and is reported to onError, if not nil,
+// the following messages being still applied. It blocks until ctx is done, returning
+// nil, or until receiving a message fails, returning the error.
+func Subscribe(ctx context.Context, sub Subscriber, onError func(msg *Message, err error)) error {
+	return gf.Subscribe(ctx, sub, onError)
+}
+
+// Subscribe applies the config update messages received by sub, pushing the config
+// documents (see Push) and applying the patches (see ApplyPatch). A message which can't
+// be applied leaves the configuration untouched and is reported to onError, if not nil,
+// the following messages being still applied. It blocks until ctx is done, returning
+// nil, or until receiving a message fails, returning the error.
+func (gf *Gofig) Subscribe(ctx context.Context, sub Subscriber, onError func(msg *Message, err error)) error {
+	for {
+		msg, err := sub.Next(ctx)
+		if ctx.Err() != nil {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		err = gf.pushContent(msg.ContentType, msg.Data, msg.Signature)
+		if err != nil && onError != nil {
+			onError(msg, err)
+		}
+	}
+}
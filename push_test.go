@@ -0,0 +1,152 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPushHandler(t *testing.T) {
+	s := buildTestStruct()
+	gf := New(ContinueOnError)
+	err := gf.ParseWithArgs(s, []string{"-int", "3"})
+	assert.NoError(t, err)
+
+	changes := 0
+	gf.OnChange(func() { changes++ })
+
+	server := httptest.NewServer(gf.PushHandler("secret"))
+	defer server.Close()
+
+	push := func(method string, token string, contentType string, body string) int {
+		req, err := http.NewRequest(method, server.URL, strings.NewReader(body))
+		assert.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", contentType)
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Case 1: unauthorized
+	assert.Equal(t, http.StatusUnauthorized, push(http.MethodPut, "wrong", "application/json", `{"str": "pushed"}`))
+	assert.Equal(t, "user-defined", s.Str)
+
+	// Case 2: pushed document, flags still take precedence
+	assert.Equal(t, http.StatusNoContent, push(http.MethodPut, "secret", "application/json", `{"str": "pushed", "int": 5}`))
+	assert.Equal(t, "pushed", s.Str)
+	assert.Equal(t, 3, s.Int)
+	assert.Equal(t, 1, changes)
+
+	// Case 3: invalid document
+	assert.Equal(t, http.StatusBadRequest, push(http.MethodPut, "secret", "application/yaml", `int: abc`))
+	assert.Equal(t, http.StatusUnsupportedMediaType, push(http.MethodPut, "secret", "text/plain", `str: x`))
+	assert.Equal(t, "pushed", s.Str)
+	assert.Equal(t, 1, changes)
+
+	// Case 4: cleared document
+	assert.Equal(t, http.StatusNoContent, push(http.MethodDelete, "secret", "", ""))
+	assert.Equal(t, "user-defined", s.Str)
+	assert.Equal(t, 2, changes)
+}
+
+func TestPushHandlerPatch(t *testing.T) {
+	s := buildTestStruct()
+	gf := New(ContinueOnError)
+	err := gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+
+	handler := gf.PushHandler("secret")
+	patch := func(contentType string, body string) int {
+		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusNoContent, patch("application/merge-patch+json", `{"int": 7}`))
+	assert.Equal(t, 7, s.Int)
+	assert.Equal(t, http.StatusNoContent, patch("application/json-patch+json", `[{"op": "replace", "path": "/str", "value": "patched"}]`))
+	assert.Equal(t, "patched", s.Str)
+	assert.Equal(t, 7, s.Int)
+	assert.Equal(t, http.StatusUnsupportedMediaType, patch("application/json", `{}`))
+}
+
+// chanSubscriber receives messages from a channel
+type chanSubscriber chan *Message
+
+func (c chanSubscriber) Next(ctx context.Context) (*Message, error) {
+	select {
+	case msg := <-c:
+		return msg, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	s := buildTestStruct()
+	gf := New(ContinueOnError)
+	err := gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+
+	changed := make(chan struct{})
+	gf.OnChange(func() { changed <- struct{}{} })
+
+	sub := make(chanSubscriber)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	rejected := make(chan error)
+	go func() {
+		done <- gf.Subscribe(ctx, sub, func(msg *Message, err error) { rejected <- err })
+	}()
+
+	// Case 1: full document
+	sub <- &Message{ContentType: "application/yaml", Data: []byte("str: message\nint: 5")}
+	<-changed
+	assert.Equal(t, "message", s.Str)
+	assert.Equal(t, 5, s.Int)
+
+	// Case 2: patch
+	sub <- &Message{ContentType: "application/merge-patch+json", Data: []byte(`{"int": 6}`)}
+	<-changed
+	assert.Equal(t, "message", s.Str)
+	assert.Equal(t, 6, s.Int)
+
+	// Case 3: invalid messages are reported, the following ones being applied
+	sub <- &Message{ContentType: "text/plain", Data: []byte("str: message")}
+	assert.Equal(t, errUnsupportedContentType, <-rejected)
+	sub <- &Message{ContentType: "application/json", Data: []byte(`{"int": "x"}`)}
+	assert.Error(t, <-rejected)
+	assert.Equal(t, 6, s.Int)
+	sub <- &Message{ContentType: "application/merge-patch+json", Data: []byte(`{"int": 7}`)}
+	<-changed
+	assert.Equal(t, 7, s.Int)
+
+	cancel()
+	assert.NoError(t, <-done)
+
+	// Case 4: the subscription fails
+	err = gf.Subscribe(context.Background(), failingSubscriber{}, nil)
+	assert.EqualError(t, err, "connection closed")
+}
+
+// failingSubscriber fails to receive messages
+type failingSubscriber struct{}
+
+func (failingSubscriber) Next(ctx context.Context) (*Message, error) {
+	return nil, errors.New("connection closed")
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"reflect"
+	"strconv"
+)
+
+const (
+	// minTag is the struct tag holding the minimum value of a numeric or duration field,
+	// e.g. min:"1" or min:"100ms".
+	minTag = "min"
+	// maxTag is the struct tag holding the maximum value of a numeric or duration field,
+	// e.g. max:"65535" or max:"1m".
+	maxTag = "max"
+)
+
+// checkRanges returns an error if a numeric or duration field of v holds a value out of
+// the range of its min and max tags. The nil pointers and the fields of the inactive
+// sections aren't checked.
+func (gf *Gofig) checkRanges(v interface{}) error {
+	return parseStruct(v, func(path []string, name string, f *reflect.Value, tags *reflect.StructTag) error {
+		for _, rule := range []string{minTag, maxTag} {
+			limit, ok := tags.Lookup(rule)
+			if !ok || gf.isInactive(path) {
+				continue
+			}
+			val := *f
+			if val.Kind() == reflect.Ptr {
+				if val.IsNil() {
+					return nil
+				}
+				val = val.Elem()
+			}
+			if !isRangeType(val.Type()) {
+				return errorf("field %v has a %v tag, but isn't a number or a duration", name, rule)
+			}
+			violation, err := checkCompare(val, rule, limit)
+			if err != nil {
+				return errorf("invalid %v tag of field %v: %v", rule, name, err)
+			}
+			if violation != nil {
+				names := gf.fieldNames(v, "json", true)
+				return errorf("invalid field %v (%v): %v", name, names[name].format(gf.translate), violation)
+			}
+		}
+		return nil
+	}, "json")
+}
+
+// isRangeType returns whether the fields of type rt can have a min or max tag.
+func isRangeType(rt reflect.Type) bool {
+	if rt == durationType || rt == timeDurationType {
+		return true
+	}
+	switch rt.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return !isTextType(rt)
+	}
+	return false
+}
+
+// rangeSchema returns the bound of a min or max tag for the JSON schema of a numeric
+// field of type rt.
+func rangeSchema(rt reflect.Type, limit string) (float64, bool) {
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if !isRangeType(rt) || rt == durationType || rt == timeDurationType {
+		return 0, false // the durations are strings
+	}
+	n, err := strconv.ParseFloat(limit, 64)
+	return n, err == nil
+}
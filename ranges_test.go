@@ -0,0 +1,60 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type rangeStruct struct {
+	Port    int      `json:"port" min:"1" max:"65535"`
+	Ratio   float64  `json:"ratio" max:"1"`
+	Timeout Duration `json:"timeout" min:"100ms" max:"1m" default:"1s"`
+	Workers *uint    `json:"workers" min:"1"`
+}
+
+func TestRanges(t *testing.T) {
+	for args, expected := range map[string]string{
+		"":                      "invalid field Port (flag -port, env PORT, key port): must be at least 1",
+		"-port 80":              "",
+		"-port 70000":           "invalid field Port (flag -port, env PORT, key port): must be at most 65535",
+		"-port 80 -ratio 1.5":   "invalid field Ratio (flag -ratio, env RATIO, key ratio): must be at most 1",
+		"-port 80 -timeout 1ms": "invalid field Timeout (flag -timeout, env TIMEOUT, key timeout): must be at least 100ms",
+		"-port 80 -timeout 2m":  "invalid field Timeout (flag -timeout, env TIMEOUT, key timeout): must be at most 1m",
+		"-port 80 -timeout 1s":  "",
+		"-port 80 -workers 0":   "invalid field Workers (flag -workers, env WORKERS, key workers): must be at least 1",
+	} {
+		gf := New(ContinueOnError)
+		err := gf.ParseWithArgs(&rangeStruct{}, strings.Fields(args))
+		if expected == "" {
+			assert.NoError(t, err, args)
+		} else {
+			assert.EqualError(t, err, expected, args)
+		}
+	}
+
+	// invalid tags
+	gf := New(ContinueOnError)
+	err := gf.ParseWithArgs(&struct {
+		Name string `min:"1"`
+	}{}, []string{})
+	assert.EqualError(t, err, "field Name has a min tag, but isn't a number or a duration")
+
+	gf = New(ContinueOnError)
+	err = gf.ParseWithArgs(&struct {
+		Port int `max:"x"`
+	}{}, []string{})
+	assert.EqualError(t, err, "invalid max tag of field Port: invalid parameter 'x' of rule max")
+
+	// the bounds are in the JSON schema
+	schema, err := GenerateOpenAPISchema(&rangeStruct{})
+	assert.NoError(t, err)
+	assert.Contains(t, string(schema), `"maximum": 65535,`)
+	assert.Contains(t, string(schema), `"minimum": 1,`)
+}
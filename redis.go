@@ -0,0 +1,74 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+)
+
+// RedisClient is the subset of a Redis client used by RedisSource, e.g. a thin adapter
+// around github.com/redis/go-redis.
+type RedisClient interface {
+	// Get returns the string value of key.
+	Get(ctx context.Context, key string) (string, error)
+	// HGetAll returns all the fields and values of the hash stored at key.
+	HGetAll(ctx context.Context, key string) (map[string]string, error)
+	// WaitMessage blocks until a message is published on the pub/sub channel, or ctx is done.
+	WaitMessage(ctx context.Context, channel string) error
+}
+
+// RedisSource loads the configuration from Redis, either as a config document stored
+// in a string key (if Format is set) or as a hash whose fields are dot-separated key
+// paths (e.g. "db.port"). If Channel is set, a message published on that pub/sub
+// channel triggers a reload when watching (see Gofig.Watch).
+type RedisSource struct {
+	// Client is the Redis client.
+	Client RedisClient
+	// Key holding the config document or hash.
+	Key string
+	// Format of the config document stored at Key: "json", "toml" or "yaml". If empty,
+	// Key holds a hash.
+	Format string
+	// Channel is the pub/sub channel notifying the config changes.
+	Channel string
+}
+
+// NewRedisSource returns a Source loading the configuration from Redis.
+func NewRedisSource(client RedisClient, key string, format string) *RedisSource {
+	return &RedisSource{Client: client, Key: key, Format: format}
+}
+
+// Name returns the Redis key.
+func (s *RedisSource) Name() string {
+	return "redis " + s.Key
+}
+
+// Load reads the config key.
+func (s *RedisSource) Load(ctx context.Context) (*Document, error) {
+	if s.Format != "" {
+		data, err := s.Client.Get(ctx, s.Key)
+		if err != nil {
+			return nil, err
+		}
+		return &Document{Format: s.Format, Data: []byte(data)}, nil
+	}
+
+	values, err := s.Client.HGetAll(ctx, s.Key)
+	if err != nil {
+		return nil, err
+	}
+	return &Document{Values: values}, nil
+}
+
+// Watch blocks until a message is published on the notification channel. Without a
+// channel, it blocks until ctx is done.
+func (s *RedisSource) Watch(ctx context.Context) error {
+	if s.Channel == "" {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return s.Client.WaitMessage(ctx, s.Channel)
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRedis is an in-memory Redis
+type fakeRedis struct {
+	mu       sync.Mutex
+	strings  map[string]string
+	hashes   map[string]map[string]string
+	messages chan string
+}
+
+func (r *fakeRedis) Get(ctx context.Context, key string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	val, ok := r.strings[key]
+	if !ok {
+		return "", errors.New("redis: nil")
+	}
+	return val, nil
+}
+
+func (r *fakeRedis) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	hash := make(map[string]string)
+	for k, v := range r.hashes[key] {
+		hash[k] = v
+	}
+	return hash, nil
+}
+
+func (r *fakeRedis) WaitMessage(ctx context.Context, channel string) error {
+	for {
+		select {
+		case msg := <-r.messages:
+			if msg == channel {
+				return nil
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (r *fakeRedis) hset(key string, field string, value string, channel string) {
+	r.mu.Lock()
+	r.hashes[key][field] = value
+	r.mu.Unlock()
+	r.messages <- channel
+}
+
+func TestRedisSource(t *testing.T) {
+	redis := &fakeRedis{
+		strings:  map[string]string{"app:config": `{"str": "redis"}`},
+		hashes:   map[string]map[string]string{"app:hash": {"int": "5", "sub.str": "renamed-redis"}},
+		messages: make(chan string),
+	}
+
+	// Case 1: config document
+	s := buildTestStruct()
+	gf := New(ContinueOnError)
+	gf.AddSource(NewRedisSource(redis, "app:config", "json"))
+	err := gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "redis", s.Str)
+
+	// Case 2: hash, with change notifications
+	s = buildTestStruct()
+	gf = New(ContinueOnError)
+	src := NewRedisSource(redis, "app:hash", "")
+	src.Channel = "app:changes"
+	gf.AddSource(src)
+	err = gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, s.Int)
+	assert.Equal(t, "renamed-redis", s.Sub.RenamedStr)
+
+	changed := make(chan struct{}, 1)
+	gf.OnChange(func() { changed <- struct{}{} })
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- gf.Watch(ctx) }()
+
+	redis.hset("app:hash", "int", "6", "app:changes")
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("configuration not reloaded")
+	}
+	assert.Equal(t, 6, s.Int)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
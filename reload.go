@@ -0,0 +1,183 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// errNotParsed is returned when changing the configuration before it was parsed.
+var errNotParsed = errors.New("the configuration must be parsed first")
+
+// OnChange registers a function called each time the parsed configuration changes.
+func OnChange(fn func()) { gf.OnChange(fn) }
+
+// OnChange registers a function called each time the parsed configuration changes.
+func (gf *Gofig) OnChange(fn func()) {
+	gf.mu.Lock()
+	defer gf.mu.Unlock()
+	gf.listeners = append(gf.listeners, fn)
+}
+
+// Override sets a runtime override for the field at the dot-separated key path (which
+// follows the json tags, e.g. "sub.str"). Overrides take precedence over every other
+// source, including flags. The value is decoded like an environment variable.
+// If the configuration was already parsed, it is updated and the listeners are notified.
+func Override(path string, value string) error { return gf.Override(path, value) }
+
+// Override sets a runtime override for the field at the dot-separated key path (which
+// follows the json tags, e.g. "sub.str"). Overrides take precedence over every other
+// source, including flags. The value is decoded like an environment variable.
+// If the configuration was already parsed, it is updated and the listeners are notified.
+func (gf *Gofig) Override(path string, value string) error {
+	path = strings.ToLower(path)
+	return gf.update(func() (func(), error) {
+		prev, existed := gf.overrides[path]
+		if gf.target != nil && !hasKey(gf.target, path) {
+			return nil, fmt.Errorf("unknown key '%v'", path)
+		}
+		if gf.overrides == nil {
+			gf.overrides = make(map[string]string)
+		}
+		gf.overrides[path] = value
+		return func() {
+			if existed {
+				gf.overrides[path] = prev
+			} else {
+				delete(gf.overrides, path)
+			}
+		}, nil
+	})
+}
+
+// ClearOverride removes the runtime override of the field at the key path, if any.
+func ClearOverride(path string) error { return gf.ClearOverride(path) }
+
+// ClearOverride removes the runtime override of the field at the key path, if any.
+func (gf *Gofig) ClearOverride(path string) error {
+	path = strings.ToLower(path)
+	return gf.update(func() (func(), error) {
+		prev, existed := gf.overrides[path]
+		delete(gf.overrides, path)
+		return func() {
+			if existed {
+				gf.overrides[path] = prev
+			}
+		}, nil
+	})
+}
+
+// update applies a change to the sources under the lock then reloads the configuration.
+// The change returns a function to revert it if the new configuration is invalid.
+func (gf *Gofig) update(change func() (revert func(), err error)) error {
+	return gf.updateNotify(change, true)
+}
+
+// updateNotify is update, only notifying the listeners of an unchanged configuration
+// if always is set.
+func (gf *Gofig) updateNotify(change func() (revert func(), err error), always bool) error {
+	gf.mu.Lock()
+	revert, err := change()
+	if err != nil {
+		gf.mu.Unlock()
+		return err
+	}
+	if gf.target == nil {
+		gf.mu.Unlock()
+		return nil // applied by Parse
+	}
+
+	err = gf.reload()
+	if err != nil {
+		revert()
+		gf.mu.Unlock()
+		return err
+	}
+	listeners := gf.listeners
+	if !always && !gf.changed {
+		listeners = nil
+	}
+	gf.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn()
+	}
+	return nil
+}
+
+// reload recomputes the configuration from the user-defined values and all the sources
+// into a new struct, then swaps it into the parsed struct. It must be called with the
+// lock held.
+func (gf *Gofig) reload() error {
+	if gf.target == nil {
+		return errNotParsed
+	}
+
+	v := reflect.New(gf.defaults.Type())
+	v.Elem().Set(deepCopy(gf.defaults, make(map[uintptr]reflect.Value)))
+	err := gf.parseInto(v.Interface(), gf.newFlagSet(), gf.args)
+	if err != nil {
+		return err
+	}
+
+	changed := !reflect.DeepEqual(reflect.ValueOf(gf.target).Elem().Interface(), v.Elem().Interface())
+	gf.rolloutHeld = false
+	if changed && gf.rollout != nil {
+		in, err := gf.rollout.includes(v.Interface())
+		if err != nil {
+			return err
+		}
+		if !in {
+			// keep the current configuration until the change is rolled out to the instance
+			gf.rolloutHeld = true
+			gf.changes, gf.changed = nil, false
+			return nil
+		}
+	}
+
+	gf.changes, err = Diff(gf.target, v.Interface())
+	if err != nil {
+		return err
+	}
+	gf.changed = changed
+	reflect.ValueOf(gf.target).Elem().Set(v.Elem())
+	return nil
+}
+
+// newFlagSet returns a silent flag set used to recompute the configuration, parseInto
+// declares the other flags of the main flag set into it.
+func (gf *Gofig) newFlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+// snapshot returns a deep copy of the struct pointed to by v.
+func snapshot(v interface{}) reflect.Value {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return reflect.Value{}
+	}
+	return deepCopy(rv.Elem(), make(map[uintptr]reflect.Value))
+}
+
+// hasKey returns whether the dot-separated key path maps to a field of v.
+func hasKey(v interface{}, path string) bool {
+	found := false
+	_ = parseStruct(v, func(p []string, name string, val *reflect.Value, tags *reflect.StructTag) error {
+		if strings.Join(p, ".") == path {
+			found = true
+		}
+		return nil
+	}, "json")
+	return found
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverride(t *testing.T) {
+	s := buildTestStruct()
+	gf := New(ContinueOnError)
+
+	changes := 0
+	gf.OnChange(func() { changes++ })
+
+	// Case 1: override set before parsing, above flags
+	err := gf.Override("str", "override")
+	assert.NoError(t, err)
+	err = gf.ParseWithArgs(s, []string{"-str", "flag", "-int", "3"})
+	assert.NoError(t, err)
+	assert.Equal(t, "override", s.Str)
+	assert.Equal(t, 3, s.Int)
+	assert.Equal(t, 0, changes)
+
+	// Case 2: override set after parsing
+	err = gf.Override("Sub.Str", "renamed-override")
+	assert.NoError(t, err)
+	assert.Equal(t, "renamed-override", s.Sub.RenamedStr)
+	assert.Equal(t, 3, s.Int)
+	assert.Equal(t, 1, changes)
+
+	// Case 3: cleared override, back to the flag value
+	err = gf.ClearOverride("str")
+	assert.NoError(t, err)
+	assert.Equal(t, "flag", s.Str)
+	assert.Equal(t, 2, changes)
+
+	// Case 4: invalid values are rejected and not kept
+	err = gf.Override("int", "abc")
+	assert.EqualError(t, err, "error parsing key 'int' with value 'abc' into int (flag -int, env INT, key int)")
+	assert.Equal(t, 3, s.Int)
+	err = gf.Override("unknown", "abc")
+	assert.EqualError(t, err, "unknown key 'unknown'")
+	err = gf.ClearOverride("sub.str")
+	assert.NoError(t, err)
+	assert.Equal(t, "renamed-user-defined", s.Sub.RenamedStr)
+	assert.Equal(t, 3, changes)
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"reflect"
+	"strings"
+)
+
+// ParseSnapshot parses the configuration recorded in a support bundle (see
+// LoadSupportBundle) into v instead of the config files, sources, environment variables
+// and flags, to reproduce a configuration issue locally without the infrastructure it
+// came from. The default tags, the required, enum, range and validation tags, the
+// Validate methods and the added checks are applied as by Parse, but not the paths and
+// scratch directories, which are local to the host. The redacted secrets keep the
+// values of v, so that test values can be set before the call.
+func ParseSnapshot(bundle *SupportBundle, v interface{}) error {
+	return gf.ParseSnapshot(bundle, v)
+}
+
+// ParseSnapshot parses the configuration recorded in a support bundle (see
+// LoadSupportBundle) into v instead of the config files, sources, environment variables
+// and flags, to reproduce a configuration issue locally without the infrastructure it
+// came from. The default tags, the required, enum, range and validation tags, the
+// Validate methods and the added checks are applied as by Parse, but not the paths and
+// scratch directories, which are local to the host. The redacted secrets keep the
+// values of v, so that test values can be set before the call.
+func (gf *Gofig) ParseSnapshot(bundle *SupportBundle, v interface{}) error {
+	return gf.handleError(gf.formatError(gf.replay(bundle, v)))
+}
+
+// replay decodes the configuration of a support bundle into v and checks it, without
+// changing the state of gf.
+func (gf *Gofig) replay(bundle *SupportBundle, v interface{}) error {
+	if bundle == nil || len(bundle.Config) == 0 {
+		return errorf("invalid support bundle: missing %v", bundleConfigFile)
+	}
+	hooks, err := fieldHooks(v)
+	if err != nil {
+		return err
+	}
+	err = applyDefaults(v, hooks)
+	if err != nil {
+		return err
+	}
+	secrets := make(map[string]reflect.Value)
+	err = parseStruct(v, func(path []string, name string, f *reflect.Value, tags *reflect.StructTag) error {
+		if isSecret(tags) {
+			val := reflect.New(f.Type()).Elem()
+			val.Set(*f)
+			secrets[strings.Join(path, ".")] = val
+		}
+		return nil
+	}, "json")
+	if err != nil {
+		return err
+	}
+	t := parseTree(bundle.Config, yamlExtention)
+	decoded := make(map[string]bool)
+	if t.root != nil {
+		collectDecodedKeys(t.root, v, "yaml", decoded)
+	}
+	err = decodeConfigTree(t, v)
+	if err != nil {
+		return errorf("error decoding support bundle %v: %v", bundleConfigFile, gf.withFieldNames(err, v, "yaml", true))
+	}
+	// restore the secrets, redacted by ExportSupportBundle
+	err = parseStruct(v, func(path []string, name string, f *reflect.Value, tags *reflect.StructTag) error {
+		if val, ok := secrets[strings.Join(path, ".")]; ok {
+			f.Set(val)
+		}
+		return nil
+	}, "json")
+	if err != nil {
+		return err
+	}
+
+	// check it with a copy of gf holding the sections of v
+	rp := &Gofig{
+		envPrefix:  gf.envPrefix,
+		translator: gf.translator,
+		validation: gf.validation,
+		checks:     gf.checks,
+		aggregate:  gf.aggregate,
+		scope:      gf.scope,
+		decoded:    decoded,
+	}
+	rp.sections, err = sections(v, "json")
+	if err != nil {
+		return err
+	}
+	errs := errorList{aggregate: rp.aggregate}
+	for _, check := range []func(v interface{}) error{
+		rp.checkRequired,
+		rp.checkEnums,
+		rp.checkRanges,
+		rp.checkValidateTags,
+		rp.validate,
+		rp.runChecks,
+	} {
+		if errs.add(check(v)) {
+			return errs.err()
+		}
+	}
+	return errs.err()
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSnapshot(t *testing.T) {
+	os.Setenv("GFR_WORKERS", "4")
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("GFR")
+	gf.AddSource(&testSource{name: "yaml", doc: &Document{Format: "yaml", Data: []byte("host: db\nport: 9000\npassword: hunter2\n")}})
+	err := gf.ParseWithArgs(&bundleStruct{}, []string{"-level", "debug"})
+	os.Unsetenv("GFR_WORKERS")
+	assert.NoError(t, err)
+	var b bytes.Buffer
+	assert.NoError(t, gf.ExportSupportBundle(&b))
+	bundle, err := LoadSupportBundle(&b)
+	assert.NoError(t, err)
+
+	// Case 1: the recorded values, the local environment and arguments being ignored
+	os.Setenv("GFR_HOST", "local")
+	defer os.Unsetenv("GFR_HOST")
+	gf = New(ContinueOnError)
+	gf.SetEnvPrefix("GFR")
+	cfg := bundleStruct{Password: "test"}
+	err = gf.ParseSnapshot(bundle, &cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, bundleStruct{Host: "db", Port: 9000, Workers: 4, Level: "debug", Password: "test"}, cfg)
+
+	// Case 2: the issues of the recorded configuration with the local checks
+	gf = New(ContinueOnError)
+	gf.AddCheck(func(v interface{}) error {
+		if v.(*bundleStruct).Workers > 2 {
+			return errors.New("too many workers")
+		}
+		return nil
+	})
+	err = gf.ParseSnapshot(bundle, &bundleStruct{})
+	assert.EqualError(t, err, "too many workers")
+	type Config struct {
+		Port int `json:"port" max:"8999"`
+	}
+	err = New(ContinueOnError).ParseSnapshot(bundle, &Config{})
+	assert.EqualError(t, err, "invalid field Port (flag -port, env PORT, key port): must be at most 8999")
+
+	// Case 3: invalid bundles
+	err = New(ContinueOnError).ParseSnapshot(&SupportBundle{}, &bundleStruct{})
+	assert.EqualError(t, err, "invalid support bundle: missing config.yaml")
+	err = New(ContinueOnError).ParseSnapshot(&SupportBundle{Config: []byte("port: x\n")}, &bundleStruct{})
+	var fe *FieldError
+	assert.True(t, errors.As(err, &fe))
+	assert.Equal(t, "Port", fe.Field)
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+const (
+	// requiredTag is the struct tag of the fields which must be set by a source, or have
+	// a default value, e.g. required:"true".
+	requiredTag = "required"
+	// gofigTag is the struct tag of the gofig options of a field, e.g. gofig:"required".
+	gofigTag = "gofig"
+)
+
+// checkRequired returns an error listing every field of v tagged with required:"true" or
+// gofig:"required" which isn't set by any source and still holds its zero value once
+// parsed, with its names in each source, so that a zero value (e.g. false or 0) can be
+// explicitly set. The fields of the inactive sections aren't required.
+func (gf *Gofig) checkRequired(v interface{}) error {
+	names := gf.fieldNames(v, "json", true)
+	keys := gf.fieldNames(v, "json", false)
+	var missing []string
+	_ = parseStruct(v, func(path []string, name string, f *reflect.Value, tags *reflect.StructTag) error {
+		if isRequired(tags) && !gf.isInactive(path) && f.IsZero() && !gf.isSet(names[name], keys[name].key) {
+			missing = append(missing, name)
+		}
+		return nil
+	}, "json")
+	if len(missing) == 0 {
+		return nil
+	}
+
+	fields := make([]string, len(missing))
+	for i, name := range missing {
+		fields[i] = fmt.Sprintf("%v (%v)", name, names[name].format(gf.translate))
+	}
+	if len(fields) == 1 {
+		return errorf("missing required field %v", fields[0])
+	}
+	return errorf("missing required fields %v", strings.Join(fields, "; "))
+}
+
+// isRequired returns whether a field is tagged with required:"true" or gofig:"required".
+func isRequired(tags *reflect.StructTag) bool {
+	if tags.Get(requiredTag) == "true" {
+		return true
+	}
+	for _, opt := range strings.Split(tags.Get(gofigTag), ",") {
+		if strings.TrimSpace(opt) == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+// isSet returns whether a field, with its names n and its key path (not prefixed with
+// the scope of a child), was set by a source during a parse: a config document, the
+// keyring, an env variable, a flag or an override.
+func (gf *Gofig) isSet(n *fieldNames, key string) bool {
+	if gf.decoded[n.key] || gf.decoded[key] || gf.visited[n.flag] {
+		return true
+	}
+	if _, ok := gf.overrides[key]; ok {
+		return true
+	}
+	_, ok := gf.lookupEnv(n.env)
+	return ok
+}
+
+// setFields returns whether a field of v, by name, was set by a source during the parse.
+func (gf *Gofig) setFields(v interface{}) func(name string) bool {
+	names := gf.fieldNames(v, "json", true)
+	keys := gf.fieldNames(v, "json", false)
+	return func(name string) bool {
+		n, ok := names[name]
+		return ok && gf.isSet(n, keys[name].key)
+	}
+}
+
+// collectDecodedKeys adds the key paths of the fields of the struct pointed to by v set
+// by a document tree to decoded, following the json tags. The keys of the tree follow
+// the keys of cfgTag.
+func collectDecodedKeys(tree map[string]interface{}, v interface{}, cfgTag string, decoded map[string]bool) {
+	keys := make(map[string]string)
+	_ = parseStruct(v, func(path []string, name string, f *reflect.Value, tags *reflect.StructTag) error {
+		keys[name] = strings.Join(path, ".")
+		return nil
+	}, "json")
+	_ = parseStruct(v, func(path []string, name string, f *reflect.Value, tags *reflect.StructTag) error {
+		if hasKeyPath(tree, path) {
+			decoded[keys[name]] = true
+		}
+		return nil
+	}, cfgTag)
+}
+
+// hasKeyPath returns whether a document tree holds a non-null value at a key path, the
+// keys being case-insensitive.
+func hasKeyPath(tree map[string]interface{}, path []string) bool {
+	for i, p := range path {
+		var val interface{}
+		found := false
+		for k, v := range tree {
+			if strings.EqualFold(k, p) {
+				val, found = v, true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+		if i == len(path)-1 {
+			return val != nil
+		}
+		if tree, found = val.(map[string]interface{}); !found {
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type requiredStruct struct {
+	Token   string `json:"token" required:"true"`
+	Port    *int   `json:"port" required:"true"`
+	Region  string `json:"region" required:"true"`
+	Metrics struct {
+		Enabled bool   `json:"enabled"`
+		URL     string `json:"url" required:"true"`
+	} `json:"metrics" enabledBy:"metrics.enabled"`
+}
+
+func TestRequiredFields(t *testing.T) {
+	// Case 1: all the missing fields are listed, the default values being set
+	s := &requiredStruct{Region: "eu"}
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("GFR")
+	err := gf.ParseWithArgs(s, []string{})
+	assert.EqualError(t, err, "missing required fields Token (flag -token, env GFR_TOKEN, key token); Port (flag -port, env GFR_PORT, key port)")
+
+	// Case 2: set by any source, a pointer accepting a zero value
+	os.Setenv("GFR_TOKEN", "secret")
+	defer os.Unsetenv("GFR_TOKEN")
+	s = &requiredStruct{Region: "eu"}
+	gf = New(ContinueOnError)
+	gf.SetEnvPrefix("GFR")
+	err = gf.ParseWithArgs(s, []string{"-port", "0"})
+	assert.NoError(t, err)
+
+	// Case 3: the fields of an active section are required
+	gf = New(ContinueOnError)
+	gf.SetEnvPrefix("GFR")
+	err = gf.ParseWithArgs(&requiredStruct{Region: "eu"}, []string{"-port", "0", "-metrics-enabled"})
+	assert.EqualError(t, err, "missing required field Metrics.URL (flag -metrics-url, env GFR_METRICS_URL, key metrics.url)")
+}
+
+func TestRequiredZeroValues(t *testing.T) {
+	type Config struct {
+		Debug   bool   `json:"debug" yaml:"debug" required:"true"`
+		Retries int    `json:"retries" yaml:"retries" gofig:"required"`
+		Name    string `json:"name" yaml:"name" gofig:"omitempty,required"`
+	}
+	parse := func(doc string, args ...string) error {
+		gf := New(ContinueOnError)
+		gf.SetEnvPrefix("GFZ")
+		gf.AddSource(&testSource{name: "yaml", doc: &Document{Format: "yaml", Data: []byte(doc)}})
+		return gf.ParseWithArgs(&Config{}, args)
+	}
+
+	// Case 1: both tags, the null values being unset
+	err := parse("debug: null\n")
+	assert.EqualError(t, err, "missing required fields Debug (flag -debug, env GFZ_DEBUG, key debug); Retries (flag -retries, env GFZ_RETRIES, key retries); Name (flag -name, env GFZ_NAME, key name)")
+
+	// Case 2: zero values explicitly set by a document, a flag or an env variable
+	assert.NoError(t, parse("debug: false\nretries: 0\nname: ''\n"))
+	assert.NoError(t, parse("name: ''\n", "-debug=false", "-retries", "0"))
+	os.Setenv("GFZ_RETRIES", "0")
+	defer os.Unsetenv("GFZ_RETRIES")
+	err = parse("debug: false\n")
+	assert.EqualError(t, err, "missing required field Name (flag -name, env GFZ_NAME, key name)")
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"hash/fnv"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// rolloutPolicy gates the config changes applied by the reloads on the percentage of the
+// fleet they are rolled out to.
+type rolloutPolicy struct {
+	bucket float64 // position of the instance in the fleet, in [0, 100)
+	key    string  // key path of the rollout percentage
+}
+
+// SetRollout rolls the config changes out gradually across a fleet: a reload (of a pushed
+// document or patch, an override or a watched source) changing the configuration is only
+// applied if the instance falls within the rollout percentage, read from the field at the
+// dot-separated key path of the new configuration (e.g. "rollout.percent", a number from
+// 0 to 100). The instances are placed by a hash of instanceID, the hostname if empty, so
+// raising the percentage extends a change to more instances, the others keeping their
+// current configuration (see RolloutPending). Give the field a default of 100 so the
+// documents not setting it are applied everywhere. The initial parse isn't gated.
+func SetRollout(instanceID string, key string) { gf.SetRollout(instanceID, key) }
+
+// SetRollout rolls the config changes out gradually across a fleet: a reload (of a pushed
+// document or patch, an override or a watched source) changing the configuration is only
+// applied if the instance falls within the rollout percentage, read from the field at the
+// dot-separated key path of the new configuration (e.g. "rollout.percent", a number from
+// 0 to 100). The instances are placed by a hash of instanceID, the hostname if empty, so
+// raising the percentage extends a change to more instances, the others keeping their
+// current configuration (see RolloutPending). Give the field a default of 100 so the
+// documents not setting it are applied everywhere. The initial parse isn't gated.
+func (gf *Gofig) SetRollout(instanceID string, key string) {
+	if instanceID == "" {
+		instanceID, _ = os.Hostname()
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(instanceID))
+	gf.mu.Lock()
+	defer gf.mu.Unlock()
+	gf.rollout = &rolloutPolicy{
+		bucket: float64(h.Sum32()%10000) / 100,
+		key:    strings.ToLower(key),
+	}
+}
+
+// RolloutPending returns whether a config change is held back by the rollout policy,
+// the instance being out of its rollout percentage.
+func RolloutPending() bool { return gf.RolloutPending() }
+
+// RolloutPending returns whether a config change is held back by the rollout policy,
+// the instance being out of its rollout percentage.
+func (gf *Gofig) RolloutPending() bool {
+	gf.mu.Lock()
+	defer gf.mu.Unlock()
+	return gf.rolloutHeld
+}
+
+// includes returns whether the instance falls within the rollout percentage of the
+// configuration v.
+func (p *rolloutPolicy) includes(v interface{}) (bool, error) {
+	f, ok := fieldByKey(v, p.key)
+	if !ok {
+		return false, errorf("unknown rollout key '%v'", p.key)
+	}
+	var percent float64
+	switch f.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		percent = float64(f.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		percent = float64(f.Uint())
+	case reflect.Float32, reflect.Float64:
+		percent = f.Float()
+	default:
+		return false, errorf("rollout key '%v' must be a number, not %v", p.key, f.Type())
+	}
+	return p.bucket < percent, nil
+}
+
+// fieldByKey returns the field of v at the dot-separated key path.
+func fieldByKey(v interface{}, path string) (reflect.Value, bool) {
+	var field reflect.Value
+	found := false
+	_ = parseStruct(v, func(p []string, name string, val *reflect.Value, tags *reflect.StructTag) error {
+		if strings.Join(p, ".") == path {
+			field, found = *val, true
+		}
+		return nil
+	}, "json")
+	return field, found
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type rolloutStruct struct {
+	Str     string `json:"str"`
+	Rollout struct {
+		Percent float64 `json:"percent"`
+	} `json:"rollout"`
+}
+
+func TestRollout(t *testing.T) {
+	s := &rolloutStruct{Str: "initial"}
+	s.Rollout.Percent = 100
+	gf := New(ContinueOnError)
+	gf.SetRollout("web-3", "Rollout.Percent")
+	err := gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	bucket := gf.rollout.bucket
+
+	// Case 1: the instance is out of the rollout, the change is held back
+	err = gf.Push(&Document{Format: "json", Data: []byte(fmt.Sprintf(`{"str": "canary", "rollout": {"percent": %v}}`, bucket))})
+	assert.NoError(t, err)
+	assert.Equal(t, "initial", s.Str)
+	assert.True(t, gf.RolloutPending())
+	assert.Empty(t, gf.Changes())
+
+	// Case 2: the rollout is extended to the instance
+	err = gf.Push(&Document{Format: "json", Data: []byte(fmt.Sprintf(`{"str": "canary", "rollout": {"percent": %v}}`, bucket+0.01))})
+	assert.NoError(t, err)
+	assert.Equal(t, "canary", s.Str)
+	assert.False(t, gf.RolloutPending())
+
+	// Case 3: the documents not setting the percentage are applied everywhere
+	err = gf.Push(&Document{Format: "json", Data: []byte(`{"str": "everywhere"}`)})
+	assert.NoError(t, err)
+	assert.Equal(t, "everywhere", s.Str)
+
+	// Case 4: unknown key
+	gf = New(ContinueOnError)
+	gf.SetRollout("web-3", "percent")
+	err = gf.ParseWithArgs(&rolloutStruct{}, []string{})
+	assert.NoError(t, err)
+	err = gf.Override("str", "x")
+	assert.EqualError(t, err, "unknown rollout key 'percent'")
+}
+
+func TestRolloutBuckets(t *testing.T) {
+	// the instances are spread across the fleet
+	in := 0
+	for i := 0; i < 1000; i++ {
+		gf := New(ContinueOnError)
+		gf.SetRollout(fmt.Sprintf("pod-%v", i), "percent")
+		if gf.rollout.bucket < 25 {
+			in++
+		}
+	}
+	assert.InDelta(t, 250, in, 50)
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// createTag and minFreeTag are the struct tags of the ScratchDir fields: create:"0750"
+// creates the directory with these permissions if it doesn't exist, and minFree:"1GiB"
+// requires this space to be available in it.
+const (
+	createTag  = "create"
+	minFreeTag = "minFree"
+)
+
+// ScratchDir is a directory for temporary or spool files. Once parsed, an empty value is
+// resolved to the temporary directory of the OS and a relative path is made absolute.
+// The directory must exist, unless the field has a create tag holding the permissions
+// to create it with, e.g. create:"0700", and the space available in it is checked
+// against the minFree tag, e.g. minFree:"512MB" (where the OS reports it).
+type ScratchDir string
+
+// Join joins path elements to the directory.
+func (d ScratchDir) Join(elem ...string) string {
+	return filepath.Join(append([]string{string(d)}, elem...)...)
+}
+
+var scratchDirType = reflect.TypeOf(ScratchDir(""))
+
+// sizeUnits are the units of the sizes of the minFree tag
+var sizeUnits = map[string]uint64{
+	"":    1,
+	"B":   1,
+	"KB":  1e3,
+	"MB":  1e6,
+	"GB":  1e9,
+	"TB":  1e12,
+	"KiB": 1 << 10,
+	"MiB": 1 << 20,
+	"GiB": 1 << 30,
+	"TiB": 1 << 40,
+}
+
+// parseSize parses a size in bytes with an optional unit, e.g. "512MB" or "1GiB".
+func parseSize(s string) (uint64, bool) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		i = len(s)
+	}
+	unit, ok := sizeUnits[strings.TrimSpace(s[i:])]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return uint64(n * float64(unit)), true
+}
+
+// formatSize formats a size in bytes with a binary unit, e.g. "1.5GiB".
+func formatSize(n uint64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	f := float64(n)
+	i := 0
+	for f >= 1024 && i < len(units)-1 {
+		f /= 1024
+		i++
+	}
+	return strconv.FormatFloat(math.Round(f*10)/10, 'f', -1, 64) + units[i]
+}
+
+// resolveScratchDirs resolves the ScratchDir fields of v, creating them if their create
+// tag is set and checking their available space. The fields of the inactive sections
+// aren't resolved.
+func (gf *Gofig) resolveScratchDirs(v interface{}) error {
+	return parseStruct(v, func(path []string, name string, f *reflect.Value, tags *reflect.StructTag) error {
+		if f.Type() != scratchDirType {
+			return nil
+		}
+		var perm os.FileMode
+		create, hasCreate := tags.Lookup(createTag)
+		if hasCreate {
+			n, err := strconv.ParseUint(create, 8, 32)
+			if err != nil || n&^uint64(os.ModePerm) != 0 {
+				return errorf("invalid create tag '%v' of field %v, it must be octal permissions, e.g. 0750", create, name)
+			}
+			perm = os.FileMode(n)
+		}
+		var minFree uint64
+		if tag, ok := tags.Lookup(minFreeTag); ok {
+			if minFree, ok = parseSize(tag); !ok {
+				return errorf("invalid minFree tag '%v' of field %v, it must be a size, e.g. 512MB or 1GiB", tag, name)
+			}
+		}
+		if gf.isInactive(path) {
+			return nil
+		}
+
+		dir := f.String()
+		if dir == "" {
+			dir = os.TempDir()
+		}
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			return &fieldError{name: name, err: errorf("error resolving directory '%v': %v", f.String(), err)}
+		}
+		if hasCreate {
+			err = os.MkdirAll(dir, perm)
+			if err != nil {
+				return &fieldError{name: name, err: errorf("error creating directory '%v': %v", dir, err)}
+			}
+		}
+		err = checkPath(dir, "dir", false, false)
+		if err != nil {
+			return &fieldError{name: name, err: err}
+		}
+		if minFree > 0 {
+			if free, ok := freeSpace(dir); ok && free < minFree {
+				return &fieldError{name: name, err: errorf("directory '%v' has %v available, less than the minimum of %v", dir, formatSize(free), formatSize(minFree))}
+			}
+		}
+		f.SetString(dir)
+		return nil
+	}, "json")
+}
@@ -0,0 +1,14 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux && !darwin && !freebsd
+
+package gofig
+
+// freeSpace returns the space available in the file system of dir, and whether it is
+// known, which it isn't on this OS.
+func freeSpace(dir string) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,20 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build linux || darwin || freebsd
+
+package gofig
+
+import "syscall"
+
+// freeSpace returns the space available to unprivileged users in the file system of
+// dir, and whether it is known.
+func freeSpace(dir string) (uint64, bool) {
+	var st syscall.Statfs_t
+	if syscall.Statfs(dir, &st) != nil {
+		return 0, false
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), true
+}
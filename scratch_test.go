@@ -0,0 +1,58 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScratchDir(t *testing.T) {
+	type config struct {
+		Tmp   ScratchDir `json:"tmp"`
+		Spool ScratchDir `json:"spool" create:"0750" minFree:"1KiB"`
+		Cache ScratchDir `json:"cache"`
+	}
+	dir := t.TempDir()
+	spool := filepath.Join(dir, "spool", "out")
+
+	// Case 1: the empty directory is the temp directory, the spool directory is created
+	s := &config{Cache: ScratchDir(dir)}
+	gf := New(ContinueOnError)
+	err := gf.ParseWithArgs(s, []string{"-spool", spool})
+	assert.NoError(t, err)
+	tmp, _ := filepath.Abs(os.TempDir())
+	assert.Equal(t, ScratchDir(tmp), s.Tmp)
+	assert.Equal(t, ScratchDir(spool), s.Spool)
+	assert.Equal(t, filepath.Join(spool, "a.msg"), s.Spool.Join("a.msg"))
+	fi, err := os.Stat(spool)
+	if assert.NoError(t, err) {
+		assert.True(t, fi.IsDir())
+	}
+
+	// Case 2: missing directory without a create tag
+	missing := filepath.Join(dir, "missing")
+	gf = New(ContinueOnError)
+	err = gf.ParseWithArgs(&config{}, []string{"-cache", missing})
+	assert.EqualError(t, err, "directory '"+missing+"' doesn't exist (flag -cache, env CACHE, key cache)")
+
+	// Case 3: invalid tags
+	gf = New(ContinueOnError)
+	err = gf.ParseWithArgs(&struct {
+		Spool ScratchDir `json:"spool" minFree:"lots"`
+	}{}, []string{})
+	assert.EqualError(t, err, "invalid minFree tag 'lots' of field Spool, it must be a size, e.g. 512MB or 1GiB")
+
+	for s, expected := range map[string]uint64{"512": 512, "1.5KiB": 1536, "2 MB": 2e6, "1GiB": 1 << 30} {
+		n, ok := parseSize(s)
+		assert.True(t, ok, s)
+		assert.Equal(t, expected, n, s)
+	}
+	assert.Equal(t, "1.5GiB", formatSize(3<<29))
+}
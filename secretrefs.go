@@ -0,0 +1,198 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"reflect"
+	"strings"
+)
+
+// SecretResolver resolves the secret references of a scheme, e.g. op://vault/item/field.
+type SecretResolver interface {
+	// Resolve returns the secret of a reference, including its scheme.
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+// SetSecretResolver sets the resolver of the secret references of a scheme, e.g. "op"
+// for the op://vault/item/field references of OnePasswordCLI. Once the configuration
+// is parsed, the string fields (and the strings of the slice and map fields) holding
+// such a reference, from any source, are set to the secret.
+func SetSecretResolver(scheme string, r SecretResolver) { gf.SetSecretResolver(scheme, r) }
+
+// SetSecretResolver sets the resolver of the secret references of a scheme, e.g. "op"
+// for the op://vault/item/field references of OnePasswordCLI. Once the configuration
+// is parsed, the string fields (and the strings of the slice and map fields) holding
+// such a reference, from any source, are set to the secret.
+func (gf *Gofig) SetSecretResolver(scheme string, r SecretResolver) {
+	if gf.resolvers == nil {
+		gf.resolvers = make(map[string]SecretResolver)
+	}
+	gf.resolvers[scheme] = r
+}
+
+// resolveSecrets replaces the secret references of the string fields of v by their
+// secret, and returns the names of the fields set from a reference, which are redacted
+// like the secret fields.
+func (gf *Gofig) resolveSecrets(ctx context.Context, v interface{}) (map[string]bool, error) {
+	resolved := make(map[string]bool)
+	if len(gf.resolvers) == 0 {
+		return resolved, nil
+	}
+	err := parseStruct(v, func(path []string, name string, f *reflect.Value, tags *reflect.StructTag) error {
+		if gf.isInactive(path) {
+			return nil
+		}
+		ok, err := gf.resolveValue(ctx, *f)
+		if err != nil {
+			return &fieldError{name: name, source: ProvenanceSecret, err: err}
+		}
+		if ok {
+			resolved[name] = true
+		}
+		return nil
+	}, "json")
+	return resolved, err
+}
+
+// isSecretField returns whether the field of a name is a secret: tagged as a secret, read
+// from the keyring or a credential, or set from a secret reference by the last parse.
+func (gf *Gofig) isSecretField(name string, tags *reflect.StructTag) bool {
+	return isSecret(tags) || gf.resolved[name]
+}
+
+// resolveValue replaces the secret references of a string, or of the strings of a slice
+// or map, by their secret, and returns whether one was.
+func (gf *Gofig) resolveValue(ctx context.Context, val reflect.Value) (resolved bool, err error) {
+	switch val.Kind() {
+	case reflect.String:
+		ref := val.String()
+		i := strings.Index(ref, "://")
+		if i < 0 {
+			return false, nil
+		}
+		r, ok := gf.resolvers[ref[:i]]
+		if !ok {
+			return false, nil
+		}
+		secret, err := r.Resolve(ctx, ref)
+		if err != nil {
+			return false, errorf("error resolving secret reference '%v': %v", ref, err)
+		}
+		val.SetString(secret)
+		return true, nil
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			ok, err := gf.resolveValue(ctx, val.Index(i))
+			if err != nil {
+				return false, err
+			}
+			resolved = resolved || ok
+		}
+	case reflect.Map:
+		if val.Type().Elem().Kind() != reflect.String {
+			return false, nil
+		}
+		iter := val.MapRange()
+		for iter.Next() {
+			elem := reflect.New(val.Type().Elem()).Elem()
+			elem.Set(iter.Value())
+			ok, err := gf.resolveValue(ctx, elem)
+			if err != nil {
+				return false, err
+			}
+			resolved = resolved || ok
+			val.SetMapIndex(iter.Key(), elem)
+		}
+	}
+	return resolved, nil
+}
+
+// OnePasswordCLI returns a SecretResolver of the op://vault/item/field references (or
+// op://vault/item/section/field) of 1Password, read with the op command, which must be
+// signed in (e.g. with OP_SERVICE_ACCOUNT_TOKEN, or the desktop app integration).
+func OnePasswordCLI() SecretResolver {
+	return onePasswordCLI{}
+}
+
+type onePasswordCLI struct{}
+
+// Resolve reads a secret reference with op read.
+func (onePasswordCLI) Resolve(ctx context.Context, ref string) (string, error) {
+	out, err := runSecretCommand(ctx, "op", "read", "--no-newline", ref)
+	return string(out), err
+}
+
+// BitwardenCLI returns a SecretResolver of the bw://item/field references of Bitwarden,
+// read with the bw command, which must be unlocked (e.g. with BW_SESSION). The item is
+// its name or id, and the field one of password, username, notes and totp, or the name
+// of a custom field.
+func BitwardenCLI() SecretResolver {
+	return bitwardenCLI{}
+}
+
+type bitwardenCLI struct{}
+
+// Resolve reads a secret reference with bw get.
+func (bitwardenCLI) Resolve(ctx context.Context, ref string) (string, error) {
+	path := strings.TrimPrefix(ref, "bw://")
+	i := strings.LastIndexByte(path, '/')
+	if i <= 0 {
+		return "", errors.New("invalid Bitwarden reference, it must be bw://item/field")
+	}
+	item, field := path[:i], path[i+1:]
+	if strings.HasPrefix(item, "-") {
+		return "", fmt.Errorf("invalid Bitwarden item '%v'", item) // an option of bw
+	}
+	switch field {
+	case "password", "username", "notes", "totp":
+		out, err := runSecretCommand(ctx, "bw", "get", field, item)
+		return string(out), err
+	}
+
+	// custom field
+	out, err := runSecretCommand(ctx, "bw", "get", "item", item)
+	if err != nil {
+		return "", err
+	}
+	var fields struct {
+		Fields []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"fields"`
+	}
+	err = json.Unmarshal(out, &fields)
+	if err != nil {
+		return "", err
+	}
+	for _, f := range fields.Fields {
+		if f.Name == field {
+			return f.Value, nil
+		}
+	}
+	return "", fmt.Errorf("field %v not found in item %v", field, item)
+}
+
+// runSecretCommand runs the command of a password manager, returning its output and
+// reporting its error message.
+func runSecretCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%v: %v", err, msg)
+		}
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretResolvers(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake commands are shell scripts")
+	}
+	type Config struct {
+		DB struct {
+			Password string
+			User     string
+		}
+		APIKey  string
+		Tokens  []string
+		Headers map[string]string
+		URL     string
+	}
+
+	// fake op and bw commands
+	dir := t.TempDir()
+	op := `#!/bin/sh
+case "$3" in
+  op://prod/db/password) printf 'db-s3cret' ;;
+  op://prod/api/key) printf 'api-key' ;;
+  *) echo "[ERROR] could not read secret" >&2; exit 1 ;;
+esac
+`
+	bw := `#!/bin/sh
+if [ "$2" = "username" ] && [ "$3" = "db" ]; then printf 'admin'; exit 0; fi
+if [ "$2" = "item" ] && [ "$3" = "ci" ]; then printf '{"fields": [{"name": "token", "value": "ci-token"}]}'; exit 0; fi
+echo "Not found." >&2; exit 1
+`
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "op"), []byte(op), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "bw"), []byte(bw), 0755))
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+	defer os.Setenv("PATH", path)
+
+	// Case 1: references from any source, other values unchanged
+	os.Setenv("GFR_APIKEY", "op://prod/api/key")
+	defer os.Unsetenv("GFR_APIKEY")
+	s := &Config{URL: "https://example.com", Headers: map[string]string{"Authorization": "bw://ci/token"}}
+	s.DB.Password = "op://prod/db/password"
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("GFR")
+	gf.SetSecretResolver("op", OnePasswordCLI())
+	gf.SetSecretResolver("bw", BitwardenCLI())
+	err := gf.ParseWithArgs(s, []string{"-db-user", "bw://db/username", "-tokens", "plain,bw://ci/token"})
+	assert.NoError(t, err)
+	assert.Equal(t, "db-s3cret", s.DB.Password)
+	assert.Equal(t, "admin", s.DB.User)
+	assert.Equal(t, "api-key", s.APIKey)
+	assert.Equal(t, []string{"plain", "ci-token"}, s.Tokens)
+	assert.Equal(t, map[string]string{"Authorization": "ci-token"}, s.Headers)
+	assert.Equal(t, "https://example.com", s.URL)
+
+	// Case 2: errors
+	for ref, expected := range map[string]string{
+		"op://prod/unknown/x":  "error resolving secret reference 'op://prod/unknown/x': exit status 1: [ERROR] could not read secret (flag -apikey, env GFR_APIKEY, key apikey)",
+		"bw://ci/other":        "error resolving secret reference 'bw://ci/other': field other not found in item ci (flag -apikey, env GFR_APIKEY, key apikey)",
+		"bw://unknown":         "error resolving secret reference 'bw://unknown': invalid Bitwarden reference, it must be bw://item/field (flag -apikey, env GFR_APIKEY, key apikey)",
+		"bw://--help/password": "error resolving secret reference 'bw://--help/password': invalid Bitwarden item '--help' (flag -apikey, env GFR_APIKEY, key apikey)",
+	} {
+		os.Setenv("GFR_APIKEY", ref)
+		gf = New(ContinueOnError)
+		gf.SetEnvPrefix("GFR")
+		gf.SetSecretResolver("op", OnePasswordCLI())
+		gf.SetSecretResolver("bw", BitwardenCLI())
+		err = gf.ParseWithArgs(&Config{}, []string{})
+		assert.EqualError(t, err, expected)
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// defaultSelector is the key of the value of a selector map used when no label
+// selector matches.
+const defaultSelector = "default"
+
+// SetLabels sets the labels of the instance, e.g. {"region": "eu", "tier": "canary"},
+// resolving the value selectors of the config documents: a map whose keys are label
+// selectors, such as "region=eu" (or "region=eu,tier=canary" for several labels), and
+// an optional "default" key, e.g. `value: {default: 10, "region=eu": 20}`, is replaced by
+// the value of the matching selector having the most labels, or by its default value.
+// The field is left unchanged if none matches and there's no default. The selectors
+// aren't resolved until the labels are set or the facts added (see AddFacts), the
+// selectors matching the facts by their key, e.g. "facts.zone=eu-west-1a".
+func SetLabels(labels map[string]string) { gf.SetLabels(labels) }
+
+// SetLabels sets the labels of the instance, e.g. {"region": "eu", "tier": "canary"},
+// resolving the value selectors of the config documents: a map whose keys are label
+// selectors, such as "region=eu" (or "region=eu,tier=canary" for several labels), and
+// an optional "default" key, e.g. `value: {default: 10, "region=eu": 20}`, is replaced by
+// the value of the matching selector having the most labels, or by its default value.
+// The field is left unchanged if none matches and there's no default. The selectors
+// aren't resolved until the labels are set or the facts added (see AddFacts), the
+// selectors matching the facts by their key, e.g. "facts.zone=eu-west-1a".
+func (gf *Gofig) SetLabels(labels map[string]string) {
+	gf.labels = make(map[string]string, len(labels))
+	for k, v := range labels {
+		gf.labels[k] = v
+	}
+}
+
+// selectValues resolves the value selectors of a config document tree against the
+// labels.
+func (gf *Gofig) selectValues(t *docTree) error {
+	if t.root == nil {
+		return nil // syntax errors are left to the decoder
+	}
+	selected, err := gf.selectTree(t.root, "")
+	t.changed = t.changed || selected
+	return err
+}
+
+// selectTree replaces the selector maps of a document tree by their selected value, and
+// returns whether some were replaced.
+func (gf *Gofig) selectTree(node interface{}, path string) (bool, error) {
+	selected := false
+	switch node := node.(type) {
+	case map[string]interface{}:
+		for k, val := range node {
+			key := k
+			if path != "" {
+				key = path + "." + k
+			}
+			for {
+				m, ok := val.(map[string]interface{})
+				if !ok || !isSelectorMap(m) {
+					break
+				}
+				var found bool
+				var err error
+				val, found, err = gf.selectValue(m, key)
+				if err != nil {
+					return false, err
+				}
+				if found {
+					node[k] = val
+				} else {
+					delete(node, k)
+				}
+				selected = true
+			}
+			sel, err := gf.selectTree(val, key)
+			if err != nil {
+				return false, err
+			}
+			selected = selected || sel
+		}
+	case []interface{}:
+		for i, val := range node {
+			key := fmt.Sprintf("%v[%v]", path, i)
+			if m, ok := val.(map[string]interface{}); ok && isSelectorMap(m) {
+				return false, errorf("value selectors aren't supported in lists, at key '%v'", key)
+			}
+			sel, err := gf.selectTree(val, key)
+			if err != nil {
+				return false, err
+			}
+			selected = selected || sel
+		}
+	}
+	return selected, nil
+}
+
+// selectValue returns the value of the matching selector of m having the most labels,
+// or its default value, and whether there's one.
+func (gf *Gofig) selectValue(m map[string]interface{}, key string) (interface{}, bool, error) {
+	selectors := make([]string, 0, len(m))
+	for sel := range m {
+		selectors = append(selectors, sel)
+	}
+	sort.Strings(selectors)
+
+	best, bestLabels := "", 0
+	for _, sel := range selectors {
+		if sel == defaultSelector {
+			continue
+		}
+		conds := strings.Split(sel, ",")
+		if !gf.matchLabels(conds) {
+			continue
+		}
+		if len(conds) == bestLabels {
+			return nil, false, errorf("ambiguous value selectors '%v' and '%v' at key '%v'", best, sel, key)
+		}
+		if len(conds) > bestLabels {
+			best, bestLabels = sel, len(conds)
+		}
+	}
+	if best == "" {
+		best = defaultSelector
+	}
+	val, ok := m[best]
+	return val, ok, nil
+}
+
+// matchLabels returns whether the labels match all the label=value conditions.
+func (gf *Gofig) matchLabels(conds []string) bool {
+	for _, cond := range conds {
+		kv := strings.SplitN(cond, "=", 2)
+		val, ok := gf.labels[strings.TrimSpace(kv[0])]
+		if !ok {
+			val, ok = gf.lookupFact(strings.TrimSpace(kv[0]))
+		}
+		if !ok || val != strings.TrimSpace(kv[1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isSelectorMap returns whether a map is a value selector: all its keys being label
+// selectors, or the default key, with at least one selector.
+func isSelectorMap(m map[string]interface{}) bool {
+	hasSelector := false
+	for k := range m {
+		if k == defaultSelector {
+			continue
+		}
+		for _, cond := range strings.Split(k, ",") {
+			kv := strings.SplitN(cond, "=", 2)
+			if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+				return false
+			}
+		}
+		hasSelector = true
+	}
+	return hasSelector
+}
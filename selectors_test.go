@@ -0,0 +1,71 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type selectorStruct struct {
+	Replicas int               `json:"replicas" yaml:"replicas" toml:"replicas"`
+	Endpoint string            `json:"endpoint" yaml:"endpoint" toml:"endpoint"`
+	Tags     map[string]string `json:"tags" yaml:"tags" toml:"tags"`
+	Sub      struct {
+		Timeout Duration `json:"timeout" yaml:"timeout" toml:"timeout"`
+	} `json:"sub" yaml:"sub" toml:"sub"`
+}
+
+func TestSetLabels(t *testing.T) {
+	decode := func(labels map[string]string, ext string, doc string) (*selectorStruct, error) {
+		s := &selectorStruct{Endpoint: "user-defined"}
+		gf := New(ContinueOnError)
+		if labels != nil {
+			gf.SetLabels(labels)
+		}
+		return s, gf.decodeConfig(bytes.NewReader([]byte(doc)), ext, s)
+	}
+
+	yamlDoc := `
+replicas: {default: 10, "region=eu": 20, "region=eu,tier=canary": 1}
+endpoint: {"region=us": us.example.com}
+tags: {team: infra}
+sub:
+  timeout: {default: 5s, "tier=canary": 1m}
+`
+	// Case 1: default values
+	s, err := decode(map[string]string{"region": "ap"}, yamlExtention, yamlDoc)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, s.Replicas)
+	assert.Equal(t, "user-defined", s.Endpoint)
+	assert.Equal(t, map[string]string{"team": "infra"}, s.Tags)
+	assert.Equal(t, "5s", s.Sub.Timeout.String())
+
+	// Case 2: the selector with the most labels wins
+	s, err = decode(map[string]string{"region": "eu", "tier": "canary"}, yamlExtention, yamlDoc)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, s.Replicas)
+	assert.Equal(t, "1m0s", s.Sub.Timeout.String())
+
+	s, err = decode(map[string]string{"region": "us"}, jsonExtention, `{"replicas": {"default": 10, "region=us": 30}, "endpoint": {"region=us": "us.example.com"}}`)
+	assert.NoError(t, err)
+	assert.Equal(t, 30, s.Replicas)
+	assert.Equal(t, "us.example.com", s.Endpoint)
+
+	s, err = decode(map[string]string{"region": "eu"}, tomlExtention, "[replicas]\ndefault = 10\n\"region=eu\" = 20\n")
+	assert.NoError(t, err)
+	assert.Equal(t, 20, s.Replicas)
+
+	// Case 3: ambiguous selectors
+	_, err = decode(map[string]string{"region": "eu", "tier": "canary"}, jsonExtention, `{"replicas": {"region=eu": 1, "tier=canary": 2}}`)
+	assert.EqualError(t, err, "ambiguous value selectors 'region=eu' and 'tier=canary' at key 'replicas'")
+
+	// Case 4: not resolved without labels
+	_, err = decode(nil, jsonExtention, `{"replicas": {"default": 10}}`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+)
+
+// SetOverrideFlag adds a repeatable flag overriding any field by its dot-separated key
+// path, Helm-style, e.g. -set db.port=5433 -set tags=a,b, so that a field can be set
+// without a dedicated flag. The values are decoded like environment variables and
+// applied in order after the runtime overrides (Override), taking precedence over every
+// other source. An unknown key is an error.
+func SetOverrideFlag(name string, desc string) { gf.SetOverrideFlag(name, desc) }
+
+// SetOverrideFlag adds a repeatable flag overriding any field by its dot-separated key
+// path, Helm-style, e.g. -set db.port=5433 -set tags=a,b, so that a field can be set
+// without a dedicated flag. The values are decoded like environment variables and
+// applied in order after the runtime overrides (Override), taking precedence over every
+// other source. An unknown key is an error.
+func (gf *Gofig) SetOverrideFlag(name string, desc string) {
+	gf.setFlagName = name
+	gf.flagSet.Var(&setFlag{}, name, gf.translate(desc))
+	gf.declareFlag(gf.flagSet, name)
+}
+
+// SetOverrideJSONFlag adds a repeatable flag merging an inline JSON document into the
+// configuration, e.g. -set-json '{"db":{"port":5433}}', so that CI jobs can compose
+// overrides programmatically. The documents are applied with the values of the other
+// override flags, in command-line order. An unknown key is an error.
+func SetOverrideJSONFlag(name string, desc string) { gf.SetOverrideJSONFlag(name, desc) }
+
+// SetOverrideJSONFlag adds a repeatable flag merging an inline JSON document into the
+// configuration, e.g. -set-json '{"db":{"port":5433}}', so that CI jobs can compose
+// overrides programmatically. The documents are applied with the values of the other
+// override flags, in command-line order. An unknown key is an error.
+func (gf *Gofig) SetOverrideJSONFlag(name string, desc string) {
+	gf.setJSONFlag = name
+	gf.flagSet.Var(&docFlag{}, name, gf.translate(desc))
+	gf.declareFlag(gf.flagSet, name)
+}
+
+// SetOverrideFileFlag adds a repeatable flag merging a JSON, TOML or YAML document file
+// into the configuration, e.g. -set-file overrides.yaml. The files are applied with the
+// values of the other override flags, in command-line order. An unknown key is an error.
+func SetOverrideFileFlag(name string, desc string) { gf.SetOverrideFileFlag(name, desc) }
+
+// SetOverrideFileFlag adds a repeatable flag merging a JSON, TOML or YAML document file
+// into the configuration, e.g. -set-file overrides.yaml. The files are applied with the
+// values of the other override flags, in command-line order. An unknown key is an error.
+func (gf *Gofig) SetOverrideFileFlag(name string, desc string) {
+	gf.setFileFlag = name
+	gf.flagSet.Var(&docFlag{}, name, gf.translate(desc))
+	gf.declareFlag(gf.flagSet, name)
+}
+
+// setFlag is the flag.Value of the override flag, checking the syntax of its values,
+// which are applied by applySetFlags.
+type setFlag struct {
+	vals []string
+}
+
+func (f *setFlag) String() string {
+	return strings.Join(f.vals, " ")
+}
+
+func (f *setFlag) Set(s string) error {
+	if !strings.Contains(s, "=") {
+		return errorf("must be key=value")
+	}
+	f.vals = append(f.vals, s)
+	return nil
+}
+
+// docFlag is the flag.Value of the override document flags, whose values are applied
+// by applySetFlags.
+type docFlag struct {
+	vals []string
+}
+
+func (f *docFlag) String() string {
+	return strings.Join(f.vals, " ")
+}
+
+func (f *docFlag) Set(s string) error {
+	f.vals = append(f.vals, s)
+	return nil
+}
+
+// applySetFlags applies the values of the override flags in args to v, in command-line
+// order.
+func (gf *Gofig) applySetFlags(ctx context.Context, v interface{}, args []string) error {
+	names := make(map[string]bool)
+	for _, name := range []string{gf.setFlagName, gf.setJSONFlag, gf.setFileFlag} {
+		if name != "" {
+			names["-"+name] = true
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	for i := 0; i < len(args); i++ {
+		name, val := args[i], ""
+		if as := strings.SplitN(name, "=", 2); len(as) > 1 {
+			name, val = as[0], as[1]
+		} else if names[name] && len(args) > i+1 {
+			val = args[i+1]
+			i++
+		}
+		if !names[name] {
+			continue
+		}
+		var err error
+		switch strings.TrimPrefix(name, "-") {
+		case gf.setFlagName:
+			err = gf.applySetFlag(v, val)
+		case gf.setJSONFlag:
+			err = gf.applySetDocument(v, name, []byte(val), jsonExtention)
+		default:
+			var data []byte
+			var ext string
+			data, err = gf.readConfigFile(ctx, val)
+			if err == nil {
+				data, ext, err = gf.transformFile(ctx, val, data)
+			}
+			if err == nil {
+				err = gf.applySetDocument(v, name, data, ext)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applySetFlag applies a key=value of the override flag to v.
+func (gf *Gofig) applySetFlag(v interface{}, kv string) error {
+	i := strings.IndexByte(kv, '=')
+	if i < 0 {
+		return errorf("invalid value '%v' of flag -%v, it must be key=value", kv, gf.setFlagName)
+	}
+	key := strings.ToLower(strings.TrimSpace(kv[:i]))
+	if !hasKey(v, key) {
+		return errorf("unknown key '%v' in flag -%v", key, gf.setFlagName)
+	}
+	if gf.decoded != nil {
+		gf.decoded[key] = true
+	}
+	return withSource(decodeValues(map[string]string{key: kv[i+1:]}, v), ProvenanceOverrideFlag)
+}
+
+// applySetDocument merges a document of an override document flag into v, after
+// checking that all its keys are known.
+func (gf *Gofig) applySetDocument(v interface{}, flagName string, data []byte, ext string) error {
+	var tree interface{}
+	err := decodeConfig(bytes.NewReader(data), ext, &tree)
+	if err != nil {
+		return errorf("error decoding flag %v: %v", flagName, err)
+	}
+	unused := make(map[string]struct{})
+	collectUnusedKeys(stringKeys(tree), v, strings.TrimPrefix(ext, "."), unused)
+	if len(unused) > 0 {
+		keys := make([]string, 0, len(unused))
+		for key := range unused {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return errorf("unknown key '%v' in flag %v", keys[0], flagName)
+	}
+	if root, ok := stringKeys(tree).(map[string]interface{}); ok && gf.decoded != nil {
+		collectDecodedKeys(root, v, strings.TrimPrefix(ext, "."), gf.decoded)
+	}
+	return gf.decodeConfig(bytes.NewReader(data), ext, v)
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetOverrideFlag(t *testing.T) {
+	// Case 1: applied in order, over the flags and the runtime overrides
+	s := &TestStruct{}
+	gf := New(ContinueOnError)
+	gf.SetOverrideFlag("set", "override a `key=value`")
+	gf.Override("int", "1")
+	err := gf.ParseWithArgs(s, []string{"-int", "2", "-set", "int=3", "-set=Sub.Str=a=b", "-set", "int=4"})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, s.Int)
+	assert.Equal(t, "a=b", s.Sub.RenamedStr)
+
+	// Case 2: invalid values
+	for args, expected := range map[string]string{
+		"port=1": "unknown key 'port' in flag -set",
+		"int=x":  "error parsing key 'int' with value 'x' into int (flag -int, env INT, key int)",
+		"int":    "invalid value \"int\" for flag -set: must be key=value",
+	} {
+		gf := New(ContinueOnError)
+		gf.SetOverrideFlag("set", "override a `key=value`")
+		err := gf.ParseWithArgs(&TestStruct{}, []string{"-set", args})
+		assert.EqualError(t, err, expected, args)
+	}
+}
+
+func TestSetOverrideDocumentFlags(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "overrides.yaml")
+	err := os.WriteFile(path, []byte("int: 5\nsub:\n  str: yaml\n"), 0600)
+	assert.NoError(t, err)
+
+	// Case 1: merged in command-line order with the override flag, over the flags
+	s := &TestStruct{}
+	gf := New(ContinueOnError)
+	gf.SetOverrideFlag("set", "override a `key=value`")
+	gf.SetOverrideJSONFlag("set-json", "merge a JSON `document`")
+	gf.SetOverrideFileFlag("set-file", "merge a document `file`")
+	err = gf.ParseWithArgs(s, []string{"-str", "flag", "-int", "1", "-set-json", `{"int": 2, "str": "json"}`, "-set", "int=3", "-set-file", path})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, s.Int)
+	assert.Equal(t, "json", s.Str)
+	assert.Equal(t, "yaml", s.Sub.RenamedStr)
+
+	// Case 2: invalid documents
+	for args, expected := range map[string]string{
+		`{"port": 1}`:         "unknown key 'port' in flag -set-json",
+		`{"sub": {"int": 1}}`: "unknown key 'sub.int' in flag -set-json",
+		`{"int":`:             "error decoding flag -set-json: unexpected EOF",
+	} {
+		gf := New(ContinueOnError)
+		gf.SetOverrideJSONFlag("set-json", "merge a JSON `document`")
+		err := gf.ParseWithArgs(&TestStruct{}, []string{"-set-json=" + args})
+		assert.EqualError(t, err, expected, args)
+	}
+	gf = New(ContinueOnError)
+	gf.SetOverrideFileFlag("set-file", "merge a document `file`")
+	err = gf.ParseWithArgs(&TestStruct{}, []string{"-set-file", filepath.Join(dir, "missing.yaml")})
+	assert.Error(t, err)
+}
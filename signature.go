@@ -0,0 +1,234 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Verifier verifies the detached signatures of the config documents.
+type Verifier interface {
+	// SignatureSuffix returns the suffix of the signature of a document, appended to its
+	// path or URL, e.g. ".minisig".
+	SignatureSuffix() string
+	// Verify verifies the signature of a document.
+	Verify(data, signature []byte) error
+}
+
+// SignaturePolicy defines which config documents must be signed
+type SignaturePolicy int
+
+const (
+	// VerifySigned verifies the signature of the signed documents, the unsigned ones
+	// being accepted
+	VerifySigned SignaturePolicy = iota
+	// RequireSigned rejects the unsigned documents, and the sources not supporting
+	// signatures
+	RequireSigned
+)
+
+// SignedSource is a Source whose documents can have a detached signature.
+type SignedSource interface {
+	Source
+	// LoadSignature fetches the signature of the document, at its location with the
+	// suffix appended. It returns an error matching fs.ErrNotExist if there's none.
+	LoadSignature(ctx context.Context, suffix string) ([]byte, error)
+}
+
+// SetSignatureVerifier sets the verifier of the detached signatures of the config files
+// and of the documents of the sources implementing SignedSource, such as FileSource and
+// HTTPSource, e.g. a MinisignVerifier or a CosignVerifier. A document whose signature
+// is invalid is rejected, as are the unsigned ones with the RequireSigned policy. The
+// sources wrapped with Optional, WithTimeout, WithRetry or WithCache are verified as the
+// source they wrap. The documents pushed with their Signature and the messages received
+// with Subscribe are verified too, while the base64-encoded config environment variable,
+// which can't be signed, is rejected with the RequireSigned policy. The runtime overrides
+// aren't verified.
+func SetSignatureVerifier(v Verifier, policy SignaturePolicy) { gf.SetSignatureVerifier(v, policy) }
+
+// SetSignatureVerifier sets the verifier of the detached signatures of the config files
+// and of the documents of the sources implementing SignedSource, such as FileSource and
+// HTTPSource, e.g. a MinisignVerifier or a CosignVerifier. A document whose signature
+// is invalid is rejected, as are the unsigned ones with the RequireSigned policy. The
+// sources wrapped with Optional, WithTimeout, WithRetry or WithCache are verified as the
+// source they wrap. The documents pushed with their Signature and the messages received
+// with Subscribe are verified too, while the base64-encoded config environment variable,
+// which can't be signed, is rejected with the RequireSigned policy. The runtime overrides
+// aren't verified.
+func (gf *Gofig) SetSignatureVerifier(v Verifier, policy SignaturePolicy) {
+	gf.verifier = v
+	gf.sigPolicy = policy
+}
+
+// verifyDocument verifies the signature of the document at location, loaded by load.
+func (gf *Gofig) verifyDocument(location string, data []byte, load func(suffix string) ([]byte, error)) error {
+	if gf.verifier == nil {
+		return nil
+	}
+	sig, err := load(gf.verifier.SignatureSuffix())
+	if errors.Is(err, fs.ErrNotExist) {
+		if gf.sigPolicy == RequireSigned {
+			return errorf("config %v is not signed", location)
+		}
+		return nil
+	} else if err != nil {
+		// not wrapped, a missing config file being skipped
+		return errorf("error loading the signature of %v: %v", location, err.Error())
+	}
+	err = gf.verifier.Verify(data, sig)
+	if err != nil {
+		return errorf("invalid signature of %v: %v", location, err)
+	}
+	return nil
+}
+
+// verifyConfigFile verifies the signature of a local config file.
+func (gf *Gofig) verifyConfigFile(path string, data []byte) error {
+	return gf.verifyDocument(path, data, func(suffix string) ([]byte, error) {
+		return os.ReadFile(path + suffix)
+	})
+}
+
+// verifySource verifies the signature of the document of a source.
+func (gf *Gofig) verifySource(ctx context.Context, src Source, doc *Document) error {
+	if gf.verifier == nil || doc == nil {
+		return nil
+	}
+	ss, ok := signedSource(src)
+	if !ok {
+		if gf.sigPolicy == RequireSigned {
+			return errorf("source %v doesn't support signatures", src.Name())
+		}
+		return nil
+	}
+	return gf.verifyDocument(src.Name(), doc.Data, func(suffix string) ([]byte, error) {
+		return ss.LoadSignature(ctx, suffix)
+	})
+}
+
+// signedSource returns the SignedSource of src, possibly wrapped with Optional,
+// WithTimeout, WithRetry or WithCache, so that wrapping a source doesn't bypass the
+// verification of its signature.
+func signedSource(src Source) (SignedSource, bool) {
+	for {
+		if ss, ok := src.(SignedSource); ok {
+			return ss, true
+		}
+		switch s := src.(type) {
+		case *optionalSource:
+			src = s.Source
+		case *timeoutSource:
+			src = s.Source
+		case *retrySource:
+			src = s.Source
+		case *CachedSource:
+			src = s.Source
+		default:
+			return nil, false
+		}
+	}
+}
+
+// verifyAttached verifies the signature attached to a document which isn't loaded from
+// a location, e.g. a pushed document, sig being nil if it isn't signed.
+func (gf *Gofig) verifyAttached(location string, data []byte, sig []byte) error {
+	return gf.verifyDocument(location, data, func(suffix string) ([]byte, error) {
+		if sig == nil {
+			return nil, fs.ErrNotExist
+		}
+		return sig, nil
+	})
+}
+
+// LoadSignature reads the signature file, at the file path with the suffix appended.
+func (s *FileSource) LoadSignature(ctx context.Context, suffix string) ([]byte, error) {
+	return os.ReadFile(s.Path + suffix)
+}
+
+// LoadSignature fetches the signature, at the URL with the suffix appended to its path.
+func (s *HTTPSource) LoadSignature(ctx context.Context, suffix string) ([]byte, error) {
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		return nil, err
+	}
+	u.Path += suffix
+	u.RawPath = ""
+	sigSource := &HTTPSource{URL: u.String(), Client: s.Client, TokenSource: s.TokenSource}
+	resp, _, err := sigSource.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return readLimited(ctx, resp.Body)
+	case http.StatusNotFound:
+		return nil, fs.ErrNotExist
+	}
+	return nil, fmt.Errorf("unexpected HTTP status %v", resp.Status)
+}
+
+// cosignVerifier verifies the signatures of cosign sign-blob
+type cosignVerifier struct {
+	key crypto.PublicKey
+}
+
+// CosignVerifier returns a Verifier of the signatures created by cosign sign-blob
+// --output-signature, stored in .sig files, with the public key of cosign generate-key-pair
+// (a PEM-encoded ECDSA or RSA key).
+func CosignVerifier(publicKeyPEM []byte) (Verifier, error) {
+	block, _ := pem.Decode(publicKeyPEM)
+	if block == nil {
+		return nil, errors.New("invalid cosign public key, PEM block not found")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cosign public key: %v", err)
+	}
+	switch key.(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey:
+	default:
+		return nil, fmt.Errorf("unsupported cosign public key type %T", key)
+	}
+	return &cosignVerifier{key: key}, nil
+}
+
+func (v *cosignVerifier) SignatureSuffix() string {
+	return ".sig"
+}
+
+func (v *cosignVerifier) Verify(data, signature []byte) error {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return fmt.Errorf("invalid cosign signature: %v", err)
+	}
+	digest := sha256.Sum256(data)
+	switch key := v.key.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, digest[:], sig) {
+			return errors.New("signature mismatch")
+		}
+	case *rsa.PublicKey:
+		if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) != nil {
+			return errors.New("signature mismatch")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCosignVerifier(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	assert.NoError(t, err)
+	v, err := CosignVerifier(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	assert.NoError(t, err)
+	assert.Equal(t, ".sig", v.SignatureSuffix())
+
+	data := []byte(`{"port": 8080}`)
+	digest := sha256.Sum256(data)
+	sig, err := ecdsa.SignASN1(rand.Reader, priv, digest[:])
+	assert.NoError(t, err)
+	assert.NoError(t, v.Verify(data, []byte(base64.StdEncoding.EncodeToString(sig)+"\n")))
+	assert.EqualError(t, v.Verify([]byte(`{"port": 8081}`), []byte(base64.StdEncoding.EncodeToString(sig))), "signature mismatch")
+
+	_, err = CosignVerifier([]byte("not a key"))
+	assert.EqualError(t, err, "invalid cosign public key, PEM block not found")
+}
+
+func TestSetSignatureVerifier(t *testing.T) {
+	type Config struct {
+		Port int
+	}
+	pubKey, sign := minisignTestKey(t)
+	verifier, err := MinisignVerifier(pubKey)
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+	signed := filepath.Join(dir, "signed.yaml")
+	assert.NoError(t, os.WriteFile(signed, []byte("port: 8080\n"), 0644))
+	assert.NoError(t, os.WriteFile(signed+".minisig", sign([]byte("port: 8080\n"), true), 0644))
+	tampered := filepath.Join(dir, "tampered.yaml")
+	assert.NoError(t, os.WriteFile(tampered, []byte("port: 6666\n"), 0644))
+	assert.NoError(t, os.WriteFile(tampered+".minisig", sign([]byte("port: 8080\n"), true), 0644))
+	unsigned := filepath.Join(dir, "unsigned.yaml")
+	assert.NoError(t, os.WriteFile(unsigned, []byte("port: 8080\n"), 0644))
+
+	parse := func(policy SignaturePolicy, setup func(gf *Gofig)) (*Config, error) {
+		s := &Config{}
+		gf := New(ContinueOnError)
+		gf.SetSignatureVerifier(verifier, policy)
+		setup(gf)
+		return s, gf.ParseWithArgs(s, []string{})
+	}
+
+	// Case 1: config files
+	s, err := parse(RequireSigned, func(gf *Gofig) { gf.AddConfigFile(filepath.Join(dir, "signed")) })
+	assert.NoError(t, err)
+	assert.Equal(t, 8080, s.Port)
+	_, err = parse(VerifySigned, func(gf *Gofig) { gf.AddConfigFile(filepath.Join(dir, "tampered")) })
+	assert.EqualError(t, err, "invalid signature of "+tampered+": signature mismatch")
+	s, err = parse(VerifySigned, func(gf *Gofig) { gf.AddConfigFile(filepath.Join(dir, "unsigned")) })
+	assert.NoError(t, err)
+	assert.Equal(t, 8080, s.Port)
+	_, err = parse(RequireSigned, func(gf *Gofig) { gf.AddConfigFile(filepath.Join(dir, "unsigned")) })
+	assert.EqualError(t, err, "config "+unsigned+" is not signed")
+
+	// Case 2: file and HTTP sources
+	_, err = parse(RequireSigned, func(gf *Gofig) { gf.AddSource(NewFileSource(tampered)) })
+	assert.EqualError(t, err, "invalid signature of "+tampered+": signature mismatch")
+	server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer server.Close()
+	s, err = parse(RequireSigned, func(gf *Gofig) { gf.AddConfigURL(server.URL + "/signed.yaml") })
+	assert.NoError(t, err)
+	assert.Equal(t, 8080, s.Port)
+	_, err = parse(RequireSigned, func(gf *Gofig) { gf.AddConfigURL(server.URL + "/unsigned.yaml") })
+	assert.EqualError(t, err, "config "+server.URL+"/unsigned.yaml is not signed")
+
+	// Case 3: sources not supporting signatures
+	doc := &testSource{name: "test", doc: &Document{Format: "yaml", Data: []byte("port: 8080\n")}}
+	_, err = parse(RequireSigned, func(gf *Gofig) { gf.AddSource(doc) })
+	assert.EqualError(t, err, "source test doesn't support signatures")
+	_, err = parse(VerifySigned, func(gf *Gofig) { gf.AddSource(doc) })
+	assert.NoError(t, err)
+
+	// Case 4: wrapped sources are verified as the source they wrap
+	for _, src := range []Source{
+		WithTimeout(NewFileSource(tampered), time.Second),
+		WithRetry(Optional(NewFileSource(tampered)), RetryPolicy{}),
+		WithCache(NewFileSource(tampered), time.Minute),
+	} {
+		_, err = parse(VerifySigned, func(gf *Gofig) { gf.AddSource(src) })
+		assert.EqualError(t, err, "invalid signature of "+tampered+": signature mismatch")
+	}
+
+	// Case 5: the config environment variable can't be signed
+	os.Setenv("GFSIG_CONFIG_B64", base64.StdEncoding.EncodeToString([]byte("port: 6666\n")))
+	defer os.Unsetenv("GFSIG_CONFIG_B64")
+	_, err = parse(RequireSigned, func(gf *Gofig) { gf.SetEnvPrefix("GFSIG") })
+	assert.EqualError(t, err, "config environment variable 'GFSIG_CONFIG_B64' is not signed")
+	s, err = parse(VerifySigned, func(gf *Gofig) { gf.SetEnvPrefix("GFSIG") })
+	assert.NoError(t, err)
+	assert.Equal(t, 6666, s.Port)
+	os.Unsetenv("GFSIG_CONFIG_B64")
+
+	// Case 6: pushed documents and patches
+	gf := New(ContinueOnError)
+	gf.SetSignatureVerifier(verifier, RequireSigned)
+	s = &Config{}
+	assert.NoError(t, gf.ParseWithArgs(s, []string{}))
+	data := []byte("port: 9090\n")
+	err = gf.Push(&Document{Format: "yaml", Data: data})
+	assert.EqualError(t, err, "config pushed document is not signed")
+	err = gf.Push(&Document{Format: "yaml", Data: data, Signature: sign([]byte("port: 6666\n"), true)})
+	assert.EqualError(t, err, "invalid signature of pushed document: signature mismatch")
+	assert.NoError(t, gf.Push(&Document{Format: "yaml", Data: data, Signature: sign(data, true)}))
+	assert.Equal(t, 9090, s.Port)
+	patch := []byte(`{"port": 6666}`)
+	err = gf.pushContent("application/merge-patch+json", patch, nil)
+	assert.EqualError(t, err, "config pushed patch is not signed")
+	assert.NoError(t, gf.pushContent("application/merge-patch+json", patch, sign(patch, false)))
+	assert.Equal(t, 6666, s.Port)
+}
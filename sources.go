@@ -0,0 +1,544 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultSourceConcurrency is the default number of sources fetched concurrently.
+const defaultSourceConcurrency = 4
+
+// defaultRefreshTimeout is the default timeout of the background refresh of a
+// CachedSource.
+const defaultRefreshTimeout = 30 * time.Second
+
+// Document is the content loaded from a Source.
+type Document struct {
+	// Format is the format of Data: "json", "toml" or "yaml".
+	Format string
+	// Data is a config document, decoded like a config file.
+	Data []byte
+	// Values holds flat values keyed by their dot-separated key path (e.g. "sub.str"),
+	// decoded like environment variables. Key paths follow the json tags.
+	Values map[string]string
+	// Env holds values keyed by environment variable name (including the prefix),
+	// decoded like environment variables.
+	Env map[string]string
+	// Credentials holds secret values keyed by credential name, decoded like environment
+	// variables into the fields tagged with their name, e.g. `credential:"db-password"`.
+	Credentials map[string]string
+	// Signature is the detached signature of Data, verified by the signature verifier
+	// (see SetSignatureVerifier) when the document is pushed.
+	Signature []byte
+}
+
+// Source is a configuration source other than the local config files, e.g. a remote
+// HTTP endpoint. Sources override the config file values and are overridden by the
+// environment variables.
+type Source interface {
+	// Name identifies the source in error messages.
+	Name() string
+	// Load fetches the source content. It must return when ctx is done.
+	Load(ctx context.Context) (*Document, error)
+}
+
+// AddSource adds one or more configuration source(s). Sources are fetched concurrently
+// and applied in the order they are added, the last one taking precedence.
+func AddSource(src ...Source) { gf.AddSource(src...) }
+
+// AddSource adds one or more configuration source(s). Sources are fetched concurrently
+// and applied in the order they are added, the last one taking precedence.
+func (gf *Gofig) AddSource(src ...Source) {
+	gf.sources = append(gf.sources, src...)
+}
+
+// AddConfigURL adds a remote config file. HTTP(S) URLs are fetched with a GET request,
+// the format being detected from the Content-Type header or the URL extension. Other
+// URLs (e.g. "s3://bucket/app/config.yaml") are fetched from the object store
+// registered for their scheme (see RegisterObjectStore).
+func AddConfigURL(url string) { gf.AddConfigURL(url) }
+
+// AddConfigURL adds a remote config file. HTTP(S) URLs are fetched with a GET request,
+// the format being detected from the Content-Type header or the URL extension. Other
+// URLs (e.g. "s3://bucket/app/config.yaml") are fetched from the object store
+// registered for their scheme (see RegisterObjectStore).
+func (gf *Gofig) AddConfigURL(rawURL string) {
+	var src Source
+	if strings.HasPrefix(rawURL, "http://") || strings.HasPrefix(rawURL, "https://") {
+		src = NewHTTPSource(rawURL)
+	} else {
+		src = NewObjectSource(rawURL)
+	}
+	if gf.urlSources == nil {
+		gf.urlSources = make(map[Source]bool)
+	}
+	gf.urlSources[src] = true
+	gf.AddSource(src)
+}
+
+// SetSourceConcurrency sets the maximum number of sources fetched concurrently (default 4).
+func SetSourceConcurrency(n int) { gf.SetSourceConcurrency(n) }
+
+// SetSourceConcurrency sets the maximum number of sources fetched concurrently (default 4).
+func (gf *Gofig) SetSourceConcurrency(n int) {
+	if n < 1 {
+		n = 1
+	}
+	gf.sourceConcurrency = n
+}
+
+// loadSources fetches all the sources with a bounded worker pool and returns their
+// documents in the order the sources were added.
+func (gf *Gofig) loadSources(ctx context.Context, args []string) ([]*Document, error) {
+	docs := make([]*Document, len(gf.sources))
+	errs := make([]error, len(gf.sources))
+	loaded := make([]int32, len(gf.sources))
+	ctx = withMaxSize(ctx, gf.limits.MaxSize)
+
+	sem := make(chan struct{}, gf.sourceConcurrency)
+	var wg sync.WaitGroup
+	for i, src := range gf.sources {
+		wg.Add(1)
+		go func(i int, src Source) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			docs[i], errs[i] = src.Load(ctx)
+			atomic.StoreInt32(&loaded[i], 1)
+			gf.recordFetch(i, errs[i])
+		}(i, src)
+	}
+
+	// don't wait for the sources ignoring the parse timeout
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		for i := range loaded {
+			if atomic.LoadInt32(&loaded[i]) == 0 {
+				return nil, gf.timeoutError(ctx, "loading source "+gf.sources[i].Name())
+			}
+		}
+		<-done
+	}
+
+	sums := gf.pinnedChecksums(args)
+	for i, err := range errs {
+		if err != nil {
+			if isOptional(gf.sources[i]) {
+				continue // fall back on the lower-precedence sources
+			}
+			if ctx.Err() != nil {
+				return nil, gf.timeoutError(ctx, "loading source "+gf.sources[i].Name())
+			}
+			return nil, errorf("error loading source %v: %v", gf.sources[i].Name(), err)
+		}
+		err = gf.verifySource(ctx, gf.sources[i], docs[i])
+		if err != nil {
+			return nil, err
+		}
+		if sums != nil && gf.urlSources[gf.sources[i]] {
+			err = verifyChecksum(gf.sources[i], docs[i], sums)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return docs, nil
+}
+
+// decodeSources decodes the documents fetched from the sources into v in declared order.
+func (gf *Gofig) decodeSources(docs []*Document, v interface{}) error {
+	for i, doc := range docs {
+		err := gf.decodeDocument(doc, v)
+		if err != nil {
+			return errorf("error decoding source %v: %v", gf.sources[i].Name(), err)
+		}
+	}
+	return nil
+}
+
+// decodeDocument decodes a source document into v.
+func (gf *Gofig) decodeDocument(doc *Document, v interface{}) error {
+	if doc == nil {
+		return nil
+	}
+	if len(doc.Data) > 0 {
+		err := gf.decodeConfig(bytes.NewReader(doc.Data), "."+doc.Format, v)
+		if err != nil {
+			return err
+		}
+	}
+	if len(doc.Values) > 0 {
+		err := decodeValues(doc.Values, v)
+		if err != nil {
+			return gf.withFieldNames(withSource(err, ProvenanceConfig), v, "json", false)
+		}
+	}
+	if len(doc.Env) > 0 {
+		err := parseStruct(v, func(path []string, name string, f *reflect.Value, tags *reflect.StructTag) error {
+			key := gf.getEnvKey(path)
+			val, ok := doc.Env[key]
+			if !ok {
+				return nil
+			}
+			err := decodeField(f, val, tags)
+			if err != nil {
+				return &fieldError{name: name, source: ProvenanceEnv, raw: val, err: errorf("error parsing environment variable '%v' with value '%v' into %v", key, val, f.Type())}
+			}
+			return nil
+		}, "env")
+		if err != nil {
+			return gf.withFieldNames(err, v, "json", false)
+		}
+	}
+	if len(doc.Credentials) > 0 {
+		err := decodeCredentials(doc.Credentials, v)
+		if err != nil {
+			return gf.withFieldNames(err, v, "json", false)
+		}
+	}
+	return nil
+}
+
+// decodeValues decodes flat values keyed by dot-separated key paths into v.
+func decodeValues(values map[string]string, v interface{}) error {
+	lower := make(map[string]string, len(values))
+	for k, val := range values {
+		lower[strings.ToLower(k)] = val
+	}
+
+	return parseStruct(v, func(path []string, name string, f *reflect.Value, tags *reflect.StructTag) error {
+		key := strings.Join(path, ".")
+		val, ok := lower[key]
+		if !ok {
+			return nil
+		}
+		err := decodeField(f, val, tags)
+		if err != nil {
+			return &fieldError{name: name, key: key, raw: val, err: errorf("error parsing key '%v' with value '%v' into %v", key, val, f.Type())}
+		}
+		return nil
+	}, "json")
+}
+
+// WithTimeout returns a Source whose Load is canceled after the timeout d.
+func WithTimeout(src Source, d time.Duration) Source {
+	return &timeoutSource{Source: src, timeout: d}
+}
+
+type timeoutSource struct {
+	Source
+	timeout time.Duration
+}
+
+func (s *timeoutSource) Load(ctx context.Context) (*Document, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.Source.Load(ctx)
+}
+
+// RetryPolicy defines how a failed Source fetch is retried, with an exponential backoff.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of fetch attempts, 0 for no limit.
+	MaxAttempts int
+	// InitialInterval is the delay before the first retry (default 100ms).
+	InitialInterval time.Duration
+	// MaxInterval caps the delay between two attempts (default 10s).
+	MaxInterval time.Duration
+	// Multiplier is applied to the delay after each attempt (default 2).
+	Multiplier float64
+	// Deadline is the total time allowed for all the attempts, 0 for no deadline.
+	Deadline time.Duration
+}
+
+// WithRetry returns a Source retrying failed fetches according to the retry policy.
+func WithRetry(src Source, policy RetryPolicy) Source {
+	if policy.InitialInterval <= 0 {
+		policy.InitialInterval = 100 * time.Millisecond
+	}
+	if policy.MaxInterval <= 0 {
+		policy.MaxInterval = 10 * time.Second
+	}
+	if policy.Multiplier < 1 {
+		policy.Multiplier = 2
+	}
+	return &retrySource{Source: src, policy: policy}
+}
+
+type retrySource struct {
+	Source
+	policy RetryPolicy
+}
+
+func (s *retrySource) Load(ctx context.Context) (*Document, error) {
+	if s.policy.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.policy.Deadline)
+		defer cancel()
+	}
+
+	interval := s.policy.InitialInterval
+	for attempt := 1; ; attempt++ {
+		doc, err := s.Source.Load(ctx)
+		if err == nil {
+			return doc, nil
+		}
+		if s.policy.MaxAttempts > 0 && attempt >= s.policy.MaxAttempts {
+			return nil, fmt.Errorf("%v (after %v attempts)", err, attempt)
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("%v (after %v attempts)", err, attempt)
+		}
+
+		interval = time.Duration(float64(interval) * s.policy.Multiplier)
+		if interval > s.policy.MaxInterval {
+			interval = s.policy.MaxInterval
+		}
+	}
+}
+
+// Optional marks a Source as non-critical: if it can't be fetched, it is skipped and
+// the lower-precedence sources are used instead of failing the parsing.
+func Optional(src Source) Source {
+	return &optionalSource{Source: src}
+}
+
+type optionalSource struct {
+	Source
+}
+
+// isOptional returns whether src is marked with Optional, possibly wrapped with
+// WithTimeout, WithRetry or WithCache.
+func isOptional(src Source) bool {
+	for {
+		switch s := src.(type) {
+		case *optionalSource:
+			return true
+		case *timeoutSource:
+			src = s.Source
+		case *retrySource:
+			src = s.Source
+		case *CachedSource:
+			src = s.Source
+		default:
+			return false
+		}
+	}
+}
+
+// CachedSource caches the document of a Source, e.g. secrets resolved from a secret
+// manager, so repeated parsing doesn't hit the backend each time.
+type CachedSource struct {
+	Source
+	ttl            time.Duration
+	refreshTimeout time.Duration
+
+	mu         sync.Mutex
+	doc        *Document
+	fetched    time.Time
+	refreshing bool
+}
+
+// WithCache returns a Source caching the documents of src for the ttl duration. Once
+// expired, the cached document is still returned while it is refreshed in the background,
+// each refresh being canceled after 30s (see SetRefreshTimeout).
+func WithCache(src Source, ttl time.Duration) *CachedSource {
+	return &CachedSource{Source: src, ttl: ttl, refreshTimeout: defaultRefreshTimeout}
+}
+
+// SetRefreshTimeout sets the timeout of the background refresh of the expired document,
+// so that a hung backend doesn't block the refresh forever. The stale document is kept
+// if it times out, and refreshed again by the next Load.
+func (s *CachedSource) SetRefreshTimeout(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refreshTimeout = d
+}
+
+// Load returns the cached document, fetching it if there is none.
+func (s *CachedSource) Load(ctx context.Context) (*Document, error) {
+	s.mu.Lock()
+	doc := s.doc
+	if doc != nil && time.Since(s.fetched) >= s.ttl && !s.refreshing {
+		s.refreshing = true
+		go s.refresh(s.refreshTimeout)
+	}
+	s.mu.Unlock()
+
+	if doc != nil {
+		return doc, nil
+	}
+
+	doc, err := s.Source.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.store(doc)
+	return doc, nil
+}
+
+// Invalidate drops the cached document, the next Load fetches it again.
+func (s *CachedSource) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.doc = nil
+}
+
+// refresh fetches the document in the background, keeping the stale one on error.
+func (s *CachedSource) refresh(timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	doc, err := s.Source.Load(ctx)
+
+	s.mu.Lock()
+	s.refreshing = false
+	s.mu.Unlock()
+
+	if err == nil {
+		s.store(doc)
+	}
+}
+
+func (s *CachedSource) store(doc *Document) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.doc = doc
+	s.fetched = time.Now()
+}
+
+// HTTPSource loads a config document with an HTTP GET request.
+type HTTPSource struct {
+	// URL of the config document.
+	URL string
+	// Client is the HTTP client used for the request, http.DefaultClient if nil.
+	Client *http.Client
+	// TokenSource optionally provides the bearer tokens authenticating the request, e.g.
+	// a ClientCredentials or TokenExchange. A rejected token is renewed once.
+	TokenSource TokenSource
+}
+
+// NewHTTPSource returns a Source fetching the config document at url.
+func NewHTTPSource(url string) *HTTPSource {
+	return &HTTPSource{URL: url}
+}
+
+// Name returns the source URL.
+func (s *HTTPSource) Name() string {
+	return s.URL
+}
+
+// Load fetches the config document.
+func (s *HTTPSource) Load(ctx context.Context) (*Document, error) {
+	resp, token, err := s.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if inv, ok := s.TokenSource.(tokenInvalidator); ok && resp.StatusCode == http.StatusUnauthorized {
+		// the token was revoked or expired early, renew it
+		resp.Body.Close()
+		inv.invalidate(token)
+		resp, _, err = s.get(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected HTTP status %v", resp.Status)
+	}
+
+	data, err := readLimited(ctx, resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	format := formatFromContentType(resp.Header.Get("Content-Type"))
+	if format == "" {
+		format = formatFromURL(s.URL)
+	}
+	if format == "" {
+		return nil, fmt.Errorf("unable to detect the config format")
+	}
+
+	return &Document{Format: format, Data: data}, nil
+}
+
+// get sends the GET request of the config document, returning the access token sent.
+func (s *HTTPSource) get(ctx context.Context) (resp *http.Response, token string, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if s.TokenSource != nil {
+		token, err = s.TokenSource.Token(ctx)
+		if err != nil {
+			return nil, "", err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err = client.Do(req)
+	return resp, token, err
+}
+
+// formatFromContentType returns the config format matching a MIME type, if any.
+func formatFromContentType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	switch mediaType {
+	case "application/json":
+		return "json"
+	case "application/toml":
+		return "toml"
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return "yaml"
+	}
+	return ""
+}
+
+// formatFromURL returns the config format matching the URL path extension, if any.
+func formatFromURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	ext := path.Ext(u.Path)
+	for _, e := range cfgFileExt {
+		if ext == e {
+			return ext[1:]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,301 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testSource is an in-memory source
+type testSource struct {
+	name  string
+	doc   *Document
+	err   error
+	delay time.Duration
+
+	running    *int32
+	maxRunning *int32
+}
+
+func (s *testSource) Name() string { return s.name }
+
+func (s *testSource) Load(ctx context.Context) (*Document, error) {
+	if s.running != nil {
+		n := atomic.AddInt32(s.running, 1)
+		defer atomic.AddInt32(s.running, -1)
+		for {
+			max := atomic.LoadInt32(s.maxRunning)
+			if n <= max || atomic.CompareAndSwapInt32(s.maxRunning, max, n) {
+				break
+			}
+		}
+	}
+	select {
+	case <-time.After(s.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return s.doc, s.err
+}
+
+func TestAddSource(t *testing.T) {
+	// Case 1: sources are applied in declared order, whatever their fetch time
+	t.Run("order", func(t *testing.T) {
+		s := buildTestStruct()
+		gf := New(ContinueOnError)
+		gf.AddSource(
+			&testSource{name: "slow", delay: 20 * time.Millisecond, doc: &Document{Format: "json", Data: []byte(`{"str": "slow", "int": 1}`)}},
+			&testSource{name: "fast", doc: &Document{Format: "yaml", Data: []byte(`str: fast`)}},
+		)
+		err := gf.ParseWithArgs(s, []string{})
+		assert.NoError(t, err)
+		assert.Equal(t, "fast", s.Str)
+		assert.Equal(t, 1, s.Int)
+	})
+
+	// Case 2: flat values
+	t.Run("values", func(t *testing.T) {
+		s := buildTestStruct()
+		gf := New(ContinueOnError)
+		gf.AddSource(&testSource{name: "values", doc: &Document{Values: map[string]string{
+			"Str":      "values",
+			"duration": "5s",
+			"sub.str":  "renamed-values",
+		}}})
+		err := gf.ParseWithArgs(s, []string{})
+		assert.NoError(t, err)
+		assert.Equal(t, "values", s.Str)
+		assert.Equal(t, Duration(5*time.Second), s.Duration)
+		assert.Equal(t, "renamed-values", s.Sub.RenamedStr)
+	})
+
+	// Case 3: env overrides the sources
+	t.Run("env", func(t *testing.T) {
+		os.Setenv("GF_STR", "env")
+		defer os.Unsetenv("GF_STR")
+
+		s := buildTestStruct()
+		gf := New(ContinueOnError)
+		gf.SetEnvPrefix("GF")
+		gf.AddSource(&testSource{name: "values", doc: &Document{Values: map[string]string{"str": "values"}}})
+		err := gf.ParseWithArgs(s, []string{})
+		assert.NoError(t, err)
+		assert.Equal(t, "env", s.Str)
+	})
+
+	// Case 4: errors
+	t.Run("errors", func(t *testing.T) {
+		gf := New(ContinueOnError)
+		gf.AddSource(&testSource{name: "bad", doc: &Document{Values: map[string]string{"int": "abc"}}})
+		err := gf.ParseWithArgs(buildTestStruct(), []string{})
+		assert.EqualError(t, err, "error decoding source bad: error parsing key 'int' with value 'abc' into int (flag -int, env INT, key int)")
+
+		gf = New(ContinueOnError)
+		gf.AddSource(WithTimeout(&testSource{name: "hung", delay: time.Hour}, 10*time.Millisecond))
+		err = gf.ParseWithArgs(buildTestStruct(), []string{})
+		assert.EqualError(t, err, "error loading source hung: context deadline exceeded")
+	})
+}
+
+func TestSetSourceConcurrency(t *testing.T) {
+	var running, maxRunning int32
+	gf := New(ContinueOnError)
+	gf.SetSourceConcurrency(2)
+	for i := 0; i < 6; i++ {
+		gf.AddSource(&testSource{name: "src", delay: 10 * time.Millisecond, running: &running, maxRunning: &maxRunning})
+	}
+	err := gf.ParseWithArgs(buildTestStruct(), []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), maxRunning)
+}
+
+func TestAddConfigURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/config":
+			w.Header().Set("Content-Type", "application/yaml")
+			_, _ = w.Write([]byte("str: yaml"))
+		case "/config.json":
+			_, _ = w.Write([]byte(`{"int": 42}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	s := buildTestStruct()
+	gf := New(ContinueOnError)
+	gf.AddConfigURL(server.URL + "/config")
+	gf.AddConfigURL(server.URL + "/config.json")
+	err := gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "yaml", s.Str)
+	assert.Equal(t, 42, s.Int)
+
+	gf = New(ContinueOnError)
+	gf.AddConfigURL(server.URL + "/missing.json")
+	err = gf.ParseWithArgs(buildTestStruct(), []string{})
+	assert.EqualError(t, err, "error loading source "+server.URL+"/missing.json: unexpected HTTP status 404 Not Found")
+}
+
+// flakySource fails a number of times before succeeding
+type flakySource struct {
+	failures int32
+	attempts int32
+}
+
+func (s *flakySource) Name() string { return "flaky" }
+
+func (s *flakySource) Load(ctx context.Context) (*Document, error) {
+	if atomic.AddInt32(&s.attempts, 1) <= s.failures {
+		return nil, errors.New("unavailable")
+	}
+	return &Document{Values: map[string]string{"str": "flaky"}}, nil
+}
+
+func TestWithRetry(t *testing.T) {
+	// Case 1: succeeds after retries
+	src := &flakySource{failures: 2}
+	s := buildTestStruct()
+	gf := New(ContinueOnError)
+	gf.AddSource(WithRetry(src, RetryPolicy{MaxAttempts: 3, InitialInterval: time.Millisecond}))
+	err := gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "flaky", s.Str)
+	assert.Equal(t, int32(3), src.attempts)
+
+	// Case 2: too many failures
+	src = &flakySource{failures: 5}
+	gf = New(ContinueOnError)
+	gf.AddSource(WithRetry(src, RetryPolicy{MaxAttempts: 3, InitialInterval: time.Millisecond}))
+	err = gf.ParseWithArgs(buildTestStruct(), []string{})
+	assert.EqualError(t, err, "error loading source flaky: unavailable (after 3 attempts)")
+
+	// Case 3: total deadline
+	src = &flakySource{failures: 1000}
+	gf = New(ContinueOnError)
+	gf.AddSource(WithRetry(src, RetryPolicy{InitialInterval: time.Millisecond, MaxInterval: 5 * time.Millisecond, Deadline: 50 * time.Millisecond}))
+	err = gf.ParseWithArgs(buildTestStruct(), []string{})
+	assert.Error(t, err)
+	assert.True(t, src.attempts > 1 && src.attempts < 1000)
+}
+
+func TestOptional(t *testing.T) {
+	s := buildTestStruct()
+	gf := New(ContinueOnError)
+	gf.AddSource(
+		&testSource{name: "base", doc: &Document{Values: map[string]string{"str": "base"}}},
+		Optional(&testSource{name: "down", err: errors.New("unavailable")}),
+	)
+	err := gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "base", s.Str)
+
+	// wrapped optional sources
+	s = buildTestStruct()
+	gf = New(ContinueOnError)
+	gf.AddSource(
+		&testSource{name: "base", doc: &Document{Values: map[string]string{"str": "base"}}},
+		WithTimeout(Optional(&testSource{name: "down", err: errors.New("unavailable")}), time.Second),
+		WithRetry(Optional(&testSource{name: "down", err: errors.New("unavailable")}), RetryPolicy{MaxAttempts: 2, InitialInterval: time.Millisecond}),
+		WithCache(Optional(&testSource{name: "down", err: errors.New("unavailable")}), time.Minute),
+	)
+	err = gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "base", s.Str)
+	for _, status := range gf.SourcesHealth()[1:] {
+		assert.True(t, status.Optional)
+	}
+}
+
+// countingSource returns a new value on each load
+type countingSource struct {
+	loads int32
+}
+
+func (s *countingSource) Name() string { return "counting" }
+
+func (s *countingSource) Load(ctx context.Context) (*Document, error) {
+	n := atomic.AddInt32(&s.loads, 1)
+	return &Document{Values: map[string]string{"int": fmt.Sprint(n)}}, nil
+}
+
+func TestWithCache(t *testing.T) {
+	src := &countingSource{}
+	cached := WithCache(src, 20*time.Millisecond)
+
+	parse := func() int {
+		s := buildTestStruct()
+		gf := New(ContinueOnError)
+		gf.AddSource(cached)
+		err := gf.ParseWithArgs(s, []string{})
+		assert.NoError(t, err)
+		return s.Int
+	}
+
+	// Case 1: cached value
+	assert.Equal(t, 1, parse())
+	assert.Equal(t, 1, parse())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&src.loads))
+
+	// Case 2: expired, the stale value is returned while refreshing
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, 1, parse())
+	deadline := time.Now().Add(time.Second)
+	for parse() != 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, 2, parse())
+
+	// Case 3: invalidated
+	cached.Invalidate()
+	assert.Equal(t, 3, parse())
+}
+
+// hangingSource blocks until its context is done once its first document is loaded
+type hangingSource struct {
+	loads    int32
+	canceled int32
+}
+
+func (s *hangingSource) Name() string { return "hanging" }
+
+func (s *hangingSource) Load(ctx context.Context) (*Document, error) {
+	if atomic.AddInt32(&s.loads, 1) == 1 {
+		return &Document{Values: map[string]string{"str": "cached"}}, nil
+	}
+	<-ctx.Done()
+	atomic.AddInt32(&s.canceled, 1)
+	return nil, ctx.Err()
+}
+
+func TestCachedSourceRefreshTimeout(t *testing.T) {
+	src := &hangingSource{}
+	cached := WithCache(src, time.Millisecond)
+	cached.SetRefreshTimeout(10 * time.Millisecond)
+
+	// the stale document is kept and refreshed again once the refresh times out
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&src.canceled) < 2 && time.Now().Before(deadline) {
+		s := buildTestStruct()
+		gf := New(ContinueOnError)
+		gf.AddSource(cached)
+		assert.NoError(t, gf.ParseWithArgs(s, []string{}))
+		assert.Equal(t, "cached", s.Str)
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.True(t, atomic.LoadInt32(&src.canceled) >= 2)
+}
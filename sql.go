@@ -0,0 +1,116 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// defaultSQLQuery is the default query of SQLSource.
+const defaultSQLQuery = "SELECT key, value FROM app_config"
+
+// SQLSource loads the configuration from a database table of key/value rows, the keys
+// being dot-separated key paths (e.g. "db.port"). When watching (see Gofig.Watch), the
+// table is polled every PollInterval and the configuration reloaded on changes.
+type SQLSource struct {
+	// DB is the database handle.
+	DB *sql.DB
+	// Query returns the key/value rows, "SELECT key, value FROM app_config" by default.
+	Query string
+	// PollInterval is the polling interval when watching, 0 to disable polling.
+	PollInterval time.Duration
+
+	mu   sync.Mutex
+	last map[string]string // last loaded rows, or polled by Watch
+}
+
+// NewSQLSource returns a Source loading the key/value rows of the query.
+func NewSQLSource(db *sql.DB, query string) *SQLSource {
+	return &SQLSource{DB: db, Query: query}
+}
+
+// Name returns the SQL query.
+func (s *SQLSource) Name() string {
+	return "sql " + s.query()
+}
+
+// Load runs the query.
+func (s *SQLSource) Load(ctx context.Context) (*Document, error) {
+	values, err := s.values(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.last = values
+	s.mu.Unlock()
+
+	return &Document{Values: values}, nil
+}
+
+// Watch polls the query until its result differs from the last loaded one, or the one
+// the last call returned on.
+func (s *SQLSource) Watch(ctx context.Context) error {
+	if s.PollInterval <= 0 {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	ticker := time.NewTicker(s.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		values, err := s.values(ctx)
+		if err != nil {
+			return err
+		}
+		s.mu.Lock()
+		changed := !reflect.DeepEqual(values, s.last)
+		if changed {
+			s.last = values // reported once, until the next change
+		}
+		s.mu.Unlock()
+		if changed {
+			return nil
+		}
+	}
+}
+
+func (s *SQLSource) query() string {
+	if s.Query == "" {
+		return defaultSQLQuery
+	}
+	return s.Query
+}
+
+// values returns the key/value rows of the query.
+func (s *SQLSource) values(ctx context.Context) (map[string]string, error) {
+	rows, err := s.DB.QueryContext(ctx, s.query())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		err = rows.Scan(&key, &value)
+		if err != nil {
+			return nil, err
+		}
+		values[key] = value
+	}
+	return values, rows.Err()
+}
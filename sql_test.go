@@ -0,0 +1,107 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSQLDriver serves the same key/value rows to any query
+type fakeSQLDriver struct {
+	mu   sync.Mutex
+	rows [][2]string
+}
+
+func (d *fakeSQLDriver) Open(name string) (driver.Conn, error) { return &fakeSQLConn{d}, nil }
+
+func (d *fakeSQLDriver) set(rows [][2]string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.rows = rows
+}
+
+type fakeSQLConn struct{ d *fakeSQLDriver }
+
+func (c *fakeSQLConn) Prepare(query string) (driver.Stmt, error) { return &fakeSQLStmt{c.d}, nil }
+func (c *fakeSQLConn) Close() error                              { return nil }
+func (c *fakeSQLConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+type fakeSQLStmt struct{ d *fakeSQLDriver }
+
+func (s *fakeSQLStmt) Close() error                                    { return nil }
+func (s *fakeSQLStmt) NumInput() int                                   { return 0 }
+func (s *fakeSQLStmt) Exec(args []driver.Value) (driver.Result, error) { return nil, driver.ErrSkip }
+func (s *fakeSQLStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	return &fakeSQLRows{rows: append([][2]string(nil), s.d.rows...)}, nil
+}
+
+type fakeSQLRows struct{ rows [][2]string }
+
+func (r *fakeSQLRows) Columns() []string { return []string{"key", "value"} }
+func (r *fakeSQLRows) Close() error      { return nil }
+func (r *fakeSQLRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	dest[0], dest[1] = r.rows[0][0], r.rows[0][1]
+	r.rows = r.rows[1:]
+	return nil
+}
+
+var testSQLDriver = &fakeSQLDriver{}
+
+func init() {
+	sql.Register("gofig-test", testSQLDriver)
+}
+
+func TestSQLSource(t *testing.T) {
+	testSQLDriver.set([][2]string{{"str", "sql"}, {"sub.str", "renamed-sql"}, {"int", "5"}})
+	db, err := sql.Open("gofig-test", "")
+	assert.NoError(t, err)
+	defer db.Close()
+
+	s := buildTestStruct()
+	gf := New(ContinueOnError)
+	src := NewSQLSource(db, "")
+	src.PollInterval = 5 * time.Millisecond
+	gf.AddSource(src)
+	err = gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "sql", s.Str)
+	assert.Equal(t, "renamed-sql", s.Sub.RenamedStr)
+	assert.Equal(t, 5, s.Int)
+	assert.Equal(t, "sql SELECT key, value FROM app_config", src.Name())
+
+	// polling
+	gf.SetWatchQuietPeriod(20 * time.Millisecond) // longer than the poll interval
+	changed := make(chan struct{}, 1)
+	gf.OnChange(func() { changed <- struct{}{} })
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- gf.Watch(ctx) }()
+
+	testSQLDriver.set([][2]string{{"str", "sql"}, {"int", "6"}})
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("configuration not reloaded")
+	}
+	assert.Equal(t, 6, s.Int)
+	assert.Equal(t, "renamed-user-defined", s.Sub.RenamedStr)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
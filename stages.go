@@ -0,0 +1,50 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import "context"
+
+// The stages of the parse pipeline, in order.
+const (
+	// StageSources fetches the sources, v being left unchanged.
+	StageSources = "sources"
+	// StageDocuments decodes the config files, the sources and pushed documents.
+	StageDocuments = "documents"
+	// StageEnv decodes the environment variables.
+	StageEnv = "env"
+	// StageFlags parses the flags.
+	StageFlags = "flags"
+	// StageOverrides applies the runtime overrides.
+	StageOverrides = "overrides"
+	// StageSecrets resolves the secret references (see SetSecretResolver).
+	StageSecrets = "secrets"
+)
+
+// Stage is a stage of the parse pipeline, named by one of the Stage constants, setting
+// the values of its source into v, the pointer to the struct being parsed.
+type Stage func(ctx context.Context, name string, v interface{}) error
+
+// Middleware wraps the stages of the parse pipeline, e.g. to time them, transform the
+// values set by a stage, or veto some of them by restoring the previous values.
+type Middleware func(next Stage) Stage
+
+// Use adds middlewares wrapping each stage of the parse pipeline, including the stages
+// run by the reloads. The first middleware added is the outermost one.
+func Use(mw ...Middleware) { gf.Use(mw...) }
+
+// Use adds middlewares wrapping each stage of the parse pipeline, including the stages
+// run by the reloads. The first middleware added is the outermost one.
+func (gf *Gofig) Use(mw ...Middleware) {
+	gf.middlewares = append(gf.middlewares[:len(gf.middlewares):len(gf.middlewares)], mw...)
+}
+
+// runStage runs a stage wrapped by the middlewares.
+func (gf *Gofig) runStage(ctx context.Context, name string, v interface{}, stage Stage) error {
+	for i := len(gf.middlewares) - 1; i >= 0; i-- {
+		stage = gf.middlewares[i](stage)
+	}
+	return stage(ctx, name, v)
+}
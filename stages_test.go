@@ -0,0 +1,71 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddlewares(t *testing.T) {
+	os.Setenv("MW_STR", "env")
+	defer os.Unsetenv("MW_STR")
+
+	var stages []string
+	s := buildTestStruct()
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("MW")
+	gf.Use(func(next Stage) Stage {
+		return func(ctx context.Context, name string, v interface{}) error {
+			stages = append(stages, name)
+			return next(ctx, name, v)
+		}
+	}, func(next Stage) Stage {
+		return func(ctx context.Context, name string, v interface{}) error {
+			cfg := v.(*TestStruct)
+			prevInt := cfg.Int
+			err := next(ctx, name, v)
+			switch name {
+			case StageEnv:
+				// transform the values
+				cfg.Str = strings.ToUpper(cfg.Str)
+			case StageOverrides:
+				// veto the overrides of int
+				cfg.Int = prevInt
+			}
+			return err
+		}
+	})
+	err := gf.ParseWithArgs(s, []string{"-int", "3"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{StageSources, StageDocuments, StageEnv, StageFlags, StageOverrides, StageSecrets}, stages)
+	assert.Equal(t, "ENV", s.Str)
+	assert.Equal(t, 3, s.Int)
+
+	// the stages of the reloads are wrapped too
+	stages = nil
+	assert.NoError(t, gf.Override("int", "5"))
+	assert.Equal(t, 3, s.Int)
+	assert.Len(t, stages, 6)
+
+	// a stage failing
+	gf = New(ContinueOnError)
+	gf.Use(func(next Stage) Stage {
+		return func(ctx context.Context, name string, v interface{}) error {
+			if name == StageFlags {
+				return errors.New("flags disabled")
+			}
+			return next(ctx, name, v)
+		}
+	})
+	err = gf.ParseWithArgs(buildTestStruct(), []string{})
+	assert.EqualError(t, err, "flags disabled")
+}
@@ -0,0 +1,20 @@
+module github.com/curvegrid/gofig/starconfig
+
+go 1.18
+
+require (
+	github.com/curvegrid/gofig v0.0.0
+	github.com/stretchr/testify v1.3.0
+	go.starlark.net v0.0.0-20231121155337-90ade8b19d09
+)
+
+require (
+	github.com/BurntSushi/toml v1.4.0 // indirect
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/crypto v0.17.0 // indirect
+	golang.org/x/sys v0.15.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/curvegrid/gofig => ../
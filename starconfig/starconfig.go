@@ -0,0 +1,141 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// Package starconfig evaluates Starlark config files for gofig. It is a separate module,
+// so that the users of gofig which don't need Starlark don't depend on its interpreter.
+//
+// A Starlark config file defines a config dict, decoded as a JSON document:
+//
+//	config = {
+//	    "region": getenv("REGION", "eu"),
+//	    "replicas": [{"port": 8080 + i} for i in range(3)],
+//	}
+//
+// The scripts are sandboxed: they can't load modules nor access files, and only read
+// the environment variables allowed with WithEnv.
+package starconfig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/curvegrid/gofig"
+	starlarkjson "go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+)
+
+// Ext is the extension of the Starlark config files
+const Ext = ".star"
+
+// configGlobal is the global variable holding the configuration built by a script
+const configGlobal = "config"
+
+// options are the options of the evaluation of the Starlark config files
+type options struct {
+	env      map[string]bool
+	maxSteps uint64
+}
+
+// Option is an option of the evaluation of the Starlark config files.
+type Option func(o *options)
+
+// WithEnv allows the scripts to read the environment variables names with
+// getenv(name, default=None), which returns default if the variable isn't set. Reading
+// other variables is an error.
+func WithEnv(names ...string) Option {
+	return func(o *options) {
+		for _, name := range names {
+			o.env[name] = true
+		}
+	}
+}
+
+// WithMaxSteps limits the number of computation steps of a script, so that an infinite
+// loop is an error.
+func WithMaxSteps(steps uint64) Option {
+	return func(o *options) {
+		o.maxSteps = steps
+	}
+}
+
+// Transformer returns a gofig transformer evaluating a Starlark config file into the
+// JSON document of its config dict, to be registered in json format, e.g.
+// gofig.AddTransformer(starconfig.Ext, "json", starconfig.Transformer()). The evaluation
+// is canceled with the context.
+func Transformer(opts ...Option) gofig.Transformer {
+	o := options{env: make(map[string]bool)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return func(ctx context.Context, path string, data []byte) ([]byte, error) {
+		return eval(ctx, path, data, &o)
+	}
+}
+
+// eval evaluates a Starlark config file.
+func eval(ctx context.Context, path string, data []byte, o *options) ([]byte, error) {
+	// no Load function: the load statements are errors
+	thread := &starlark.Thread{
+		Name:  path,
+		Print: func(*starlark.Thread, string) {},
+	}
+	if o.maxSteps > 0 {
+		thread.SetMaxExecutionSteps(o.maxSteps)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			thread.Cancel(ctx.Err().Error())
+		case <-done:
+		}
+	}()
+
+	predeclared := starlark.StringDict{
+		"getenv": starlark.NewBuiltin("getenv", o.getenv),
+		"json":   starlarkjson.Module,
+	}
+	globals, err := starlark.ExecFile(thread, path, data, predeclared)
+	if err != nil {
+		var evalErr *starlark.EvalError
+		if errors.As(err, &evalErr) {
+			return nil, errors.New(evalErr.Backtrace())
+		}
+		return nil, err
+	}
+
+	cfg, ok := globals[configGlobal]
+	if !ok {
+		return nil, fmt.Errorf("the script doesn't define %v", configGlobal)
+	}
+	if _, ok := cfg.(*starlark.Dict); !ok {
+		return nil, fmt.Errorf("%v must be a dict, not %v", configGlobal, cfg.Type())
+	}
+	out, err := starlark.Call(thread, starlarkjson.Module.Members["encode"], starlark.Tuple{cfg}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(out.(starlark.String).GoString()), nil
+}
+
+// getenv is the getenv builtin of the scripts, reading the allowed environment variables.
+func (o *options) getenv(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name string
+	var def starlark.Value = starlark.None
+	if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "name", &name, "default?", &def); err != nil {
+		return nil, err
+	}
+	if !o.env[name] {
+		return nil, fmt.Errorf("%v: environment variable %v isn't allowed", fn.Name(), name)
+	}
+	val, ok := os.LookupEnv(name)
+	if !ok {
+		return def, nil
+	}
+	return starlark.String(val), nil
+}
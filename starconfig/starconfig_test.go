@@ -0,0 +1,84 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package starconfig
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/curvegrid/gofig"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformer(t *testing.T) {
+	os.Setenv("STARCONFIG_REGION", "us")
+	defer os.Unsetenv("STARCONFIG_REGION")
+	eval := func(script string, opts ...Option) (string, error) {
+		out, err := Transformer(opts...)(context.Background(), "config.star", []byte(script))
+		return string(out), err
+	}
+
+	// Case 1: the config dict, with the allowed environment variables
+	out, err := eval(`
+def port(i):
+    return 8080 + i
+
+config = {
+    "region": getenv("STARCONFIG_REGION"),
+    "zone": getenv("STARCONFIG_ZONE", "a"),
+    "ports": [port(i) for i in range(3)],
+}
+`, WithEnv("STARCONFIG_REGION", "STARCONFIG_ZONE"))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"ports":[8080,8081,8082],"region":"us","zone":"a"}`, out)
+
+	// Case 2: the sandbox
+	_, err = eval(`config = {"region": getenv("STARCONFIG_REGION")}`)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "getenv: environment variable STARCONFIG_REGION isn't allowed")
+	_, err = eval("load(\"secrets.star\", \"token\")\nconfig = {}\n")
+	assert.Error(t, err)
+	_, err = eval("config = {\"data\": open(\"/etc/passwd\")}\n")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "undefined: open")
+	_, err = eval("def loop():\n    for i in range(1000000000):\n        pass\n\nloop()\nconfig = {}\n", WithMaxSteps(1000))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "too many steps")
+
+	// Case 3: invalid configurations
+	_, err = eval(`cfg = {}`)
+	assert.EqualError(t, err, "the script doesn't define config")
+	_, err = eval(`config = [1]`)
+	assert.EqualError(t, err, "config must be a dict, not list")
+
+	// Case 4: canceled evaluation
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = Transformer()(ctx, "config.star", []byte("def loop():\n    for i in range(1000000000):\n        pass\n\nloop()\n"))
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "deadline exceeded"), err.Error())
+}
+
+func TestConfigFile(t *testing.T) {
+	type Config struct {
+		Region string `json:"region"`
+		Ports  []int  `json:"ports"`
+	}
+	path := filepath.Join(t.TempDir(), "config.star")
+	assert.NoError(t, os.WriteFile(path, []byte(`config = {"region": "eu", "ports": [80 + i for i in range(2)]}`), 0600))
+
+	gf := gofig.New(gofig.ContinueOnError)
+	gf.SetConfigFileFlag("config", "config file")
+	gf.AddTransformer(Ext, "json", Transformer())
+	s := &Config{}
+	err := gf.ParseWithArgs(s, []string{"-config", path})
+	assert.NoError(t, err)
+	assert.Equal(t, Config{Region: "eu", Ports: []int{80, 81}}, *s)
+}
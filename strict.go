@@ -0,0 +1,69 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"sort"
+	"strings"
+)
+
+// SetStrict enables or disables the strict mode, where the keys of the config documents
+// (config files, sources and pushed documents) which aren't mapped to any field are
+// errors instead of being ignored, so that a typo in a key isn't silently dropped. The
+// keys of the children and of the tenants are mapped by their structs, and the YAML
+// templates (see UnusedKeys) are accepted.
+func SetStrict(enabled bool) { gf.SetStrict(enabled) }
+
+// SetStrict enables or disables the strict mode, where the keys of the config documents
+// (config files, sources and pushed documents) which aren't mapped to any field are
+// errors instead of being ignored, so that a typo in a key isn't silently dropped. The
+// keys of the children and of the tenants are mapped by their structs, and the YAML
+// templates (see UnusedKeys) are accepted.
+func (gf *Gofig) SetStrict(enabled bool) {
+	gf.strict = enabled
+}
+
+// claim records a key path decoded by a child or the tenants, which isn't unknown to gf
+// in strict mode.
+func (gf *Gofig) claim(path string) {
+	gf.mu.Lock()
+	defer gf.mu.Unlock()
+	if !containsString(gf.claimed, path) {
+		gf.claimed = append(gf.claimed, path)
+	}
+}
+
+// checkUnknownKeys returns an error in strict mode if some of the unused keys collected
+// during a parse start with prefix and aren't claimed by a child or the tenants.
+func (gf *Gofig) checkUnknownKeys(unused map[string]struct{}, prefix string) error {
+	if !gf.strict {
+		return nil
+	}
+	var unknown []string
+	for key := range unused {
+		if strings.HasPrefix(key, prefix) && !gf.isClaimed(key) {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	if len(unknown) == 1 {
+		return errorf("unknown key '%v' in the config documents", unknown[0])
+	}
+	return errorf("unknown keys in the config documents: %v", strings.Join(unknown, ", "))
+}
+
+// isClaimed returns whether a key path is decoded by a child or the tenants.
+func (gf *Gofig) isClaimed(key string) bool {
+	for _, path := range gf.claimed {
+		if key == path || strings.HasPrefix(key, path+".") {
+			return true
+		}
+	}
+	return false
+}
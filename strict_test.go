@@ -0,0 +1,62 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStrict(t *testing.T) {
+	type DB struct {
+		Host string `json:"host" toml:"host" yaml:"host"`
+	}
+	type Config struct {
+		Name string `json:"name" toml:"name" yaml:"name"`
+		DB   DB     `json:"db" toml:"db" yaml:"db"`
+	}
+	parse := func(strict bool, format string, doc string) error {
+		gf := New(ContinueOnError)
+		gf.SetStrict(strict)
+		gf.AddSource(&testSource{name: format, doc: &Document{Format: format, Data: []byte(doc)}})
+		return gf.ParseWithArgs(&Config{}, []string{})
+	}
+
+	// Case 1: unknown keys of each format
+	err := parse(true, "json", `{"name": "a", "db": {"hots": "b"}}`)
+	assert.EqualError(t, err, "unknown key 'db.hots' in the config documents")
+	err = parse(true, "toml", "nmae = \"a\"\n[db]\nhots = \"b\"\n")
+	assert.EqualError(t, err, "unknown keys in the config documents: db.hots, nmae")
+	err = parse(true, "yaml", "name: a\nport: 1\n")
+	assert.EqualError(t, err, "unknown key 'port' in the config documents")
+
+	// Case 2: known keys, YAML templates and the default mode
+	assert.NoError(t, parse(true, "yaml", "x-db: &db {host: b}\nname: a\ndb: *db\n"))
+	assert.NoError(t, parse(false, "json", `{"name": "a", "db": {"hots": "b"}}`))
+
+	// Case 3: the keys of the children are known by the parent
+	gf := New(ContinueOnError)
+	gf.SetStrict(true)
+	gf.AddSource(&testSource{name: "yaml", doc: &Document{Format: "yaml", Data: []byte("name: a\ncache: {size: 1}\n")}})
+	type Cache struct {
+		Size int `yaml:"size"`
+	}
+	child := gf.Child("cache")
+	assert.NoError(t, child.ParseWithArgs(&Cache{}, []string{}))
+	assert.NoError(t, gf.ParseWithArgs(&Config{}, []string{}))
+	child.AddSource(&testSource{name: "typo", doc: &Document{Format: "yaml", Data: []byte("cache: {sise: 1}\n")}})
+	err = child.ParseWithArgs(&Cache{}, []string{})
+	assert.EqualError(t, err, "unknown key 'cache.sise' in the config documents")
+
+	// Case 4: the keys of the tenants are known
+	gf = New(ContinueOnError)
+	gf.SetStrict(true)
+	gf.AddSource(&testSource{name: "yaml", doc: &Document{Format: "yaml", Data: []byte("name: a\ntenants: {acme: {host: b}}\n")}})
+	tenants, err := gf.ParseTenantsWithArgs(&Config{}, func() interface{} { return &DB{} }, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "b", tenants["acme"].(*DB).Host)
+}
@@ -0,0 +1,240 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+const (
+	// bundleConfigFile is the file of a support bundle holding the effective configuration
+	bundleConfigFile = "config.yaml"
+	// bundleInfoFile is the file of a support bundle holding the other information
+	bundleInfoFile = "bundle.json"
+)
+
+// The sources of the values of the fields, of the provenance of a support bundle
+// following the order of priority, and of a FieldError.
+const (
+	ProvenanceOverrideFlag = "override flag"
+	ProvenanceOverride     = "override"
+	ProvenanceFlag         = "flag"
+	ProvenanceEnv          = "env"
+	ProvenanceConfig       = "config" // a config file, source or pushed document
+	ProvenanceDefault      = "default"
+	ProvenanceSecret       = "secret" // a keyring, credential or secret reference, only for a FieldError
+)
+
+// SupportBundle is the snapshot of a parsed configuration written by
+// ExportSupportBundle and read by LoadSupportBundle, to be attached to support tickets.
+type SupportBundle struct {
+	// Created is the creation time of the bundle.
+	Created time.Time `json:"created"`
+	// Versions are the versions of Go, gofig and the main module, if known.
+	Versions map[string]string `json:"versions"`
+	// Provenance is the source of the value of each field, by key path, e.g.
+	// ProvenanceEnv.
+	Provenance map[string]string `json:"provenance"`
+	// Sources is the fetch status of each source.
+	Sources []SupportBundleSource `json:"sources,omitempty"`
+	// Warnings are the warnings of the last parse.
+	Warnings []string `json:"warnings,omitempty"`
+	// UnusedKeys are the keys of the config documents not mapped to any field.
+	UnusedKeys []string `json:"unusedKeys,omitempty"`
+	// InactiveSections are the key paths of the sections disabled by their toggle.
+	InactiveSections []string `json:"inactiveSections,omitempty"`
+	// Config is the effective configuration as YAML, the secrets being redacted.
+	Config []byte `json:"-"`
+}
+
+// SupportBundleSource is the fetch status of a source in a support bundle.
+type SupportBundleSource struct {
+	Name          string    `json:"name"`
+	Optional      bool      `json:"optional,omitempty"`
+	LastFetch     time.Time `json:"lastFetch,omitempty"`
+	LastError     string    `json:"lastError,omitempty"`
+	LastErrorTime time.Time `json:"lastErrorTime,omitempty"`
+}
+
+// ExportSupportBundle writes a gzipped tar archive of the parsed configuration for
+// support tickets: the effective configuration with the secrets redacted, the source
+// of the value of each field, the status of the sources, the warnings, unused keys and
+// inactive sections of the last parse, and the versions of Go, gofig and the program.
+// It can be inspected offline with LoadSupportBundle.
+func ExportSupportBundle(w io.Writer) error { return gf.ExportSupportBundle(w) }
+
+// ExportSupportBundle writes a gzipped tar archive of the parsed configuration for
+// support tickets: the effective configuration with the secrets redacted, the source
+// of the value of each field, the status of the sources, the warnings, unused keys and
+// inactive sections of the last parse, and the versions of Go, gofig and the program.
+// It can be inspected offline with LoadSupportBundle.
+func (gf *Gofig) ExportSupportBundle(w io.Writer) error {
+	bundle := &SupportBundle{
+		Created:          time.Now().UTC(),
+		Versions:         buildVersions(),
+		Warnings:         gf.Warnings(),
+		UnusedKeys:       gf.UnusedKeys(),
+		InactiveSections: gf.InactiveSections(),
+	}
+	for _, s := range gf.SourcesHealth() {
+		src := SupportBundleSource{Name: s.Name, Optional: s.Optional, LastFetch: s.LastFetch, LastErrorTime: s.LastErrorTime}
+		if s.LastError != nil {
+			src.LastError = s.LastError.Error()
+		}
+		bundle.Sources = append(bundle.Sources, src)
+	}
+
+	gf.mu.Lock()
+	if gf.target == nil {
+		gf.mu.Unlock()
+		return errNotParsed
+	}
+	bundle.Provenance = gf.provenance()
+	var config bytes.Buffer
+	err := gf.encodeSections(&config, gf.redactSecrets(gf.target))
+	gf.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	bundle.Config = config.Bytes()
+
+	info, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, file := range []struct {
+		name string
+		data []byte
+	}{{bundleInfoFile, info}, {bundleConfigFile, bundle.Config}} {
+		err = tw.WriteHeader(&tar.Header{Name: file.name, Mode: 0600, Size: int64(len(file.data)), ModTime: bundle.Created})
+		if err == nil {
+			_, err = tw.Write(file.data)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	err = tw.Close()
+	if err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// LoadSupportBundle reads a support bundle written by ExportSupportBundle.
+func LoadSupportBundle(r io.Reader) (*SupportBundle, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, errorf("invalid support bundle: %v", err)
+	}
+	defer gz.Close()
+
+	bundle := &SupportBundle{}
+	found := 0
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, errorf("invalid support bundle: %v", err)
+		}
+		switch hdr.Name {
+		case bundleInfoFile:
+			err = json.NewDecoder(tr).Decode(bundle)
+		case bundleConfigFile:
+			bundle.Config, err = io.ReadAll(tr)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, errorf("invalid support bundle file %v: %v", hdr.Name, err)
+		}
+		found++
+	}
+	if found < 2 {
+		return nil, errorf("invalid support bundle: missing %v or %v", bundleInfoFile, bundleConfigFile)
+	}
+	return bundle, nil
+}
+
+// provenance returns the source of the value of each field of the parsed configuration,
+// by key path, following the order of priority.
+func (gf *Gofig) provenance() map[string]string {
+	setFlags := make(map[string]bool)
+	if gf.flagSet.Parsed() {
+		gf.flagSet.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+	}
+	setKeys := make(map[string]bool)
+	if gf.setFlagName != "" {
+		for _, kv := range flagArgs(gf.args, gf.setFlagName) {
+			setKeys[strings.ToLower(strings.TrimSpace(strings.SplitN(kv, "=", 2)[0]))] = true
+		}
+	}
+	// the user-defined values and the default tags
+	var defaults map[string]string
+	if gf.defaults.IsValid() {
+		ptr := reflect.New(gf.defaults.Type())
+		ptr.Elem().Set(gf.defaults)
+		_ = applyDefaults(ptr.Interface(), nil)
+		defaults = Flatten(ptr.Interface())
+	}
+	values := Flatten(gf.target)
+
+	// the flags and env variables of a child are scoped, not its keys
+	names := gf.fieldNames(gf.target, "json", true)
+	keys := gf.fieldNames(gf.target, "json", false)
+	provenance := make(map[string]string, len(names))
+	for name, n := range names {
+		key := keys[name].key
+		_, overridden := gf.overrides[key]
+		_, env := gf.lookupEnv(n.env)
+		switch {
+		case setKeys[key]:
+			provenance[key] = ProvenanceOverrideFlag
+		case overridden:
+			provenance[key] = ProvenanceOverride
+		case setFlags[n.flag]:
+			provenance[key] = ProvenanceFlag
+		case env:
+			provenance[key] = ProvenanceEnv
+		case values[key] != defaults[key]:
+			provenance[key] = ProvenanceConfig
+		default:
+			provenance[key] = ProvenanceDefault
+		}
+	}
+	return provenance
+}
+
+// buildVersions returns the versions of Go, gofig and the main module.
+func buildVersions() map[string]string {
+	versions := map[string]string{"go": runtime.Version()}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return versions
+	}
+	versions[info.Main.Path] = info.Main.Version
+	for _, dep := range info.Deps {
+		if dep.Path == "github.com/curvegrid/gofig" {
+			versions[dep.Path] = dep.Version
+		}
+	}
+	return versions
+}
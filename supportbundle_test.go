@@ -0,0 +1,106 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type bundleStruct struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port" default:"8080"`
+	Workers  int    `json:"workers"`
+	Level    string `json:"level"`
+	Region   string `json:"region"`
+	Name     string `json:"name"`
+	Password string `json:"password" secret:"true"`
+}
+
+func TestSupportBundle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte("host: db\npassword: hunter2\nextra: 1\n"), 0600)
+	assert.NoError(t, err)
+	os.Setenv("GFB_WORKERS", "4")
+	defer os.Unsetenv("GFB_WORKERS")
+
+	// export
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("GFB")
+	gf.SetConfigFileFlag("config", "config file")
+	gf.SetOverrideFlag("set", "override a `key=value`")
+	err = gf.ExportSupportBundle(&bytes.Buffer{})
+	assert.Equal(t, errNotParsed, err)
+
+	err = gf.ParseWithArgs(&bundleStruct{Name: "app"}, []string{"-config", path, "-level", "debug", "-set", "region=eu"})
+	assert.NoError(t, err)
+	assert.NoError(t, gf.Override("name", "api"))
+	var b bytes.Buffer
+	err = gf.ExportSupportBundle(&b)
+	assert.NoError(t, err)
+
+	// load
+	bundle, err := LoadSupportBundle(&b)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"host":     ProvenanceConfig,
+		"port":     ProvenanceDefault,
+		"workers":  ProvenanceEnv,
+		"level":    ProvenanceFlag,
+		"region":   ProvenanceOverrideFlag,
+		"name":     ProvenanceOverride,
+		"password": ProvenanceConfig,
+	}, bundle.Provenance)
+	assert.Equal(t, []string{"extra"}, bundle.UnusedKeys)
+	assert.Equal(t, runtime.Version(), bundle.Versions["go"])
+	assert.False(t, bundle.Created.IsZero())
+	config := string(bundle.Config)
+	assert.Contains(t, config, "host: db\n")
+	assert.Contains(t, config, "name: api\n")
+	assert.NotContains(t, config, "hunter2")
+
+	// invalid bundles
+	_, err = LoadSupportBundle(strings.NewReader("not a bundle"))
+	assert.Error(t, err)
+}
+
+func TestSupportBundleSecrets(t *testing.T) {
+	type Config struct {
+		Host   string `json:"host"`
+		Token  string `json:"token" keyring:"mycli/token"`
+		APIKey string `json:"apikey"`
+	}
+
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("GFB")
+	gf.SetKeyring(testKeyring{"mycli/token": "SUPERSECRET"})
+	gf.SetSecretResolver("vault", testResolver{})
+	s := &Config{}
+	err := gf.ParseWithArgs(s, []string{"-host", "db", "-apikey", "vault://api/key"})
+	assert.NoError(t, err)
+	assert.Equal(t, &Config{Host: "db", Token: "SUPERSECRET", APIKey: "secret"}, s)
+
+	// Case 1: keyring and resolved values redacted from the bundle
+	var b bytes.Buffer
+	assert.NoError(t, gf.ExportSupportBundle(&b))
+	bundle, err := LoadSupportBundle(&b)
+	assert.NoError(t, err)
+	config := string(bundle.Config)
+	assert.Contains(t, config, "host: db\n")
+	assert.NotContains(t, config, "SUPERSECRET")
+	assert.Contains(t, config, "apikey: <redacted>\n")
+
+	// Case 2: and from the exported environment variables
+	b.Reset()
+	assert.NoError(t, gf.ExportEnv(s, &b))
+	assert.Equal(t, "export GFB_HOST='db'\n# export GFB_TOKEN=<redacted>\n# export GFB_APIKEY=<redacted>\n", b.String())
+}
@@ -0,0 +1,222 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+const (
+	// credentialTag is the struct tag naming the systemd credential of a field, e.g.
+	// `credential:"db-password"`.
+	credentialTag = "credential"
+	// credentialsDirEnvKey is the environment variable set by systemd to the directory
+	// of the credentials of a service.
+	credentialsDirEnvKey = "CREDENTIALS_DIRECTORY"
+)
+
+// EnvFileSource loads a file in the syntax of the systemd EnvironmentFile directive:
+// KEY=VALUE assignments, one per line, with optional single or double quotes and
+// backslash line continuations, and # or ; comment lines. The variables are decoded
+// like environment variables, and like those must include the prefix (e.g.
+// GF_DB_PORT=5432). Wrap it with Optional for the files prefixed with "-" in the unit.
+type EnvFileSource struct {
+	// Path of the environment file.
+	Path string
+}
+
+// NewEnvFileSource returns a Source loading the environment file at path.
+func NewEnvFileSource(path string) *EnvFileSource {
+	return &EnvFileSource{Path: path}
+}
+
+// AddEnvFile adds a systemd environment file as a source, see EnvFileSource.
+func AddEnvFile(path string) { gf.AddEnvFile(path) }
+
+// AddEnvFile adds a systemd environment file as a source, see EnvFileSource.
+func (gf *Gofig) AddEnvFile(path string) {
+	gf.AddSource(NewEnvFileSource(path))
+}
+
+// Name returns the environment file path.
+func (s *EnvFileSource) Name() string {
+	return s.Path
+}
+
+// Load reads the environment file.
+func (s *EnvFileSource) Load(ctx context.Context) (*Document, error) {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	env, err := parseEnvFile(string(data))
+	if err != nil {
+		return nil, err
+	}
+	return &Document{Env: env}, nil
+}
+
+// parseEnvFile parses the assignments of an environment file.
+func parseEnvFile(data string) (map[string]string, error) {
+	env := make(map[string]string)
+	lines := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNum := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		// join the continuation lines
+		for strings.HasSuffix(line, `\`) && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + strings.TrimSpace(lines[i])
+		}
+
+		eq := strings.IndexByte(line, '=')
+		if eq < 0 {
+			return nil, fmt.Errorf("line %v: missing '=' in assignment", lineNum)
+		}
+		key := strings.TrimSpace(line[:eq])
+		if key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("line %v: invalid variable name '%v'", lineNum, key)
+		}
+		val, err := unquoteEnvValue(strings.TrimSpace(line[eq+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %v", lineNum, err)
+		}
+		env[key] = val
+	}
+	return env, nil
+}
+
+// unquoteEnvValue returns the value of an assignment, removing its quotes. The quoted
+// parts can be concatenated with unquoted ones, e.g. "a b"c.
+func unquoteEnvValue(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '"', '\'':
+			end := i + 1
+			for ; end < len(s) && s[end] != c; end++ {
+				if c == '"' && s[end] == '\\' && end+1 < len(s) {
+					end++
+					b.WriteByte(unescapeEnvChar(s[end]))
+					continue
+				}
+				b.WriteByte(s[end])
+			}
+			if end >= len(s) {
+				return "", fmt.Errorf("unterminated quoted value")
+			}
+			i = end
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				c = s[i]
+			}
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// unescapeEnvChar returns the character of a backslash escape of a double-quoted value.
+func unescapeEnvChar(c byte) byte {
+	switch c {
+	case 'n':
+		return '\n'
+	case 't':
+		return '\t'
+	}
+	return c
+}
+
+// CredentialsSource loads the systemd credentials of a service (from the LoadCredential,
+// LoadCredentialEncrypted and SetCredential directives), one file per credential, into
+// the fields tagged with their name, e.g. `credential:"db-password"`. A trailing newline
+// of the credentials is removed.
+type CredentialsSource struct {
+	// Dir is the credentials directory, $CREDENTIALS_DIRECTORY if empty. There are no
+	// credentials if neither is set.
+	Dir string
+}
+
+// NewCredentialsSource returns a Source loading the systemd credentials in dir, or in
+// $CREDENTIALS_DIRECTORY if dir is empty.
+func NewCredentialsSource(dir string) *CredentialsSource {
+	return &CredentialsSource{Dir: dir}
+}
+
+// AddCredentials adds the systemd credentials of the service as a source, see
+// CredentialsSource.
+func AddCredentials() { gf.AddCredentials() }
+
+// AddCredentials adds the systemd credentials of the service as a source, see
+// CredentialsSource.
+func (gf *Gofig) AddCredentials() {
+	gf.AddSource(NewCredentialsSource(""))
+}
+
+// Name returns the credentials directory.
+func (s *CredentialsSource) Name() string {
+	if s.Dir == "" {
+		return "$" + credentialsDirEnvKey
+	}
+	return s.Dir
+}
+
+// Load reads the credentials files.
+func (s *CredentialsSource) Load(ctx context.Context) (*Document, error) {
+	dir := s.Dir
+	if dir == "" {
+		dir = os.Getenv(credentialsDirEnvKey)
+	}
+	if dir == "" {
+		return &Document{}, nil // not run by systemd, or without credentials
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	creds := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		creds[entry.Name()] = strings.TrimSuffix(string(data), "\n")
+	}
+	return &Document{Credentials: creds}, nil
+}
+
+// decodeCredentials decodes credentials keyed by name into the fields of v tagged with
+// their name.
+func decodeCredentials(creds map[string]string, v interface{}) error {
+	return parseStruct(v, func(path []string, name string, f *reflect.Value, tags *reflect.StructTag) error {
+		cred := tags.Get(credentialTag)
+		val, ok := creds[cred]
+		if cred == "" || !ok {
+			return nil
+		}
+		err := decodeField(f, val, tags)
+		if err != nil {
+			// the value of a credential is secret
+			return &fieldError{name: name, source: ProvenanceSecret, err: errorf("error parsing credential '%v' into %v", cred, f.Type())}
+		}
+		return nil
+	}, "json")
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddEnvFile(t *testing.T) {
+	type Config struct {
+		Host string
+		Port int
+		Name string
+		Args string
+	}
+	envFile := `# comment
+; another comment
+GFS_HOST=example.com
+GFS_PORT = 8080
+GFS_NAME="a \"quoted\" name"
+GFS_ARGS='-v' \
+  -x
+`
+	dir := t.TempDir()
+	path := filepath.Join(dir, "service.env")
+	assert.NoError(t, os.WriteFile(path, []byte(envFile), 0644))
+
+	s := &Config{}
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("GFS")
+	gf.AddEnvFile(path)
+	gf.AddSource(Optional(NewEnvFileSource(filepath.Join(dir, "missing.env"))))
+	err := gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, &Config{Host: "example.com", Port: 8080, Name: `a "quoted" name`, Args: "-v -x"}, s)
+
+	// errors
+	for data, expected := range map[string]string{
+		"GFS_HOST":    "line 1: missing '=' in assignment",
+		"\nA B=1":     "line 2: invalid variable name 'A B'",
+		`GFS_HOST="a`: "line 1: unterminated quoted value",
+		"=1":          "line 1: invalid variable name ''",
+	} {
+		_, err := parseEnvFile(data)
+		assert.EqualError(t, err, expected, data)
+	}
+}
+
+func TestAddCredentials(t *testing.T) {
+	type Config struct {
+		DB struct {
+			Password string `credential:"db-password"`
+			Port     int    `credential:"db-port"`
+		}
+		Token string `credential:"token"`
+	}
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "db-password"), []byte("s3cret\n"), 0600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "db-port"), []byte("5432"), 0600))
+
+	// Case 1: credentials directory set by systemd
+	os.Setenv(credentialsDirEnvKey, dir)
+	defer os.Unsetenv(credentialsDirEnvKey)
+	s := &Config{Token: "default"}
+	gf := New(ContinueOnError)
+	gf.AddCredentials()
+	err := gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cret", s.DB.Password)
+	assert.Equal(t, 5432, s.DB.Port)
+	assert.Equal(t, "default", s.Token)
+
+	// Case 2: no credentials directory
+	os.Unsetenv(credentialsDirEnvKey)
+	s = &Config{}
+	gf = New(ContinueOnError)
+	gf.AddCredentials()
+	assert.NoError(t, gf.ParseWithArgs(s, []string{}))
+	assert.Equal(t, "", s.DB.Password)
+
+	// Case 3: invalid value, which isn't reported
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "db-port"), []byte("secret"), 0600))
+	gf = New(ContinueOnError)
+	gf.AddSource(NewCredentialsSource(dir))
+	err = gf.ParseWithArgs(&Config{}, []string{})
+	assert.EqualError(t, err, "error decoding source "+dir+": error parsing credential 'db-port' into int (flag -db-port, env DB_PORT, key db.port)")
+}
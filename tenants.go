@@ -0,0 +1,204 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// tenantsKey is the top-level key of the tenants map in config documents, and the
+// prefix of the tenants environment variables and flags.
+const (
+	tenantsKey = "tenants"
+	tenantKey  = "tenant"
+)
+
+// tenantName is the pattern of the valid tenant names, which are part of environment
+// variable and flag names.
+var tenantName = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// ParseTenants parses v like Parse, and the config sections of the top-level "tenants"
+// map of the config documents into per-tenant structs created by factory, which must
+// return a pointer to a struct holding the tenant default values. The tenant values can
+// be overridden by environment variables and flags, e.g. PREFIX_TENANT_ACME_DB_PORT and
+// -tenant-acme-db-port, and by runtime overrides before parsing, e.g. "tenants.acme.db.port".
+// The sources are loaded once for v and the tenants, whose secret references are resolved
+// and fields checked like the fields of v. Tenants are not updated when the configuration
+// is reloaded.
+func ParseTenants(v interface{}, factory func() interface{}) (map[string]interface{}, error) {
+	return gf.ParseTenants(v, factory)
+}
+
+// ParseTenants parses v like Parse, and the config sections of the top-level "tenants"
+// map of the config documents into per-tenant structs created by factory, which must
+// return a pointer to a struct holding the tenant default values. The tenant values can
+// be overridden by environment variables and flags, e.g. PREFIX_TENANT_ACME_DB_PORT and
+// -tenant-acme-db-port, and by runtime overrides before parsing, e.g. "tenants.acme.db.port".
+// The sources are loaded once for v and the tenants, whose secret references are resolved
+// and fields checked like the fields of v. Tenants are not updated when the configuration
+// is reloaded.
+func (gf *Gofig) ParseTenants(v interface{}, factory func() interface{}) (map[string]interface{}, error) {
+	return gf.ParseTenantsWithArgs(v, factory, os.Args[1:])
+}
+
+// ParseTenantsWithArgs is like ParseTenants with the provided arguments.
+func (gf *Gofig) ParseTenantsWithArgs(v interface{}, factory func() interface{}, args []string) (map[string]interface{}, error) {
+	tenants, err := gf.parseTenants(v, factory, args)
+	err = gf.handleError(gf.formatError(err))
+	if err != nil {
+		return nil, err
+	}
+	return tenants, nil
+}
+
+func (gf *Gofig) parseTenants(v interface{}, factory func() interface{}, args []string) (map[string]interface{}, error) {
+	// find the tenant names in the config documents
+	ctx, cancel := gf.parseContext()
+	defer cancel()
+	expanded, err := gf.expandArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := gf.loadSources(ctx, expanded)
+	if err != nil {
+		return nil, err
+	}
+	probe := &struct {
+		Tenants map[string]interface{} `json:"tenants" toml:"tenants" yaml:"tenants" env:"-" flag:"-"`
+	}{}
+	err = gf.decodeDocuments(ctx, probe, expanded, docs)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(probe.Tenants))
+	for name := range probe.Tenants {
+		if !tenantName.MatchString(name) {
+			return nil, errorf("invalid tenant name '%v', only letters, digits and underscores are allowed", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// decode the tenants through a struct with a field for each tenant
+	tenants := make(map[string]interface{}, len(names))
+	fields := make([]reflect.StructField, len(names))
+	for i, name := range names {
+		tenant := reflect.ValueOf(factory())
+		if tenant.Kind() != reflect.Ptr || tenant.IsNil() || tenant.Elem().Kind() != reflect.Struct {
+			return nil, errInvalidValue
+		}
+		tenants[name] = tenant.Interface()
+		fields[i] = reflect.StructField{
+			Name: "T" + strconv.Itoa(i),
+			Type: tenant.Elem().Type(),
+			Tag:  reflect.StructTag(fmt.Sprintf(`json:%[1]q toml:%[1]q yaml:%[1]q env:%[1]q flag:%[1]q`, name)),
+		}
+	}
+	wrapper := reflect.New(reflect.StructOf([]reflect.StructField{{
+		Name: "Tenants",
+		Type: reflect.StructOf(fields),
+		Tag:  reflect.StructTag(fmt.Sprintf(`json:%[1]q toml:%[1]q yaml:%[1]q env:%[2]q flag:%[2]q`, tenantsKey, tenantKey)),
+	}}))
+	for i, name := range names {
+		wrapper.Elem().Field(0).Field(i).Set(reflect.ValueOf(tenants[name]).Elem())
+	}
+
+	gf.claim(tenantsKey)
+	gf.mu.Lock()
+	decoded := make(map[string]bool)
+	gf.decoded = decoded // the key paths set by the documents, for the required fields
+	hooks, err := fieldHooks(wrapper.Interface())
+	if err == nil {
+		err = applyDefaults(wrapper.Interface(), hooks)
+	}
+	if err == nil {
+		err = gf.decodeDocuments(ctx, wrapper.Interface(), expanded, docs)
+	}
+	gf.decoded = nil
+	if err == nil {
+		err = parseStruct(wrapper.Interface(), gf.envDecoder(hooks), "env")
+	}
+	if err == nil {
+		// the tenants flags are parsed along with the flags of v
+		err = parseStruct(wrapper.Interface(), gf.flagBuilder(gf.flagSet, hooks), "flag")
+	}
+	gf.loaded = docs // v is decoded from the same documents, rather than loaded again
+	gf.mu.Unlock()
+	if err == nil {
+		err = gf.parse(v, args)
+	}
+	gf.mu.Lock()
+	gf.loaded = nil
+	gf.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	gf.mu.Lock()
+	err = withSource(decodeValues(gf.overrides, wrapper.Interface()), ProvenanceOverride)
+	if err == nil {
+		err = gf.checkTenants(ctx, wrapper.Interface(), decoded)
+	}
+	// the tenants keys are used by the tenants
+	unused := gf.unusedKeys[:0]
+	for _, key := range gf.unusedKeys {
+		if key != tenantsKey && !strings.HasPrefix(key, tenantsKey+".") {
+			unused = append(unused, key)
+		}
+	}
+	gf.unusedKeys = unused
+	gf.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, name := range names {
+		reflect.ValueOf(tenants[name]).Elem().Set(wrapper.Elem().Field(0).Field(i))
+	}
+	err = gf.runBuiltinFlags(v)
+	if err != nil {
+		return nil, err
+	}
+	return tenants, nil
+}
+
+// checkTenants resolves the secret references of the tenants of wrapper, once parsed,
+// and checks them like the fields of the configuration struct: the required fields, the
+// enums, the ranges, the validation rules and the structs implementing Validator. The
+// key paths set by the documents of the tenants are in decoded.
+func (gf *Gofig) checkTenants(ctx context.Context, wrapper interface{}, decoded map[string]bool) error {
+	gf.decoded, gf.visited = decoded, make(map[string]bool)
+	defer func() { gf.decoded, gf.visited = nil, nil }()
+	if gf.flagSet.Parsed() {
+		gf.flagSet.Visit(func(f *flag.Flag) { gf.visited[f.Name] = true })
+	}
+
+	_, err := gf.resolveSecrets(ctx, wrapper)
+	if err != nil {
+		return gf.withFieldNames(err, wrapper, "json", true)
+	}
+	for _, check := range []func(v interface{}) error{
+		gf.checkRequired,
+		gf.checkEnums,
+		gf.checkRanges,
+		gf.checkValidateTags,
+		gf.validate,
+	} {
+		err = check(wrapper)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTenants(t *testing.T) {
+	os.Setenv("GFT_TENANT_GLOBEX_SUB_STR", "env")
+	defer os.Unsetenv("GFT_TENANT_GLOBEX_SUB_STR")
+
+	s := &TestStruct{}
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("GFT")
+	gf.AddSource(&testSource{name: "base", doc: &Document{Format: "json", Data: []byte(`{
+		"str": "base",
+		"tenants": {
+			"acme": {"str": "acme", "int": 1},
+			"globex": {"str": "globex"}
+		}
+	}`)}})
+	gf.AddSource(&testSource{name: "override", doc: &Document{Format: "yaml", Data: []byte("tenants:\n  acme:\n    int: 2\n")}})
+	assert.NoError(t, gf.Override("tenants.globex.int", "3"))
+
+	factory := func() interface{} { return &TestStruct{Int: 10, Uint: 10} }
+	tenants, err := gf.ParseTenantsWithArgs(s, factory, []string{"-str", "flag", "-tenant-acme-sub-str", "flag"})
+	assert.NoError(t, err)
+	assert.Equal(t, "flag", s.Str)
+	assert.Len(t, tenants, 2)
+
+	acme := tenants["acme"].(*TestStruct)
+	assert.Equal(t, "acme", acme.Str)
+	assert.Equal(t, 2, acme.Int)
+	assert.Equal(t, uint(10), acme.Uint)
+	assert.Equal(t, "flag", acme.Sub.RenamedStr)
+
+	globex := tenants["globex"].(*TestStruct)
+	assert.Equal(t, "globex", globex.Str)
+	assert.Equal(t, 3, globex.Int)
+	assert.Equal(t, "env", globex.Sub.RenamedStr)
+
+	// the tenants flags don't prevent reloading
+	assert.NoError(t, gf.Override("int", "4"))
+	assert.Equal(t, 4, s.Int)
+	assert.Equal(t, "flag", s.Str)
+}
+
+// versionedSource is a Source whose document changes on every load
+type versionedSource struct {
+	loads int
+}
+
+func (s *versionedSource) Name() string { return "versioned" }
+
+func (s *versionedSource) Load(ctx context.Context) (*Document, error) {
+	s.loads++
+	return &Document{Format: "json", Data: []byte(fmt.Sprintf(`{"str": "v%[1]v", "tenants": {"acme": {"str": "v%[1]v"}}}`, s.loads))}, nil
+}
+
+func TestParseTenantsSingleLoad(t *testing.T) {
+	src := &versionedSource{}
+	s := &TestStruct{}
+	gf := New(ContinueOnError)
+	gf.AddSource(src)
+	tenants, err := gf.ParseTenantsWithArgs(s, func() interface{} { return &TestStruct{} }, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, src.loads)
+	assert.Equal(t, "v1", s.Str)
+	assert.Equal(t, "v1", tenants["acme"].(*TestStruct).Str)
+}
+
+func TestParseTenantsErrors(t *testing.T) {
+	factory := func() interface{} { return &TestStruct{} }
+
+	// Case 1: invalid tenant name
+	gf := New(ContinueOnError)
+	gf.AddSource(&testSource{name: "test", doc: &Document{Format: "json", Data: []byte(`{"tenants": {"a-b": {}}}`)}})
+	_, err := gf.ParseTenantsWithArgs(&TestStruct{}, factory, []string{})
+	assert.EqualError(t, err, "invalid tenant name 'a-b', only letters, digits and underscores are allowed")
+
+	// Case 2: invalid factory
+	gf = New(ContinueOnError)
+	gf.AddSource(&testSource{name: "test", doc: &Document{Format: "json", Data: []byte(`{"tenants": {"a": {}}}`)}})
+	_, err = gf.ParseTenantsWithArgs(&TestStruct{}, func() interface{} { return TestStruct{} }, []string{})
+	assert.Equal(t, errInvalidValue, err)
+
+	// Case 3: no tenant
+	gf = New(ContinueOnError)
+	tenants, err := gf.ParseTenantsWithArgs(&TestStruct{}, factory, []string{})
+	assert.NoError(t, err)
+	assert.Empty(t, tenants)
+
+	// Case 4: the tenants are checked like the configuration struct
+	type tenantConfig struct {
+		Plan  string `json:"plan" enum:"free,pro"`
+		Seats int    `json:"seats" max:"100"`
+		Owner string `json:"owner" required:"true"`
+		Quota int    `json:"quota" required:"true"`
+	}
+	tenantFactory := func() interface{} { return &tenantConfig{} }
+	for doc, expected := range map[string]string{
+		`{"acme": {"plan": "gold", "owner": "a", "quota": 1}}`: "invalid value 'gold' of field Tenants.T0.Plan (flag -tenant-acme-plan, env TENANT_ACME_PLAN, key tenants.acme.plan), it must be one of free, pro",
+		`{"acme": {"seats": 101, "owner": "a", "quota": 1}}`:   "invalid field Tenants.T0.Seats (flag -tenant-acme-seats, env TENANT_ACME_SEATS, key tenants.acme.seats): must be at most 100",
+		`{"acme": {"quota": 1}}`:                               "missing required field Tenants.T0.Owner (flag -tenant-acme-owner, env TENANT_ACME_OWNER, key tenants.acme.owner)",
+	} {
+		gf = New(ContinueOnError)
+		gf.AddSource(&testSource{name: "test", doc: &Document{Format: "json", Data: []byte(`{"tenants": ` + doc + `}`)}})
+		_, err = gf.ParseTenantsWithArgs(&TestStruct{}, tenantFactory, []string{})
+		assert.EqualError(t, err, expected, doc)
+	}
+
+	// Case 5: a required field explicitly set to its zero value
+	gf = New(ContinueOnError)
+	gf.AddSource(&testSource{name: "test", doc: &Document{Format: "json", Data: []byte(`{"tenants": {"acme": {"owner": "a", "quota": 0}}}`)}})
+	_, err = gf.ParseTenantsWithArgs(&TestStruct{}, tenantFactory, []string{})
+	assert.NoError(t, err)
+}
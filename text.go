@@ -0,0 +1,51 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"encoding"
+	"reflect"
+)
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// isTextType returns whether the values of type t are set as a single value with
+// encoding.TextUnmarshaler, e.g. net.IP or a user-defined ID type, rather than by kind.
+// Times are set in the layout of their field instead.
+func isTextType(t reflect.Type) bool {
+	return t != timeType && reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+// isValueType returns whether the values of type t are set as a single value from their
+// text, rather than by kind or walked as a struct: the times, the text types and the
+// flag.Value types.
+func isValueType(t reflect.Type) bool {
+	return t == timeType || isTextType(t) || isFlagValueType(t)
+}
+
+// textUnmarshaler returns the encoding.TextUnmarshaler of the field f, if its type is a
+// text type.
+func textUnmarshaler(f *reflect.Value) (encoding.TextUnmarshaler, bool) {
+	if !f.CanAddr() || !isTextType(f.Type()) {
+		return nil, false
+	}
+	return f.Addr().Interface().(encoding.TextUnmarshaler), true
+}
+
+// textMarshaler returns the encoding.TextMarshaler of the field f, if its type is a text
+// type implementing it.
+func textMarshaler(f *reflect.Value) (encoding.TextMarshaler, bool) {
+	if !isTextType(f.Type()) {
+		return nil, false
+	}
+	if f.Type().Implements(textMarshalerType) {
+		return f.Interface().(encoding.TextMarshaler), true
+	}
+	if f.CanAddr() && f.Addr().Type().Implements(textMarshalerType) {
+		return f.Addr().Interface().(encoding.TextMarshaler), true
+	}
+	return nil, false
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type logLevel int
+
+func (l *logLevel) UnmarshalText(text []byte) error {
+	for i, name := range []string{"debug", "info", "error"} {
+		if string(text) == name {
+			*l = logLevel(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown log level %v", string(text))
+}
+
+func (l logLevel) MarshalText() ([]byte, error) {
+	return []byte([]string{"debug", "info", "error"}[l]), nil
+}
+
+type owner struct {
+	Team string
+	Name string
+}
+
+func (o *owner) UnmarshalText(text []byte) error {
+	i := strings.IndexByte(string(text), '/')
+	if i < 0 {
+		return fmt.Errorf("invalid owner %v", string(text))
+	}
+	o.Team, o.Name = string(text[:i]), string(text[i+1:])
+	return nil
+}
+
+type textStruct struct {
+	Level  logLevel   `json:"level"`
+	Levels []logLevel `json:"levels"`
+	Addr   net.IP     `json:"addr"`
+	Owner  owner      `json:"owner"`
+}
+
+func TestTextUnmarshaler(t *testing.T) {
+	// Case 1: environment variables
+	os.Setenv("GFX_LEVEL", "error")
+	os.Setenv("GFX_LEVELS", "debug,info")
+	os.Setenv("GFX_ADDR", "10.0.0.1")
+	os.Setenv("GFX_OWNER", "infra/alice")
+	defer os.Unsetenv("GFX_LEVEL")
+	defer os.Unsetenv("GFX_LEVELS")
+	defer os.Unsetenv("GFX_ADDR")
+	defer os.Unsetenv("GFX_OWNER")
+	s := &textStruct{}
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("GFX")
+	err := gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, logLevel(2), s.Level)
+	assert.Equal(t, []logLevel{0, 1}, s.Levels)
+	assert.Equal(t, "10.0.0.1", s.Addr.String())
+	assert.Equal(t, owner{Team: "infra", Name: "alice"}, s.Owner)
+	assert.Equal(t, map[string]string{"level": "error", "levels": "debug,info", "addr": "10.0.0.1"}, Flatten(s))
+
+	// Case 2: flags
+	s = &textStruct{}
+	gf = New(ContinueOnError)
+	err = gf.ParseWithArgs(s, []string{"-level", "info", "-addr", "::1", "-owner", "web/bob"})
+	assert.NoError(t, err)
+	assert.Equal(t, logLevel(1), s.Level)
+	assert.Equal(t, "::1", s.Addr.String())
+	assert.Equal(t, owner{Team: "web", Name: "bob"}, s.Owner)
+
+	s = &textStruct{}
+	gf = New(ContinueOnError)
+	err = gf.ParseWithArgs(s, []string{"-level", "trace"})
+	assert.Error(t, err)
+}
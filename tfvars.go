@@ -0,0 +1,423 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TfvarsSource loads a Terraform variable definitions file, in the HCL syntax of .tfvars
+// files or the JSON syntax of .tfvars.json files. Each variable is a top-level key
+// (matched case-insensitively against the json tags or field names, e.g. `json:"db_port"`
+// for db_port), and its value a literal: a string, a heredoc, a number, a bool, null, a
+// list or an object. Expressions, such as string templates and function calls, are
+// not supported.
+type TfvarsSource struct {
+	// Path of the variable definitions file.
+	Path string
+}
+
+// NewTfvarsSource returns a Source loading the variable definitions file at path.
+func NewTfvarsSource(path string) *TfvarsSource {
+	return &TfvarsSource{Path: path}
+}
+
+// AddTfvars adds a Terraform variable definitions file as a source, see TfvarsSource.
+func AddTfvars(path string) { gf.AddTfvars(path) }
+
+// AddTfvars adds a Terraform variable definitions file as a source, see TfvarsSource.
+func (gf *Gofig) AddTfvars(path string) {
+	gf.AddSource(NewTfvarsSource(path))
+}
+
+// Name returns the variable definitions file path.
+func (s *TfvarsSource) Name() string {
+	return s.Path
+}
+
+// Load reads the variable definitions file.
+func (s *TfvarsSource) Load(ctx context.Context) (*Document, error) {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(s.Path, jsonExtention) {
+		return &Document{Format: "json", Data: data}, nil
+	}
+	return parseTfvars(data)
+}
+
+// parseTfvars converts HCL variable definitions into a document.
+func parseTfvars(data []byte) (*Document, error) {
+	p := &tfvarsParser{src: string(data), line: 1}
+	tree, err := p.parseBody(0)
+	if err != nil {
+		return nil, err
+	}
+	data, err = json.Marshal(tree)
+	if err != nil {
+		return nil, err
+	}
+	return &Document{Format: "json", Data: data}, nil
+}
+
+// tfvarsNumber matches an HCL number literal
+var tfvarsNumber = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?`)
+
+// tfvarsParser parses the literal values of HCL variable definitions.
+type tfvarsParser struct {
+	src  string
+	pos  int
+	line int
+}
+
+// errorf returns a syntax error at the current line.
+func (p *tfvarsParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("tfvars line %v: %v", p.line, fmt.Sprintf(format, args...))
+}
+
+// peek returns the next character, or 0 at the end of the input.
+func (p *tfvarsParser) peek() byte {
+	if p.pos >= len(p.src) {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+// next consumes the next character.
+func (p *tfvarsParser) next() byte {
+	c := p.peek()
+	if c == '\n' {
+		p.line++
+	}
+	if c != 0 {
+		p.pos++
+	}
+	return c
+}
+
+// skipSpace skips the spaces and comments, and the newlines if newlines is set.
+func (p *tfvarsParser) skipSpace(newlines bool) error {
+	for p.pos < len(p.src) {
+		rest := p.src[p.pos:]
+		switch {
+		case rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\r':
+			p.next()
+		case rest[0] == '\n':
+			if !newlines {
+				return nil
+			}
+			p.next()
+		case rest[0] == '#' || strings.HasPrefix(rest, "//"):
+			for p.peek() != '\n' && p.peek() != 0 {
+				p.next()
+			}
+		case strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest[2:], "*/")
+			if end < 0 {
+				return p.errorf("unterminated comment")
+			}
+			for i := 0; i < end+4; i++ {
+				p.next()
+			}
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// parseBody parses the attributes of the top-level body (end is 0) or of an object (end
+// is '}'), separated by newlines or commas.
+func (p *tfvarsParser) parseBody(end byte) (map[string]interface{}, error) {
+	tree := make(map[string]interface{})
+	for {
+		err := p.skipSpace(true)
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() == end {
+			p.next()
+			return tree, nil
+		}
+		if p.peek() == 0 {
+			return nil, p.errorf("unexpected end of input, expecting '}'")
+		}
+
+		var key string
+		if p.peek() == '"' {
+			key, err = p.parseString()
+		} else {
+			key, err = p.parseIdent()
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err = p.skipSpace(false); err != nil {
+			return nil, err
+		}
+		if c := p.next(); c != '=' && (c != ':' || end == 0) {
+			return nil, p.errorf("expecting '=' after '%v'", key)
+		}
+		if _, ok := tree[key]; ok {
+			return nil, p.errorf("'%v' is defined more than once", key)
+		}
+		if tree[key], err = p.parseValue(); err != nil {
+			return nil, err
+		}
+
+		if err = p.skipSpace(false); err != nil {
+			return nil, err
+		}
+		switch c := p.peek(); {
+		case c == '\n' || (c == ',' && end != 0):
+			p.next()
+		case c == end:
+		case c == 0:
+			return nil, p.errorf("unexpected end of input, expecting '}'")
+		default:
+			return nil, p.errorf("unexpected '%c' after the value of '%v'", c, key)
+		}
+	}
+}
+
+// parseValue parses a literal value.
+func (p *tfvarsParser) parseValue() (interface{}, error) {
+	err := p.skipSpace(false)
+	if err != nil {
+		return nil, err
+	}
+	rest := p.src[p.pos:]
+	switch c := p.peek(); {
+	case c == '"':
+		return p.parseString()
+	case strings.HasPrefix(rest, "<<"):
+		return p.parseHeredoc()
+	case c == '[':
+		p.next()
+		return p.parseList()
+	case c == '{':
+		p.next()
+		return p.parseBody('}')
+	case c == '-' || (c >= '0' && c <= '9'):
+		n := tfvarsNumber.FindString(rest)
+		if n == "" {
+			return nil, p.errorf("invalid number")
+		}
+		p.pos += len(n)
+		return json.Number(n), nil
+	}
+	ident, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	switch ident {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	return nil, p.errorf("unsupported expression '%v', only literal values are supported", ident)
+}
+
+// parseList parses the values of a list, after its '['.
+func (p *tfvarsParser) parseList() ([]interface{}, error) {
+	list := []interface{}{}
+	for {
+		err := p.skipSpace(true)
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() == ']' {
+			p.next()
+			return list, nil
+		}
+		val, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, val)
+		if err = p.skipSpace(true); err != nil {
+			return nil, err
+		}
+		switch p.peek() {
+		case ',':
+			p.next()
+		case ']':
+		default:
+			return nil, p.errorf("expecting ',' or ']' in list")
+		}
+	}
+}
+
+// parseIdent parses an identifier.
+func (p *tfvarsParser) parseIdent() (string, error) {
+	start := p.pos
+	for p.pos < len(p.src) {
+		r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' {
+			break
+		}
+		p.pos += size
+	}
+	if p.pos == start {
+		return "", p.errorf("unexpected '%c'", p.peek())
+	}
+	return p.src[start:p.pos], nil
+}
+
+// parseString parses a quoted string.
+func (p *tfvarsParser) parseString() (string, error) {
+	p.next() // "
+	var b strings.Builder
+	for {
+		c := p.next()
+		switch c {
+		case 0, '\n':
+			return "", p.errorf("unterminated string")
+		case '"':
+			return b.String(), nil
+		case '\\':
+			s, err := p.parseEscape()
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(s)
+		case '$', '%':
+			if p.peek() == c && strings.HasPrefix(p.src[p.pos+1:], "{") {
+				p.next() // $${ and %%{ are literal
+			} else if p.peek() == '{' {
+				return "", p.errorf("unsupported string template, only literal values are supported")
+			}
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+// parseEscape parses an escape sequence of a quoted string, after its backslash.
+func (p *tfvarsParser) parseEscape() (string, error) {
+	c := p.next()
+	switch c {
+	case 'n':
+		return "\n", nil
+	case 'r':
+		return "\r", nil
+	case 't':
+		return "\t", nil
+	case '"', '\\':
+		return string(c), nil
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		if p.pos+n > len(p.src) {
+			return "", p.errorf("invalid escape sequence")
+		}
+		code, err := strconv.ParseUint(p.src[p.pos:p.pos+n], 16, 32)
+		if err != nil {
+			return "", p.errorf("invalid escape sequence")
+		}
+		p.pos += n
+		return string(rune(code)), nil
+	}
+	return "", p.errorf("invalid escape sequence '\\%c'", c)
+}
+
+// parseHeredoc parses a heredoc string, e.g. <<EOT or <<-EOT for an indented one, whose
+// common leading spaces are removed.
+func (p *tfvarsParser) parseHeredoc() (string, error) {
+	p.next() // <
+	p.next() // <
+	indented := p.peek() == '-'
+	if indented {
+		p.next()
+	}
+	marker, err := p.parseIdent()
+	if err != nil {
+		return "", err
+	}
+	if err = p.skipSpace(false); err != nil {
+		return "", err
+	}
+	if p.next() != '\n' {
+		return "", p.errorf("expecting a newline after the heredoc marker %v", marker)
+	}
+
+	var lines []string
+	for {
+		if p.pos >= len(p.src) {
+			return "", p.errorf("unterminated heredoc, expecting %v", marker)
+		}
+		// advance with next, which counts the lines for the errors
+		start := p.pos
+		for p.peek() != '\n' && p.peek() != 0 {
+			p.next()
+		}
+		line := strings.TrimSuffix(p.src[start:p.pos], "\r")
+		if strings.TrimSpace(line) == marker {
+			break
+		}
+		p.next() // \n
+		lines = append(lines, line)
+	}
+
+	if indented {
+		indent := -1
+		for _, line := range lines {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			n := len(line) - len(strings.TrimLeft(line, " \t"))
+			if indent < 0 || n < indent {
+				indent = n
+			}
+		}
+		for i, line := range lines {
+			if len(line) >= indent && indent > 0 {
+				lines[i] = line[indent:]
+			} else if indent > 0 {
+				lines[i] = "" // a blank line
+			}
+		}
+	}
+	s := strings.Join(lines, "\n")
+	if len(lines) > 0 {
+		s += "\n"
+	}
+	return p.literal(s)
+}
+
+// literal returns the literal text of a heredoc template, where $${ and %%{ are the
+// escapes of ${ and %{.
+func (p *tfvarsParser) literal(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' && s[i] != '%' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if strings.HasPrefix(s[i+1:], string(s[i])+"{") {
+			i++ // escaped
+		} else if strings.HasPrefix(s[i+1:], "{") {
+			return "", p.errorf("unsupported string template, only literal values are supported")
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String(), nil
+}
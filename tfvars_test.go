@@ -0,0 +1,92 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddTfvars(t *testing.T) {
+	type Config struct {
+		Region   string
+		DBPort   int `json:"db_port"`
+		Replicas []string
+		Tags     map[string]string
+		Debug    bool
+		Script   string
+		Server   struct {
+			Host    string
+			Timeout Duration
+		}
+	}
+	tfvars := `
+# environment
+region  = "eu-west-1" // inline comment
+db_port = 5432
+replicas = [
+  "a",
+  "b", /* trailing comma */
+]
+tags = { team = "core", "cost-center": "42" }
+debug = true
+script = <<-EOT
+    echo "$${HOME}"
+      done
+    EOT
+server = {
+  host    = "example.com"
+  timeout = "5s"
+}
+`
+	dir := t.TempDir()
+	path := filepath.Join(dir, "prod.tfvars")
+	assert.NoError(t, os.WriteFile(path, []byte(tfvars), 0644))
+
+	s := &Config{}
+	gf := New(ContinueOnError)
+	gf.AddTfvars(path)
+	err := gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "eu-west-1", s.Region)
+	assert.Equal(t, 5432, s.DBPort)
+	assert.Equal(t, []string{"a", "b"}, s.Replicas)
+	assert.Equal(t, map[string]string{"team": "core", "cost-center": "42"}, s.Tags)
+	assert.True(t, s.Debug)
+	assert.Equal(t, "echo \"${HOME}\"\n  done\n", s.Script)
+	assert.Equal(t, "example.com", s.Server.Host)
+	assert.Equal(t, "5s", s.Server.Timeout.String())
+
+	// JSON syntax
+	path = filepath.Join(dir, "prod.tfvars.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"region": "us-east-1"}`), 0644))
+	s = &Config{}
+	gf = New(ContinueOnError)
+	gf.AddTfvars(path)
+	assert.NoError(t, gf.ParseWithArgs(s, []string{}))
+	assert.Equal(t, "us-east-1", s.Region)
+}
+
+func TestParseTfvarsErrors(t *testing.T) {
+	for tfvars, expected := range map[string]string{
+		`region = "${var.x}"`:                  `tfvars line 1: unsupported string template, only literal values are supported`,
+		"a = 1\nb = var.region":                `tfvars line 2: unsupported expression 'var', only literal values are supported`,
+		`a = "unterminated`:                    `tfvars line 1: unterminated string`,
+		"a = 1\na = 2":                         `tfvars line 2: 'a' is defined more than once`,
+		"a = [1 2]":                            `tfvars line 1: expecting ',' or ']' in list`,
+		"a = { b = 1":                          `tfvars line 1: unexpected end of input, expecting '}'`,
+		"a = <<EOT\nno end":                    `tfvars line 2: unterminated heredoc, expecting EOT`,
+		"a = <<EOT\nx\n\ny\nEOT\nb = ]":        `tfvars line 6: unexpected ']'`,
+		"a = <<-EOT\n  x\n  EOT\n\n# b\nb = ]": `tfvars line 6: unexpected ']'`,
+		"a: 1":                                 `tfvars line 1: expecting '=' after 'a'`,
+	} {
+		_, err := parseTfvars([]byte(tfvars))
+		assert.EqualError(t, err, expected, tfvars)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// TimeoutError is returned when parsing the configuration exceeds the parse timeout.
+type TimeoutError struct {
+	// Stage describes the stage which stalled, e.g. "loading source https://example.com".
+	Stage string
+	// Timeout is the parse timeout.
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("parse timeout of %v exceeded while %v", e.Timeout, e.Stage)
+}
+
+// Unwrap returns context.DeadlineExceeded, so that errors.Is(err, context.DeadlineExceeded) works.
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// SetParseTimeout bounds the time spent reading the config files and loading the sources
+// when parsing or reloading the configuration, so that a hung network file system or a
+// slow source can't stall startup forever. A *TimeoutError is returned on timeout.
+// 0 (the default) means no timeout.
+func SetParseTimeout(d time.Duration) { gf.SetParseTimeout(d) }
+
+// SetParseTimeout bounds the time spent reading the config files and loading the sources
+// when parsing or reloading the configuration, so that a hung network file system or a
+// slow source can't stall startup forever. A *TimeoutError is returned on timeout.
+// 0 (the default) means no timeout.
+func (gf *Gofig) SetParseTimeout(d time.Duration) {
+	gf.parseTimeout = d
+}
+
+// WithParseTimeout sets the parse timeout, see SetParseTimeout.
+func WithParseTimeout(d time.Duration) Option {
+	return func(o *options) { o.gf.SetParseTimeout(d) }
+}
+
+// parseContext returns the context bounding a parse.
+func (gf *Gofig) parseContext() (context.Context, context.CancelFunc) {
+	if gf.parseTimeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), gf.parseTimeout)
+}
+
+// timeoutError returns the error of a stage interrupted by the end of the parse context.
+func (gf *Gofig) timeoutError(ctx context.Context, stage string) error {
+	if ctx.Err() != context.DeadlineExceeded {
+		return ctx.Err()
+	}
+	return &TimeoutError{Stage: stage, Timeout: gf.parseTimeout}
+}
+
+// readConfigFile reads a config file, up to the maximum size of a config document plus
+// one byte, giving up when the parse context ends.
+func (gf *Gofig) readConfigFile(ctx context.Context, path string) ([]byte, error) {
+	type result struct {
+		data []byte
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		f, err := os.Open(path)
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer f.Close()
+
+		var r io.Reader = f
+		if gf.limits.MaxSize > 0 {
+			r = io.LimitReader(f, gf.limits.MaxSize+1)
+		}
+		data, err := io.ReadAll(r)
+		done <- result{data: data, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.data, res.err
+	case <-ctx.Done():
+		return nil, gf.timeoutError(ctx, "reading config file "+path)
+	}
+}
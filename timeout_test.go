@@ -0,0 +1,61 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// hungSource never returns from Load, ignoring the context
+type hungSource struct {
+	release chan struct{}
+}
+
+func (s *hungSource) Name() string { return "hung" }
+
+func (s *hungSource) Load(ctx context.Context) (*Document, error) {
+	<-s.release
+	return nil, nil
+}
+
+func TestSetParseTimeout(t *testing.T) {
+	src := &hungSource{release: make(chan struct{})}
+	defer close(src.release)
+
+	// Case 1: source ignoring the context
+	gf := New(ContinueOnError)
+	gf.SetParseTimeout(10 * time.Millisecond)
+	gf.AddSource(&testSource{name: "fast", doc: &Document{Format: "json", Data: []byte(`{"str": "fast"}`)}})
+	gf.AddSource(src)
+	err := gf.ParseWithArgs(&TestStruct{}, []string{})
+	assert.EqualError(t, err, "parse timeout of 10ms exceeded while loading source hung")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	var timeoutErr *TimeoutError
+	assert.True(t, errors.As(err, &timeoutErr))
+	assert.Equal(t, "loading source hung", timeoutErr.Stage)
+
+	// Case 2: source honoring the context
+	_, err = ParseAs[TestStruct](
+		WithParseTimeout(10*time.Millisecond),
+		WithSource(&testSource{name: "slow", delay: time.Second}),
+		WithArgs([]string{}),
+	)
+	assert.EqualError(t, err, "parse timeout of 10ms exceeded while loading source slow")
+
+	// Case 3: no timeout
+	gf = New(ContinueOnError)
+	gf.SetParseTimeout(time.Second)
+	gf.AddConfigFile("gofig_test_json")
+	s := &TestStruct{}
+	err = gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "config-file", s.Str)
+}
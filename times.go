@@ -0,0 +1,115 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"reflect"
+	"time"
+)
+
+// layoutTag is the struct tag holding the layout of a time.Time field, in the format of
+// time.Parse, e.g. layout:"2006-01-02".
+const layoutTag = "layout"
+
+// timeType is the type of the time.Time fields, which are set as a single value rather
+// than walked as a struct.
+var timeType = reflect.TypeOf(time.Time{})
+
+// timeLayout returns the layout of a time.Time field, RFC 3339 by default.
+func timeLayout(tags *reflect.StructTag) string {
+	if tags != nil {
+		if layout := tags.Get(layoutTag); layout != "" {
+			return layout
+		}
+	}
+	return time.RFC3339
+}
+
+// timeFlag is a flag.Value setting a time.Time field in its layout.
+type timeFlag struct {
+	t      *time.Time
+	layout string
+}
+
+func (f *timeFlag) String() string {
+	if f.t == nil {
+		return "" // zero timeFlag created by flag.PrintDefaults
+	}
+	return formatTime(*f.t, f.layout)
+}
+
+func (f *timeFlag) Set(s string) error {
+	t, err := parseTime(s, f.layout)
+	if err != nil {
+		return err
+	}
+	*f.t = t
+	return nil
+}
+
+// parseTime parses a time in layout, an empty string being the zero time.
+func parseTime(s string, layout string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(layout, s)
+}
+
+// formatTime is the inverse of parseTime.
+func formatTime(t time.Time, layout string) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(layout)
+}
+
+// decodeField is decodeString for a field with tags, decoding the time.Time fields in
+// their layout.
+func decodeField(f *reflect.Value, val string, tags *reflect.StructTag) error {
+	if isScalarPointer(f.Type()) {
+		return decodePointer(f, func(elem *reflect.Value) error { return decodeField(elem, val, tags) })
+	}
+	if f.Type() != timeType {
+		return decodeString(f, val)
+	}
+	t, err := parseTime(val, timeLayout(tags))
+	if err != nil {
+		return err
+	}
+	f.Set(reflect.ValueOf(t))
+	return nil
+}
+
+// encodeField is the inverse of decodeField, it is encodeValue encoding the time.Time
+// fields in their layout.
+func encodeField(f *reflect.Value, tags *reflect.StructTag) (string, bool) {
+	if isScalarPointer(f.Type()) {
+		if f.IsNil() {
+			return "", false // not set
+		}
+		elem := f.Elem()
+		return encodeField(&elem, tags)
+	}
+	if f.Type() != timeType {
+		return encodeValue(f)
+	}
+	return formatTime(f.Interface().(time.Time), timeLayout(tags)), true
+}
+
+// coerceTime converts a string decoded for a time.Time field in a layout other than
+// RFC 3339 into an RFC 3339 string, which the decoders parse.
+func coerceTime(val *interface{}, layout string, key string) (bool, error) {
+	s, ok := (*val).(string)
+	if !ok || layout == "" || layout == time.RFC3339 {
+		return false, nil
+	}
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		return false, errorf("error parsing key '%v' with value '%v' into %v", key, s, timeType)
+	}
+	*val = t.Format(time.RFC3339Nano)
+	return true, nil
+}
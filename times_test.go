@@ -0,0 +1,75 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type timesStruct struct {
+	Started time.Time `json:"started" yaml:"started" toml:"started"`
+	Expires time.Time `json:"expires" yaml:"expires" toml:"expires" layout:"2006-01-02"`
+}
+
+func TestTimeFields(t *testing.T) {
+	started := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	expires := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	// Case 1: config documents, the layout applying to strings
+	for ext, doc := range map[string]string{
+		jsonExtention: `{"started": "2024-03-01T12:30:00Z", "expires": "2025-01-31"}`,
+		yamlExtention: "started: 2024-03-01T12:30:00Z\nexpires: \"2025-01-31\"",
+		tomlExtention: "started = 2024-03-01T12:30:00Z\nexpires = \"2025-01-31\"",
+	} {
+		s := &timesStruct{}
+		err := decodeConfig(bytes.NewReader([]byte(doc)), ext, s)
+		assert.NoError(t, err, ext)
+		assert.True(t, started.Equal(s.Started), ext)
+		assert.True(t, expires.Equal(s.Expires), ext)
+	}
+
+	s := &timesStruct{}
+	err := decodeConfig(bytes.NewReader([]byte(`{"expires": "2025-01-31T00:00:00Z"}`)), jsonExtention, s)
+	assert.EqualError(t, err, "error parsing key 'expires' with value '2025-01-31T00:00:00Z' into time.Time")
+
+	// Case 2: environment variables and flags
+	os.Setenv("GFT_STARTED", "2024-03-01T12:30:00Z")
+	os.Setenv("GFT_EXPIRES", "2025-01-31")
+	defer os.Unsetenv("GFT_STARTED")
+	defer os.Unsetenv("GFT_EXPIRES")
+	s = &timesStruct{}
+	gf := New(ContinueOnError)
+	gf.SetEnvPrefix("GFT")
+	err = gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.True(t, started.Equal(s.Started))
+	assert.True(t, expires.Equal(s.Expires))
+	assert.Equal(t, map[string]string{"started": "2024-03-01T12:30:00Z", "expires": "2025-01-31"}, Flatten(s))
+
+	s = &timesStruct{}
+	gf = New(ContinueOnError)
+	err = gf.ParseWithArgs(s, []string{"-started", "2024-03-02T08:00:00+09:00", "-expires", "2025-01-31"})
+	assert.NoError(t, err)
+	assert.True(t, time.Date(2024, 3, 1, 23, 0, 0, 0, time.UTC).Equal(s.Started))
+	assert.True(t, expires.Equal(s.Expires))
+
+	s = &timesStruct{}
+	gf = New(ContinueOnError)
+	err = gf.ParseWithArgs(s, []string{"-expires", "2025-01-31T00:00:00Z"})
+	assert.Error(t, err)
+
+	// Case 3: flat values
+	s = &timesStruct{}
+	err = Unflatten(map[string]string{"started": "2024-03-01T12:30:00Z", "expires": "2025-01-31"}, s)
+	assert.NoError(t, err)
+	assert.True(t, started.Equal(s.Started))
+	assert.True(t, expires.Equal(s.Expires))
+}
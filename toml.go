@@ -0,0 +1,54 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"reflect"
+	"time"
+)
+
+// isTOMLLocal returns whether t was decoded from a TOML local date-time, date or time,
+// which the decoder returns with dedicated time zones.
+func isTOMLLocal(t time.Time) bool {
+	switch t.Location().String() {
+	case "datetime-local", "date-local", "time-local":
+		return true
+	}
+	return false
+}
+
+// localTimes converts the TOML local date-times and dates of a decoded document tree to
+// the local time zone, with the offset in effect at their date (the decoder uses the
+// current offset, which differs across daylight saving time changes). TOML local times
+// are kept as decoded.
+func localTimes(rv reflect.Value) {
+	switch rv.Kind() {
+	case reflect.Interface:
+		if rv.IsNil() {
+			break
+		}
+		if t, ok := rv.Interface().(time.Time); ok {
+			switch t.Location().String() {
+			case "datetime-local", "date-local":
+				rv.Set(reflect.ValueOf(time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.Local)))
+			}
+			break
+		}
+		localTimes(rv.Elem())
+	case reflect.Slice:
+		for i := 0; i < rv.Len(); i++ {
+			localTimes(rv.Index(i))
+		}
+	case reflect.Map:
+		iter := rv.MapRange()
+		for iter.Next() {
+			val := reflect.New(rv.Type().Elem()).Elem()
+			val.Set(iter.Value())
+			localTimes(val)
+			rv.SetMapIndex(iter.Key(), val)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTOML(t *testing.T) {
+	type Server struct {
+		Host string
+		Port int
+	}
+	type Config struct {
+		Server   Server
+		Backup   *Server
+		Replicas []Server
+		Labels   map[string]string
+		Date     time.Time
+		Local    time.Time
+		Offset   time.Time
+		Timeout  Duration
+		Interval Duration
+	}
+
+	// Case 1: TOML v1.0 inline tables, dotted keys, arrays of tables and date-times
+	s := &Config{Backup: &Server{Host: "default", Port: 1}}
+	err := decodeConfig(strings.NewReader(`
+server = { host = "main", port = 80 }
+backup.port = 81
+labels."app.kubernetes.io/name" = "gofig"
+date = 2020-01-02
+local = 2020-01-02T03:04:05.5
+offset = 2020-01-02T03:04:05+09:00
+timeout = "1m30s"
+interval = 00:01:30
+
+[[replicas]]
+host = "r1"
+
+[[replicas]]
+host = "r2"
+port = 82
+`), ".toml", s)
+	assert.NoError(t, err)
+	assert.Equal(t, Server{Host: "main", Port: 80}, s.Server)
+	assert.Equal(t, &Server{Host: "default", Port: 81}, s.Backup)
+	assert.Equal(t, []Server{{Host: "r1"}, {Host: "r2", Port: 82}}, s.Replicas)
+	assert.Equal(t, map[string]string{"app.kubernetes.io/name": "gofig"}, s.Labels)
+	assert.True(t, time.Date(2020, 1, 2, 0, 0, 0, 0, time.Local).Equal(s.Date))
+	assert.True(t, time.Date(2020, 1, 2, 3, 4, 5, 5e8, time.Local).Equal(s.Local))
+	assert.True(t, time.Date(2020, 1, 1, 18, 4, 5, 0, time.UTC).Equal(s.Offset))
+	assert.Equal(t, Duration(90*time.Second), s.Timeout)
+	assert.Equal(t, Duration(90*time.Second), s.Interval)
+
+	// Case 2: local date-times in generic documents
+	var doc map[string]interface{}
+	err = decodeConfig(strings.NewReader("a.b = 2020-01-02\nc = [03:04:05]\n"), ".toml", &doc)
+	assert.NoError(t, err)
+	assert.True(t, time.Date(2020, 1, 2, 0, 0, 0, 0, time.Local).Equal(doc["a"].(map[string]interface{})["b"].(time.Time)))
+	local := doc["c"].([]interface{})[0].(time.Time)
+	assert.Equal(t, "03:04:05", local.Format("15:04:05"))
+
+	// Case 3: invalid durations
+	err = decodeConfig(strings.NewReader("timeout = 2020-01-02\n"), ".toml", s)
+	assert.Error(t, err)
+	err = decodeConfig(strings.NewReader("timeout = true\n"), ".toml", s)
+	assert.Error(t, err)
+}
+
+func TestTOMLDaylightSavingTime(t *testing.T) {
+	// the local time zone is set by running the test in a subprocess
+	if os.Getenv("GOFIG_TEST_TZ") == "" {
+		if _, err := time.LoadLocation("America/New_York"); err != nil {
+			t.Skip("time zone database not available")
+		}
+		cmd := exec.Command(os.Args[0], "-test.run=^TestTOMLDaylightSavingTime$")
+		cmd.Env = append(os.Environ(), "GOFIG_TEST_TZ=1", "TZ=America/New_York")
+		out, err := cmd.CombinedOutput()
+		assert.NoError(t, err, string(out))
+		return
+	}
+
+	// the offset is the one in effect at each date
+	var s struct {
+		Winter time.Time
+		Summer time.Time
+	}
+	err := decodeConfig(strings.NewReader("winter = 2020-01-02T12:00:00\nsummer = 2020-07-02\n"), ".toml", &s)
+	assert.NoError(t, err)
+	assert.True(t, time.Date(2020, 1, 2, 12, 0, 0, 0, time.Local).Equal(s.Winter), s.Winter)
+	assert.True(t, time.Date(2020, 7, 2, 0, 0, 0, 0, time.Local).Equal(s.Summer), s.Summer)
+}
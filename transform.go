@@ -0,0 +1,88 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+)
+
+// Transformer renders or decrypts the raw bytes of a config file before it's decoded,
+// e.g. to evaluate a Jsonnet program, execute a template or decrypt a SOPS document.
+// The path of the file is given so that relative imports can be resolved.
+type Transformer func(ctx context.Context, path string, data []byte) ([]byte, error)
+
+// transform is a transformer selected by the extension of the config files
+type transform struct {
+	ext    string
+	format string
+	fn     Transformer
+}
+
+// AddTransformer registers a transformer of the config files whose name ends with ext,
+// e.g. ".jsonnet" or ".yaml.tmpl". The transformed document is decoded in format
+// ("json", "toml" or "yaml"), or, if format is empty, in the format of the extension
+// preceding ext, e.g. YAML for config.yaml.tmpl with the ".tmpl" extension. When
+// several extensions match, the longest wins. The config files added with
+// AddConfigFile are also searched with the extensions of the transformers (preceded by
+// the built-in extensions if format is empty), after the built-in ones. The
+// transformers apply to the config file and the -set-file override flag
+// (SetOverrideFileFlag).
+func AddTransformer(ext string, format string, fn Transformer) { gf.AddTransformer(ext, format, fn) }
+
+// AddTransformer registers a transformer of the config files whose name ends with ext,
+// e.g. ".jsonnet" or ".yaml.tmpl". The transformed document is decoded in format
+// ("json", "toml" or "yaml"), or, if format is empty, in the format of the extension
+// preceding ext, e.g. YAML for config.yaml.tmpl with the ".tmpl" extension. When
+// several extensions match, the longest wins. The config files added with
+// AddConfigFile are also searched with the extensions of the transformers (preceded by
+// the built-in extensions if format is empty), after the built-in ones. The
+// transformers apply to the config file and the -set-file override flag
+// (SetOverrideFileFlag).
+func (gf *Gofig) AddTransformer(ext string, format string, fn Transformer) {
+	gf.transforms = append(gf.transforms, transform{ext: strings.ToLower(ext), format: format, fn: fn})
+}
+
+// cfgFileExts returns the extensions the config files are searched with.
+func (gf *Gofig) cfgFileExts() []string {
+	exts := cfgFileExt[:len(cfgFileExt):len(cfgFileExt)]
+	for _, t := range gf.allTransforms() {
+		if t.format != "" {
+			exts = append(exts, t.ext)
+			continue
+		}
+		for _, ext := range cfgFileExt {
+			exts = append(exts, ext+t.ext)
+		}
+	}
+	return exts
+}
+
+// transformFile applies the transformer matching the name of the config file at path
+// to its data, returning the transformed data and the extension of their format.
+func (gf *Gofig) transformFile(ctx context.Context, path string, data []byte) ([]byte, string, error) {
+	var match *transform
+	name := strings.ToLower(path)
+	transforms := gf.allTransforms()
+	for i, t := range transforms {
+		if strings.HasSuffix(name, t.ext) && (match == nil || len(t.ext) > len(match.ext)) {
+			match = &transforms[i]
+		}
+	}
+	if match == nil {
+		return data, filepath.Ext(path), nil
+	}
+
+	data, err := match.fn(ctx, path, data)
+	if err != nil {
+		return nil, "", errorf("error transforming config file '%v': %v", path, err)
+	}
+	if match.format != "" {
+		return data, "." + match.format, nil
+	}
+	return data, filepath.Ext(path[:len(path)-len(match.ext)]), nil
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// renderTemplate is a transformer executing a text/template
+func renderTemplate(ctx context.Context, path string, data []byte) ([]byte, error) {
+	tmpl, err := template.New(filepath.Base(path)).Parse(string(data))
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	err = tmpl.Execute(&b, map[string]string{"Str": "rendered"})
+	return b.Bytes(), err
+}
+
+func TestTransformers(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data string) string {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, []byte(data), 0600))
+		return path
+	}
+	tmpl := write("config.yaml.tmpl", "str: {{ .Str }}\nint: 1\n")
+	json := write("config.jsonnet", `{"int": 2}`)
+	write("search.yaml.tmpl", "int: 3\n")
+
+	// Case 1: selected by extension, the format following the transformer or the
+	// preceding extension
+	for _, test := range []struct {
+		args     []string
+		cfgFile  string
+		expected TestStruct
+	}{
+		{args: []string{"-config", tmpl}, expected: TestStruct{Str: "rendered", Int: 1}},
+		{args: []string{"-config", json}, expected: TestStruct{Int: 2}},
+		{cfgFile: filepath.Join(dir, "search"), expected: TestStruct{Int: 3}},
+	} {
+		s := &TestStruct{}
+		gf := New(ContinueOnError)
+		gf.SetConfigFileFlag("config", "config file")
+		gf.AddTransformer(".tmpl", "", renderTemplate)
+		gf.AddTransformer(".jsonnet", "json", func(ctx context.Context, path string, data []byte) ([]byte, error) {
+			return data, nil
+		})
+		if test.cfgFile != "" {
+			gf.AddConfigFile(test.cfgFile)
+		}
+		err := gf.ParseWithArgs(s, test.args)
+		assert.NoError(t, err, test.args)
+		assert.Equal(t, test.expected, *s, test.args)
+	}
+
+	// Case 2: the longest extension wins
+	s := &TestStruct{}
+	gf := New(ContinueOnError)
+	gf.SetConfigFileFlag("config", "config file")
+	gf.AddTransformer(".tmpl", "json", func(ctx context.Context, path string, data []byte) ([]byte, error) {
+		return nil, errors.New("unexpected")
+	})
+	gf.AddTransformer(".yaml.tmpl", "yaml", renderTemplate)
+	err := gf.ParseWithArgs(s, []string{"-config", tmpl})
+	assert.NoError(t, err)
+	assert.Equal(t, "rendered", s.Str)
+
+	// Case 3: transformer error
+	gf = New(ContinueOnError)
+	gf.SetConfigFileFlag("config", "config file")
+	gf.AddTransformer(".jsonnet", "json", func(ctx context.Context, path string, data []byte) ([]byte, error) {
+		return nil, errors.New("syntax error")
+	})
+	err = gf.ParseWithArgs(&TestStruct{}, []string{"-config", json})
+	assert.EqualError(t, err, "error transforming config file '"+json+"': syntax error")
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+
+	"github.com/BurntSushi/toml"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// docTree is a config document decoded once into a tree, which the conversions of a
+// parse (value selectors, parse methods, numeric rules and lenient scalars) edit in
+// place before it is decoded into the struct. The decoding errors keep the positions of
+// the document: TOML trees are decoded with the metadata of the document, and the YAML
+// nodes encoded from an edited tree take the positions of the nodes it was decoded from.
+type docTree struct {
+	ext     string
+	data    []byte
+	root    map[string]interface{} // nil if the document isn't a valid map
+	changed bool                   // whether root was edited
+
+	node yaml.Node // YAML document
+	md   toml.MetaData
+	prim toml.Primitive // TOML document, sharing its maps and lists with root
+}
+
+// parseTree decodes a config document into a tree keeping the exact value of the
+// numbers. The documents which aren't valid maps are left to the decoders, which report
+// their errors.
+func parseTree(data []byte, ext string) *docTree {
+	t := &docTree{ext: ext, data: data}
+	defer func() {
+		if recover() != nil {
+			t.root = nil // the decoder panics again, on decoding
+		}
+	}()
+
+	switch ext {
+	case jsonExtention:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if dec.Decode(&t.root) != nil {
+			t.root = nil
+		}
+	case tomlExtention:
+		md, err := toml.Decode(string(data), &t.prim)
+		if err != nil {
+			return t
+		}
+		// an empty interface is decoded as the tree of the primitive itself
+		var tree interface{}
+		if md.PrimitiveDecode(t.prim, &tree) != nil {
+			return t
+		}
+		t.md = md
+		t.root, _ = tree.(map[string]interface{})
+	case yamlExtention:
+		if yaml.Unmarshal(data, &t.node) != nil || t.node.Kind != yaml.DocumentNode {
+			return t
+		}
+		var doc interface{}
+		if t.node.Decode(&doc) != nil {
+			return t
+		}
+		t.root, _ = stringKeys(doc).(map[string]interface{})
+	}
+	return t
+}
+
+// decode decodes the tree into v, or the document if it isn't a valid map.
+func (t *docTree) decode(v interface{}) error {
+	switch t.ext {
+	case jsonExtention:
+		if t.root == nil || !t.changed {
+			return json.NewDecoder(bytes.NewReader(t.data)).Decode(v)
+		}
+		data, err := json.Marshal(t.root)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(data, v)
+	case tomlExtention:
+		if t.root == nil {
+			_, err := toml.Decode(string(t.data), v)
+			return err
+		}
+		localTimes(reflect.ValueOf(t.root))
+		return t.md.PrimitiveDecode(t.prim, v)
+	default:
+		if t.root == nil {
+			return yamlErrorKey(yaml.NewDecoder(bytes.NewReader(t.data)).Decode(v), t.data)
+		}
+		if !t.changed {
+			return yamlErrorKey(t.node.Decode(v), t.data)
+		}
+		var node yaml.Node
+		err := node.Encode(t.root)
+		if err != nil {
+			return err
+		}
+		copyYAMLPositions(&node, t.node.Content[0])
+		return yamlErrorKey(node.Decode(v), t.data)
+	}
+}
+
+// copyYAMLPositions sets the positions of the nodes encoded from a YAML document tree to
+// the positions of the nodes of the document they were decoded from, resolving the
+// aliases and the keys merged with <<.
+func copyYAMLPositions(node *yaml.Node, orig *yaml.Node) {
+	for orig.Kind == yaml.AliasNode && orig.Alias != nil {
+		orig = orig.Alias
+	}
+	node.Line, node.Column = orig.Line, orig.Column
+
+	switch {
+	case node.Kind == yaml.MappingNode && orig.Kind == yaml.MappingNode:
+		pairs := make(map[string][2]*yaml.Node)
+		yamlPairs(orig, pairs)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if pair, ok := pairs[node.Content[i].Value]; ok {
+				copyYAMLPositions(node.Content[i], pair[0])
+				copyYAMLPositions(node.Content[i+1], pair[1])
+			}
+		}
+	case node.Kind == yaml.SequenceNode && orig.Kind == yaml.SequenceNode:
+		for i := 0; i < len(node.Content) && i < len(orig.Content); i++ {
+			copyYAMLPositions(node.Content[i], orig.Content[i])
+		}
+	}
+}
+
+// yamlPairs adds the key and value nodes of a YAML mapping node to pairs by key. The
+// explicit keys take precedence over the merged ones, the first merged node over the
+// next ones.
+func yamlPairs(node *yaml.Node, pairs map[string][2]*yaml.Node) {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	var merges []*yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, val := node.Content[i], node.Content[i+1]
+		if key.Tag == "!!merge" {
+			if val.Kind == yaml.SequenceNode {
+				merges = append(merges, val.Content...)
+			} else {
+				merges = append(merges, val)
+			}
+			continue
+		}
+		pairs[key.Value] = [2]*yaml.Node{key, val}
+	}
+	for _, m := range merges {
+		merged := make(map[string][2]*yaml.Node)
+		yamlPairs(m, merged)
+		for k, pair := range merged {
+			if _, ok := pairs[k]; !ok {
+				pairs[k] = pair
+			}
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"os"
+	"reflect"
+	"sync/atomic"
+)
+
+// Option configures the Gofig instance used by ParseAs and NewStore.
+type Option func(o *options)
+
+type options struct {
+	gf   *Gofig
+	args []string
+}
+
+// WithGofig uses a configured Gofig instance instead of a new one with ContinueOnError.
+// It must come before the options configuring the instance.
+func WithGofig(gf *Gofig) Option {
+	return func(o *options) { o.gf = gf }
+}
+
+// WithArgs parses the provided arguments instead of os.Args[1:].
+func WithArgs(args []string) Option {
+	return func(o *options) { o.args = args }
+}
+
+// WithEnvPrefix sets the environment variables prefix, see SetEnvPrefix.
+func WithEnvPrefix(prefix string) Option {
+	return func(o *options) { o.gf.SetEnvPrefix(prefix) }
+}
+
+// WithConfigFile adds config files, see AddConfigFile.
+func WithConfigFile(path ...string) Option {
+	return func(o *options) { o.gf.AddConfigFile(path...) }
+}
+
+// WithConfigFileFlag adds a config file flag, see SetConfigFileFlag.
+func WithConfigFileFlag(name string, desc string) Option {
+	return func(o *options) { o.gf.SetConfigFileFlag(name, desc) }
+}
+
+// WithSource adds a config source, see AddSource.
+func WithSource(src Source) Option {
+	return func(o *options) { o.gf.AddSource(src) }
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{gf: New(ContinueOnError), args: os.Args[1:]}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// ParseAs parses a configuration of type T, a struct type. Its user-defined values are
+// the zero values of T. Use a Store to get the updates of the configuration.
+func ParseAs[T any](opts ...Option) (T, error) {
+	var v T
+	o := newOptions(opts)
+	err := o.gf.ParseWithArgs(&v, o.args)
+	return v, err
+}
+
+// Store holds a parsed configuration of type T, kept up to date when it is reloaded.
+// Each version of the configuration is an immutable snapshot swapped atomically, so that
+// it can be read concurrently with the reloads, unlike the struct passed to Parse which
+// is updated in place.
+type Store[T any] struct {
+	gf  *Gofig
+	v   *T           // the parsed struct, updated in place under the lock of gf
+	cur atomic.Value // *T, the snapshot of the current configuration
+}
+
+// NewStore parses a configuration of type T, a struct type, into a new Store.
+func NewStore[T any](opts ...Option) (*Store[T], error) {
+	o := newOptions(opts)
+	s := &Store[T]{gf: o.gf, v: new(T)}
+	err := o.gf.ParseWithArgs(s.v, o.args)
+	if err != nil {
+		return nil, err
+	}
+	s.swap()
+	// registered first, so that the listeners of the store get the new snapshot
+	o.gf.OnChange(s.swap)
+	return s, nil
+}
+
+// swap stores a snapshot of the parsed struct.
+func (s *Store[T]) swap() {
+	s.gf.mu.Lock()
+	v := deepCopy(reflect.ValueOf(s.v).Elem(), make(map[uintptr]reflect.Value)).Interface().(T)
+	s.gf.mu.Unlock()
+	s.cur.Store(&v)
+}
+
+// Load returns the snapshot of the current configuration, without copying it. It is
+// shared by all the readers and must not be modified.
+func (s *Store[T]) Load() *T {
+	return s.cur.Load().(*T)
+}
+
+// Get returns a deep copy of the current configuration.
+func (s *Store[T]) Get() T {
+	return deepCopy(reflect.ValueOf(s.Load()).Elem(), make(map[uintptr]reflect.Value)).Interface().(T)
+}
+
+// OnChange registers a function called with the new configuration each time it changes.
+func (s *Store[T]) OnChange(fn func(T)) {
+	s.gf.OnChange(func() { fn(s.Get()) })
+}
+
+// Gofig returns the Gofig instance of the store, e.g. to override values or watch sources.
+func (s *Store[T]) Gofig() *Gofig {
+	return s.gf
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAs(t *testing.T) {
+	os.Setenv("GFA_INT", "1")
+	defer os.Unsetenv("GFA_INT")
+
+	cfg, err := ParseAs[TestStruct](
+		WithEnvPrefix("GFA"),
+		WithConfigFile("gofig_test_yaml"),
+		WithArgs([]string{"-str", "flag"}),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "flag", cfg.Str)
+	assert.Equal(t, 1, cfg.Int)
+	assert.Equal(t, "renamed-config-file", cfg.Sub.RenamedStr)
+
+	_, err = ParseAs[TestStruct](WithArgs([]string{"-int", "one"}))
+	assert.Error(t, err)
+
+	_, err = ParseAs[int](WithArgs([]string{}))
+	assert.Equal(t, errInvalidValue, err)
+}
+
+func TestStore(t *testing.T) {
+	gf := New(ContinueOnError)
+	store, err := NewStore[TestStruct](WithGofig(gf), WithArgs([]string{"-str", "flag"}))
+	assert.NoError(t, err)
+	assert.Equal(t, gf, store.Gofig())
+	assert.Equal(t, "flag", store.Get().Str)
+
+	var changed TestStruct
+	store.OnChange(func(cfg TestStruct) { changed = cfg })
+	err = gf.Override("int", "2")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, store.Get().Int)
+	assert.Equal(t, 2, changed.Int)
+
+	_, err = NewStore[TestStruct](WithArgs([]string{"-int", "one"}))
+	assert.Error(t, err)
+}
+
+func TestStoreConcurrentReads(t *testing.T) {
+	gf := New(ContinueOnError)
+	store, err := NewStore[TestStruct](WithGofig(gf), WithArgs([]string{}))
+	assert.NoError(t, err)
+
+	// the snapshots are read while the configuration is reloaded (see go test -race)
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				cfg := store.Load()
+				assert.Equal(t, cfg.Int, len(cfg.Str))
+			}
+		}
+	}()
+	for i := 1; i <= 20; i++ {
+		assert.NoError(t, gf.Push(&Document{Values: map[string]string{"int": fmt.Sprint(i), "str": fmt.Sprintf("%0*d", i, 0)}}))
+	}
+	close(done)
+	wg.Wait()
+	assert.Equal(t, 20, store.Load().Int)
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// UnusedKeys returns the dot-separated key paths found in the config documents (config
+// file, sources and pushed documents) of the last parse or reload which aren't mapped
+// to any field, so that dead configuration can be pruned. Keys are lowercased.
+func UnusedKeys() []string { return gf.UnusedKeys() }
+
+// UnusedKeys returns the dot-separated key paths found in the config documents (config
+// file, sources and pushed documents) of the last parse or reload which aren't mapped
+// to any field, so that dead configuration can be pruned. Keys are lowercased.
+func (gf *Gofig) UnusedKeys() []string {
+	gf.mu.Lock()
+	defer gf.mu.Unlock()
+	return gf.unusedKeys
+}
+
+// setUnusedKeys sets the sorted unused keys collected during a parse, keeping only
+// those starting with prefix.
+func (gf *Gofig) setUnusedKeys(unused map[string]struct{}, prefix string) {
+	gf.unusedKeys = nil
+	for key := range unused {
+		if strings.HasPrefix(key, prefix) {
+			gf.unusedKeys = append(gf.unusedKeys, key)
+		}
+	}
+	sort.Strings(gf.unusedKeys)
+}
+
+// isYAMLTemplateKey returns whether a top-level YAML key holds a template only used
+// through anchors and aliases, by convention prefixed with "x-" (like Docker Compose
+// extension fields) or "." (like GitLab CI hidden keys).
+func isYAMLTemplateKey(key string) bool {
+	return strings.HasPrefix(key, "x-") || strings.HasPrefix(key, ".")
+}
+
+// collectUnusedKeys adds the key paths of the decoded document tree which aren't mapped
+// to a field of v, following the keys of the format tag, to unused.
+func collectUnusedKeys(tree interface{}, v interface{}, cfgTag string, unused map[string]struct{}) {
+	leaves := make(map[string]bool)
+	prefixes := make(map[string]bool)
+	_ = parseStruct(v, func(path []string, name string, val *reflect.Value, tags *reflect.StructTag) error {
+		leaves[strings.Join(path, ".")] = true
+		for i := 1; i < len(path); i++ {
+			prefixes[strings.Join(path[:i], ".")] = true
+		}
+		return nil
+	}, cfgTag)
+
+	var walk func(node interface{}, path string)
+	walk = func(node interface{}, path string) {
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for k, val := range m {
+			key := strings.ToLower(k)
+			if path != "" {
+				key = path + "." + key
+			}
+			if leaves[key] {
+				continue
+			} else if prefixes[key] {
+				walk(val, key)
+			} else if cfgTag != "yaml" || path != "" || !isYAMLTemplateKey(k) {
+				unused[key] = struct{}{}
+			}
+		}
+	}
+	walk(tree, "")
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnusedKeys(t *testing.T) {
+	type Config struct {
+		TestStruct `json:"test" yaml:"test"`
+		Map        map[string]int
+		Labels     map[string]string `yaml:"labels"`
+	}
+
+	gf := New(ContinueOnError)
+	gf.AddSource(&testSource{name: "json", doc: &Document{Format: "json", Data: []byte(`{
+		"Test": {"Str": "a", "Sub": {"Str": "b", "Old": 1}, "Removed": true},
+		"map": {"a": 1},
+		"legacy": {"a": 1}
+	}`)}})
+	gf.AddSource(&testSource{name: "yaml", doc: &Document{Format: "yaml", Data: []byte("labels: {a: b}\ntest: {int: 1, typo: 2}\n")}})
+	s := &Config{}
+	err := gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "b", s.Sub.RenamedStr)
+	assert.Equal(t, []string{"legacy", "test.removed", "test.sub.old", "test.typo"}, gf.UnusedKeys())
+
+	// recomputed on reload
+	err = gf.Push(&Document{Format: "json", Data: []byte(`{"pushed": 1}`)})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"legacy", "pushed", "test.removed", "test.sub.old", "test.typo"}, gf.UnusedKeys())
+
+	// Case 2: skipped field
+	gf = New(ContinueOnError)
+	gf.AddConfigFile("gofig_test_toml")
+	err = gf.ParseWithArgs(&TestStruct{}, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"skipped"}, gf.UnusedKeys())
+}
+
+func TestYAMLAnchors(t *testing.T) {
+	type Server struct {
+		Host string
+		Port int
+		Tags []string
+	}
+	type Config struct {
+		Primary Server
+		Replica Server
+		Backup  Server
+	}
+
+	// anchors, aliases and merge keys: explicit keys override the merged ones, and the
+	// first merged map takes precedence over the next ones
+	gf := New(ContinueOnError)
+	gf.AddSource(&testSource{name: "yaml", doc: &Document{Format: "yaml", Data: []byte(`
+x-defaults: &defaults
+  host: localhost
+  port: 80
+  tags: [a, b]
+.other: &other
+  port: 90
+  extra: 1
+primary:
+  <<: *defaults
+  host: primary
+replica:
+  <<: [*other, *defaults]
+backup: *defaults
+`)}})
+	s := &Config{}
+	err := gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, Server{Host: "primary", Port: 80, Tags: []string{"a", "b"}}, s.Primary)
+	assert.Equal(t, Server{Host: "localhost", Port: 90, Tags: []string{"a", "b"}}, s.Replica)
+	assert.Equal(t, Server{Host: "localhost", Port: 80, Tags: []string{"a", "b"}}, s.Backup)
+
+	// the template keys and the merge keys aren't reported, the merged keys are
+	assert.Equal(t, []string{"replica.extra"}, gf.UnusedKeys())
+
+	// template keys are only ignored at the top level of YAML documents
+	gf = New(ContinueOnError)
+	gf.AddSource(
+		&testSource{name: "yaml", doc: &Document{Format: "yaml", Data: []byte("primary: {x-port: 1}\n")}},
+		&testSource{name: "json", doc: &Document{Format: "json", Data: []byte(`{"x-json": 1}`)}},
+	)
+	err = gf.ParseWithArgs(&Config{}, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"primary.x-port", "x-json"}, gf.UnusedKeys())
+}
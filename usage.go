@@ -0,0 +1,99 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"flag"
+	"fmt"
+	"io"
+)
+
+// flagUsage holds how the flags of a flag set are listed in the usage message, shared by a
+// Gofig and its children.
+type flagUsage struct {
+	declared []string // flag names in declaration order
+	unsorted bool     // whether the flags are listed in declaration order
+}
+
+// SetFlagOutput sets the destination of the usage and error messages of the flags
+// (os.Stderr by default).
+func SetFlagOutput(w io.Writer) { gf.SetFlagOutput(w) }
+
+// SetFlagOutput sets the destination of the usage and error messages of the flags
+// (os.Stderr by default).
+func (gf *Gofig) SetFlagOutput(w io.Writer) {
+	gf.flagSet.SetOutput(&flagOutput{gf: gf, w: w})
+}
+
+// SetUsage sets the function printing the usage message when a flag is invalid or -h is
+// given. It may call PrintDefaults to list the flags.
+func SetUsage(usage func()) { gf.SetUsage(usage) }
+
+// SetUsage sets the function printing the usage message when a flag is invalid or -h is
+// given. It may call PrintDefaults to list the flags.
+func (gf *Gofig) SetUsage(usage func()) {
+	gf.flagSet.Usage = usage
+}
+
+// SetSortFlags sets whether the flags are listed sorted by name in the usage message
+// (the default), or in the declaration order of the fields of the config struct, to
+// follow its logical grouping.
+func SetSortFlags(sorted bool) { gf.SetSortFlags(sorted) }
+
+// SetSortFlags sets whether the flags are listed sorted by name in the usage message
+// (the default), or in the declaration order of the fields of the config struct, to
+// follow its logical grouping.
+func (gf *Gofig) SetSortFlags(sorted bool) {
+	gf.flags.unsorted = !sorted
+}
+
+// PrintDefaults prints the flags and their default values to the flag output, in the
+// order set by SetSortFlags. The built-in -gofig-* flags are hidden.
+func PrintDefaults() { gf.PrintDefaults() }
+
+// PrintDefaults prints the flags and their default values to the flag output, in the
+// order set by SetSortFlags. The built-in -gofig-* flags are hidden.
+func (gf *Gofig) PrintDefaults() {
+	// print each flag with a flag set of its own, to get the same format
+	printed := make(map[string]bool)
+	printFlag := func(f *flag.Flag) {
+		if printed[f.Name] || isBuiltinFlag(f.Name) {
+			return
+		}
+		printed[f.Name] = true
+		fs := flag.NewFlagSet(f.Name, flag.ContinueOnError)
+		fs.SetOutput(gf.flagSet.Output())
+		fs.Var(f.Value, f.Name, f.Usage)
+		fs.Lookup(f.Name).DefValue = f.DefValue
+		fs.PrintDefaults()
+	}
+	if gf.flags.unsorted {
+		for _, name := range gf.flags.declared {
+			if f := gf.flagSet.Lookup(name); f != nil {
+				printFlag(f)
+			}
+		}
+	}
+	gf.flagSet.VisitAll(printFlag) // sorted, or declared directly into the flag set
+}
+
+// defaultUsage prints the default usage message of the flag package, listing the flags
+// with PrintDefaults.
+func (gf *Gofig) defaultUsage() {
+	if gf.flagSet.Name() == "" {
+		fmt.Fprintln(gf.flagSet.Output(), gf.translate("Usage:"))
+	} else {
+		fmt.Fprintln(gf.flagSet.Output(), gf.translatef("Usage of %v:", gf.flagSet.Name()))
+	}
+	gf.PrintDefaults()
+}
+
+// declareFlag records the declaration of a flag into the flag set of gf.
+func (gf *Gofig) declareFlag(fs *flag.FlagSet, name string) {
+	if fs == gf.flagSet && fs.Lookup(name) != nil {
+		gf.flags.declared = append(gf.flags.declared, name)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"bytes"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlagUsage(t *testing.T) {
+	type Config struct {
+		Server struct {
+			Port int    `desc:"port to listen on"`
+			Host string `desc:"host to listen on"`
+		}
+		Debug bool `flag:"d" desc:"enable debugging"`
+	}
+
+	// Case 1: flags sorted by name, as by the flag package
+	var out bytes.Buffer
+	gf := New(ContinueOnError)
+	gf.SetFlagOutput(&out)
+	gf.SetConfigFileFlag("c", "config file")
+	s := &Config{}
+	s.Server.Port = 80
+	err := gf.ParseWithArgs(s, []string{"-h"})
+	assert.Equal(t, flag.ErrHelp, err)
+	assert.Equal(t, `Usage of `+gf.flagSet.Name()+`:
+  -c string
+    	config file
+  -d	enable debugging
+  -server-host string
+    	host to listen on
+  -server-port int
+    	port to listen on (default 80)
+`, out.String())
+
+	// Case 2: flags in declaration order
+	out.Reset()
+	gf = New(ContinueOnError)
+	gf.SetFlagOutput(&out)
+	gf.SetSortFlags(false)
+	gf.SetConfigFileFlag("c", "config file")
+	s = &Config{}
+	s.Server.Port = 80
+	err = gf.ParseWithArgs(s, []string{"-server-host", "localhost", "-h"})
+	assert.Equal(t, flag.ErrHelp, err)
+	assert.Equal(t, `Usage of `+gf.flagSet.Name()+`:
+  -c string
+    	config file
+  -server-port int
+    	port to listen on (default 80)
+  -server-host string
+    	host to listen on
+  -d	enable debugging
+`, out.String())
+
+	// Case 3: custom usage function
+	out.Reset()
+	gf = New(ContinueOnError)
+	gf.SetFlagOutput(&out)
+	gf.SetSortFlags(false)
+	gf.SetUsage(func() {
+		out.WriteString("usage: server [flags]\n")
+		gf.PrintDefaults()
+	})
+	err = gf.ParseWithArgs(&Config{}, []string{"-unknown"})
+	assert.Error(t, err)
+	assert.Equal(t, `flag provided but not defined: -unknown
+usage: server [flags]
+  -server-port int
+    	port to listen on
+  -server-host string
+    	host to listen on
+  -d	enable debugging
+`, out.String())
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import "reflect"
+
+// Validator is implemented by the config structs, or nested structs, checking their
+// cross-field invariants. Validate is called once all the sources are applied, and its
+// error is handled like any parsing error.
+type Validator interface {
+	Validate() error
+}
+
+// validate calls the Validate method of the struct pointed to by v and of its nested
+// structs, the nested structs first. The structs of the inactive sections aren't
+// validated. The errors of a nested struct are prefixed with its field name, and wrap
+// the error returned by Validate.
+func (gf *Gofig) validate(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	return gf.validateStruct(rv.Elem(), nil, "")
+}
+
+// validateStruct validates the nested structs of the addressable struct value rv, then
+// rv itself.
+func (gf *Gofig) validateStruct(rv reflect.Value, path []string, name string) error {
+	fields := structFields(rv.Type(), "json")
+	for i := range fields {
+		if fields[i].key == "" {
+			continue
+		}
+		f := rv.Field(i)
+		if f.Kind() == reflect.Ptr {
+			if f.IsNil() {
+				continue
+			}
+			f = f.Elem()
+		}
+		if f.Kind() != reflect.Struct || isValueType(f.Type()) {
+			continue
+		}
+		fieldPath := append(path[:len(path):len(path)], fields[i].key)
+		if gf.isInactive(fieldPath) {
+			continue
+		}
+		fieldName := fields[i].name
+		if name != "" {
+			fieldName = name + "." + fieldName
+		}
+		err := gf.validateStruct(f, fieldPath, fieldName)
+		if err != nil {
+			return err
+		}
+	}
+
+	validator, ok := rv.Addr().Interface().(Validator)
+	if !ok {
+		return nil
+	}
+	err := validator.Validate()
+	if err != nil && name != "" {
+		return errorf("invalid %v: %v", name, err)
+	}
+	return err
+}
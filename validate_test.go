@@ -0,0 +1,70 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errPortRange = errors.New("min port is greater than max port")
+
+type portRange struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+func (r portRange) Validate() error {
+	if r.Min > r.Max {
+		return errPortRange
+	}
+	return nil
+}
+
+type validatedStruct struct {
+	TLS     bool      `json:"tls"`
+	Cert    string    `json:"cert"`
+	Ports   portRange `json:"ports"`
+	Metrics struct {
+		Enabled bool      `json:"enabled"`
+		Ports   portRange `json:"ports"`
+	} `json:"metrics" enabledBy:"metrics.enabled"`
+}
+
+func (s *validatedStruct) Validate() error {
+	if s.TLS && s.Cert == "" {
+		return errors.New("tls requires a cert")
+	}
+	return nil
+}
+
+func TestValidate(t *testing.T) {
+	for args, expected := range map[string]string{
+		"":                                      "",
+		"-tls":                                  "tls requires a cert",
+		"-tls -cert c.pem":                      "",
+		"-ports-min 2":                          "invalid Ports: min port is greater than max port",
+		"-ports-min 2 -tls":                     "invalid Ports: min port is greater than max port",
+		"-metrics-ports-min 2":                  "",
+		"-metrics-ports-min 2 -metrics-enabled": "invalid Metrics.Ports: min port is greater than max port",
+	} {
+		gf := New(ContinueOnError)
+		err := gf.ParseWithArgs(&validatedStruct{}, strings.Fields(args))
+		if expected == "" {
+			assert.NoError(t, err, args)
+		} else {
+			assert.EqualError(t, err, expected, args)
+		}
+	}
+
+	// the error returned by Validate is wrapped
+	gf := New(ContinueOnError)
+	err := gf.ParseWithArgs(&validatedStruct{}, []string{"-ports-min", "2"})
+	assert.True(t, errors.Is(err, errPortRange))
+}
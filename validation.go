@@ -0,0 +1,283 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// validateTag is the struct tag holding the comma-separated validation rules of a field,
+// following the go-playground/validator syntax, e.g. validate:"omitempty,min=1,max=65535".
+const validateTag = "validate"
+
+var (
+	timeDurationType = reflect.TypeOf(time.Duration(0))
+
+	alphaRegexp    = regexp.MustCompile(`^[a-zA-Z]+$`)
+	alphanumRegexp = regexp.MustCompile(`^[a-zA-Z0-9]+$`)
+	numericRegexp  = regexp.MustCompile(`^[-+]?[0-9]+(?:\.[0-9]+)?$`)
+	hostnameRegexp = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*\.?$`)
+)
+
+// EnableValidation enables the validation rules of the validate struct tags once parsed,
+// following the go-playground/validator syntax: omitempty, required, min, max, len, eq,
+// ne, gt, gte, lt, lte, oneof, url, email, hostname, hostname_port, ip, ipv4, ipv6, cidr,
+// alpha, alphanum and numeric. The lengths of the strings, slices and maps are checked
+// by min, max and len, and the durations are compared with duration parameters (e.g.
+// min=1s). The violations are reported with the flag, environment variable and key of
+// their field.
+func EnableValidation() { gf.EnableValidation() }
+
+// EnableValidation enables the validation rules of the validate struct tags once parsed,
+// following the go-playground/validator syntax: omitempty, required, min, max, len, eq,
+// ne, gt, gte, lt, lte, oneof, url, email, hostname, hostname_port, ip, ipv4, ipv6, cidr,
+// alpha, alphanum and numeric. The lengths of the strings, slices and maps are checked
+// by min, max and len, and the durations are compared with duration parameters (e.g.
+// min=1s). The violations are reported with the flag, environment variable and key of
+// their field.
+func (gf *Gofig) EnableValidation() {
+	gf.validation = true
+}
+
+// checkValidateTags returns an error listing the violations of the validation rules of
+// the fields of v, the fields of the inactive sections being skipped.
+func (gf *Gofig) checkValidateTags(v interface{}) error {
+	if !gf.validation {
+		return nil
+	}
+	var invalid []string
+	var violations []error
+	err := parseStruct(v, func(path []string, name string, f *reflect.Value, tags *reflect.StructTag) error {
+		rules, ok := tags.Lookup(validateTag)
+		if !ok || gf.isInactive(path) {
+			return nil
+		}
+		violation, err := checkRules(*f, rules)
+		if err != nil {
+			return errorf("invalid validate tag of field %v: %v", name, err)
+		}
+		if violation != nil {
+			invalid = append(invalid, name)
+			violations = append(violations, violation)
+		}
+		return nil
+	}, "json")
+	if err != nil || len(invalid) == 0 {
+		return err
+	}
+
+	names := gf.fieldNames(v, "json", true)
+	if len(invalid) == 1 {
+		return errorf("invalid field %v (%v): %v", invalid[0], names[invalid[0]].format(gf.translate), violations[0])
+	}
+	fields := make([]string, len(invalid))
+	for i, name := range invalid {
+		fields[i] = fmt.Sprintf("%v (%v): %v", name, names[name].format(gf.translate), gf.translate(violations[i].Error()))
+	}
+	return errorf("invalid fields %v", strings.Join(fields, "; "))
+}
+
+// checkRules returns the first violation of the comma-separated rules by the field
+// value f, or an error if a rule isn't supported or its parameter is invalid. The rules
+// of a pointer apply to the value it points to, omitempty only skipping a nil pointer.
+func checkRules(f reflect.Value, rules string) (violation error, err error) {
+	ptr := f.Kind() == reflect.Ptr
+	for f.Kind() == reflect.Ptr {
+		if f.IsNil() {
+			if strings.Contains(","+rules+",", ",required,") {
+				return errorf("is required"), nil
+			}
+			return nil, nil
+		}
+		f = f.Elem()
+	}
+	for _, rule := range strings.Split(rules, ",") {
+		name, param := rule, ""
+		if i := strings.IndexByte(rule, '='); i >= 0 {
+			name, param = rule[:i], rule[i+1:]
+		}
+		switch name {
+		case "":
+		case "omitempty":
+			if !ptr && f.IsZero() {
+				return nil, nil
+			}
+		case "required":
+			if f.IsZero() {
+				return errorf("is required"), nil
+			}
+		case "min", "max", "len", "eq", "ne", "gt", "gte", "lt", "lte":
+			violation, err = checkCompare(f, name, param)
+		case "oneof":
+			violation = checkOneOf(f, strings.Fields(param))
+		default:
+			violation, err = checkFormat(f, name)
+		}
+		if violation != nil || err != nil {
+			return violation, err
+		}
+	}
+	return nil, nil
+}
+
+// compareMessages are the messages of the violations of the comparison rules, for the
+// numbers, the lengths of the strings and the lengths of the slices and maps.
+var compareMessages = map[string][3]string{
+	"min": {"must be at least %v", "must be at least %v characters long", "must have at least %v items"},
+	"max": {"must be at most %v", "must be at most %v characters long", "must have at most %v items"},
+	"len": {"must be %v", "must be %v characters long", "must have %v items"},
+	"eq":  {"must be equal to %v", "must be equal to %v", "must have %v items"},
+	"ne":  {"must not be equal to %v", "must not be equal to %v", "must not have %v items"},
+	"gt":  {"must be greater than %v", "must be more than %v characters long", "must have more than %v items"},
+	"gte": {"must be greater than or equal to %v", "must be at least %v characters long", "must have at least %v items"},
+	"lt":  {"must be less than %v", "must be less than %v characters long", "must have less than %v items"},
+	"lte": {"must be less than or equal to %v", "must be at most %v characters long", "must have at most %v items"},
+}
+
+// checkCompare checks a comparison rule, on the value of the numbers and durations, the
+// length of the slices and maps, and the length of the strings except for eq and ne.
+func checkCompare(f reflect.Value, rule string, param string) (error, error) {
+	var val, limit float64
+	var err error
+	msg := compareMessages[rule][0]
+	switch {
+	case f.Type() == timeDurationType || f.Type() == durationType:
+		var d time.Duration
+		d, err = time.ParseDuration(param)
+		val, limit = float64(f.Int()), float64(d)
+	case f.Kind() == reflect.String && (rule == "eq" || rule == "ne"):
+		if (f.String() == param) != (rule == "eq") {
+			return errorf(msg, strconv.Quote(param)), nil
+		}
+		return nil, nil
+	case f.Kind() == reflect.String:
+		msg = compareMessages[rule][1]
+		val = float64(utf8.RuneCountInString(f.String()))
+		limit, err = strconv.ParseFloat(param, 64)
+	case f.Kind() == reflect.Slice || f.Kind() == reflect.Map || f.Kind() == reflect.Array:
+		msg = compareMessages[rule][2]
+		val = float64(f.Len())
+		limit, err = strconv.ParseFloat(param, 64)
+	case f.CanInt():
+		val = float64(f.Int())
+		limit, err = strconv.ParseFloat(param, 64)
+	case f.CanUint():
+		val = float64(f.Uint())
+		limit, err = strconv.ParseFloat(param, 64)
+	case f.CanFloat():
+		val = f.Float()
+		limit, err = strconv.ParseFloat(param, 64)
+	default:
+		return nil, errorf("rule %v isn't supported on %v", rule, f.Type())
+	}
+	if err != nil {
+		return nil, errorf("invalid parameter '%v' of rule %v", param, rule)
+	}
+
+	var ok bool
+	switch rule {
+	case "min", "gte":
+		ok = val >= limit
+	case "max", "lte":
+		ok = val <= limit
+	case "len", "eq":
+		ok = val == limit
+	case "ne":
+		ok = val != limit
+	case "gt":
+		ok = val > limit
+	case "lt":
+		ok = val < limit
+	}
+	if ok {
+		return nil, nil
+	}
+	return errorf(msg, param), nil
+}
+
+// checkOneOf checks the oneof rule, comparing the text of the value with the choices.
+func checkOneOf(f reflect.Value, choices []string) error {
+	val, _ := encodeString(&f)
+	for _, choice := range choices {
+		if val == choice {
+			return nil
+		}
+	}
+	return errorf("must be one of %v", strings.Join(choices, ", "))
+}
+
+// checkFormat checks a format rule on a string, or an error if rule isn't a format.
+func checkFormat(f reflect.Value, rule string) (error, error) {
+	var msg string
+	var check func(s string) bool
+	switch rule {
+	case "url":
+		msg = "must be a URL"
+		check = func(s string) bool {
+			u, err := url.Parse(s)
+			return err == nil && u.Scheme != "" && (u.Host != "" || u.Opaque != "" || u.Path != "")
+		}
+	case "email":
+		msg = "must be an email address"
+		check = func(s string) bool {
+			addr, err := mail.ParseAddress(s)
+			return err == nil && addr.Address == s
+		}
+	case "hostname":
+		msg = "must be a hostname"
+		check = hostnameRegexp.MatchString
+	case "hostname_port":
+		msg = "must be a host:port"
+		check = func(s string) bool {
+			host, port, err := net.SplitHostPort(s)
+			if err != nil || (host != "" && !hostnameRegexp.MatchString(host) && net.ParseIP(host) == nil) {
+				return false
+			}
+			n, err := strconv.ParseUint(port, 10, 16)
+			return err == nil && n > 0
+		}
+	case "ip":
+		msg = "must be an IP address"
+		check = func(s string) bool { return net.ParseIP(s) != nil }
+	case "ipv4":
+		msg = "must be an IPv4 address"
+		check = func(s string) bool { ip := net.ParseIP(s); return ip != nil && ip.To4() != nil }
+	case "ipv6":
+		msg = "must be an IPv6 address"
+		check = func(s string) bool { ip := net.ParseIP(s); return ip != nil && ip.To4() == nil }
+	case "cidr":
+		msg = "must be a CIDR notation IP address"
+		check = func(s string) bool { _, _, err := net.ParseCIDR(s); return err == nil }
+	case "alpha":
+		msg = "must contain only letters"
+		check = alphaRegexp.MatchString
+	case "alphanum":
+		msg = "must contain only letters and digits"
+		check = alphanumRegexp.MatchString
+	case "numeric":
+		msg = "must be numeric"
+		check = numericRegexp.MatchString
+	default:
+		return nil, errorf("unknown rule %v", rule)
+	}
+	val, ok := encodeString(&f)
+	if !ok {
+		return nil, errorf("rule %v isn't supported on %v", rule, f.Type())
+	}
+	if check(val) {
+		return nil, nil
+	}
+	return errorf(msg), nil
+}
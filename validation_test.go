@@ -0,0 +1,83 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type validationStruct struct {
+	Port    int      `json:"port" validate:"min=1,max=65535"`
+	Name    string   `json:"name" validate:"omitempty,alphanum,max=8"`
+	Mode    string   `json:"mode" validate:"oneof=dev prod"`
+	URL     string   `json:"url" validate:"omitempty,url"`
+	Admin   string   `json:"admin" validate:"omitempty,email"`
+	Timeout Duration `json:"timeout" validate:"gte=1s"`
+	Hosts   []string `json:"hosts" validate:"omitempty,min=2"`
+	Ratio   *float64 `json:"ratio" validate:"omitempty,gt=0,lte=1"`
+	Workers uint     `json:"workers" validate:"lte=16"`
+	Metrics struct {
+		Enabled bool   `json:"enabled"`
+		Addr    string `json:"addr" validate:"required,hostname_port"`
+	} `json:"metrics" enabledBy:"metrics.enabled"`
+}
+
+func TestValidationTags(t *testing.T) {
+	args := []string{"-port", "80", "-mode", "dev", "-timeout", "5s"}
+
+	// Case 1: disabled by default
+	gf := New(ContinueOnError)
+	err := gf.ParseWithArgs(&validationStruct{}, []string{})
+	assert.NoError(t, err)
+
+	// Case 2: all the rules, the inactive sections being skipped
+	for extra, expected := range map[string]string{
+		"":                                   "",
+		"-port 0":                            "invalid field Port (flag -port, env PORT, key port): must be at least 1",
+		"-port 70000":                        "invalid field Port (flag -port, env PORT, key port): must be at most 65535",
+		"-name ab-c":                         "invalid field Name (flag -name, env NAME, key name): must contain only letters and digits",
+		"-name abcdefghi":                    "invalid field Name (flag -name, env NAME, key name): must be at most 8 characters long",
+		"-mode test":                         "invalid field Mode (flag -mode, env MODE, key mode): must be one of dev, prod",
+		"-url https://example.com/x":         "",
+		"-url example":                       "invalid field URL (flag -url, env URL, key url): must be a URL",
+		"-admin ops@example.com":             "",
+		"-admin ops":                         "invalid field Admin (flag -admin, env ADMIN, key admin): must be an email address",
+		"-timeout 10ms":                      "invalid field Timeout (flag -timeout, env TIMEOUT, key timeout): must be greater than or equal to 1s",
+		"-hosts a":                           "invalid field Hosts (flag -hosts, env HOSTS, key hosts): must have at least 2 items",
+		"-hosts a,b":                         "",
+		"-ratio 0.5":                         "",
+		"-ratio 0":                           "invalid field Ratio (flag -ratio, env RATIO, key ratio): must be greater than 0",
+		"-workers 17":                        "invalid field Workers (flag -workers, env WORKERS, key workers): must be less than or equal to 16",
+		"-metrics-enabled":                   "invalid field Metrics.Addr (flag -metrics-addr, env METRICS_ADDR, key metrics.addr): is required",
+		"-metrics-enabled -metrics-addr :80": "",
+		"-metrics-enabled -metrics-addr x":   "invalid field Metrics.Addr (flag -metrics-addr, env METRICS_ADDR, key metrics.addr): must be a host:port",
+		"-port 0 -mode x":                    "invalid fields Port (flag -port, env PORT, key port): must be at least 1; Mode (flag -mode, env MODE, key mode): must be one of dev, prod",
+	} {
+		gf := New(ContinueOnError)
+		gf.EnableValidation()
+		err := gf.ParseWithArgs(&validationStruct{}, append(args[:len(args):len(args)], strings.Fields(extra)...))
+		if expected == "" {
+			assert.NoError(t, err, extra)
+		} else {
+			assert.EqualError(t, err, expected, extra)
+		}
+	}
+
+	// Case 3: invalid rules
+	for rules, expected := range map[string]string{
+		"min=x":   "invalid parameter 'x' of rule min",
+		"uuid":    "unknown rule uuid",
+		"min=1s":  "invalid parameter '1s' of rule min",
+		"dive,ip": "unknown rule dive",
+	} {
+		_, err := checkRules(reflect.ValueOf(1), rules)
+		assert.EqualError(t, err, expected, rules)
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// WatchableSource is a Source able to notify its changes.
+type WatchableSource interface {
+	Source
+	// Watch blocks until the source content changes or ctx is done.
+	Watch(ctx context.Context) error
+}
+
+// Reload recomputes the configuration from all the sources, swaps it in and notifies
+// the listeners. On error, the current configuration is left untouched.
+func Reload() error { return gf.Reload() }
+
+// Reload recomputes the configuration from all the sources, swaps it in and notifies
+// the listeners. On error, the current configuration is left untouched.
+func (gf *Gofig) Reload() error {
+	return gf.update(gf.checkParsed)
+}
+
+// checkParsed is an update change checking that the configuration was parsed.
+func (gf *Gofig) checkParsed() (func(), error) {
+	if gf.target == nil {
+		return nil, errNotParsed
+	}
+	return func() {}, nil
+}
+
+// settleCheckInterval is the interval at which a batched reload checks whether the
+// sources still being updated have settled.
+const settleCheckInterval = 10 * time.Millisecond
+
+// settler is implemented by the sources able to tell that they are being updated, e.g.
+// a file detected as changed but not stable yet.
+type settler interface {
+	settling() bool
+}
+
+// SetWatchQuietPeriod sets the quiet period used to batch the changes of the watched
+// sources: the configuration is reloaded once no source reported a change for d, and
+// none is still being updated, so that an update spanning several sources (e.g. a base
+// file and an overlay file) is applied at once.
+func SetWatchQuietPeriod(d time.Duration) { gf.SetWatchQuietPeriod(d) }
+
+// SetWatchQuietPeriod sets the quiet period used to batch the changes of the watched
+// sources: the configuration is reloaded once no source reported a change for d, and
+// none is still being updated, so that an update spanning several sources (e.g. a base
+// file and an overlay file) is applied at once.
+func (gf *Gofig) SetWatchQuietPeriod(d time.Duration) {
+	gf.watchQuietPeriod = d
+}
+
+// Watch watches the sources implementing WatchableSource and reloads the configuration
+// each time one of them changes, notifying the listeners if the configuration changed.
+// It blocks until ctx is done, returning nil, or until a watch or a reload fails,
+// returning the error.
+func Watch(ctx context.Context) error { return gf.Watch(ctx) }
+
+// Watch watches the sources implementing WatchableSource and reloads the configuration
+// each time one of them changes, notifying the listeners if the configuration changed.
+// It blocks until ctx is done, returning nil, or until a watch or a reload fails,
+// returning the error.
+func (gf *Gofig) Watch(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var once sync.Once
+	var watchErr error
+	fail := func(err error) {
+		once.Do(func() {
+			watchErr = err
+			cancel()
+		})
+	}
+
+	var wg sync.WaitGroup
+	watching := 0
+	var settlers []settler
+	changed := make(chan struct{}, 1)
+	for i, src := range gf.sources {
+		ws, ok := src.(WatchableSource)
+		if !ok {
+			continue
+		}
+		watching++
+		if st, ok := src.(settler); ok {
+			settlers = append(settlers, st)
+		}
+		wg.Add(1)
+		go func(i int, ws WatchableSource) {
+			defer wg.Done()
+			for {
+				err := ws.Watch(ctx)
+				if ctx.Err() != nil {
+					return
+				}
+				if err != nil {
+					gf.recordError(i, err)
+					fail(err)
+					return
+				}
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			}
+		}(i, ws)
+	}
+	if watching == 0 {
+		return nil
+	}
+
+	// reload once the changes are settled
+	var reload <-chan time.Time
+	timer := time.NewTimer(0)
+	<-timer.C
+	defer timer.Stop()
+	for done := false; !done; {
+		select {
+		case <-changed:
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(gf.watchQuietPeriod)
+			reload = timer.C
+		case <-reload:
+			if isSettling(settlers) {
+				timer.Reset(settleCheckInterval)
+				continue
+			}
+			reload = nil
+			err := gf.updateNotify(gf.checkParsed, false)
+			if err != nil {
+				fail(err)
+			}
+		case <-ctx.Done():
+			done = true
+		}
+	}
+	wg.Wait()
+	return watchErr
+}
+
+// isSettling returns whether one of the sources is still being updated.
+func isSettling(settlers []settler) bool {
+	for _, st := range settlers {
+		if st.settling() {
+			return true
+		}
+	}
+	return false
+}
+
+// Watcher is a running watch of the configuration sources, started by StartWatching.
+type Watcher struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	errs   chan error
+	err    error
+}
+
+// StartWatching starts watching the sources implementing WatchableSource in the
+// background, reloading the configuration each time one of them changes, until ctx is
+// done, Stop is called or a watch or a reload fails. The configuration must be parsed
+// first.
+func StartWatching(ctx context.Context) (*Watcher, error) { return gf.StartWatching(ctx) }
+
+// StartWatching starts watching the sources implementing WatchableSource in the
+// background, reloading the configuration each time one of them changes, until ctx is
+// done, Stop is called or a watch or a reload fails. The configuration must be parsed
+// first.
+func (gf *Gofig) StartWatching(ctx context.Context) (*Watcher, error) {
+	gf.mu.Lock()
+	parsed := gf.target != nil
+	gf.mu.Unlock()
+	if !parsed {
+		return nil, errNotParsed
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	w := &Watcher{
+		cancel: cancel,
+		done:   make(chan struct{}),
+		errs:   make(chan error, 1),
+	}
+	go func() {
+		defer close(w.done)
+		defer close(w.errs)
+		w.err = gf.Watch(ctx)
+		if w.err != nil {
+			w.errs <- w.err
+		}
+	}()
+	return w, nil
+}
+
+// Errors returns a channel receiving the error which stopped the watcher, if any. It is
+// closed once the watcher is stopped.
+func (w *Watcher) Errors() <-chan error {
+	return w.errs
+}
+
+// Done returns a channel closed once the watcher is stopped.
+func (w *Watcher) Done() <-chan struct{} {
+	return w.done
+}
+
+// Stop stops the watcher and waits for its goroutines to return. It returns the error
+// which stopped the watcher before, if any.
+func (w *Watcher) Stop() error {
+	w.cancel()
+	<-w.done
+	return w.err
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// chanSource is a watchable source notified of its changes through a channel
+type chanSource struct {
+	values  chan string
+	current string
+}
+
+func (s *chanSource) Name() string { return "chan" }
+
+func (s *chanSource) Load(ctx context.Context) (*Document, error) {
+	return &Document{Values: map[string]string{"str": s.current}}, nil
+}
+
+func (s *chanSource) Watch(ctx context.Context) error {
+	select {
+	case value := <-s.values:
+		if value == "" {
+			return errors.New("watch failed")
+		}
+		s.current = value
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestStartWatching(t *testing.T) {
+	// Case 1: not parsed
+	gf := New(ContinueOnError)
+	_, err := gf.StartWatching(context.Background())
+	assert.Equal(t, errNotParsed, err)
+
+	// Case 2: reloads until stopped
+	src := &chanSource{values: make(chan string), current: "first"}
+	s := buildTestStruct()
+	gf = New(ContinueOnError)
+	gf.AddSource(src)
+	err = gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "first", s.Str)
+
+	changed := make(chan struct{}, 1)
+	gf.OnChange(func() { changed <- struct{}{} })
+	w, err := gf.StartWatching(context.Background())
+	assert.NoError(t, err)
+
+	src.values <- "second"
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("configuration not reloaded")
+	}
+	assert.Equal(t, "second", s.Str)
+
+	assert.NoError(t, w.Stop())
+	_, open := <-w.Errors()
+	assert.False(t, open)
+	assert.NoError(t, w.Stop())
+
+	// Case 3: a failed watch stops the watcher
+	w, err = gf.StartWatching(context.Background())
+	assert.NoError(t, err)
+	src.values <- ""
+	select {
+	case err = <-w.Errors():
+		assert.EqualError(t, err, "watch failed")
+	case <-time.After(5 * time.Second):
+		t.Fatal("watcher not stopped")
+	}
+	<-w.Done()
+	assert.EqualError(t, w.Stop(), "watch failed")
+}
+
+func TestSetWatchQuietPeriod(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.yaml")
+	overlay := filepath.Join(dir, "overlay.yaml")
+	assert.NoError(t, os.WriteFile(base, []byte("str: base1\n"), 0644))
+	assert.NoError(t, os.WriteFile(overlay, []byte("int: 1\n"), 0644))
+
+	s := buildTestStruct()
+	gf := New(ContinueOnError)
+	gf.SetWatchQuietPeriod(50 * time.Millisecond)
+	for _, path := range []string{base, overlay} {
+		src := NewFileSource(path)
+		src.PollInterval = 5 * time.Millisecond
+		src.QuietPeriod = 20 * time.Millisecond
+		gf.AddSource(src)
+	}
+	err := gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+
+	var mu sync.Mutex
+	var seen []string
+	changed := make(chan struct{}, 10)
+	gf.OnChange(func() {
+		mu.Lock()
+		seen = append(seen, fmt.Sprintf("%v %v", s.Str, s.Int))
+		mu.Unlock()
+		changed <- struct{}{}
+	})
+	w, err := gf.StartWatching(context.Background())
+	assert.NoError(t, err)
+
+	// the overlay is missing while the base is rewritten, then written back
+	assert.NoError(t, os.Remove(overlay))
+	assert.NoError(t, os.WriteFile(base, []byte("str: base2\n"), 0644))
+	time.Sleep(150 * time.Millisecond)
+	assert.NoError(t, os.WriteFile(overlay, []byte("int: 2\n"), 0644))
+
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("configuration not reloaded")
+	}
+	time.Sleep(100 * time.Millisecond)
+	assert.NoError(t, w.Stop())
+	assert.Equal(t, []string{"base2 2"}, seen)
+}
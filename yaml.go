@@ -0,0 +1,73 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// position is the location of a key in a config document.
+type position struct {
+	Line   int
+	Column int
+}
+
+// yamlPositions returns the position of the keys of a YAML document by dot-separated,
+// lowercased key path. Keys merged with << or set through an alias are located at their
+// definition.
+func yamlPositions(data []byte) (map[string]position, error) {
+	var root yaml.Node
+	err := yaml.Unmarshal(data, &root)
+	if err != nil {
+		return nil, err
+	}
+	positions := make(map[string]position)
+	collectYAMLPositions(&root, "", positions, false)
+	return positions, nil
+}
+
+// collectYAMLPositions adds the positions of the keys of a YAML node to positions. The
+// keys of merged nodes don't override the existing keys, merges being shallow.
+func collectYAMLPositions(node *yaml.Node, path string, positions map[string]position, merged bool) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			collectYAMLPositions(child, path, positions, merged)
+		}
+	case yaml.AliasNode:
+		collectYAMLPositions(node.Alias, path, positions, merged)
+	case yaml.MappingNode:
+		// explicit keys take precedence over the merged ones, the first merged node
+		// over the next ones
+		var merges []*yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, val := node.Content[i], node.Content[i+1]
+			if key.Tag == "!!merge" {
+				if val.Kind == yaml.SequenceNode {
+					merges = append(merges, val.Content...)
+				} else {
+					merges = append(merges, val)
+				}
+				continue
+			}
+
+			keyPath := strings.ToLower(key.Value)
+			if path != "" {
+				keyPath = path + "." + keyPath
+			}
+			if _, ok := positions[keyPath]; ok && merged {
+				continue
+			}
+			positions[keyPath] = position{Line: key.Line, Column: key.Column}
+			collectYAMLPositions(val, keyPath, positions, merged)
+		}
+		for _, m := range merges {
+			collectYAMLPositions(m, path, positions, true)
+		}
+	}
+}
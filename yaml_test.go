@@ -0,0 +1,92 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// endpoint is set from a "host:port" scalar or a {host, port} mapping
+type endpoint struct {
+	Host string
+	Port int
+}
+
+func (e *endpoint) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.MappingNode {
+		type plain endpoint
+		return node.Decode((*plain)(e))
+	}
+	i := strings.LastIndexByte(node.Value, ':')
+	if i < 0 {
+		return fmt.Errorf("line %v: invalid endpoint %v", node.Line, node.Value)
+	}
+	port, err := strconv.Atoi(node.Value[i+1:])
+	if err != nil {
+		return fmt.Errorf("line %v: invalid endpoint port %v", node.Line, node.Value)
+	}
+	e.Host, e.Port = node.Value[:i], port
+	return nil
+}
+
+func TestYAMLUnmarshaler(t *testing.T) {
+	var s struct {
+		Primary endpoint
+		Replica endpoint
+		Port    int
+	}
+
+	// Case 1: custom unmarshalers
+	err := decodeConfig(strings.NewReader("primary: db:5432\nreplica: {host: replica, port: 5433}\n"), yamlExtention, &s)
+	assert.NoError(t, err)
+	assert.Equal(t, endpoint{Host: "db", Port: 5432}, s.Primary)
+	assert.Equal(t, endpoint{Host: "replica", Port: 5433}, s.Replica)
+
+	// Case 2: errors hold the line of the node
+	err = decodeConfig(strings.NewReader("primary: db:5432\nreplica: replica\n"), yamlExtention, &s)
+	assert.EqualError(t, err, "line 2: invalid endpoint replica")
+	err = decodeConfig(strings.NewReader("primary: db:5432\n\nport: abc\n"), yamlExtention, &s)
+	assert.EqualError(t, err, "yaml: unmarshal errors:\n  line 3: cannot unmarshal !!str `abc` into int")
+}
+
+func TestYAMLPositions(t *testing.T) {
+	positions, err := yamlPositions([]byte(`x-defaults: &defaults
+  host: localhost
+  port: 80
+.other: &other
+  port: 90
+  db: {name: other}
+primary:
+  <<: [*other, *defaults]
+  Host: primary
+  db:
+    user: app
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]position{
+		"x-defaults":      {Line: 1, Column: 1},
+		"x-defaults.host": {Line: 2, Column: 3},
+		"x-defaults.port": {Line: 3, Column: 3},
+		".other":          {Line: 4, Column: 1},
+		".other.port":     {Line: 5, Column: 3},
+		".other.db":       {Line: 6, Column: 3},
+		".other.db.name":  {Line: 6, Column: 8},
+		"primary":         {Line: 7, Column: 1},
+		"primary.host":    {Line: 9, Column: 3},
+		"primary.port":    {Line: 5, Column: 3},
+		"primary.db":      {Line: 10, Column: 3},
+		"primary.db.user": {Line: 11, Column: 5},
+	}, positions)
+
+	_, err = yamlPositions([]byte("a: [\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"path"
+	"strings"
+)
+
+// ZookeeperClient is the subset of a Zookeeper client used by ZookeeperSource, e.g. a
+// thin adapter around github.com/go-zookeeper/zk.
+type ZookeeperClient interface {
+	// Get returns the data of the node at path.
+	Get(path string) ([]byte, error)
+	// Children returns the names of the children of the node at path.
+	Children(path string) ([]string, error)
+	// Watch blocks until the node at path or one of its descendants changes, or ctx is done.
+	Watch(ctx context.Context, path string) error
+}
+
+// ZookeeperSource loads the configuration from Zookeeper, either as a config document
+// stored in a single node (if Format is set) or as a tree of nodes whose leaves map onto
+// the fields: the node "<Path>/db/port" sets the field at the key path "db.port".
+type ZookeeperSource struct {
+	// Client is the Zookeeper client.
+	Client ZookeeperClient
+	// Path of the config node or of the root of the config tree.
+	Path string
+	// Format of the config document stored at Path: "json", "toml" or "yaml". If
+	// empty, Path is the root of a tree of nodes.
+	Format string
+}
+
+// NewZookeeperSource returns a Source loading the configuration from Zookeeper.
+func NewZookeeperSource(client ZookeeperClient, path string, format string) *ZookeeperSource {
+	return &ZookeeperSource{Client: client, Path: path, Format: format}
+}
+
+// Name returns the Zookeeper path.
+func (s *ZookeeperSource) Name() string {
+	return "zookeeper " + s.Path
+}
+
+// Load reads the config node or tree.
+func (s *ZookeeperSource) Load(ctx context.Context) (*Document, error) {
+	if s.Format != "" {
+		data, err := s.Client.Get(s.Path)
+		if err != nil {
+			return nil, err
+		}
+		return &Document{Format: s.Format, Data: data}, nil
+	}
+
+	values := make(map[string]string)
+	err := s.loadTree(ctx, s.Path, nil, values)
+	if err != nil {
+		return nil, err
+	}
+	return &Document{Values: values}, nil
+}
+
+// loadTree recursively reads the leaves below the node at p.
+func (s *ZookeeperSource) loadTree(ctx context.Context, p string, keys []string, values map[string]string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	children, err := s.Client.Children(p)
+	if err != nil {
+		return err
+	}
+	if len(children) == 0 && len(keys) > 0 {
+		data, err := s.Client.Get(p)
+		if err != nil {
+			return err
+		}
+		values[strings.Join(keys, ".")] = string(data)
+		return nil
+	}
+
+	for _, child := range children {
+		err = s.loadTree(ctx, path.Join(p, child), append(keys[:len(keys):len(keys)], child), values)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Watch blocks until the config node or tree changes.
+func (s *ZookeeperSource) Watch(ctx context.Context) error {
+	return s.Client.Watch(ctx, s.Path)
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2019 Curvegrid Inc.
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package gofig
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeZookeeper is an in-memory Zookeeper tree
+type fakeZookeeper struct {
+	mu      sync.Mutex
+	nodes   map[string]string
+	changes chan struct{}
+}
+
+func newFakeZookeeper(nodes map[string]string) *fakeZookeeper {
+	return &fakeZookeeper{nodes: nodes, changes: make(chan struct{})}
+}
+
+func (z *fakeZookeeper) Get(p string) ([]byte, error) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	data, ok := z.nodes[p]
+	if !ok {
+		return nil, errors.New("node does not exist")
+	}
+	return []byte(data), nil
+}
+
+func (z *fakeZookeeper) Children(p string) ([]string, error) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	seen := make(map[string]bool)
+	var children []string
+	for node := range z.nodes {
+		if strings.HasPrefix(node, p+"/") {
+			child := strings.Split(node[len(p)+1:], "/")[0]
+			if !seen[child] {
+				seen[child] = true
+				children = append(children, child)
+			}
+		}
+	}
+	sort.Strings(children)
+	return children, nil
+}
+
+func (z *fakeZookeeper) Watch(ctx context.Context, p string) error {
+	select {
+	case <-z.changes:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (z *fakeZookeeper) set(p string, data string) {
+	z.mu.Lock()
+	z.nodes[p] = data
+	z.mu.Unlock()
+	z.changes <- struct{}{}
+}
+
+func TestZookeeperSource(t *testing.T) {
+	zk := newFakeZookeeper(map[string]string{
+		"/app/config.yaml":  "str: zookeeper",
+		"/app/tree/int":     "5",
+		"/app/tree/sub/str": "renamed-zookeeper",
+	})
+
+	// Case 1: config document
+	s := buildTestStruct()
+	gf := New(ContinueOnError)
+	gf.AddSource(NewZookeeperSource(zk, "/app/config.yaml", "yaml"))
+	err := gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "zookeeper", s.Str)
+
+	// Case 2: tree of nodes
+	s = buildTestStruct()
+	gf = New(ContinueOnError)
+	gf.AddSource(NewZookeeperSource(zk, "/app/tree", ""))
+	err = gf.ParseWithArgs(s, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, s.Int)
+	assert.Equal(t, "renamed-zookeeper", s.Sub.RenamedStr)
+
+	// Case 3: watched tree
+	changed := make(chan struct{}, 1)
+	gf.OnChange(func() { changed <- struct{}{} })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- gf.Watch(ctx) }()
+
+	zk.set("/app/tree/int", "6")
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("configuration not reloaded")
+	}
+	assert.Equal(t, 6, s.Int)
+
+	cancel()
+	assert.NoError(t, <-done)
+}